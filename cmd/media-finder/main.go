@@ -1,13 +1,19 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
+	"github.com/mmenanno/media-usage-finder/internal/api"
+	"github.com/mmenanno/media-usage-finder/internal/auth"
 	"github.com/mmenanno/media-usage-finder/internal/config"
 	"github.com/mmenanno/media-usage-finder/internal/constants"
 	"github.com/mmenanno/media-usage-finder/internal/database"
@@ -75,6 +81,11 @@ orphaned files and optimizes storage through hardlink detection.`,
 				return fmt.Errorf("failed to open database: %w", err)
 			}
 
+			// Register service providers so usage records can reference them
+			if err := registerServices(db); err != nil {
+				return fmt.Errorf("failed to register services: %w", err)
+			}
+
 			return nil
 		},
 		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
@@ -124,6 +135,8 @@ orphaned files and optimizes storage through hardlink detection.`,
 		RunE:  runExport,
 	}
 	exportCmd.Flags().BoolP("orphaned", "o", false, "Export only orphaned files")
+	exportCmd.Flags().Int("orphaned-days", 0, "Export only files orphaned for at least this many days")
+	exportCmd.Flags().Bool("safe-to-delete", false, "Export torrent files that finished seeding and no media server uses")
 	exportCmd.Flags().StringP("format", "f", "json", "Output format (json, csv)")
 	exportCmd.Flags().StringP("output", "O", "", "Output file (default: stdout)")
 
@@ -159,13 +172,52 @@ orphaned files and optimizes storage through hardlink detection.`,
 
 	configCmd.AddCommand(configValidateCmd, configShowCmd)
 
-	rootCmd.AddCommand(serveCmd, scanCmd, diskScanCmd, statsCmd, exportCmd, deleteCmd, configCmd)
+	// User command (account recovery when locked out of the web UI)
+	userCmd := &cobra.Command{
+		Use:   "user",
+		Short: "Web UI user management",
+	}
+
+	userCreateCmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a web UI user",
+		RunE:  runUserCreate,
+	}
+	userCreateCmd.Flags().StringP("username", "u", "", "Username")
+	userCreateCmd.Flags().StringP("password", "p", "", "Password")
+	userCreateCmd.Flags().StringP("role", "r", auth.RoleAdmin, "Role (admin, user)")
+
+	userResetPasswordCmd := &cobra.Command{
+		Use:   "reset-password",
+		Short: "Reset a web UI user's password and sign out their sessions",
+		RunE:  runUserResetPassword,
+	}
+	userResetPasswordCmd.Flags().StringP("username", "u", "", "Username")
+	userResetPasswordCmd.Flags().StringP("password", "p", "", "New password")
+
+	userCmd.AddCommand(userCreateCmd, userResetPasswordCmd)
+
+	rootCmd.AddCommand(serveCmd, scanCmd, diskScanCmd, statsCmd, exportCmd, deleteCmd, configCmd, userCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
 }
 
+// registerServices records every registered service provider in the database
+func registerServices(db *database.DB) error {
+	providers := api.Providers()
+	services := make([]database.Service, 0, len(providers))
+	for _, p := range providers {
+		services = append(services, database.Service{
+			Name:        p.Name(),
+			DisplayName: p.DisplayName(),
+			Type:        p.Type(),
+		})
+	}
+	return db.RegisterServices(services)
+}
+
 func runServe(cmd *cobra.Command, args []string) error {
 	srv := server.NewServer(db, cfg, Version)
 
@@ -261,29 +313,21 @@ func runStats(cmd *cobra.Command, args []string) error {
 
 func runExport(cmd *cobra.Command, args []string) error {
 	orphaned, _ := cmd.Flags().GetBool("orphaned")
+	orphanedDays, _ := cmd.Flags().GetInt("orphaned-days")
+	safeToDelete, _ := cmd.Flags().GetBool("safe-to-delete")
 	format, _ := cmd.Flags().GetString("format")
 	output, _ := cmd.Flags().GetString("output")
 
-	files, _, err := db.ListFiles(orphaned, nil, "any", false, nil, nil, constants.MaxExportFiles, 0, "path", "asc")
-	if err != nil {
-		return fmt.Errorf("failed to list files: %w", err)
-	}
-
 	var data []byte
-	switch format {
-	case "json":
-		data, err = json.MarshalIndent(files, "", "  ")
-	case "csv":
-		data = []byte("path,size,is_orphaned\n")
-		for _, file := range files {
-			data = append(data, []byte(fmt.Sprintf("%s,%d,%v\n", file.Path, file.Size, file.IsOrphaned))...)
-		}
-	default:
-		return fmt.Errorf("unsupported format: %s", format)
+	var count int
+	var err error
+	if safeToDelete {
+		data, count, err = exportSafeToDeleteFiles(format)
+	} else {
+		data, count, err = exportFiles(orphaned, orphanedDays, format)
 	}
-
 	if err != nil {
-		return fmt.Errorf("failed to marshal data: %w", err)
+		return err
 	}
 
 	if output != "" {
@@ -296,7 +340,7 @@ func runExport(cmd *cobra.Command, args []string) error {
 		if err := os.WriteFile(output, data, 0644); err != nil {
 			return fmt.Errorf("failed to write output file: %w", err)
 		}
-		log.Printf("Exported %d files to %s", len(files), output)
+		log.Printf("Exported %d files to %s", count, output)
 	} else {
 		fmt.Println(string(data))
 	}
@@ -304,6 +348,89 @@ func runExport(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// exportFiles renders all files, or only orphaned ones, in the given format
+// orphanedDays limits the export to files orphaned for at least that many days
+func exportFiles(orphaned bool, orphanedDays int, format string) ([]byte, int, error) {
+	files, _, err := db.ListFiles(orphaned, orphanedDays, nil, "any", false, false, nil, nil, constants.MaxExportFiles, 0, "path", "asc")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	var data []byte
+	switch format {
+	case "json":
+		data, err = json.MarshalIndent(files, "", "  ")
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"path", "size", "is_orphaned", "orphaned_since", "last_used_by"})
+		for _, file := range files {
+			orphanedSince := ""
+			if file.OrphanedSince != nil {
+				orphanedSince = file.OrphanedSince.Format(time.RFC3339)
+			}
+			w.Write([]string{
+				file.Path,
+				strconv.FormatInt(file.Size, 10),
+				strconv.FormatBool(file.IsOrphaned),
+				orphanedSince,
+				file.LastUsedBy,
+			})
+		}
+		w.Flush()
+		data, err = buf.Bytes(), w.Error()
+	default:
+		return nil, 0, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	return data, len(files), nil
+}
+
+// exportSafeToDeleteFiles renders torrent files that have finished seeding and no media server uses
+func exportSafeToDeleteFiles(format string) ([]byte, int, error) {
+	files, err := db.ListSafeToDeleteFiles(constants.MaxExportFiles, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var data []byte
+	switch format {
+	case "json":
+		data, err = json.MarshalIndent(files, "", "  ")
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"path", "size", "service", "torrent_name", "torrent_hash", "reason", "ratio", "seeding_time", "tracker_status"})
+		for _, file := range files {
+			w.Write([]string{
+				file.Path,
+				strconv.FormatInt(file.Size, 10),
+				file.Service,
+				file.TorrentName,
+				file.TorrentHash,
+				file.Reason,
+				strconv.FormatFloat(file.Ratio, 'f', 2, 64),
+				strconv.FormatInt(file.SeedingTime, 10),
+				file.TrackerStatus,
+			})
+		}
+		w.Flush()
+		data, err = buf.Bytes(), w.Error()
+	default:
+		return nil, 0, fmt.Errorf("unsupported format: %s", format)
+	}
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to marshal data: %w", err)
+	}
+
+	return data, len(files), nil
+}
+
 func runConfigValidate(cmd *cobra.Command, args []string) error {
 	if err := cfg.Validate(); err != nil {
 		fmt.Printf("Configuration is INVALID: %v\n", err)
@@ -324,6 +451,66 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runUserCreate(cmd *cobra.Command, args []string) error {
+	username, _ := cmd.Flags().GetString("username")
+	password, _ := cmd.Flags().GetString("password")
+	role, _ := cmd.Flags().GetString("role")
+
+	if username == "" || password == "" {
+		return fmt.Errorf("must specify --username and --password")
+	}
+	if len(password) < auth.MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", auth.MinPasswordLength)
+	}
+	if !auth.ValidRole(role) {
+		return fmt.Errorf("invalid role: %s", role)
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.CreateUser(username, hash, role); err != nil {
+		return err
+	}
+
+	log.Printf("Created %s user: %s", role, username)
+	return nil
+}
+
+func runUserResetPassword(cmd *cobra.Command, args []string) error {
+	username, _ := cmd.Flags().GetString("username")
+	password, _ := cmd.Flags().GetString("password")
+
+	if username == "" || password == "" {
+		return fmt.Errorf("must specify --username and --password")
+	}
+	if len(password) < auth.MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", auth.MinPasswordLength)
+	}
+
+	user, err := db.GetUserByUsername(username)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user not found: %s", username)
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	if err := db.UpdateUserPassword(user.ID, hash); err != nil {
+		return err
+	}
+
+	log.Printf("Password reset for user: %s", user.Username)
+	return nil
+}
+
 // Note: mark-rescan CLI command was removed in v0.58.0
 // The feature was changed to an active "rescan now" feature accessible via the web UI
 // Files are no longer marked for rescan but are instead rescanned immediately
@@ -361,7 +548,7 @@ func runDelete(cmd *cobra.Command, args []string) error {
 	}
 
 	// Delete orphaned files
-	files, _, err := db.ListFiles(true, nil, "any", false, nil, nil, constants.MaxExportFiles, 0, "path", "asc")
+	files, _, err := db.ListFiles(true, 0, nil, "any", false, false, nil, nil, constants.MaxExportFiles, 0, "path", "asc")
 	if err != nil {
 		return fmt.Errorf("failed to list orphaned files: %w", err)
 	}
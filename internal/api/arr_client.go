@@ -9,29 +9,43 @@ import (
 	"time"
 )
 
-// ArrClient is a generic client for Sonarr/Radarr (both use same API structure)
+// ArrClient is a generic client for the *arr apps (Sonarr, Radarr, Lidarr, Readarr all use the same API structure)
 type ArrClient struct {
-	baseURL  string
-	apiKey   string
-	client   *http.Client
-	appName  string
-	fileType string // "episode" or "movie"
+	baseURL    string
+	apiKey     string
+	client     *http.Client
+	appName    string
+	fileType   string // "episode", "movie", "track" or "book"
+	apiVersion string // "v3" for Sonarr/Radarr, "v1" for Lidarr/Readarr
 }
 
 // NewArrClient creates a new generic *arr API client
 func NewArrClient(baseURL, apiKey, appName, fileType string, timeout time.Duration) *ArrClient {
 	return &ArrClient{
-		baseURL:  baseURL,
-		apiKey:   apiKey,
-		client:   &http.Client{Timeout: timeout},
-		appName:  appName,
-		fileType: fileType,
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		client:     &http.Client{Timeout: timeout},
+		appName:    appName,
+		fileType:   fileType,
+		apiVersion: "v3",
 	}
 }
 
+// newArrClientV1 creates a generic *arr API client for apps on the v1 API (Lidarr, Readarr)
+func newArrClientV1(baseURL, apiKey, appName, fileType string, timeout time.Duration) *ArrClient {
+	client := NewArrClient(baseURL, apiKey, appName, fileType, timeout)
+	client.apiVersion = "v1"
+	return client
+}
+
+// apiPath returns the versioned API path for an endpoint (e.g. "/system/status" -> "/api/v3/system/status")
+func (a *ArrClient) apiPath(endpoint string) string {
+	return "/api/" + a.apiVersion + endpoint
+}
+
 // Test tests the connection
 func (a *ArrClient) Test() error {
-	req, err := http.NewRequest("GET", a.baseURL+"/api/v3/system/status", nil)
+	req, err := http.NewRequest("GET", a.baseURL+a.apiPath("/system/status"), nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request for %s at %s: %w. Check the URL format", a.appName, a.baseURL, err)
 	}
@@ -82,3 +96,33 @@ func (a *ArrClient) doRequest(ctx context.Context, endpoint string, result inter
 
 	return nil
 }
+
+// samplePaths collects up to limit file paths under pathPrefix, taking the first match from each
+// group (series, movie, artist or author) so the sample spans the library
+// endpoint returns the file listing endpoint for a group; groups that fail to load are skipped
+func (a *ArrClient) samplePaths(ctx context.Context, groupIDs []int64, endpoint func(id int64) string, pathPrefix string, limit int) ([]string, error) {
+	var paths []string
+	for _, id := range groupIDs {
+		if len(paths) >= limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var files []struct {
+			Path string `json:"path"`
+		}
+		if err := a.doRequest(ctx, endpoint(id), &files); err != nil {
+			continue
+		}
+
+		for _, f := range files {
+			if matchesPrefix(f.Path, pathPrefix) {
+				paths = append(paths, f.Path)
+				break
+			}
+		}
+	}
+	return paths, nil
+}
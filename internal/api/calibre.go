@@ -10,6 +10,8 @@ import (
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
 )
 
 // CalibreClient handles communication with Calibre via direct SQLite access
@@ -83,12 +85,13 @@ func (c *CalibreClient) Test() error {
 	return nil
 }
 
-// GetAllFiles retrieves all files tracked by Calibre
-func (c *CalibreClient) GetAllFiles(ctx context.Context) ([]CalibreFile, error) {
+// ForEachFilePage retrieves the files tracked by Calibre, handing them to fn in pages of
+// ServiceFilePageSize as the database rows are read. Returning an error from fn stops the listing
+func (c *CalibreClient) ForEachFilePage(ctx context.Context, fn func([]CalibreFile) error) error {
 	// Open database in read-only mode
 	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", c.dbPath))
 	if err != nil {
-		return nil, fmt.Errorf("failed to open Calibre database: %w", err)
+		return fmt.Errorf("failed to open Calibre database: %w", err)
 	}
 	defer db.Close()
 
@@ -116,18 +119,25 @@ func (c *CalibreClient) GetAllFiles(ctx context.Context) ([]CalibreFile, error)
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query Calibre database: %w", err)
+		return fmt.Errorf("failed to query Calibre database: %w", err)
 	}
 	defer rows.Close()
 
 	var files []CalibreFile
+	total := 0
+	flush := func() error {
+		total += len(files)
+		err := fn(files)
+		files = nil
+		return err
+	}
 	bookPaths := make(map[string]bool) // Track unique book paths for metadata files
 
 	for rows.Next() {
 		// Check for context cancellation
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		default:
 		}
 
@@ -144,7 +154,7 @@ func (c *CalibreClient) GetAllFiles(ctx context.Context) ([]CalibreFile, error)
 		)
 
 		if err := rows.Scan(&bookID, &bookPath, &title, &author, &series, &seriesIndex, &format, &name, &size); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		// Construct full file path: {library_path}/{book.path}/{name}.{format}
@@ -161,41 +171,21 @@ func (c *CalibreClient) GetAllFiles(ctx context.Context) ([]CalibreFile, error)
 			Format:      format,
 		})
 
-		// Track book path for metadata files
-		bookPaths[bookPath] = true
-	}
+		// Add per-book metadata files (cover.jpg, metadata.opf) with the book's first format
+		if !bookPaths[bookPath] {
+			bookPaths[bookPath] = true
+			files = append(files, calibreMetadataFiles(filepath.Join(c.libraryPath, bookPath))...)
+		}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+		if len(files) >= constants.ServiceFilePageSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
 	}
 
-	// Add per-book metadata files (cover.jpg, metadata.opf)
-	for bookPath := range bookPaths {
-		bookDir := filepath.Join(c.libraryPath, bookPath)
-
-		// Add cover.jpg
-		files = append(files, CalibreFile{
-			Path:        filepath.Join(bookDir, "cover.jpg"),
-			Size:        0, // Size not available without stat-ing
-			BookID:      0,
-			Title:       "Cover Image",
-			Author:      "",
-			Series:      "",
-			SeriesIndex: 0,
-			Format:      "jpg",
-		})
-
-		// Add metadata.opf
-		files = append(files, CalibreFile{
-			Path:        filepath.Join(bookDir, "metadata.opf"),
-			Size:        0,
-			BookID:      0,
-			Title:       "Metadata",
-			Author:      "",
-			Series:      "",
-			SeriesIndex: 0,
-			Format:      "opf",
-		})
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
 	}
 
 	// Add library-level metadata files
@@ -220,84 +210,131 @@ func (c *CalibreClient) GetAllFiles(ctx context.Context) ([]CalibreFile, error)
 		})
 	}
 
-	log.Printf("Total Calibre files found: %d (including metadata files)", len(files))
-	return files, nil
+	if err := flush(); err != nil {
+		return err
+	}
+
+	log.Printf("Total Calibre files found: %d (including metadata files)", total)
+	return nil
 }
 
-// GetSampleFile retrieves a single sample file from Calibre that matches the path prefix
-// This is optimized for path mapping validation - it stops as soon as it finds one matching file
-func (c *CalibreClient) GetSampleFile(pathPrefix string) (string, error) {
-	// Use background context (not cancellable)
-	ctx := context.Background()
+// calibreMetadataFiles returns the cover and metadata files Calibre keeps in a book's directory
+func calibreMetadataFiles(bookDir string) []CalibreFile {
+	return []CalibreFile{
+		{
+			Path:   filepath.Join(bookDir, "cover.jpg"),
+			Size:   0, // Size not available without stat-ing
+			Title:  "Cover Image",
+			Format: "jpg",
+		},
+		{
+			Path:   filepath.Join(bookDir, "metadata.opf"),
+			Title:  "Metadata",
+			Format: "opf",
+		},
+	}
+}
 
+// GetSampleFiles returns up to limit file paths under pathPrefix, one per book
+// This is optimized for path mapping validation and discovery - it stops once limit files are found
+func (c *CalibreClient) GetSampleFiles(ctx context.Context, pathPrefix string, limit int) ([]string, error) {
 	// Open database in read-only mode
 	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", c.dbPath))
 	if err != nil {
-		return "", fmt.Errorf("failed to open Calibre database: %w", err)
+		return nil, fmt.Errorf("failed to open Calibre database: %w", err)
 	}
 	defer db.Close()
 
 	// Set connection timeout
 	db.SetConnMaxLifetime(c.timeout)
 
-	// Query to get first file
+	// One format per book
 	query := `
 		SELECT
 			b.path,
-			d.format,
-			d.name
+			MIN(d.format),
+			MIN(d.name)
 		FROM books b
 		JOIN data d ON b.id = d.book
-		LIMIT 1
+		GROUP BY b.id
+		ORDER BY b.id
 	`
 
-	var (
-		bookPath string
-		format   string
-		name     string
-	)
-
-	err = db.QueryRowContext(ctx, query).Scan(&bookPath, &format, &name)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", nil // No files found
-		}
-		return "", fmt.Errorf("failed to query sample file: %w", err)
+		return nil, fmt.Errorf("failed to query sample files: %w", err)
 	}
+	defer rows.Close()
 
-	// Construct full file path
-	fullPath := filepath.Join(c.libraryPath, bookPath, fmt.Sprintf("%s.%s", name, strings.ToLower(format)))
-
-	// Check if it matches the prefix (if specified)
-	if pathPrefix != "" && !strings.HasPrefix(fullPath, pathPrefix) {
-		// Try to find one that matches
-		query = `
-			SELECT
-				b.path,
-				d.format,
-				d.name
-			FROM books b
-			JOIN data d ON b.id = d.book
-			LIMIT 10
-		`
-
-		rows, err := db.QueryContext(ctx, query)
-		if err != nil {
-			return "", fmt.Errorf("failed to query sample files: %w", err)
+	var paths []string
+	for rows.Next() && len(paths) < limit {
+		var bookPath, format, name string
+		if err := rows.Scan(&bookPath, &format, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
-		defer rows.Close()
-
-		for rows.Next() {
-			if err := rows.Scan(&bookPath, &format, &name); err != nil {
-				continue
-			}
 
-			testPath := filepath.Join(c.libraryPath, bookPath, fmt.Sprintf("%s.%s", name, strings.ToLower(format)))
-			if strings.HasPrefix(testPath, pathPrefix) {
-				return testPath, nil
-			}
+		// Construct full file path: {library_path}/{book.path}/{name}.{format}
+		if path := filepath.Join(c.libraryPath, bookPath, fmt.Sprintf("%s.%s", name, strings.ToLower(format))); matchesPrefix(path, pathPrefix) {
+			paths = append(paths, path)
 		}
 	}
 
-	return fullPath, nil
+	return paths, rows.Err()
+}
+
+// calibreProvider registers Calibre with the provider registry
+type calibreProvider struct{ providerInfo }
+
+func init() {
+	Register(&calibreProvider{providerInfo{
+		name:        "calibre",
+		displayName: "Calibre",
+		serviceType: ServiceTypeLibrary,
+		fields: []ConfigField{
+			{
+				Key: "library_path", Label: "Calibre Library Path", Kind: FieldPath, Required: true,
+				Get: func(s *config.Services) string { return s.Calibre.LibraryPath },
+				Set: func(s *config.Services, v string) { s.Calibre.LibraryPath = v },
+			},
+			{
+				Key: "db_path", Label: "Calibre Database Path", Kind: FieldPath, Required: true,
+				Get: func(s *config.Services) string { return s.Calibre.DBPath },
+				Set: func(s *config.Services, v string) { s.Calibre.DBPath = v },
+			},
+		},
+	}})
+}
+
+func (p *calibreProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return NewCalibreClient(cfg.Services.Calibre.LibraryPath, cfg.Services.Calibre.DBPath, timeout)
+}
+
+func (p *calibreProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.NewClient(cfg, timeout).Test()
+}
+
+func (p *calibreProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := NewCalibreClient(cfg.Services.Calibre.LibraryPath, cfg.Services.Calibre.DBPath, timeout)
+	return pageFiles(ctx, func(fn func([]CalibreFile) error) error {
+		return client.ForEachFilePage(ctx, fn)
+	}, func(f CalibreFile) ServiceFile {
+		return ServiceFile{
+			Path:    f.Path,
+			Size:    f.Size,
+			Group:   f.Title,
+			GroupID: fmt.Sprintf("%d", f.BookID),
+			Metadata: map[string]interface{}{
+				"book_id":      f.BookID,
+				"title":        f.Title,
+				"author":       f.Author,
+				"series":       f.Series,
+				"series_index": f.SeriesIndex,
+				"format":       f.Format,
+			},
+		}
+	}, page)
+}
+
+func (p *calibreProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	return NewCalibreClient(cfg.Services.Calibre.LibraryPath, cfg.Services.Calibre.DBPath, timeout).GetSampleFiles(ctx, pathPrefix, limit)
 }
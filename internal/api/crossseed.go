@@ -0,0 +1,96 @@
+package api
+
+import (
+	"sort"
+)
+
+// crossSeedKeys are the metadata keys kept for each torrent that shares a file
+var crossSeedKeys = []string{
+	"torrent_hash", "torrent_name", "category", "tags",
+	"tracker", "tracker_status", "ratio", "seeding_time", "state",
+}
+
+// MergeCrossSeeds combines download client files reported by several torrents at the same path
+// (cross-seeding) into one file, so every torrent is recorded on the file's single usage record.
+// Every file gets torrent_count, torrents (per-torrent summaries), trackers and categories metadata.
+// The remaining metadata comes from a torrent that still needs to seed when there is one,
+// so a file is only safe to delete once every torrent holding it is done
+func MergeCrossSeeds(files []ServiceFile) []ServiceFile {
+	var paths []string
+	byPath := make(map[string][]ServiceFile, len(files))
+	for _, f := range files {
+		if _, ok := byPath[f.Path]; !ok {
+			paths = append(paths, f.Path)
+		}
+		byPath[f.Path] = append(byPath[f.Path], f)
+	}
+
+	merged := make([]ServiceFile, 0, len(paths))
+	for _, path := range paths {
+		merged = append(merged, mergeTorrents(byPath[path]))
+	}
+	return merged
+}
+
+// mergeTorrents combines the files of every torrent holding the same path
+func mergeTorrents(files []ServiceFile) ServiceFile {
+	// Torrents that still need to seed come first so one of them describes the file
+	sort.SliceStable(files, func(i, j int) bool {
+		return SeedingDeleteReason(files[i].Metadata) == "" && SeedingDeleteReason(files[j].Metadata) != ""
+	})
+
+	merged := files[0]
+	metadata := make(map[string]interface{}, len(merged.Metadata)+4)
+	for key, value := range merged.Metadata {
+		metadata[key] = value
+	}
+
+	torrents := make([]map[string]interface{}, 0, len(files))
+	trackers := []string{}
+	categories := []string{}
+	for _, f := range files {
+		torrent := make(map[string]interface{}, len(crossSeedKeys))
+		for _, key := range crossSeedKeys {
+			if value, ok := f.Metadata[key]; ok {
+				torrent[key] = value
+			}
+		}
+		torrents = append(torrents, torrent)
+
+		trackers = appendUniqueString(trackers, f.Metadata["tracker"])
+		categories = appendUniqueString(categories, f.Metadata["category"])
+	}
+
+	metadata["torrent_count"] = len(files)
+	metadata["torrents"] = torrents
+	metadata["trackers"] = trackers
+	metadata["categories"] = categories
+	merged.Metadata = metadata
+	return merged
+}
+
+// appendUniqueString appends value if it is a non-empty string not already in values
+func appendUniqueString(values []string, value interface{}) []string {
+	s, _ := value.(string)
+	if s == "" {
+		return values
+	}
+	for _, existing := range values {
+		if existing == s {
+			return values
+		}
+	}
+	return append(values, s)
+}
+
+// TorrentCount returns how many torrents reference the file of a download client usage record
+// Records written before cross-seeds were merged count as a single torrent
+func TorrentCount(metadata map[string]interface{}) int {
+	switch count := metadata["torrent_count"].(type) {
+	case float64: // Decoded from JSON
+		return int(count)
+	case int:
+		return count
+	}
+	return 1
+}
@@ -0,0 +1,340 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+)
+
+// DelugeClient handles communication with the Deluge Web UI JSON-RPC API
+type DelugeClient struct {
+	baseURL   string
+	password  string
+	client    *http.Client
+	loginMu   sync.Mutex // Protects concurrent login attempts
+	requestID atomic.Int64
+}
+
+// DelugeFile represents a file in a Deluge torrent
+// The label comes from Deluge's Label plugin and is reported as the category
+type DelugeFile struct {
+	Path        string
+	Size        int64
+	TorrentHash string
+	TorrentName string
+	Label       string
+	Seeding     SeedingHealth
+}
+
+// NewDelugeClient creates a new Deluge Web UI client
+func NewDelugeClient(baseURL, password string, timeout time.Duration) *DelugeClient {
+	jar, _ := cookiejar.New(nil)
+
+	return &DelugeClient{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		password: password,
+		client: &http.Client{
+			Timeout: timeout,
+			Jar:     jar,
+		},
+	}
+}
+
+// login authenticates with the Web UI and makes sure it is connected to a daemon (thread-safe)
+func (d *DelugeClient) login(ctx context.Context) error {
+	d.loginMu.Lock()
+	defer d.loginMu.Unlock()
+
+	var ok bool
+	if err := d.call(ctx, "auth.login", []interface{}{d.password}, &ok); err != nil {
+		return fmt.Errorf("failed to login to Deluge: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("invalid password")
+	}
+
+	var connected bool
+	if err := d.call(ctx, "web.connected", []interface{}{}, &connected); err != nil {
+		return fmt.Errorf("failed to check Deluge daemon connection: %w", err)
+	}
+	if connected {
+		return nil
+	}
+
+	// The Web UI isn't attached to a daemon yet, connect it to the first configured host
+	var hosts [][]interface{}
+	if err := d.call(ctx, "web.get_hosts", []interface{}{}, &hosts); err != nil {
+		return fmt.Errorf("failed to list Deluge daemons: %w", err)
+	}
+	if len(hosts) == 0 || len(hosts[0]) == 0 {
+		return fmt.Errorf("Deluge Web UI has no daemon configured")
+	}
+
+	var ignored interface{}
+	if err := d.call(ctx, "web.connect", []interface{}{hosts[0][0]}, &ignored); err != nil {
+		return fmt.Errorf("failed to connect Deluge Web UI to daemon: %w", err)
+	}
+
+	return nil
+}
+
+// Test tests the connection to Deluge
+func (d *DelugeClient) Test() error {
+	if err := d.login(context.Background()); err != nil {
+		return fmt.Errorf("Deluge login failed at %s: %w. Check the URL and password", d.baseURL, err)
+	}
+
+	return nil
+}
+
+// ForEachFilePage retrieves the files of all torrents, handing each torrent's files to fn
+// core.get_torrents_status returns every torrent's file list in a single call, so this can't page
+// the request itself. Returning an error from fn stops the listing
+func (d *DelugeClient) ForEachFilePage(ctx context.Context, fn func([]DelugeFile) error) error {
+	if err := d.login(ctx); err != nil {
+		return err
+	}
+
+	torrents, err := d.getTorrents(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get torrents: %w", err)
+	}
+
+	for hash, torrent := range torrents {
+		seeding := torrent.seedingHealth()
+		files := make([]DelugeFile, 0, len(torrent.Files))
+		for _, f := range torrent.Files {
+			files = append(files, DelugeFile{
+				Path:        filepath.Join(torrent.SavePath, f.Path),
+				Size:        f.Size,
+				TorrentHash: hash,
+				TorrentName: torrent.Name,
+				Label:       torrent.Label,
+				Seeding:     seeding,
+			})
+		}
+		if err := fn(files); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetSampleFiles returns up to limit file paths under pathPrefix, one per torrent
+// This is used for path mapping validation and discovery
+func (d *DelugeClient) GetSampleFiles(ctx context.Context, pathPrefix string, limit int) ([]string, error) {
+	if err := d.login(ctx); err != nil {
+		return nil, err
+	}
+
+	torrents, err := d.getTorrents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrents: %w", err)
+	}
+
+	var paths []string
+	for _, torrent := range torrents {
+		if len(paths) >= limit {
+			break
+		}
+		for _, f := range torrent.Files {
+			if path := filepath.Join(torrent.SavePath, f.Path); matchesPrefix(path, pathPrefix) {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+type delugeTorrent struct {
+	Name          string  `json:"name"`
+	SavePath      string  `json:"save_path"`
+	Label         string  `json:"label"`
+	Ratio         float64 `json:"ratio"`
+	SeedingTime   int64   `json:"seeding_time"` // Seconds
+	State         string  `json:"state"`
+	TimeAdded     float64 `json:"time_added"`
+	TrackerHost   string  `json:"tracker_host"`
+	TrackerStatus string  `json:"tracker_status"` // e.g. "Announce OK" or "Error: unregistered torrent"
+	StopAtRatio   bool    `json:"stop_at_ratio"`
+	StopRatio     float64 `json:"stop_ratio"`
+	Files         []struct {
+		Path string `json:"path"`
+		Size int64  `json:"size"`
+	} `json:"files"`
+}
+
+// seedingHealth builds the torrent's seeding health
+// Deluge only has ratio goals, seeding time limits are a queue setting rather than per torrent
+func (t delugeTorrent) seedingHealth() SeedingHealth {
+	health := SeedingHealth{
+		Ratio:            t.Ratio,
+		SeedingTime:      t.SeedingTime,
+		State:            t.State,
+		AddedOn:          int64(t.TimeAdded),
+		RatioLimit:       -1,
+		SeedingTimeLimit: -1,
+	}
+	if t.StopAtRatio {
+		health.RatioLimit = t.StopRatio
+	}
+
+	// Deluge only reports the status of the tracker it last announced to
+	switch {
+	case t.TrackerStatus == "":
+		// Not announced yet
+	case strings.Contains(t.TrackerStatus, "Error"):
+		health.addTracker(t.TrackerHost, TrackerStatusNotWorking, t.TrackerStatus)
+	case strings.Contains(t.TrackerStatus, "Announce Sent"):
+		health.addTracker(t.TrackerHost, TrackerStatusUpdating, t.TrackerStatus)
+	default:
+		health.addTracker(t.TrackerHost, TrackerStatusWorking, t.TrackerStatus)
+	}
+
+	return health
+}
+
+func (d *DelugeClient) getTorrents(ctx context.Context) (map[string]delugeTorrent, error) {
+	var torrents map[string]delugeTorrent
+
+	params := []interface{}{
+		map[string]interface{}{}, // No filter, all torrents
+		[]string{
+			"name", "save_path", "label", "files",
+			"ratio", "seeding_time", "state", "time_added",
+			"tracker_host", "tracker_status", "stop_at_ratio", "stop_ratio",
+		},
+	}
+	if err := d.call(ctx, "core.get_torrents_status", params, &torrents); err != nil {
+		return nil, err
+	}
+
+	return torrents, nil
+}
+
+// call performs a JSON-RPC call against the Web UI
+func (d *DelugeClient) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"method": method,
+		"params": params,
+		"id":     d.requestID.Add(1),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.baseURL+"/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Deluge API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+			Code    int    `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("failed to parse Deluge response: %w", err)
+	}
+
+	if rpcResp.Error != nil {
+		return fmt.Errorf("Deluge %s failed: %s", method, rpcResp.Error.Message)
+	}
+
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("failed to parse Deluge %s result: %w", method, err)
+	}
+
+	return nil
+}
+
+// delugeProvider registers Deluge with the provider registry
+type delugeProvider struct{ providerInfo }
+
+func init() {
+	Register(&delugeProvider{providerInfo{
+		name:        "deluge",
+		displayName: "Deluge",
+		serviceType: ServiceTypeDownloadClient,
+		fields: []ConfigField{
+			{
+				Key: "url", Label: "Deluge Web UI URL", Kind: FieldURL, Required: true,
+				Get: func(s *config.Services) string { return s.Deluge.URL },
+				Set: func(s *config.Services, v string) { s.Deluge.URL = v },
+			},
+			{
+				Key: "password", Label: "Deluge Web UI Password", Kind: FieldSecret, Required: true,
+				Get: func(s *config.Services) string { return s.Deluge.Password },
+				Set: func(s *config.Services, v string) { s.Deluge.Password = v },
+			},
+		},
+	}})
+}
+
+func (p *delugeProvider) newClient(cfg *config.Config, timeout time.Duration) *DelugeClient {
+	return NewDelugeClient(cfg.Services.Deluge.URL, cfg.Services.Deluge.Password, timeout)
+}
+
+func (p *delugeProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return p.newClient(cfg, timeout)
+}
+
+func (p *delugeProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.newClient(cfg, timeout).Test()
+}
+
+func (p *delugeProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := p.newClient(cfg, timeout)
+	return pageFiles(ctx, func(fn func([]DelugeFile) error) error {
+		return client.ForEachFilePage(ctx, fn)
+	}, func(f DelugeFile) ServiceFile {
+		metadata := map[string]interface{}{
+			"torrent_hash": f.TorrentHash,
+			"torrent_name": f.TorrentName,
+			"category":     f.Label,
+			"tags":         "",
+		}
+		f.Seeding.addMetadata(metadata)
+
+		return ServiceFile{
+			Path:     f.Path,
+			Size:     f.Size,
+			Group:    f.TorrentName,
+			GroupID:  f.TorrentHash,
+			Metadata: metadata,
+		}
+	}, page)
+}
+
+func (p *delugeProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	return p.newClient(cfg, timeout).GetSampleFiles(ctx, pathPrefix, limit)
+}
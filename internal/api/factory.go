@@ -17,24 +17,13 @@ func NewClientFactory(cfg *config.Config) *ClientFactory {
 	return &ClientFactory{config: cfg}
 }
 
-// CreateClient creates a service client by name
+// CreateClient creates a service client by name using the provider registry
 func (f *ClientFactory) CreateClient(serviceName string, timeout time.Duration) (ServiceClient, error) {
-	switch serviceName {
-	case "plex":
-		return f.CreatePlexClient(timeout), nil
-	case "sonarr":
-		return f.CreateSonarrClient(timeout), nil
-	case "radarr":
-		return f.CreateRadarrClient(timeout), nil
-	case "qbittorrent":
-		return f.CreateQBittorrentClient(timeout), nil
-	case "stash":
-		return f.CreateStashClient(timeout), nil
-	case "calibre":
-		return f.CreateCalibreClient(timeout), nil
-	default:
+	provider := Lookup(serviceName)
+	if provider == nil {
 		return nil, fmt.Errorf("unknown service: %s", serviceName)
 	}
+	return provider.NewClient(f.config, timeout), nil
 }
 
 // CreatePlexClient creates a Plex API client
@@ -42,6 +31,16 @@ func (f *ClientFactory) CreatePlexClient(timeout time.Duration) *PlexClient {
 	return NewPlexClient(f.config.Services.Plex.URL, f.config.Services.Plex.Token, timeout)
 }
 
+// CreateJellyfinClient creates a Jellyfin API client
+func (f *ClientFactory) CreateJellyfinClient(timeout time.Duration) *JellyfinClient {
+	return NewJellyfinClient(f.config.Services.Jellyfin.URL, f.config.Services.Jellyfin.APIKey, "Jellyfin", timeout)
+}
+
+// CreateEmbyClient creates an Emby API client
+func (f *ClientFactory) CreateEmbyClient(timeout time.Duration) *JellyfinClient {
+	return NewJellyfinClient(f.config.Services.Emby.URL, f.config.Services.Emby.APIKey, "Emby", timeout)
+}
+
 // CreateSonarrClient creates a Sonarr API client
 func (f *ClientFactory) CreateSonarrClient(timeout time.Duration) *SonarrClient {
 	return NewSonarrClient(f.config.Services.Sonarr.URL, f.config.Services.Sonarr.APIKey, timeout)
@@ -52,12 +51,44 @@ func (f *ClientFactory) CreateRadarrClient(timeout time.Duration) *RadarrClient
 	return NewRadarrClient(f.config.Services.Radarr.URL, f.config.Services.Radarr.APIKey, timeout)
 }
 
+// CreateLidarrClient creates a Lidarr API client
+func (f *ClientFactory) CreateLidarrClient(timeout time.Duration) *LidarrClient {
+	return NewLidarrClient(f.config.Services.Lidarr.URL, f.config.Services.Lidarr.APIKey, timeout)
+}
+
+// CreateReadarrClient creates a Readarr API client
+func (f *ClientFactory) CreateReadarrClient(timeout time.Duration) *ReadarrClient {
+	return NewReadarrClient(f.config.Services.Readarr.URL, f.config.Services.Readarr.APIKey, timeout)
+}
+
 // CreateQBittorrentClient creates a qBittorrent API client
 func (f *ClientFactory) CreateQBittorrentClient(timeout time.Duration) *QBittorrentClient {
 	qbConfig := f.config.Services.QBittorrent
 	return NewQBittorrentClient(qbConfig.URL, qbConfig.Username, qbConfig.Password, qbConfig.QuiProxyURL, timeout)
 }
 
+// CreateTransmissionClient creates a Transmission RPC client
+func (f *ClientFactory) CreateTransmissionClient(timeout time.Duration) *TransmissionClient {
+	trConfig := f.config.Services.Transmission
+	return NewTransmissionClient(trConfig.URL, trConfig.Username, trConfig.Password, timeout)
+}
+
+// CreateDelugeClient creates a Deluge Web UI client
+func (f *ClientFactory) CreateDelugeClient(timeout time.Duration) *DelugeClient {
+	return NewDelugeClient(f.config.Services.Deluge.URL, f.config.Services.Deluge.Password, timeout)
+}
+
+// CreateSABnzbdClient creates a SABnzbd API client
+func (f *ClientFactory) CreateSABnzbdClient(timeout time.Duration) *SABnzbdClient {
+	return NewSABnzbdClient(f.config.Services.SABnzbd.URL, f.config.Services.SABnzbd.APIKey, timeout)
+}
+
+// CreateNZBGetClient creates an NZBGet JSON-RPC client
+func (f *ClientFactory) CreateNZBGetClient(timeout time.Duration) *NZBGetClient {
+	nzConfig := f.config.Services.NZBGet
+	return NewNZBGetClient(nzConfig.URL, nzConfig.Username, nzConfig.Password, timeout)
+}
+
 // CreateStashClient creates a Stash API client
 func (f *ClientFactory) CreateStashClient(timeout time.Duration) *StashClient {
 	return NewStashClient(f.config.Services.Stash.URL, f.config.Services.Stash.APIKey, timeout)
@@ -70,24 +101,6 @@ func (f *ClientFactory) CreateCalibreClient(timeout time.Duration) *CalibreClien
 
 // IsServiceConfigured checks if a service is configured with valid credentials
 func (f *ClientFactory) IsServiceConfigured(serviceName string) bool {
-	switch serviceName {
-	case "plex":
-		return f.config.Services.Plex.URL != "" && f.config.Services.Plex.Token != ""
-	case "sonarr":
-		return f.config.Services.Sonarr.URL != "" && f.config.Services.Sonarr.APIKey != ""
-	case "radarr":
-		return f.config.Services.Radarr.URL != "" && f.config.Services.Radarr.APIKey != ""
-	case "qbittorrent":
-		qbConfig := f.config.Services.QBittorrent
-		// Valid if either direct URL with credentials OR qui proxy URL
-		hasDirectAccess := qbConfig.URL != "" && qbConfig.Username != "" && qbConfig.Password != ""
-		hasProxyAccess := qbConfig.QuiProxyURL != ""
-		return hasDirectAccess || hasProxyAccess
-	case "stash":
-		return f.config.Services.Stash.URL != "" && f.config.Services.Stash.APIKey != ""
-	case "calibre":
-		return f.config.Services.Calibre.LibraryPath != "" && f.config.Services.Calibre.DBPath != ""
-	default:
-		return false
-	}
+	provider := Lookup(serviceName)
+	return provider != nil && provider.Configured(&f.config.Services)
 }
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"context"
 	"time"
 )
 
@@ -12,22 +11,21 @@ type ServiceClient interface {
 	Test() error
 }
 
-// FileProvider is an interface for services that can provide file listings
-type FileProvider interface {
-	ServiceClient
-
-	// GetAllFiles retrieves all files tracked by the service
-	// The return type varies by service, but all implement basic file info
-	// Context allows for cancellation during long-running operations
-	GetAllFiles(ctx context.Context) (interface{}, error)
-}
-
 // Ensure all clients implement ServiceClient
 var (
 	_ ServiceClient = (*PlexClient)(nil)
 	_ ServiceClient = (*SonarrClient)(nil)
 	_ ServiceClient = (*RadarrClient)(nil)
+	_ ServiceClient = (*LidarrClient)(nil)
+	_ ServiceClient = (*ReadarrClient)(nil)
 	_ ServiceClient = (*QBittorrentClient)(nil)
+	_ ServiceClient = (*TransmissionClient)(nil)
+	_ ServiceClient = (*DelugeClient)(nil)
+	_ ServiceClient = (*SABnzbdClient)(nil)
+	_ ServiceClient = (*NZBGetClient)(nil)
+	_ ServiceClient = (*StashClient)(nil)
+	_ ServiceClient = (*CalibreClient)(nil)
+	_ ServiceClient = (*JellyfinClient)(nil)
 	_ ServiceClient = (*ArrClient)(nil)
 )
 
@@ -40,7 +38,7 @@ type TestableClient interface {
 // ServiceInfo contains metadata about a service
 type ServiceInfo struct {
 	Name    string
-	Type    string // "media-server", "download-client", "pvr"
+	Type    string // "media-server", "download-client", "usenet-client", "pvr"
 	Enabled bool
 	URL     string
 }
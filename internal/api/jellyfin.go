@@ -0,0 +1,419 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+)
+
+// errJellyfinUnauthorized is returned when the server rejects the API key
+var errJellyfinUnauthorized = errors.New("authentication failed (401). Check your API key is valid")
+
+// JellyfinClient handles communication with Jellyfin and Emby servers
+// Emby and Jellyfin share the same API for listing libraries and items
+type JellyfinClient struct {
+	baseURL    string
+	apiKey     string
+	serverName string // "Jellyfin" or "Emby", used in error messages
+	client     *http.Client
+}
+
+// JellyfinFile represents a media source file tracked by Jellyfin or Emby
+type JellyfinFile struct {
+	Path        string
+	Size        int64
+	LibraryName string
+	Title       string
+	SeriesName  string
+	ItemID      string
+}
+
+// JellyfinLibrary represents a Jellyfin or Emby library (virtual folder)
+// JSON keys match PlexLibrarySection so the UI can render both the same way
+type JellyfinLibrary struct {
+	Key   string `json:"key"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
+
+// NewJellyfinClient creates a new Jellyfin or Emby API client
+func NewJellyfinClient(baseURL, apiKey, serverName string, timeout time.Duration) *JellyfinClient {
+	return &JellyfinClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		serverName: serverName,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Test tests the connection to the server
+func (j *JellyfinClient) Test() error {
+	var info struct {
+		ServerName string `json:"ServerName"`
+		Version    string `json:"Version"`
+	}
+
+	if err := j.doRequest(context.Background(), "/System/Info", nil, &info); err != nil {
+		if errors.Is(err, errJellyfinUnauthorized) {
+			return err
+		}
+		return fmt.Errorf("failed to connect to %s at %s: %w. Check the URL is reachable and the API key is valid", j.serverName, j.baseURL, err)
+	}
+
+	return nil
+}
+
+// GetLibraries retrieves all libraries from the server
+// This is exposed publicly for UI to fetch available libraries
+func (j *JellyfinClient) GetLibraries(ctx context.Context) ([]JellyfinLibrary, error) {
+	var folders []struct {
+		Name           string `json:"Name"`
+		ItemID         string `json:"ItemId"`
+		CollectionType string `json:"CollectionType"`
+	}
+
+	if err := j.doRequest(ctx, "/Library/VirtualFolders", nil, &folders); err != nil {
+		return nil, fmt.Errorf("failed to get libraries: %w", err)
+	}
+
+	libraries := make([]JellyfinLibrary, 0, len(folders))
+	for _, folder := range folders {
+		libraries = append(libraries, JellyfinLibrary{
+			Key:   folder.ItemID,
+			Title: folder.Name,
+			Type:  folder.CollectionType,
+		})
+	}
+
+	return libraries, nil
+}
+
+// ForEachFilePage retrieves the media source files tracked by the server one page of a library at a time,
+// handing each page's files to fn. Returning an error from fn stops the listing
+// If libraryIDs is empty, all libraries are scanned. Otherwise, only specified libraries are processed.
+func (j *JellyfinClient) ForEachFilePage(ctx context.Context, libraryIDs []string, fn func([]JellyfinFile) error) error {
+	libraries, err := j.GetLibraries(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Found %d %s libraries", len(libraries), j.serverName)
+
+	// Create a map for quick library ID lookup if filtering is enabled
+	var libraryFilter map[string]bool
+	if len(libraryIDs) > 0 {
+		libraryFilter = make(map[string]bool)
+		for _, id := range libraryIDs {
+			libraryFilter[id] = true
+		}
+		log.Printf("Filtering to %d specific %s libraries: %v", len(libraryIDs), j.serverName, libraryIDs)
+	}
+
+	total := 0
+	for _, library := range libraries {
+		if libraryFilter != nil && !libraryFilter[library.Key] {
+			log.Printf("Skipping %s library: %s (id: %s) - not in library filter", j.serverName, library.Title, library.Key)
+			continue
+		}
+
+		count := 0
+		err := j.forEachItemPage(ctx, library.Key, func(items []jellyfinItem) error {
+			files := libraryFiles(library, items)
+			count += len(files)
+			return fn(files)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get files for library %s: %w", library.Title, err)
+		}
+		log.Printf("Found %d files in %s library %s", count, j.serverName, library.Title)
+		total += count
+	}
+
+	log.Printf("Total %s files found: %d", j.serverName, total)
+	return nil
+}
+
+// GetSampleFiles returns up to limit file paths under pathPrefix, spread evenly across the libraries
+// This is optimized for path mapping validation and discovery - each library is paged only until its share is found
+func (j *JellyfinClient) GetSampleFiles(ctx context.Context, pathPrefix string, limit int) ([]string, error) {
+	libraries, err := j.GetLibraries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(libraries) == 0 {
+		return nil, nil
+	}
+
+	share := (limit + len(libraries) - 1) / len(libraries)
+	var paths []string
+	for _, library := range libraries {
+		found := 0
+		err := j.forEachItemPage(ctx, library.Key, func(items []jellyfinItem) error {
+			for _, item := range items {
+				for _, path := range item.filePaths() {
+					if !matchesPrefix(path, pathPrefix) {
+						continue
+					}
+					paths = append(paths, path)
+					found++
+					if found == share || len(paths) == limit {
+						return errSampleComplete
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errSampleComplete) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Failed to get samples from %s library %s: %v", j.serverName, library.Title, err)
+		}
+		if len(paths) == limit {
+			break
+		}
+	}
+
+	return paths, nil
+}
+
+// jellyfinItem is a library item as returned by the /Items endpoint
+type jellyfinItem struct {
+	ID           string `json:"Id"`
+	Name         string `json:"Name"`
+	Type         string `json:"Type"`
+	Path         string `json:"Path"`
+	SeriesName   string `json:"SeriesName"`
+	MediaSources []struct {
+		Path     string `json:"Path"`
+		Size     int64  `json:"Size"`
+		Protocol string `json:"Protocol"`
+	} `json:"MediaSources"`
+}
+
+// filePaths returns the local file paths of an item's media sources
+// Items without media sources (e.g. photos) fall back to the item path
+func (item jellyfinItem) filePaths() []string {
+	var paths []string
+	for _, source := range item.MediaSources {
+		if source.Path != "" && (source.Protocol == "" || source.Protocol == "File") {
+			paths = append(paths, source.Path)
+		}
+	}
+	if len(paths) == 0 && len(item.MediaSources) == 0 && item.Path != "" {
+		paths = append(paths, item.Path)
+	}
+	return paths
+}
+
+// libraryFiles maps a page of library items to their files
+func libraryFiles(library JellyfinLibrary, items []jellyfinItem) []JellyfinFile {
+	var files []JellyfinFile
+	for _, item := range items {
+		// An item can have several versions; each is a separate file
+		seen := make(map[string]bool)
+		for _, source := range item.MediaSources {
+			if source.Path == "" || seen[source.Path] || (source.Protocol != "" && source.Protocol != "File") {
+				continue
+			}
+			seen[source.Path] = true
+			files = append(files, JellyfinFile{
+				Path:        source.Path,
+				Size:        source.Size,
+				LibraryName: library.Title,
+				Title:       item.Name,
+				SeriesName:  item.SeriesName,
+				ItemID:      item.ID,
+			})
+		}
+
+		if len(item.MediaSources) == 0 && item.Path != "" {
+			files = append(files, JellyfinFile{
+				Path:        item.Path,
+				LibraryName: library.Title,
+				Title:       item.Name,
+				SeriesName:  item.SeriesName,
+				ItemID:      item.ID,
+			})
+		}
+	}
+	return files
+}
+
+// forEachItemPage pages through all non-folder items in a library
+// Returning an error from fn stops the iteration
+func (j *JellyfinClient) forEachItemPage(ctx context.Context, libraryID string, fn func([]jellyfinItem) error) error {
+	for start := 0; ; start += constants.JellyfinItemsPageSize {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		query := url.Values{}
+		query.Set("ParentId", libraryID)
+		query.Set("Recursive", "true")
+		query.Set("IsFolder", "false")
+		query.Set("Fields", "Path,MediaSources")
+		query.Set("StartIndex", strconv.Itoa(start))
+		query.Set("Limit", strconv.Itoa(constants.JellyfinItemsPageSize))
+
+		var page struct {
+			Items            []jellyfinItem `json:"Items"`
+			TotalRecordCount int            `json:"TotalRecordCount"`
+		}
+		if err := j.doRequest(ctx, "/Items", query, &page); err != nil {
+			return err
+		}
+
+		if err := fn(page.Items); err != nil {
+			return err
+		}
+
+		if len(page.Items) == 0 || start+len(page.Items) >= page.TotalRecordCount {
+			return nil
+		}
+	}
+}
+
+// doRequest performs an authenticated GET request and decodes the JSON response into result
+func (j *JellyfinClient) doRequest(ctx context.Context, endpoint string, query url.Values, result interface{}) error {
+	reqURL := j.baseURL + endpoint
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return err
+	}
+
+	// Jellyfin prefers the Authorization header, Emby uses X-Emby-Token; both are sent
+	req.Header.Set("Authorization", fmt.Sprintf("MediaBrowser Token=%q", j.apiKey))
+	req.Header.Set("X-Emby-Token", j.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("%s %w", j.serverName, errJellyfinUnauthorized)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s API returned status %d: %s", j.serverName, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", j.serverName, err)
+	}
+
+	return nil
+}
+
+// jellyfinProvider registers Jellyfin and Emby with the provider registry
+type jellyfinProvider struct {
+	providerInfo
+	settings func(s *config.Services) *config.JellyfinConfig
+}
+
+func init() {
+	Register(newJellyfinProvider("jellyfin", "Jellyfin", func(s *config.Services) *config.JellyfinConfig { return &s.Jellyfin }))
+	Register(newJellyfinProvider("emby", "Emby", func(s *config.Services) *config.JellyfinConfig { return &s.Emby }))
+}
+
+func newJellyfinProvider(name, displayName string, settings func(s *config.Services) *config.JellyfinConfig) *jellyfinProvider {
+	return &jellyfinProvider{
+		providerInfo: providerInfo{
+			name:        name,
+			displayName: displayName,
+			serviceType: ServiceTypeMediaServer,
+			fields: []ConfigField{
+				{
+					Key: "url", Label: displayName + " URL", Kind: FieldURL, Required: true,
+					Get: func(s *config.Services) string { return settings(s).URL },
+					Set: func(s *config.Services, v string) { settings(s).URL = v },
+				},
+				{
+					Key: "api_key", Label: displayName + " API Key", Kind: FieldAPIKey, Required: true,
+					Get: func(s *config.Services) string { return settings(s).APIKey },
+					Set: func(s *config.Services, v string) { settings(s).APIKey = v },
+				},
+			},
+		},
+		settings: settings,
+	}
+}
+
+func (p *jellyfinProvider) client(cfg *config.Config, timeout time.Duration) *JellyfinClient {
+	settings := p.settings(&cfg.Services)
+	return NewJellyfinClient(settings.URL, settings.APIKey, p.displayName, timeout)
+}
+
+func (p *jellyfinProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return p.client(cfg, timeout)
+}
+
+func (p *jellyfinProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.client(cfg, timeout).Test()
+}
+
+func (p *jellyfinProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := p.client(cfg, timeout)
+	return pageFiles(ctx, func(fn func([]JellyfinFile) error) error {
+		// Pass library filter from config (empty = scan all libraries)
+		return client.ForEachFilePage(ctx, p.settings(&cfg.Services).Libraries, fn)
+	}, func(f JellyfinFile) ServiceFile {
+		group := f.SeriesName
+		if group == "" {
+			group = f.Title
+		}
+		return ServiceFile{
+			Path:    f.Path,
+			Size:    f.Size,
+			Group:   group,
+			GroupID: f.ItemID,
+			Metadata: map[string]interface{}{
+				"size":         f.Size,
+				"library_name": f.LibraryName,
+				"title":        f.Title,
+				"series_name":  f.SeriesName,
+				"item_id":      f.ItemID,
+			},
+		}
+	}, page)
+}
+
+func (p *jellyfinProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	return p.client(cfg, timeout).GetSampleFiles(ctx, pathPrefix, limit)
+}
+
+func (p *jellyfinProvider) Libraries(ctx context.Context, cfg *config.Config, timeout time.Duration) ([]Library, error) {
+	folders, err := p.client(cfg, timeout).GetLibraries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	libraries := make([]Library, len(folders))
+	for i, folder := range folders {
+		libraries[i] = Library(folder)
+	}
+	return libraries, nil
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+)
+
+// LidarrClient handles communication with Lidarr
+type LidarrClient struct {
+	*ArrClient
+}
+
+// LidarrFile represents a track file tracked by Lidarr
+type LidarrFile struct {
+	Path        string
+	Size        int64
+	ArtistName  string
+	AlbumTitle  string
+	ArtistID    int64
+	AlbumID     int64
+	TrackFileID int64
+}
+
+// NewLidarrClient creates a new Lidarr API client
+func NewLidarrClient(baseURL, apiKey string, timeout time.Duration) *LidarrClient {
+	return &LidarrClient{
+		ArrClient: newArrClientV1(baseURL, apiKey, "Lidarr", "track", timeout),
+	}
+}
+
+// ForEachFilePage retrieves the track files tracked by Lidarr one artist at a time,
+// handing each artist's files to fn. Returning an error from fn stops the listing
+func (l *LidarrClient) ForEachFilePage(ctx context.Context, fn func([]LidarrFile) error) error {
+	// First, get all artists
+	artistMap, err := l.getAllArtists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get artists: %w", err)
+	}
+
+	// Album titles are looked up from a single request rather than per artist
+	albumMap, err := l.getAllAlbums(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get albums: %w", err)
+	}
+
+	// Then, get track files for each artist
+	for artistID, artistName := range artistMap {
+		// Check for context cancellation
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		var trackFiles []struct {
+			ID       int64  `json:"id"`
+			ArtistID int64  `json:"artistId"`
+			AlbumID  int64  `json:"albumId"`
+			Path     string `json:"path"`
+			Size     int64  `json:"size"`
+		}
+
+		// Query track files for this specific artist
+		endpoint := l.apiPath(fmt.Sprintf("/trackfile?artistId=%d", artistID))
+		if err := l.doRequest(ctx, endpoint, &trackFiles); err != nil {
+			return fmt.Errorf("failed to get track files for artist %d: %w", artistID, err)
+		}
+
+		files := make([]LidarrFile, 0, len(trackFiles))
+		for _, tf := range trackFiles {
+			files = append(files, LidarrFile{
+				Path:        tf.Path,
+				Size:        tf.Size,
+				ArtistName:  artistName,
+				AlbumTitle:  albumMap[tf.AlbumID], // Empty if album not found
+				ArtistID:    artistID,
+				AlbumID:     tf.AlbumID,
+				TrackFileID: tf.ID,
+			})
+		}
+		if err := fn(files); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetSampleFiles returns up to limit track file paths under pathPrefix, one per artist
+// This is optimized for path mapping validation and discovery - it stops once limit files are found
+func (l *LidarrClient) GetSampleFiles(ctx context.Context, pathPrefix string, limit int) ([]string, error) {
+	artistMap, err := l.getAllArtists(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artists: %w", err)
+	}
+
+	return l.samplePaths(ctx, slices.Collect(maps.Keys(artistMap)), func(id int64) string {
+		return l.apiPath(fmt.Sprintf("/trackfile?artistId=%d", id))
+	}, pathPrefix, limit)
+}
+
+func (l *LidarrClient) getAllArtists(ctx context.Context) (map[int64]string, error) {
+	var artists []struct {
+		ID         int64  `json:"id"`
+		ArtistName string `json:"artistName"`
+	}
+
+	if err := l.doRequest(ctx, l.apiPath("/artist"), &artists); err != nil {
+		return nil, err
+	}
+
+	artistMap := make(map[int64]string)
+	for _, a := range artists {
+		artistMap[a.ID] = a.ArtistName
+	}
+
+	return artistMap, nil
+}
+
+func (l *LidarrClient) getAllAlbums(ctx context.Context) (map[int64]string, error) {
+	var albums []struct {
+		ID    int64  `json:"id"`
+		Title string `json:"title"`
+	}
+
+	if err := l.doRequest(ctx, l.apiPath("/album"), &albums); err != nil {
+		return nil, err
+	}
+
+	albumMap := make(map[int64]string)
+	for _, a := range albums {
+		albumMap[a.ID] = a.Title
+	}
+
+	return albumMap, nil
+}
+
+// lidarrProvider registers Lidarr with the provider registry
+type lidarrProvider struct{ providerInfo }
+
+func init() {
+	Register(&lidarrProvider{providerInfo{
+		name:        "lidarr",
+		displayName: "Lidarr",
+		serviceType: ServiceTypePVR,
+		fields: []ConfigField{
+			{
+				Key: "url", Label: "Lidarr URL", Kind: FieldURL, Required: true,
+				Get: func(s *config.Services) string { return s.Lidarr.URL },
+				Set: func(s *config.Services, v string) { s.Lidarr.URL = v },
+			},
+			{
+				Key: "api_key", Label: "Lidarr API Key", Kind: FieldAPIKey, Required: true,
+				Get: func(s *config.Services) string { return s.Lidarr.APIKey },
+				Set: func(s *config.Services, v string) { s.Lidarr.APIKey = v },
+			},
+		},
+	}})
+}
+
+func (p *lidarrProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return NewLidarrClient(cfg.Services.Lidarr.URL, cfg.Services.Lidarr.APIKey, timeout)
+}
+
+func (p *lidarrProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.NewClient(cfg, timeout).Test()
+}
+
+func (p *lidarrProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := NewLidarrClient(cfg.Services.Lidarr.URL, cfg.Services.Lidarr.APIKey, timeout)
+	return pageFiles(ctx, func(fn func([]LidarrFile) error) error {
+		return client.ForEachFilePage(ctx, fn)
+	}, func(f LidarrFile) ServiceFile {
+		return ServiceFile{
+			Path:    f.Path,
+			Size:    f.Size,
+			Group:   fmt.Sprintf("%s - %s", f.ArtistName, f.AlbumTitle),
+			GroupID: fmt.Sprintf("%d", f.AlbumID),
+			Metadata: map[string]interface{}{
+				"artist_name":   f.ArtistName,
+				"album_title":   f.AlbumTitle,
+				"artist_id":     f.ArtistID,
+				"album_id":      f.AlbumID,
+				"track_file_id": f.TrackFileID,
+			},
+		}
+	}, page)
+}
+
+func (p *lidarrProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	return NewLidarrClient(cfg.Services.Lidarr.URL, cfg.Services.Lidarr.APIKey, timeout).GetSampleFiles(ctx, pathPrefix, limit)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+)
+
+// errNZBGetUnauthorized is returned when the control username or password is wrong
+var errNZBGetUnauthorized = errors.New("NZBGet authentication failed (401). Check username and password")
+
+// NZBGetClient handles communication with NZBGet over its JSON-RPC interface
+type NZBGetClient struct {
+	rpcURL    string
+	username  string
+	password  string
+	client    *http.Client
+	requestID atomic.Int64
+}
+
+// NewNZBGetClient creates a new NZBGet JSON-RPC client
+func NewNZBGetClient(baseURL, username, password string, timeout time.Duration) *NZBGetClient {
+	return &NZBGetClient{
+		rpcURL:   strings.TrimSuffix(baseURL, "/") + "/jsonrpc",
+		username: username,
+		password: password,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Test tests the connection to NZBGet
+func (n *NZBGetClient) Test() error {
+	var version string
+	if err := n.call(context.Background(), "version", []interface{}{}, &version); err != nil {
+		if errors.Is(err, errNZBGetUnauthorized) {
+			return err
+		}
+		return fmt.Errorf("failed to connect to NZBGet at %s: %w. Check the URL is reachable", n.rpcURL, err)
+	}
+
+	return nil
+}
+
+// ForEachJobPage retrieves the successful downloads in the history, handing them to fn
+// NZBGet returns the whole history in a single history call, so this can't page the request itself.
+// Returning an error from fn stops the listing
+func (n *NZBGetClient) ForEachJobPage(ctx context.Context, fn func([]UsenetJob) error) error {
+	var history []struct {
+		NZBID       int64  `json:"NZBID"`
+		Kind        string `json:"Kind"` // NZB, URL or DUP (hidden duplicates)
+		Name        string `json:"Name"`
+		Category    string `json:"Category"`
+		Status      string `json:"Status"` // e.g. SUCCESS/ALL, SUCCESS/UNPACK or FAILURE/PAR
+		DestDir     string `json:"DestDir"`
+		FinalDir    string `json:"FinalDir"` // Set when a post-processing script moved the files
+		HistoryTime int64  `json:"HistoryTime"`
+	}
+	if err := n.call(ctx, "history", []interface{}{false}, &history); err != nil {
+		return fmt.Errorf("failed to get history: %w", err)
+	}
+
+	jobs := make([]UsenetJob, 0, len(history))
+	for _, item := range history {
+		if item.Kind != "NZB" || !strings.HasPrefix(item.Status, "SUCCESS") {
+			continue
+		}
+		storage := item.FinalDir
+		if storage == "" {
+			storage = item.DestDir
+		}
+		jobs = append(jobs, UsenetJob{
+			ID:          strconv.FormatInt(item.NZBID, 10),
+			Name:        item.Name,
+			Category:    item.Category,
+			Status:      item.Status,
+			Storage:     storage,
+			CompletedAt: item.HistoryTime,
+		})
+	}
+
+	return fn(jobs)
+}
+
+// call performs a JSON-RPC call
+func (n *NZBGetClient) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"method": method,
+		"params": params,
+		"id":     n.requestID.Add(1),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.username != "" || n.password != "" {
+		req.SetBasicAuth(n.username, n.password)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errNZBGetUnauthorized
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("NZBGet RPC returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+			Code    int    `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("failed to parse NZBGet response: %w", err)
+	}
+
+	if rpcResp.Error != nil {
+		return fmt.Errorf("NZBGet %s failed: %s", method, rpcResp.Error.Message)
+	}
+
+	if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+		return fmt.Errorf("failed to parse NZBGet %s result: %w", method, err)
+	}
+
+	return nil
+}
+
+// nzbgetProvider registers NZBGet with the provider registry
+type nzbgetProvider struct{ providerInfo }
+
+func init() {
+	Register(&nzbgetProvider{providerInfo{
+		name:        "nzbget",
+		displayName: "NZBGet",
+		serviceType: ServiceTypeUsenetClient,
+		fields: []ConfigField{
+			{
+				Key: "url", Label: "NZBGet URL", Kind: FieldURL, Required: true,
+				Get: func(s *config.Services) string { return s.NZBGet.URL },
+				Set: func(s *config.Services, v string) { s.NZBGet.URL = v },
+			},
+			{
+				Key: "username", Label: "NZBGet Username", Kind: FieldText,
+				Get: func(s *config.Services) string { return s.NZBGet.Username },
+				Set: func(s *config.Services, v string) { s.NZBGet.Username = v },
+			},
+			{
+				Key: "password", Label: "NZBGet Password", Kind: FieldSecret,
+				Get: func(s *config.Services) string { return s.NZBGet.Password },
+				Set: func(s *config.Services, v string) { s.NZBGet.Password = v },
+			},
+		},
+	}})
+}
+
+func (p *nzbgetProvider) newClient(cfg *config.Config, timeout time.Duration) *NZBGetClient {
+	nz := cfg.Services.NZBGet
+	return NewNZBGetClient(nz.URL, nz.Username, nz.Password, timeout)
+}
+
+func (p *nzbgetProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return p.newClient(cfg, timeout)
+}
+
+func (p *nzbgetProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.newClient(cfg, timeout).Test()
+}
+
+func (p *nzbgetProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := p.newClient(cfg, timeout)
+	return pageFiles(ctx, func(fn func([]UsenetFile) error) error {
+		return forEachUsenetFilePage(ctx, func(jobs func([]UsenetJob) error) error {
+			return client.ForEachJobPage(ctx, jobs)
+		}, usenetLocalPath(cfg, p.name), fn)
+	}, usenetServiceFile, page)
+}
+
+func (p *nzbgetProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	client := p.newClient(cfg, timeout)
+	return sampleUsenetFiles(ctx, func(jobs func([]UsenetJob) error) error {
+		return client.ForEachJobPage(ctx, jobs)
+	}, usenetLocalPath(cfg, p.name), pathPrefix, limit)
+}
@@ -3,12 +3,16 @@ package api
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
-	"strings"
+	"strconv"
 	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
 )
 
 // PlexClient handles communication with Plex Media Server
@@ -70,13 +74,14 @@ func (p *PlexClient) Test() error {
 	return nil
 }
 
-// GetAllFiles retrieves all files tracked by Plex
+// ForEachFilePage retrieves the files tracked by Plex one page of a library section at a time,
+// handing each page's files to fn. Returning an error from fn stops the listing
 // If libraryKeys is empty, all libraries are scanned. Otherwise, only specified libraries are processed.
-func (p *PlexClient) GetAllFiles(ctx context.Context, libraryKeys []string) ([]PlexFile, error) {
+func (p *PlexClient) ForEachFilePage(ctx context.Context, libraryKeys []string, fn func([]PlexFile) error) error {
 	// First, get all library sections
 	sections, err := p.getLibrarySections(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get library sections: %w", err)
+		return fmt.Errorf("failed to get library sections: %w", err)
 	}
 
 	log.Printf("Found %d Plex library sections", len(sections))
@@ -93,17 +98,8 @@ func (p *PlexClient) GetAllFiles(ctx context.Context, libraryKeys []string) ([]P
 		log.Printf("No library filter specified - scanning all libraries")
 	}
 
-	var allFiles []PlexFile
-
-	// For each section, get all items
+	total := 0
 	for _, section := range sections {
-		// Check for context cancellation
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-		}
-
 		// Skip this section if library filtering is enabled and this section is not in the filter
 		if libraryFilter != nil && !libraryFilter[section.Key] {
 			log.Printf("Skipping Plex section: %s (key: %s) - not in library filter", section.Title, section.Key)
@@ -111,17 +107,21 @@ func (p *PlexClient) GetAllFiles(ctx context.Context, libraryKeys []string) ([]P
 		}
 
 		log.Printf("Processing Plex section: %s (type: %s, key: %s)", section.Title, section.Type, section.Key)
-		files, err := p.getFilesForSection(ctx, section.Key, section.Type, section.Title)
+		count := 0
+		err := p.forEachSectionPage(ctx, section.Key, section.Type, section.Title, func(files []PlexFile) error {
+			count += len(files)
+			return fn(files)
+		})
 		if err != nil {
 			log.Printf("ERROR: Failed to get files for section %s: %v", section.Title, err)
-			return nil, fmt.Errorf("failed to get files for section %s: %w", section.Title, err)
+			return fmt.Errorf("failed to get files for section %s: %w", section.Title, err)
 		}
-		log.Printf("Found %d files in section %s", len(files), section.Title)
-		allFiles = append(allFiles, files...)
+		log.Printf("Found %d files in section %s", count, section.Title)
+		total += count
 	}
 
-	log.Printf("Total Plex files found: %d", len(allFiles))
-	return allFiles, nil
+	log.Printf("Total Plex files found: %d", total)
+	return nil
 }
 
 // GetLibrarySections retrieves all library sections from Plex
@@ -145,31 +145,47 @@ func (p *PlexClient) GetLibrarySections(ctx context.Context) ([]PlexLibrarySecti
 	return result, nil
 }
 
-// GetSampleFile retrieves a single sample file from Plex that matches the path prefix
-// This is optimized for path mapping validation - it stops as soon as it finds one matching file
-func (p *PlexClient) GetSampleFile(pathPrefix string) (string, error) {
-	// Get library sections with background context (not cancellable)
-	ctx := context.Background()
+// GetSampleFiles returns up to limit file paths under pathPrefix, spread evenly across the library sections
+// This is optimized for path mapping validation and discovery - each section is paged only until its share is found
+func (p *PlexClient) GetSampleFiles(ctx context.Context, pathPrefix string, limit int) ([]string, error) {
 	sections, err := p.getLibrarySections(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get library sections: %w", err)
+		return nil, fmt.Errorf("failed to get library sections: %w", err)
+	}
+	if len(sections) == 0 {
+		return nil, nil
 	}
 
-	// Try each section until we find a matching file
+	share := (limit + len(sections) - 1) / len(sections)
+	var paths []string
 	for _, section := range sections {
-		samplePath, err := p.getSampleFileFromSection(section.Key, section.Type, pathPrefix)
-		if err != nil {
+		found := 0
+		err := p.forEachSectionPage(ctx, section.Key, section.Type, section.Title, func(files []PlexFile) error {
+			for _, f := range files {
+				if !matchesPrefix(f.Path, pathPrefix) {
+					continue
+				}
+				paths = append(paths, f.Path)
+				found++
+				if found == share || len(paths) == limit {
+					return errSampleComplete
+				}
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errSampleComplete) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			// Log but continue to next section
-			log.Printf("Failed to get sample from section %s: %v", section.Title, err)
-			continue
+			log.Printf("Failed to get samples from section %s: %v", section.Title, err)
 		}
-		if samplePath != "" {
-			return samplePath, nil
+		if len(paths) == limit {
+			break
 		}
 	}
 
-	// No matching file found in any section
-	return "", nil
+	return paths, nil
 }
 
 type libraryResponse struct {
@@ -231,7 +247,8 @@ func (p *PlexClient) getLibrarySections(ctx context.Context) ([]struct {
 }
 
 type mediaContainerResponse struct {
-	Video []struct {
+	TotalSize int `xml:"totalSize,attr"` // Items in the whole listing when paged
+	Video     []struct {
 		Title string `xml:"title,attr"`
 		Media []struct {
 			Part []struct {
@@ -260,110 +277,71 @@ type mediaContainerResponse struct {
 	} `xml:"Photo"`
 }
 
-func (p *PlexClient) getFilesForSection(ctx context.Context, sectionKey, sectionType, sectionTitle string) ([]PlexFile, error) {
-	// For TV shows, we need to get episodes using a different endpoint
-	if sectionType == "show" {
-		return p.getFilesForTVSection(ctx, sectionKey, sectionTitle)
-	}
-
-	// Build URL with all items in section
-	u, err := url.Parse(p.baseURL + "/library/sections/" + sectionKey + "/all")
-	if err != nil {
-		return nil, err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("X-Plex-Token", p.token)
-	req.Header.Set("Accept", "application/xml")
-
-	resp, err := p.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("plex API returned status %d", resp.StatusCode)
-	}
-
-	// Use streaming XML decoder for better performance with large libraries
-	var container mediaContainerResponse
-	decoder := xml.NewDecoder(resp.Body)
-	if err := decoder.Decode(&container); err != nil {
-		return nil, fmt.Errorf("failed to parse media container: %w", err)
-	}
-
+// files returns the files of every video, track and photo part in the container
+func (c *mediaContainerResponse) files(libraryName string) []PlexFile {
 	var files []PlexFile
-
-	// Process Video elements (movies, TV shows)
-	for _, video := range container.Video {
+	add := func(title, file string, size int64) {
+		if file != "" {
+			files = append(files, PlexFile{Path: file, Size: size, LibraryName: libraryName, Title: title})
+		}
+	}
+	for _, video := range c.Video {
 		for _, media := range video.Media {
 			for _, part := range media.Part {
-				if part.File != "" {
-					files = append(files, PlexFile{
-						Path:        part.File,
-						Size:        part.Size,
-						LibraryName: sectionTitle,
-						Title:       video.Title,
-					})
-				}
+				add(video.Title, part.File, part.Size)
 			}
 		}
 	}
-
-	// Process Track elements (music)
-	for _, track := range container.Track {
+	for _, track := range c.Track {
 		for _, media := range track.Media {
 			for _, part := range media.Part {
-				if part.File != "" {
-					files = append(files, PlexFile{
-						Path:        part.File,
-						Size:        part.Size,
-						LibraryName: sectionTitle,
-						Title:       track.Title,
-					})
-				}
+				add(track.Title, part.File, part.Size)
 			}
 		}
 	}
-
-	// Process Photo elements (photos)
-	for _, photo := range container.Photo {
+	for _, photo := range c.Photo {
 		for _, media := range photo.Media {
 			for _, part := range media.Part {
-				if part.File != "" {
-					files = append(files, PlexFile{
-						Path:        part.File,
-						Size:        part.Size,
-						LibraryName: sectionTitle,
-						Title:       photo.Title,
-					})
-				}
+				add(photo.Title, part.File, part.Size)
 			}
 		}
 	}
+	return files
+}
 
-	log.Printf("Section %s (type: %s): found %d videos, %d tracks, %d photos = %d total files",
-		sectionKey, sectionType, len(container.Video), len(container.Track), len(container.Photo), len(files))
+// forEachSectionPage pages through the items of a library section, handing each page's files to fn
+func (p *PlexClient) forEachSectionPage(ctx context.Context, sectionKey, sectionType, sectionTitle string, fn func([]PlexFile) error) error {
+	for start := 0; ; start += constants.PlexItemsPageSize {
+		container, err := p.getSectionPage(ctx, sectionKey, sectionType, start)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(container.files(sectionTitle)); err != nil {
+			return err
+		}
 
-	return files, nil
+		items := len(container.Video) + len(container.Track) + len(container.Photo)
+		if items < constants.PlexItemsPageSize || (container.TotalSize > 0 && start+items >= container.TotalSize) {
+			return nil
+		}
+	}
 }
 
-// getFilesForTVSection gets all episode files for a TV show library section
-func (p *PlexClient) getFilesForTVSection(ctx context.Context, sectionKey, sectionTitle string) ([]PlexFile, error) {
-	// For TV shows, we need to query all episodes using type=4 (episodes)
+// getSectionPage retrieves one page of a library section's items
+// TV show sections are listed by episode (type=4), since shows themselves have no files
+func (p *PlexClient) getSectionPage(ctx context.Context, sectionKey, sectionType string, start int) (*mediaContainerResponse, error) {
 	u, err := url.Parse(p.baseURL + "/library/sections/" + sectionKey + "/all")
 	if err != nil {
 		return nil, err
 	}
 
-	// Add type=4 parameter to get episodes
 	q := u.Query()
-	q.Set("type", "4")
+	if sectionType == "show" {
+		q.Set("type", "4")
+	}
+	q.Set("X-Plex-Container-Start", strconv.Itoa(start))
+	q.Set("X-Plex-Container-Size", strconv.Itoa(constants.PlexItemsPageSize))
 	u.RawQuery = q.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
@@ -384,53 +362,27 @@ func (p *PlexClient) getFilesForTVSection(ctx context.Context, sectionKey, secti
 		return nil, fmt.Errorf("plex API returned status %d", resp.StatusCode)
 	}
 
-	// Use streaming XML decoder for better performance
+	// Use streaming XML decoder for better performance with large libraries
 	var container mediaContainerResponse
-	decoder := xml.NewDecoder(resp.Body)
-	if err := decoder.Decode(&container); err != nil {
-		return nil, fmt.Errorf("failed to parse TV episodes: %w", err)
-	}
-
-	var files []PlexFile
-
-	// TV episodes are returned as Video elements
-	for _, video := range container.Video {
-		for _, media := range video.Media {
-			for _, part := range media.Part {
-				if part.File != "" {
-					files = append(files, PlexFile{
-						Path:        part.File,
-						Size:        part.Size,
-						LibraryName: sectionTitle,
-						Title:       video.Title,
-					})
-				}
-			}
-		}
+	if err := xml.NewDecoder(resp.Body).Decode(&container); err != nil {
+		return nil, fmt.Errorf("failed to parse media container: %w", err)
 	}
 
-	log.Printf("TV Section %s: found %d episode files", sectionKey, len(files))
-
-	return files, nil
+	return &container, nil
 }
 
-// getSampleFileFromSection retrieves a single sample file from a Plex section that matches the path prefix
-// Returns empty string if no matching file found
-func (p *PlexClient) getSampleFileFromSection(sectionKey, sectionType, pathPrefix string) (string, error) {
-	// For TV shows, use the TV-specific endpoint
-	if sectionType == "show" {
-		return p.getSampleFileFromTVSection(sectionKey, pathPrefix)
-	}
-
-	// Build URL to get items from section
-	u, err := url.Parse(p.baseURL + "/library/sections/" + sectionKey + "/all")
-	if err != nil {
-		return "", err
+// GetItemFiles returns the files of a library item
+// Shows, seasons, artists and albums return the files of every episode or track inside them
+func (p *PlexClient) GetItemFiles(ctx context.Context, ratingKey, itemType string) ([]PlexFile, error) {
+	endpoint := "/library/metadata/" + url.PathEscape(ratingKey)
+	switch itemType {
+	case "show", "season", "artist", "album":
+		endpoint += "/allLeaves"
 	}
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+endpoint, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req.Header.Set("X-Plex-Token", p.token)
@@ -438,105 +390,84 @@ func (p *PlexClient) getSampleFileFromSection(sectionKey, sectionType, pathPrefi
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("plex API returned status %d", resp.StatusCode)
+		return nil, fmt.Errorf("plex API returned status %d", resp.StatusCode)
 	}
 
-	// Parse response and find first matching file
 	var container mediaContainerResponse
-	decoder := xml.NewDecoder(resp.Body)
-	if err := decoder.Decode(&container); err != nil {
-		return "", fmt.Errorf("failed to parse media container: %w", err)
+	if err := xml.NewDecoder(resp.Body).Decode(&container); err != nil {
+		return nil, fmt.Errorf("failed to parse item %s: %w", ratingKey, err)
 	}
 
-	// Check Video elements (movies)
-	for _, video := range container.Video {
-		for _, media := range video.Media {
-			for _, part := range media.Part {
-				if part.File != "" && (pathPrefix == "" || strings.HasPrefix(part.File, pathPrefix)) {
-					return part.File, nil
-				}
-			}
-		}
-	}
-
-	// Check Track elements (music)
-	for _, track := range container.Track {
-		for _, media := range track.Media {
-			for _, part := range media.Part {
-				if part.File != "" && (pathPrefix == "" || strings.HasPrefix(part.File, pathPrefix)) {
-					return part.File, nil
-				}
-			}
-		}
-	}
-
-	// Check Photo elements
-	for _, photo := range container.Photo {
-		for _, media := range photo.Media {
-			for _, part := range media.Part {
-				if part.File != "" && (pathPrefix == "" || strings.HasPrefix(part.File, pathPrefix)) {
-					return part.File, nil
-				}
-			}
-		}
-	}
+	return container.files(""), nil
+}
 
-	return "", nil
+// plexProvider registers Plex with the provider registry
+type plexProvider struct{ providerInfo }
+
+func init() {
+	Register(&plexProvider{providerInfo{
+		name:        "plex",
+		displayName: "Plex",
+		serviceType: ServiceTypeMediaServer,
+		fields: []ConfigField{
+			{
+				Key: "url", Label: "Plex URL", Kind: FieldURL, Required: true,
+				Get: func(s *config.Services) string { return s.Plex.URL },
+				Set: func(s *config.Services, v string) { s.Plex.URL = v },
+			},
+			{
+				Key: "token", Label: "Plex Token", Kind: FieldSecret, Required: true,
+				Get: func(s *config.Services) string { return s.Plex.Token },
+				Set: func(s *config.Services, v string) { s.Plex.Token = v },
+			},
+		},
+	}})
 }
 
-// getSampleFileFromTVSection retrieves a single sample TV episode file that matches the path prefix
-func (p *PlexClient) getSampleFileFromTVSection(sectionKey, pathPrefix string) (string, error) {
-	// Build URL to get all episodes in section
-	u, err := url.Parse(p.baseURL + "/library/sections/" + sectionKey + "/all")
-	if err != nil {
-		return "", err
-	}
+func (p *plexProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return NewPlexClient(cfg.Services.Plex.URL, cfg.Services.Plex.Token, timeout)
+}
 
-	// Add query parameters to get episode-level data
-	q := u.Query()
-	q.Set("type", "4") // 4 = episodes in Plex
-	u.RawQuery = q.Encode()
+func (p *plexProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.NewClient(cfg, timeout).Test()
+}
 
-	req, err := http.NewRequest("GET", u.String(), nil)
-	if err != nil {
-		return "", err
-	}
+func (p *plexProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := NewPlexClient(cfg.Services.Plex.URL, cfg.Services.Plex.Token, timeout)
+	return pageFiles(ctx, func(fn func([]PlexFile) error) error {
+		// Pass library filter from config (empty = scan all libraries)
+		return client.ForEachFilePage(ctx, cfg.Services.Plex.Libraries, fn)
+	}, func(f PlexFile) ServiceFile {
+		return ServiceFile{
+			Path: f.Path,
+			Size: f.Size,
+			Metadata: map[string]interface{}{
+				"size":         f.Size,
+				"library_name": f.LibraryName,
+				"title":        f.Title,
+			},
+		}
+	}, page)
+}
 
-	req.Header.Set("X-Plex-Token", p.token)
-	req.Header.Set("Accept", "application/xml")
+func (p *plexProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	return NewPlexClient(cfg.Services.Plex.URL, cfg.Services.Plex.Token, timeout).GetSampleFiles(ctx, pathPrefix, limit)
+}
 
-	resp, err := p.client.Do(req)
+func (p *plexProvider) Libraries(ctx context.Context, cfg *config.Config, timeout time.Duration) ([]Library, error) {
+	sections, err := NewPlexClient(cfg.Services.Plex.URL, cfg.Services.Plex.Token, timeout).GetLibrarySections(ctx)
 	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("plex API returned status %d", resp.StatusCode)
-	}
-
-	// Parse response and find first matching file
-	var container mediaContainerResponse
-	decoder := xml.NewDecoder(resp.Body)
-	if err := decoder.Decode(&container); err != nil {
-		return "", fmt.Errorf("failed to parse TV episodes: %w", err)
+		return nil, err
 	}
 
-	// TV episodes are returned as Video elements
-	for _, video := range container.Video {
-		for _, media := range video.Media {
-			for _, part := range media.Part {
-				if part.File != "" && (pathPrefix == "" || strings.HasPrefix(part.File, pathPrefix)) {
-					return part.File, nil
-				}
-			}
-		}
+	libraries := make([]Library, len(sections))
+	for i, section := range sections {
+		libraries[i] = Library(section)
 	}
-
-	return "", nil
+	return libraries, nil
 }
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+)
+
+// Service types used to categorize providers
+const (
+	ServiceTypeMediaServer    = "media-server"
+	ServiceTypePVR            = "pvr"
+	ServiceTypeDownloadClient = "download-client"
+	ServiceTypeUsenetClient   = "usenet-client"
+	ServiceTypeLibrary        = "library"
+)
+
+// Provider is implemented by every external service integration
+// Providers register themselves with Register (from an init function), and the scanner,
+// health checks and config UI discover them through the registry instead of hardcoded lists
+type Provider interface {
+	// Name is the stable identifier stored in the database (e.g. "plex")
+	Name() string
+
+	// DisplayName is the human-readable name shown in the UI (e.g. "Plex")
+	DisplayName() string
+
+	// Type is one of the ServiceType* constants
+	Type() string
+
+	// ConfigFields describes the provider's settings in config.Services
+	ConfigFields() []ConfigField
+
+	// Configured reports whether enough settings are present to query the service
+	Configured(services *config.Services) bool
+
+	// NewClient creates a client for the service using cfg
+	NewClient(cfg *config.Config, timeout time.Duration) ServiceClient
+
+	// Test checks the connection to the service using cfg
+	Test(cfg *config.Config, timeout time.Duration) error
+
+	// ListFiles retrieves every file tracked by the service, mapped to ServiceFile
+	// Files are delivered in pages as the client fetches them; returning an error from page stops the listing
+	ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error
+
+	// SampleFiles returns up to limit paths of files the service reports under pathPrefix ("" for any),
+	// spread across its libraries, series or torrents, without listing every file
+	SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error)
+
+	// Libraries returns the libraries a scan of the service can be limited to, or nil if it has none
+	Libraries(ctx context.Context, cfg *config.Config, timeout time.Duration) ([]Library, error)
+}
+
+// Library is a service library a scan can be limited to
+type Library struct {
+	Key   string `json:"key"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
+
+// Config field kinds, used for form input types and validation
+const (
+	FieldText   = "text"    // Plain text
+	FieldURL    = "url"     // HTTP(S) URL
+	FieldAPIKey = "api_key" // API key
+	FieldSecret = "secret"  // Token or password
+	FieldPath   = "path"    // Filesystem path inside the container
+)
+
+// ConfigField describes a single provider setting
+// The form field name is "<provider name>_<key>" (e.g. "plex_token")
+type ConfigField struct {
+	Key      string // Setting key, matches the YAML key under services.<provider>
+	Label    string // Human-readable label
+	Kind     string // One of the Field* constants
+	Required bool   // Value must be set for the provider to be considered configured
+
+	Get func(services *config.Services) string
+	Set func(services *config.Services, value string)
+}
+
+// IsSecret reports whether the field holds a credential
+func (f ConfigField) IsSecret() bool {
+	return f.Kind == FieldAPIKey || f.Kind == FieldSecret
+}
+
+// FormName returns the HTML form field name for a provider setting
+func (f ConfigField) FormName(provider Provider) string {
+	return provider.Name() + "_" + f.Key
+}
+
+// ServiceFile is a file reported by a service, mapped to a common shape
+type ServiceFile struct {
+	Path     string                 // Path as reported by the service (before path mapping)
+	Size     int64                  // Size reported by the service, 0 if unknown
+	Group    string                 // Grouping label (series, movie, torrent, scene, book)
+	GroupID  string                 // Service identifier for the group
+	Metadata map[string]interface{} // Service-specific metadata stored with usage records
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Provider)
+)
+
+// Register adds a provider to the registry
+// It panics if a provider with the same name is already registered
+func Register(p Provider) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name := p.Name()
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("api: provider %q registered twice", name))
+	}
+	registry[name] = p
+}
+
+// Lookup returns the provider with the given name, or nil if none is registered
+func Lookup(name string) Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registry[name]
+}
+
+// Providers returns all registered providers sorted by name
+func Providers() []Provider {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	providers := make([]Provider, 0, len(registry))
+	for _, p := range registry {
+		providers = append(providers, p)
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name() < providers[j].Name()
+	})
+	return providers
+}
+
+// ConfiguredProviders returns the registered providers that are configured in services
+func ConfiguredProviders(services *config.Services) []Provider {
+	var configured []Provider
+	for _, p := range Providers() {
+		if p.Configured(services) {
+			configured = append(configured, p)
+		}
+	}
+	return configured
+}
+
+// DisplayName returns the display name for a service, falling back to the raw name
+func DisplayName(name string) string {
+	if p := Lookup(name); p != nil {
+		return p.DisplayName()
+	}
+	return name
+}
+
+// ApplyFormValues copies provider settings from form values into services
+// Values are looked up by field form name and trimmed
+func ApplyFormValues(p Provider, services *config.Services, formValue func(string) string) {
+	for _, field := range p.ConfigFields() {
+		field.Set(services, strings.TrimSpace(formValue(field.FormName(p))))
+	}
+}
+
+// MissingRequiredField returns the label of the first required field that is empty, or ""
+func MissingRequiredField(p Provider, services *config.Services) string {
+	for _, field := range p.ConfigFields() {
+		if field.Required && field.Get(services) == "" {
+			return field.Label
+		}
+	}
+	return ""
+}
+
+// providerInfo implements the descriptive parts of Provider for built-in providers
+type providerInfo struct {
+	name        string
+	displayName string
+	serviceType string
+	fields      []ConfigField
+}
+
+func (p *providerInfo) Name() string                { return p.name }
+func (p *providerInfo) DisplayName() string         { return p.displayName }
+func (p *providerInfo) Type() string                { return p.serviceType }
+func (p *providerInfo) ConfigFields() []ConfigField { return p.fields }
+
+// Configured reports whether every required field is set
+func (p *providerInfo) Configured(services *config.Services) bool {
+	for _, field := range p.fields {
+		if field.Required && field.Get(services) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Libraries returns nil; providers for services with libraries override it
+func (p *providerInfo) Libraries(ctx context.Context, cfg *config.Config, timeout time.Duration) ([]Library, error) {
+	return nil, nil
+}
+
+// pageFiles runs a client listing that delivers files in its own pages (a library, series or torrent
+// at a time), maps them to ServiceFiles and hands them to page in chunks of ServiceFilePageSize,
+// so small client pages don't reach the scanner one by one and large ones are split
+func pageFiles[T any](ctx context.Context, list func(fn func([]T) error) error, mapFile func(T) ServiceFile, page func([]ServiceFile) error) error {
+	files := make([]ServiceFile, 0, constants.ServiceFilePageSize)
+	flush := func() error {
+		if len(files) == 0 {
+			return nil
+		}
+		err := page(files)
+		files = make([]ServiceFile, 0, constants.ServiceFilePageSize)
+		return err
+	}
+
+	err := list(func(items []T) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, item := range items {
+			files = append(files, mapFile(item))
+			if len(files) == constants.ServiceFilePageSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// errSampleComplete stops paging through a library once its share of sample files is found
+var errSampleComplete = errors.New("sample complete")
+
+// matchesPrefix reports whether a service path is set and falls under pathPrefix ("" matches any path)
+func matchesPrefix(path, pathPrefix string) bool {
+	return path != "" && strings.HasPrefix(path, pathPrefix)
+}
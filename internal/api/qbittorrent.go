@@ -13,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mmenanno/media-usage-finder/internal/config"
 	"github.com/mmenanno/media-usage-finder/internal/constants"
 )
 
@@ -34,6 +35,7 @@ type QBittorrentFile struct {
 	TorrentName string
 	Category    string
 	Tags        string
+	Seeding     SeedingHealth
 }
 
 // NewQBittorrentClient creates a new qBittorrent API client
@@ -126,42 +128,46 @@ func (q *QBittorrentClient) Test() error {
 	return nil
 }
 
-// GetAllFiles retrieves all files from all torrents using concurrent workers
+// ForEachFilePage retrieves the files of all torrents using concurrent workers, handing each
+// torrent's files to fn as soon as they are fetched. Returning an error from fn stops the listing
 //
 // Concurrency Strategy:
 // - Uses a semaphore pattern to limit concurrent torrent processing to MaxConcurrentTorrentWorkers
 // - For each torrent, fetches file list and properties concurrently in separate goroutines
-// - Collects all results into a single slice protected by a mutex
+// - Calls fn for one torrent at a time, serialized by a mutex
 // - If any API call fails for a torrent, that torrent is skipped (logged but doesn't fail entire operation)
 // - This approach significantly improves performance for users with many torrents
-func (q *QBittorrentClient) GetAllFiles(ctx context.Context) ([]QBittorrentFile, error) {
+func (q *QBittorrentClient) ForEachFilePage(ctx context.Context, fn func([]QBittorrentFile) error) error {
 	if err := q.login(); err != nil {
-		return nil, err
+		return err
 	}
 
 	// Get list of all torrents
 	torrents, err := q.getTorrents(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get torrents: %w", err)
+		return fmt.Errorf("failed to get torrents: %w", err)
 	}
 
 	if len(torrents) == 0 {
-		return []QBittorrentFile{}, nil
+		return nil
 	}
 
+	// Workers stop once fn fails or the caller cancels
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Use concurrent workers to process torrents
 	sem := make(chan struct{}, constants.MaxConcurrentTorrentWorkers) // Semaphore for concurrency control
 
 	var mu sync.Mutex
-	var allFiles []QBittorrentFile
+	var fnErr error
 	var wg sync.WaitGroup
 
 	for _, torrent := range torrents {
-		// Check for context cancellation before starting new worker
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
+		// Stop starting workers once cancelled
+		if ctx.Err() != nil {
+			break
 		}
 
 		wg.Add(1)
@@ -176,11 +182,12 @@ func (q *QBittorrentClient) GetAllFiles(ctx context.Context) ([]QBittorrentFile,
 				defer func() { <-sem }()
 			}
 
-			// Get torrent files and properties concurrently with proper error handling
+			// Get torrent files, properties and trackers concurrently with proper error handling
 			type result struct {
-				files []fileInfo
-				props *torrentProperties
-				err   error
+				files    []fileInfo
+				props    *torrentProperties
+				trackers []trackerInfo
+				err      error
 			}
 			resultCh := make(chan result, 1)
 
@@ -189,7 +196,7 @@ func (q *QBittorrentClient) GetAllFiles(ctx context.Context) ([]QBittorrentFile,
 				var wg sync.WaitGroup
 				var filesErr, propsErr error
 
-				wg.Add(2)
+				wg.Add(3)
 				go func() {
 					defer wg.Done()
 					r.files, filesErr = q.getTorrentFiles(ctx, t.Hash)
@@ -200,6 +207,12 @@ func (q *QBittorrentClient) GetAllFiles(ctx context.Context) ([]QBittorrentFile,
 					r.props, propsErr = q.getTorrentProperties(ctx, t.Hash)
 				}()
 
+				// Tracker status is informational, a failure leaves it unknown rather than skipping the torrent
+				go func() {
+					defer wg.Done()
+					r.trackers, _ = q.getTorrentTrackers(ctx, t.Hash)
+				}()
+
 				wg.Wait()
 
 				// Check for errors
@@ -223,6 +236,7 @@ func (q *QBittorrentClient) GetAllFiles(ctx context.Context) ([]QBittorrentFile,
 				}
 
 				// Process files
+				seeding := t.seedingHealth(res.trackers)
 				var torrentQBFiles []QBittorrentFile
 				for _, f := range res.files {
 					// Build full file path using filepath.Join for safety
@@ -235,39 +249,52 @@ func (q *QBittorrentClient) GetAllFiles(ctx context.Context) ([]QBittorrentFile,
 						TorrentName: t.Name,
 						Category:    t.Category,
 						Tags:        t.Tags,
+						Seeding:     seeding,
 					})
 				}
 
-				// Append to results with mutex
+				// Hand the torrent's files to fn, one torrent at a time
 				mu.Lock()
-				allFiles = append(allFiles, torrentQBFiles...)
-				mu.Unlock()
+				defer mu.Unlock()
+				if fnErr == nil && ctx.Err() == nil {
+					if err := fn(torrentQBFiles); err != nil {
+						fnErr = err
+						cancel()
+					}
+				}
 			}
 		}(torrent)
 	}
 
 	wg.Wait()
-	return allFiles, nil
+	if fnErr != nil {
+		return fnErr
+	}
+	return parent.Err()
 }
 
-// GetSampleFile retrieves a single sample file from qBittorrent that matches the path prefix
-// This is optimized for path mapping validation - it stops as soon as it finds one matching file
-func (q *QBittorrentClient) GetSampleFile(pathPrefix string) (string, error) {
+// GetSampleFiles returns up to limit file paths under pathPrefix, one per torrent
+// This is optimized for path mapping validation and discovery - it stops once limit files are found
+func (q *QBittorrentClient) GetSampleFiles(ctx context.Context, pathPrefix string, limit int) ([]string, error) {
 	if err := q.login(); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Use background context (not cancellable)
-	ctx := context.Background()
-
 	// Get list of all torrents
 	torrents, err := q.getTorrents(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get torrents: %w", err)
+		return nil, fmt.Errorf("failed to get torrents: %w", err)
 	}
 
-	// Try each torrent until we find a matching file
+	var paths []string
 	for _, torrent := range torrents {
+		if len(paths) >= limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		// Get torrent files and properties
 		files, err := q.getTorrentFiles(ctx, torrent.Hash)
 		if err != nil {
@@ -281,24 +308,66 @@ func (q *QBittorrentClient) GetSampleFile(pathPrefix string) (string, error) {
 			continue
 		}
 
-		// Check each file
 		for _, f := range files {
-			fullPath := filepath.Join(props.SavePath, f.Name)
-			if fullPath != "" && (pathPrefix == "" || strings.HasPrefix(fullPath, pathPrefix)) {
-				return fullPath, nil
+			if fullPath := filepath.Join(props.SavePath, f.Name); matchesPrefix(fullPath, pathPrefix) {
+				paths = append(paths, fullPath)
+				break
 			}
 		}
 	}
 
-	// No matching file found
-	return "", nil
+	return paths, nil
 }
 
 type torrentInfo struct {
-	Hash     string `json:"hash"`
-	Name     string `json:"name"`
-	Category string `json:"category"`
-	Tags     string `json:"tags"`
+	Hash           string  `json:"hash"`
+	Name           string  `json:"name"`
+	Category       string  `json:"category"`
+	Tags           string  `json:"tags"`
+	Ratio          float64 `json:"ratio"`
+	SeedingTime    int64   `json:"seeding_time"` // Seconds
+	State          string  `json:"state"`
+	AddedOn        int64   `json:"added_on"`
+	MaxRatio       float64 `json:"max_ratio"`        // Effective ratio limit, -1 when unlimited
+	MaxSeedingTime int64   `json:"max_seeding_time"` // Effective seeding time limit in minutes, -1 when unlimited
+}
+
+// trackerInfo is an entry from /api/v2/torrents/trackers
+type trackerInfo struct {
+	URL    string `json:"url"`
+	Status int    `json:"status"` // 0 disabled, 1 not contacted, 2 working, 3 updating, 4 not working
+	Msg    string `json:"msg"`
+}
+
+// seedingHealth builds the seeding health from the torrent list entry and its trackers
+func (t torrentInfo) seedingHealth(trackers []trackerInfo) SeedingHealth {
+	health := SeedingHealth{
+		Ratio:            t.Ratio,
+		SeedingTime:      t.SeedingTime,
+		State:            t.State,
+		AddedOn:          t.AddedOn,
+		RatioLimit:       t.MaxRatio,
+		SeedingTimeLimit: -1,
+	}
+	if t.MaxSeedingTime >= 0 {
+		health.SeedingTimeLimit = t.MaxSeedingTime * 60
+	}
+
+	for _, tracker := range trackers {
+		// DHT, PeX and LSD are listed as disabled pseudo-trackers
+		switch tracker.Status {
+		case 0:
+			continue
+		case 2:
+			health.addTracker(tracker.URL, TrackerStatusWorking, tracker.Msg)
+		case 4:
+			health.addTracker(tracker.URL, TrackerStatusNotWorking, tracker.Msg)
+		default:
+			health.addTracker(tracker.URL, TrackerStatusUpdating, tracker.Msg)
+		}
+	}
+
+	return health
 }
 
 func (q *QBittorrentClient) getTorrents(ctx context.Context) ([]torrentInfo, error) {
@@ -364,6 +433,39 @@ func (q *QBittorrentClient) getTorrentFiles(ctx context.Context, hash string) ([
 	return files, nil
 }
 
+func (q *QBittorrentClient) getTorrentTrackers(ctx context.Context, hash string) ([]trackerInfo, error) {
+	u, err := url.Parse(q.getEffectiveURL() + "/api/v2/torrents/trackers")
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Add("hash", hash)
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("qBittorrent API returned status %d", resp.StatusCode)
+	}
+
+	var trackers []trackerInfo
+	if err := json.NewDecoder(resp.Body).Decode(&trackers); err != nil {
+		return nil, err
+	}
+
+	return trackers, nil
+}
+
 type torrentProperties struct {
 	SavePath string `json:"save_path"`
 }
@@ -400,3 +502,81 @@ func (q *QBittorrentClient) getTorrentProperties(ctx context.Context, hash strin
 
 	return &props, nil
 }
+
+// qbittorrentProvider registers qBittorrent with the provider registry
+type qbittorrentProvider struct{ providerInfo }
+
+func init() {
+	Register(&qbittorrentProvider{providerInfo{
+		name:        "qbittorrent",
+		displayName: "qBittorrent",
+		serviceType: ServiceTypeDownloadClient,
+		fields: []ConfigField{
+			{
+				Key: "url", Label: "qBittorrent URL", Kind: FieldURL,
+				Get: func(s *config.Services) string { return s.QBittorrent.URL },
+				Set: func(s *config.Services, v string) { s.QBittorrent.URL = v },
+			},
+			{
+				Key: "username", Label: "qBittorrent Username", Kind: FieldText,
+				Get: func(s *config.Services) string { return s.QBittorrent.Username },
+				Set: func(s *config.Services, v string) { s.QBittorrent.Username = v },
+			},
+			{
+				Key: "password", Label: "qBittorrent Password", Kind: FieldSecret,
+				Get: func(s *config.Services) string { return s.QBittorrent.Password },
+				Set: func(s *config.Services, v string) { s.QBittorrent.Password = v },
+			},
+			{
+				Key: "qui_proxy_url", Label: "qBittorrent Proxy URL", Kind: FieldURL,
+				Get: func(s *config.Services) string { return s.QBittorrent.QuiProxyURL },
+				Set: func(s *config.Services, v string) { s.QBittorrent.QuiProxyURL = v },
+			},
+		},
+	}})
+}
+
+// Configured reports whether either a direct URL or a qui proxy URL is set
+func (p *qbittorrentProvider) Configured(services *config.Services) bool {
+	return services.QBittorrent.URL != "" || services.QBittorrent.QuiProxyURL != ""
+}
+
+func (p *qbittorrentProvider) newClient(cfg *config.Config, timeout time.Duration) *QBittorrentClient {
+	qb := cfg.Services.QBittorrent
+	return NewQBittorrentClient(qb.URL, qb.Username, qb.Password, qb.QuiProxyURL, timeout)
+}
+
+func (p *qbittorrentProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return p.newClient(cfg, timeout)
+}
+
+func (p *qbittorrentProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.newClient(cfg, timeout).Test()
+}
+
+func (p *qbittorrentProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := p.newClient(cfg, timeout)
+	return pageFiles(ctx, func(fn func([]QBittorrentFile) error) error {
+		return client.ForEachFilePage(ctx, fn)
+	}, func(f QBittorrentFile) ServiceFile {
+		metadata := map[string]interface{}{
+			"torrent_hash": f.TorrentHash,
+			"torrent_name": f.TorrentName,
+			"category":     f.Category,
+			"tags":         f.Tags,
+		}
+		f.Seeding.addMetadata(metadata)
+
+		return ServiceFile{
+			Path:     f.Path,
+			Size:     f.Size,
+			Group:    f.TorrentName,
+			GroupID:  f.TorrentHash,
+			Metadata: metadata,
+		}
+	}, page)
+}
+
+func (p *qbittorrentProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	return p.newClient(cfg, timeout).GetSampleFiles(ctx, pathPrefix, limit)
+}
@@ -3,8 +3,11 @@ package api
 import (
 	"context"
 	"fmt"
-	"strings"
+	"maps"
+	"slices"
 	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
 )
 
 // RadarrClient handles communication with Radarr
@@ -28,21 +31,21 @@ func NewRadarrClient(baseURL, apiKey string, timeout time.Duration) *RadarrClien
 	}
 }
 
-// GetAllFiles retrieves all movie files tracked by Radarr
-func (r *RadarrClient) GetAllFiles(ctx context.Context) ([]RadarrFile, error) {
+// ForEachFilePage retrieves the movie files tracked by Radarr one movie at a time,
+// handing each movie's files to fn. Returning an error from fn stops the listing
+func (r *RadarrClient) ForEachFilePage(ctx context.Context, fn func([]RadarrFile) error) error {
 	// First, get all movies
 	movieMap, err := r.getAllMovies(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get movies: %w", err)
+		return fmt.Errorf("failed to get movies: %w", err)
 	}
 
 	// Then, get movie files for each movie
-	var files []RadarrFile
 	for movieID, movieInfo := range movieMap {
 		// Check for context cancellation
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		default:
 		}
 
@@ -56,10 +59,10 @@ func (r *RadarrClient) GetAllFiles(ctx context.Context) ([]RadarrFile, error) {
 		// Query movie files for this specific movie
 		endpoint := fmt.Sprintf("/api/v3/moviefile?movieId=%d", movieID)
 		if err := r.doRequest(ctx, endpoint, &movieFiles); err != nil {
-			return nil, fmt.Errorf("failed to get movie files for movie %d: %w", movieID, err)
+			return fmt.Errorf("failed to get movie files for movie %d: %w", movieID, err)
 		}
 
-		// Add all movie files for this movie
+		files := make([]RadarrFile, 0, len(movieFiles))
 		for _, mf := range movieFiles {
 			files = append(files, RadarrFile{
 				Path:       mf.Path,
@@ -69,46 +72,25 @@ func (r *RadarrClient) GetAllFiles(ctx context.Context) ([]RadarrFile, error) {
 				MovieID:    mf.MovieID,
 			})
 		}
+		if err := fn(files); err != nil {
+			return err
+		}
 	}
 
-	return files, nil
+	return nil
 }
 
-// GetSampleFile retrieves a single sample file from Radarr that matches the path prefix
-// This is optimized for path mapping validation - it stops as soon as it finds one matching file
-func (r *RadarrClient) GetSampleFile(pathPrefix string) (string, error) {
-	// Use background context (not cancellable)
-	ctx := context.Background()
-
-	// Get all movies
+// GetSampleFiles returns up to limit movie file paths under pathPrefix, one per movie
+// This is optimized for path mapping validation and discovery - it stops once limit files are found
+func (r *RadarrClient) GetSampleFiles(ctx context.Context, pathPrefix string, limit int) ([]string, error) {
 	movieMap, err := r.getAllMovies(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get movies: %w", err)
-	}
-
-	// Try each movie until we find a matching file
-	for movieID := range movieMap {
-		var movieFiles []struct {
-			Path string `json:"path"`
-		}
-
-		// Query movie files for this specific movie
-		endpoint := fmt.Sprintf("/api/v3/moviefile?movieId=%d", movieID)
-		if err := r.doRequest(ctx, endpoint, &movieFiles); err != nil {
-			// Log and continue to next movie
-			continue
-		}
-
-		// Check if any file matches the path prefix
-		for _, mf := range movieFiles {
-			if mf.Path != "" && (pathPrefix == "" || strings.HasPrefix(mf.Path, pathPrefix)) {
-				return mf.Path, nil
-			}
-		}
+		return nil, fmt.Errorf("failed to get movies: %w", err)
 	}
 
-	// No matching file found
-	return "", nil
+	return r.samplePaths(ctx, slices.Collect(maps.Keys(movieMap)), func(id int64) string {
+		return fmt.Sprintf("/api/v3/moviefile?movieId=%d", id)
+	}, pathPrefix, limit)
 }
 
 type movieInfo struct {
@@ -137,3 +119,57 @@ func (r *RadarrClient) getAllMovies(ctx context.Context) (map[int64]movieInfo, e
 
 	return movieMap, nil
 }
+
+// radarrProvider registers Radarr with the provider registry
+type radarrProvider struct{ providerInfo }
+
+func init() {
+	Register(&radarrProvider{providerInfo{
+		name:        "radarr",
+		displayName: "Radarr",
+		serviceType: ServiceTypePVR,
+		fields: []ConfigField{
+			{
+				Key: "url", Label: "Radarr URL", Kind: FieldURL, Required: true,
+				Get: func(s *config.Services) string { return s.Radarr.URL },
+				Set: func(s *config.Services, v string) { s.Radarr.URL = v },
+			},
+			{
+				Key: "api_key", Label: "Radarr API Key", Kind: FieldAPIKey, Required: true,
+				Get: func(s *config.Services) string { return s.Radarr.APIKey },
+				Set: func(s *config.Services, v string) { s.Radarr.APIKey = v },
+			},
+		},
+	}})
+}
+
+func (p *radarrProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return NewRadarrClient(cfg.Services.Radarr.URL, cfg.Services.Radarr.APIKey, timeout)
+}
+
+func (p *radarrProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.NewClient(cfg, timeout).Test()
+}
+
+func (p *radarrProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := NewRadarrClient(cfg.Services.Radarr.URL, cfg.Services.Radarr.APIKey, timeout)
+	return pageFiles(ctx, func(fn func([]RadarrFile) error) error {
+		return client.ForEachFilePage(ctx, fn)
+	}, func(f RadarrFile) ServiceFile {
+		return ServiceFile{
+			Path:    f.Path,
+			Size:    f.Size,
+			Group:   f.MovieTitle,
+			GroupID: fmt.Sprintf("%d", f.MovieID),
+			Metadata: map[string]interface{}{
+				"movie_title": f.MovieTitle,
+				"movie_year":  f.MovieYear,
+				"movie_id":    f.MovieID,
+			},
+		}
+	}, page)
+}
+
+func (p *radarrProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	return NewRadarrClient(cfg.Services.Radarr.URL, cfg.Services.Radarr.APIKey, timeout).GetSampleFiles(ctx, pathPrefix, limit)
+}
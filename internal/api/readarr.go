@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+)
+
+// ReadarrClient handles communication with Readarr
+type ReadarrClient struct {
+	*ArrClient
+}
+
+// ReadarrFile represents a book file (ebook or audiobook) tracked by Readarr
+type ReadarrFile struct {
+	Path       string
+	Size       int64
+	AuthorName string
+	BookTitle  string
+	AuthorID   int64
+	BookID     int64
+	BookFileID int64
+}
+
+// NewReadarrClient creates a new Readarr API client
+func NewReadarrClient(baseURL, apiKey string, timeout time.Duration) *ReadarrClient {
+	return &ReadarrClient{
+		ArrClient: newArrClientV1(baseURL, apiKey, "Readarr", "book", timeout),
+	}
+}
+
+// ForEachFilePage retrieves the book files tracked by Readarr one author at a time,
+// handing each author's files to fn. Returning an error from fn stops the listing
+func (r *ReadarrClient) ForEachFilePage(ctx context.Context, fn func([]ReadarrFile) error) error {
+	// First, get all authors
+	authorMap, err := r.getAllAuthors(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get authors: %w", err)
+	}
+
+	// Book titles are looked up from a single request rather than per author
+	bookMap, err := r.getAllBooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get books: %w", err)
+	}
+
+	// Then, get book files for each author
+	for authorID, authorName := range authorMap {
+		// Check for context cancellation
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		var bookFiles []struct {
+			ID       int64  `json:"id"`
+			AuthorID int64  `json:"authorId"`
+			BookID   int64  `json:"bookId"`
+			Path     string `json:"path"`
+			Size     int64  `json:"size"`
+		}
+
+		// Query book files for this specific author
+		endpoint := r.apiPath(fmt.Sprintf("/bookfile?authorId=%d", authorID))
+		if err := r.doRequest(ctx, endpoint, &bookFiles); err != nil {
+			return fmt.Errorf("failed to get book files for author %d: %w", authorID, err)
+		}
+
+		files := make([]ReadarrFile, 0, len(bookFiles))
+		for _, bf := range bookFiles {
+			files = append(files, ReadarrFile{
+				Path:       bf.Path,
+				Size:       bf.Size,
+				AuthorName: authorName,
+				BookTitle:  bookMap[bf.BookID], // Empty if book not found
+				AuthorID:   authorID,
+				BookID:     bf.BookID,
+				BookFileID: bf.ID,
+			})
+		}
+		if err := fn(files); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetSampleFiles returns up to limit book file paths under pathPrefix, one per author
+// This is optimized for path mapping validation and discovery - it stops once limit files are found
+func (r *ReadarrClient) GetSampleFiles(ctx context.Context, pathPrefix string, limit int) ([]string, error) {
+	authorMap, err := r.getAllAuthors(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authors: %w", err)
+	}
+
+	return r.samplePaths(ctx, slices.Collect(maps.Keys(authorMap)), func(id int64) string {
+		return r.apiPath(fmt.Sprintf("/bookfile?authorId=%d", id))
+	}, pathPrefix, limit)
+}
+
+func (r *ReadarrClient) getAllAuthors(ctx context.Context) (map[int64]string, error) {
+	var authors []struct {
+		ID         int64  `json:"id"`
+		AuthorName string `json:"authorName"`
+	}
+
+	if err := r.doRequest(ctx, r.apiPath("/author"), &authors); err != nil {
+		return nil, err
+	}
+
+	authorMap := make(map[int64]string)
+	for _, a := range authors {
+		authorMap[a.ID] = a.AuthorName
+	}
+
+	return authorMap, nil
+}
+
+func (r *ReadarrClient) getAllBooks(ctx context.Context) (map[int64]string, error) {
+	var books []struct {
+		ID    int64  `json:"id"`
+		Title string `json:"title"`
+	}
+
+	if err := r.doRequest(ctx, r.apiPath("/book"), &books); err != nil {
+		return nil, err
+	}
+
+	bookMap := make(map[int64]string)
+	for _, b := range books {
+		bookMap[b.ID] = b.Title
+	}
+
+	return bookMap, nil
+}
+
+// readarrProvider registers Readarr with the provider registry
+type readarrProvider struct{ providerInfo }
+
+func init() {
+	Register(&readarrProvider{providerInfo{
+		name:        "readarr",
+		displayName: "Readarr",
+		serviceType: ServiceTypePVR,
+		fields: []ConfigField{
+			{
+				Key: "url", Label: "Readarr URL", Kind: FieldURL, Required: true,
+				Get: func(s *config.Services) string { return s.Readarr.URL },
+				Set: func(s *config.Services, v string) { s.Readarr.URL = v },
+			},
+			{
+				Key: "api_key", Label: "Readarr API Key", Kind: FieldAPIKey, Required: true,
+				Get: func(s *config.Services) string { return s.Readarr.APIKey },
+				Set: func(s *config.Services, v string) { s.Readarr.APIKey = v },
+			},
+		},
+	}})
+}
+
+func (p *readarrProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return NewReadarrClient(cfg.Services.Readarr.URL, cfg.Services.Readarr.APIKey, timeout)
+}
+
+func (p *readarrProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.NewClient(cfg, timeout).Test()
+}
+
+func (p *readarrProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := NewReadarrClient(cfg.Services.Readarr.URL, cfg.Services.Readarr.APIKey, timeout)
+	return pageFiles(ctx, func(fn func([]ReadarrFile) error) error {
+		return client.ForEachFilePage(ctx, fn)
+	}, func(f ReadarrFile) ServiceFile {
+		return ServiceFile{
+			Path:    f.Path,
+			Size:    f.Size,
+			Group:   fmt.Sprintf("%s - %s", f.AuthorName, f.BookTitle),
+			GroupID: fmt.Sprintf("%d", f.BookID),
+			Metadata: map[string]interface{}{
+				"author_name":  f.AuthorName,
+				"book_title":   f.BookTitle,
+				"author_id":    f.AuthorID,
+				"book_id":      f.BookID,
+				"book_file_id": f.BookFileID,
+			},
+		}
+	}, page)
+}
+
+func (p *readarrProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	return NewReadarrClient(cfg.Services.Readarr.URL, cfg.Services.Readarr.APIKey, timeout).GetSampleFiles(ctx, pathPrefix, limit)
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+)
+
+// SABnzbdClient handles communication with the SABnzbd API
+type SABnzbdClient struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewSABnzbdClient creates a new SABnzbd client
+func NewSABnzbdClient(baseURL, apiKey string, timeout time.Duration) *SABnzbdClient {
+	return &SABnzbdClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Test tests the connection to SABnzbd
+func (s *SABnzbdClient) Test() error {
+	if _, err := s.getHistoryPage(context.Background(), 0, 1); err != nil {
+		return fmt.Errorf("failed to connect to SABnzbd at %s: %w. Check the URL and API key", s.baseURL, err)
+	}
+
+	return nil
+}
+
+// ForEachJobPage retrieves the completed jobs in the history a page at a time
+// Failed jobs are left out, their files were never moved to the completed folder.
+// Returning an error from fn stops the listing
+func (s *SABnzbdClient) ForEachJobPage(ctx context.Context, fn func([]UsenetJob) error) error {
+	for start := 0; ; start += constants.SABnzbdHistoryPageSize {
+		history, err := s.getHistoryPage(ctx, start, constants.SABnzbdHistoryPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to get history: %w", err)
+		}
+
+		jobs := make([]UsenetJob, 0, len(history.Slots))
+		for _, slot := range history.Slots {
+			if slot.Status != "Completed" {
+				continue
+			}
+			jobs = append(jobs, UsenetJob{
+				ID:          slot.NzoID,
+				Name:        slot.Name,
+				Category:    slot.Category,
+				Status:      slot.Status,
+				Storage:     slot.Storage,
+				CompletedAt: slot.Completed,
+			})
+		}
+		if err := fn(jobs); err != nil {
+			return err
+		}
+
+		if len(history.Slots) < constants.SABnzbdHistoryPageSize || start+len(history.Slots) >= history.NoOfSlots {
+			return nil
+		}
+	}
+}
+
+type sabnzbdHistory struct {
+	NoOfSlots int `json:"noofslots"`
+	Slots     []struct {
+		NzoID     string `json:"nzo_id"`
+		Name      string `json:"name"`
+		Category  string `json:"category"`
+		Status    string `json:"status"`
+		Storage   string `json:"storage"`
+		Completed int64  `json:"completed"`
+	} `json:"slots"`
+}
+
+func (s *SABnzbdClient) getHistoryPage(ctx context.Context, start, limit int) (*sabnzbdHistory, error) {
+	params := url.Values{}
+	params.Set("mode", "history")
+	params.Set("output", "json")
+	params.Set("apikey", s.apiKey)
+	params.Set("start", strconv.Itoa(start))
+	params.Set("limit", strconv.Itoa(limit))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+"/api?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("SABnzbd API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// Errors such as a wrong API key come back with status 200 and an error field
+	var result struct {
+		Status  *bool          `json:"status"`
+		Error   string         `json:"error"`
+		History sabnzbdHistory `json:"history"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse SABnzbd response: %w", err)
+	}
+	if result.Error != "" || (result.Status != nil && !*result.Status) {
+		return nil, fmt.Errorf("SABnzbd API error: %s", result.Error)
+	}
+
+	return &result.History, nil
+}
+
+// sabnzbdProvider registers SABnzbd with the provider registry
+type sabnzbdProvider struct{ providerInfo }
+
+func init() {
+	Register(&sabnzbdProvider{providerInfo{
+		name:        "sabnzbd",
+		displayName: "SABnzbd",
+		serviceType: ServiceTypeUsenetClient,
+		fields: []ConfigField{
+			{
+				Key: "url", Label: "SABnzbd URL", Kind: FieldURL, Required: true,
+				Get: func(s *config.Services) string { return s.SABnzbd.URL },
+				Set: func(s *config.Services, v string) { s.SABnzbd.URL = v },
+			},
+			{
+				Key: "api_key", Label: "SABnzbd API Key", Kind: FieldAPIKey, Required: true,
+				Get: func(s *config.Services) string { return s.SABnzbd.APIKey },
+				Set: func(s *config.Services, v string) { s.SABnzbd.APIKey = v },
+			},
+		},
+	}})
+}
+
+func (p *sabnzbdProvider) newClient(cfg *config.Config, timeout time.Duration) *SABnzbdClient {
+	return NewSABnzbdClient(cfg.Services.SABnzbd.URL, cfg.Services.SABnzbd.APIKey, timeout)
+}
+
+func (p *sabnzbdProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return p.newClient(cfg, timeout)
+}
+
+func (p *sabnzbdProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.newClient(cfg, timeout).Test()
+}
+
+func (p *sabnzbdProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := p.newClient(cfg, timeout)
+	return pageFiles(ctx, func(fn func([]UsenetFile) error) error {
+		return forEachUsenetFilePage(ctx, func(jobs func([]UsenetJob) error) error {
+			return client.ForEachJobPage(ctx, jobs)
+		}, usenetLocalPath(cfg, p.name), fn)
+	}, usenetServiceFile, page)
+}
+
+func (p *sabnzbdProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	client := p.newClient(cfg, timeout)
+	return sampleUsenetFiles(ctx, func(jobs func([]UsenetJob) error) error {
+		return client.ForEachJobPage(ctx, jobs)
+	}, usenetLocalPath(cfg, p.name), pathPrefix, limit)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Tracker status values recorded in download client usage metadata
+const (
+	TrackerStatusWorking      = "working"
+	TrackerStatusUpdating     = "updating"
+	TrackerStatusNotWorking   = "not_working"
+	TrackerStatusUnregistered = "unregistered"
+)
+
+// Reasons a torrent file is considered safe to delete
+const (
+	DeleteReasonSeedingGoalMet      = "seeding_goal_met"
+	DeleteReasonTrackerUnregistered = "tracker_unregistered"
+)
+
+// unregisteredTrackerMessages are substrings of tracker announce errors meaning the
+// tracker no longer knows the torrent (deleted, trumped or never registered)
+var unregisteredTrackerMessages = []string{
+	"unregistered",
+	"not registered",
+	"torrent not found",
+	"unknown torrent",
+	"torrent does not exist",
+	"infohash not found",
+	"torrent has been deleted",
+	"torrent has been nuked",
+	"trumped",
+}
+
+// trackerStatusRank orders tracker statuses so a torrent with several trackers reports the
+// best one: working > unregistered > updating > not working. A torrent is only treated as
+// unregistered when no other tracker still works for it
+var trackerStatusRank = map[string]int{
+	TrackerStatusNotWorking:   1,
+	TrackerStatusUpdating:     2,
+	TrackerStatusUnregistered: 3,
+	TrackerStatusWorking:      4,
+}
+
+// SeedingHealth describes how far a torrent is through its seeding goals
+type SeedingHealth struct {
+	Ratio            float64
+	SeedingTime      int64 // Seconds spent seeding
+	State            string
+	AddedOn          int64  // Unix timestamp
+	Tracker          string // Host of the tracker the status was taken from
+	TrackerStatus    string
+	TrackerMessage   string
+	RatioLimit       float64 // Negative when the torrent has no ratio goal
+	SeedingTimeLimit int64   // Seconds, negative when the torrent has no seeding time goal
+	LimitReached     bool    // The client reports the torrent stopped at its seeding limit
+}
+
+// GoalMet reports whether the torrent has reached its ratio or seeding time goal
+func (h SeedingHealth) GoalMet() bool {
+	if h.LimitReached {
+		return true
+	}
+	if h.RatioLimit >= 0 && h.Ratio >= h.RatioLimit {
+		return true
+	}
+	return h.SeedingTimeLimit >= 0 && h.SeedingTime >= h.SeedingTimeLimit
+}
+
+// addTracker folds one tracker's announce status into the torrent's tracker status
+// A message saying the torrent is unregistered overrides the status reported by the client
+func (h *SeedingHealth) addTracker(tracker, status, msg string) {
+	if isUnregisteredTrackerMessage(msg) {
+		status = TrackerStatusUnregistered
+	}
+	if trackerStatusRank[status] > trackerStatusRank[h.TrackerStatus] {
+		h.Tracker = trackerHost(tracker)
+		h.TrackerStatus = status
+		h.TrackerMessage = msg
+	}
+}
+
+// trackerHost returns the host of a tracker announce URL, or the value unchanged if it is not a URL
+func trackerHost(tracker string) string {
+	if u, err := url.Parse(tracker); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return tracker
+}
+
+// addMetadata records the seeding health in a usage metadata map
+func (h SeedingHealth) addMetadata(metadata map[string]interface{}) {
+	metadata["ratio"] = h.Ratio
+	metadata["seeding_time"] = h.SeedingTime
+	metadata["state"] = h.State
+	metadata["added_on"] = h.AddedOn
+	metadata["tracker"] = h.Tracker
+	metadata["tracker_status"] = h.TrackerStatus
+	metadata["tracker_message"] = h.TrackerMessage
+	metadata["ratio_limit"] = h.RatioLimit
+	metadata["seeding_time_limit"] = h.SeedingTimeLimit
+	metadata["seeding_goal_met"] = h.GoalMet()
+}
+
+// isUnregisteredTrackerMessage reports whether a tracker message says the torrent is gone
+func isUnregisteredTrackerMessage(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, pattern := range unregisteredTrackerMessages {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// SeedingDeleteReason returns why a download client usage record makes its file safe
+// to delete, or an empty string if the torrent still needs to seed
+func SeedingDeleteReason(metadata map[string]interface{}) string {
+	if status, _ := metadata["tracker_status"].(string); status == TrackerStatusUnregistered {
+		return DeleteReasonTrackerUnregistered
+	}
+	if met, _ := metadata["seeding_goal_met"].(bool); met {
+		return DeleteReasonSeedingGoalMet
+	}
+	return ""
+}
@@ -3,8 +3,11 @@ package api
 import (
 	"context"
 	"fmt"
-	"strings"
+	"maps"
+	"slices"
 	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
 )
 
 // SonarrClient handles communication with Sonarr
@@ -29,21 +32,21 @@ func NewSonarrClient(baseURL, apiKey string, timeout time.Duration) *SonarrClien
 	}
 }
 
-// GetAllFiles retrieves all episode files tracked by Sonarr
-func (s *SonarrClient) GetAllFiles(ctx context.Context) ([]SonarrFile, error) {
+// ForEachFilePage retrieves the episode files tracked by Sonarr one series at a time,
+// handing each series' files to fn. Returning an error from fn stops the listing
+func (s *SonarrClient) ForEachFilePage(ctx context.Context, fn func([]SonarrFile) error) error {
 	// First, get all series
 	seriesMap, err := s.getAllSeries(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get series: %w", err)
+		return fmt.Errorf("failed to get series: %w", err)
 	}
 
 	// Then, get episode files for each series
-	var files []SonarrFile
 	for seriesID, seriesTitle := range seriesMap {
 		// Check for context cancellation
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		default:
 		}
 
@@ -55,7 +58,7 @@ func (s *SonarrClient) GetAllFiles(ctx context.Context) ([]SonarrFile, error) {
 
 		episodesEndpoint := fmt.Sprintf("/api/v3/episode?seriesId=%d", seriesID)
 		if err := s.doRequest(ctx, episodesEndpoint, &episodes); err != nil {
-			return nil, fmt.Errorf("failed to get episodes for series %d: %w", seriesID, err)
+			return fmt.Errorf("failed to get episodes for series %d: %w", seriesID, err)
 		}
 
 		// Create map of episode file ID to episode number
@@ -77,10 +80,10 @@ func (s *SonarrClient) GetAllFiles(ctx context.Context) ([]SonarrFile, error) {
 		// Query episode files for this specific series
 		endpoint := fmt.Sprintf("/api/v3/episodefile?seriesId=%d", seriesID)
 		if err := s.doRequest(ctx, endpoint, &episodeFiles); err != nil {
-			return nil, fmt.Errorf("failed to get episode files for series %d: %w", seriesID, err)
+			return fmt.Errorf("failed to get episode files for series %d: %w", seriesID, err)
 		}
 
-		// Add all episode files for this series
+		files := make([]SonarrFile, 0, len(episodeFiles))
 		for _, ef := range episodeFiles {
 			files = append(files, SonarrFile{
 				Path:          ef.Path,
@@ -91,46 +94,25 @@ func (s *SonarrClient) GetAllFiles(ctx context.Context) ([]SonarrFile, error) {
 				EpisodeID:     ef.ID,
 			})
 		}
+		if err := fn(files); err != nil {
+			return err
+		}
 	}
 
-	return files, nil
+	return nil
 }
 
-// GetSampleFile retrieves a single sample file from Sonarr that matches the path prefix
-// This is optimized for path mapping validation - it stops as soon as it finds one matching file
-func (s *SonarrClient) GetSampleFile(pathPrefix string) (string, error) {
-	// Use background context (not cancellable)
-	ctx := context.Background()
-
-	// Get all series
+// GetSampleFiles returns up to limit episode file paths under pathPrefix, one per series
+// This is optimized for path mapping validation and discovery - it stops once limit files are found
+func (s *SonarrClient) GetSampleFiles(ctx context.Context, pathPrefix string, limit int) ([]string, error) {
 	seriesMap, err := s.getAllSeries(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get series: %w", err)
-	}
-
-	// Try each series until we find a matching file
-	for seriesID := range seriesMap {
-		var episodeFiles []struct {
-			Path string `json:"path"`
-		}
-
-		// Query episode files for this specific series
-		endpoint := fmt.Sprintf("/api/v3/episodefile?seriesId=%d", seriesID)
-		if err := s.doRequest(ctx, endpoint, &episodeFiles); err != nil {
-			// Log and continue to next series
-			continue
-		}
-
-		// Check if any file matches the path prefix
-		for _, ef := range episodeFiles {
-			if ef.Path != "" && (pathPrefix == "" || strings.HasPrefix(ef.Path, pathPrefix)) {
-				return ef.Path, nil
-			}
-		}
+		return nil, fmt.Errorf("failed to get series: %w", err)
 	}
 
-	// No matching file found
-	return "", nil
+	return s.samplePaths(ctx, slices.Collect(maps.Keys(seriesMap)), func(id int64) string {
+		return fmt.Sprintf("/api/v3/episodefile?seriesId=%d", id)
+	}, pathPrefix, limit)
 }
 
 func (s *SonarrClient) getAllSeries(ctx context.Context) (map[int64]string, error) {
@@ -150,3 +132,58 @@ func (s *SonarrClient) getAllSeries(ctx context.Context) (map[int64]string, erro
 
 	return seriesMap, nil
 }
+
+// sonarrProvider registers Sonarr with the provider registry
+type sonarrProvider struct{ providerInfo }
+
+func init() {
+	Register(&sonarrProvider{providerInfo{
+		name:        "sonarr",
+		displayName: "Sonarr",
+		serviceType: ServiceTypePVR,
+		fields: []ConfigField{
+			{
+				Key: "url", Label: "Sonarr URL", Kind: FieldURL, Required: true,
+				Get: func(s *config.Services) string { return s.Sonarr.URL },
+				Set: func(s *config.Services, v string) { s.Sonarr.URL = v },
+			},
+			{
+				Key: "api_key", Label: "Sonarr API Key", Kind: FieldAPIKey, Required: true,
+				Get: func(s *config.Services) string { return s.Sonarr.APIKey },
+				Set: func(s *config.Services, v string) { s.Sonarr.APIKey = v },
+			},
+		},
+	}})
+}
+
+func (p *sonarrProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return NewSonarrClient(cfg.Services.Sonarr.URL, cfg.Services.Sonarr.APIKey, timeout)
+}
+
+func (p *sonarrProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.NewClient(cfg, timeout).Test()
+}
+
+func (p *sonarrProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := NewSonarrClient(cfg.Services.Sonarr.URL, cfg.Services.Sonarr.APIKey, timeout)
+	return pageFiles(ctx, func(fn func([]SonarrFile) error) error {
+		return client.ForEachFilePage(ctx, fn)
+	}, func(f SonarrFile) ServiceFile {
+		return ServiceFile{
+			Path:    f.Path,
+			Size:    f.Size,
+			Group:   f.SeriesTitle,
+			GroupID: fmt.Sprintf("%d", f.EpisodeID),
+			Metadata: map[string]interface{}{
+				"series_title":   f.SeriesTitle,
+				"season_number":  f.SeasonNumber,
+				"episode_number": f.EpisodeNumber,
+				"episode_id":     f.EpisodeID,
+			},
+		}
+	}, page)
+}
+
+func (p *sonarrProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	return NewSonarrClient(cfg.Services.Sonarr.URL, cfg.Services.Sonarr.APIKey, timeout).GetSampleFiles(ctx, pathPrefix, limit)
+}
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
 	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
 )
 
 // StashClient handles communication with Stash
@@ -73,133 +75,103 @@ func (s *StashClient) Test() error {
 	return nil
 }
 
-// GetAllFiles retrieves all files tracked by Stash (scenes and galleries)
-func (s *StashClient) GetAllFiles(ctx context.Context) ([]StashFile, error) {
-	var allFiles []StashFile
+// stashPageSize is the number of scenes or galleries requested per page
+const stashPageSize = 100
+
+// stashSamplePages caps how many pages of each kind GetSampleFiles reads before giving up
+const stashSamplePages = 10
+
+// stashPageFunc fetches one page of scene or gallery files, returning the total count
+type stashPageFunc func(ctx context.Context, page, perPage int) ([]StashFile, int, error)
 
-	// Get scene files (videos)
-	sceneFiles, err := s.getAllSceneFiles(ctx)
+// ForEachFilePage retrieves the files tracked by Stash (scenes, then galleries) one page at a time,
+// handing each page's files to fn. Returning an error from fn stops the listing
+func (s *StashClient) ForEachFilePage(ctx context.Context, fn func([]StashFile) error) error {
+	scenes, err := s.forEachPage(ctx, "scenes", s.getFilesPage, stashPageSize, 0, fn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get scene files: %w", err)
+		return fmt.Errorf("failed to get scene files: %w", err)
 	}
-	allFiles = append(allFiles, sceneFiles...)
 
-	// Get gallery files (images)
-	galleryFiles, err := s.getAllGalleryFiles(ctx)
+	galleries, err := s.forEachPage(ctx, "galleries", s.getGalleriesPage, stashPageSize, 0, fn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get gallery files: %w", err)
+		return fmt.Errorf("failed to get gallery files: %w", err)
 	}
-	allFiles = append(allFiles, galleryFiles...)
 
-	log.Printf("Total Stash files found: %d (%d scenes, %d galleries)", len(allFiles), len(sceneFiles), len(galleryFiles))
-	return allFiles, nil
+	log.Printf("Total Stash files found: %d (%d scenes, %d galleries)", scenes+galleries, scenes, galleries)
+	return nil
 }
 
-// getAllSceneFiles retrieves all scene files (videos)
-func (s *StashClient) getAllSceneFiles(ctx context.Context) ([]StashFile, error) {
-	var allFiles []StashFile
-	page := 1
-	perPage := 100
-
-	for {
+// forEachPage pages through scenes or galleries, handing each page to fn, and returns how many files were read
+// maxPages limits the pages read (0 for all)
+func (s *StashClient) forEachPage(ctx context.Context, kind string, getPage stashPageFunc, perPage, maxPages int, fn func([]StashFile) error) (int, error) {
+	read := 0
+	for page := 1; maxPages == 0 || page <= maxPages; page++ {
 		// Check for context cancellation
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return read, ctx.Err()
 		default:
 		}
 
-		log.Printf("Fetching Stash scenes page %d (per_page: %d)", page, perPage)
+		log.Printf("Fetching Stash %s page %d (per_page: %d)", kind, page, perPage)
 
-		files, totalCount, err := s.getFilesPage(ctx, page, perPage)
+		files, totalCount, err := getPage(ctx, page, perPage)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get scenes page %d: %w", page, err)
+			return read, fmt.Errorf("failed to get %s page %d: %w", kind, page, err)
 		}
+		read += len(files)
 
-		allFiles = append(allFiles, files...)
-
-		log.Printf("Retrieved %d scene files from page %d (total so far: %d)", len(files), page, len(allFiles))
+		if err := fn(files); err != nil {
+			return read, err
+		}
 
-		// Check if we've retrieved all scenes
-		if len(allFiles) >= totalCount || len(files) == 0 {
+		// Check if we've retrieved everything
+		if read >= totalCount || len(files) == 0 {
 			break
 		}
-
-		page++
 	}
 
-	return allFiles, nil
+	return read, nil
 }
 
-// getAllGalleryFiles retrieves all gallery files (images)
-func (s *StashClient) getAllGalleryFiles(ctx context.Context) ([]StashFile, error) {
-	var allFiles []StashFile
-	page := 1
-	perPage := 100
-
-	for {
-		// Check for context cancellation
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
+// GetSampleFiles returns up to limit file paths under pathPrefix, half from scenes and half from galleries
+// This is optimized for path mapping validation and discovery - it reads at most stashSamplePages pages of each
+func (s *StashClient) GetSampleFiles(ctx context.Context, pathPrefix string, limit int) ([]string, error) {
+	var paths []string
+	for i, kind := range []string{"scenes", "galleries"} {
+		getPage := s.getFilesPage
+		if kind == "galleries" {
+			getPage = s.getGalleriesPage
 		}
 
-		log.Printf("Fetching Stash galleries page %d (per_page: %d)", page, perPage)
-
-		files, totalCount, err := s.getGalleriesPage(ctx, page, perPage)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get galleries page %d: %w", page, err)
+		// Scenes get half of the sample; galleries get what's left
+		share := limit - len(paths)
+		if i == 0 {
+			share = (limit + 1) / 2
 		}
-
-		allFiles = append(allFiles, files...)
-
-		log.Printf("Retrieved %d gallery files from page %d (total so far: %d)", len(files), page, len(allFiles))
-
-		// Check if we've retrieved all galleries
-		if len(allFiles) >= totalCount || len(files) == 0 {
+		if share <= 0 {
 			break
 		}
-
-		page++
-	}
-
-	return allFiles, nil
-}
-
-// GetSampleFile retrieves a single sample file from Stash that matches the path prefix
-// This is optimized for path mapping validation - it stops as soon as it finds one matching file
-func (s *StashClient) GetSampleFile(pathPrefix string) (string, error) {
-	// Use background context (not cancellable)
-	ctx := context.Background()
-
-	// Just fetch first page with small page size for efficiency
-	page := 1
-	perPage := 10
-
-	for page <= 10 { // Limit to checking first 10 pages (100 files max) to avoid long waits
-		files, totalCount, err := s.getFilesPage(ctx, page, perPage)
-		if err != nil {
-			return "", fmt.Errorf("failed to get scenes page %d: %w", page, err)
-		}
-
-		// Check if any file matches
-		for _, file := range files {
-			if file.Path != "" && (pathPrefix == "" || strings.HasPrefix(file.Path, pathPrefix)) {
-				return file.Path, nil
+		found := 0
+		_, err := s.forEachPage(ctx, kind, getPage, stashPageSize, stashSamplePages, func(files []StashFile) error {
+			for _, file := range files {
+				if !matchesPrefix(file.Path, pathPrefix) {
+					continue
+				}
+				paths = append(paths, file.Path)
+				found++
+				if found == share {
+					return errSampleComplete
+				}
 			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errSampleComplete) {
+			return nil, err
 		}
-
-		// If we've checked all available files, stop
-		if len(files) == 0 || len(files) < perPage || page*perPage >= totalCount {
-			break
-		}
-
-		page++
 	}
 
-	// No matching file found
-	return "", nil
+	return paths, nil
 }
 
 type graphQLRequest struct {
@@ -438,3 +410,57 @@ func (s *StashClient) doGraphQLRequest(ctx context.Context, reqBody graphQLReque
 
 	return nil
 }
+
+// stashProvider registers Stash with the provider registry
+type stashProvider struct{ providerInfo }
+
+func init() {
+	Register(&stashProvider{providerInfo{
+		name:        "stash",
+		displayName: "Stash",
+		serviceType: ServiceTypeMediaServer,
+		fields: []ConfigField{
+			{
+				Key: "url", Label: "Stash URL", Kind: FieldURL, Required: true,
+				Get: func(s *config.Services) string { return s.Stash.URL },
+				Set: func(s *config.Services, v string) { s.Stash.URL = v },
+			},
+			{
+				Key: "api_key", Label: "Stash API Key", Kind: FieldAPIKey,
+				Get: func(s *config.Services) string { return s.Stash.APIKey },
+				Set: func(s *config.Services, v string) { s.Stash.APIKey = v },
+			},
+		},
+	}})
+}
+
+func (p *stashProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return NewStashClient(cfg.Services.Stash.URL, cfg.Services.Stash.APIKey, timeout)
+}
+
+func (p *stashProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.NewClient(cfg, timeout).Test()
+}
+
+func (p *stashProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := NewStashClient(cfg.Services.Stash.URL, cfg.Services.Stash.APIKey, timeout)
+	return pageFiles(ctx, func(fn func([]StashFile) error) error {
+		return client.ForEachFilePage(ctx, fn)
+	}, func(f StashFile) ServiceFile {
+		return ServiceFile{
+			Path:    f.Path,
+			Size:    f.Size,
+			Group:   f.Title,
+			GroupID: f.SceneID,
+			Metadata: map[string]interface{}{
+				"scene_id": f.SceneID,
+				"title":    f.Title,
+				"studio":   f.Studio,
+			},
+		}
+	}, page)
+}
+
+func (p *stashProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	return NewStashClient(cfg.Services.Stash.URL, cfg.Services.Stash.APIKey, timeout).GetSampleFiles(ctx, pathPrefix, limit)
+}
@@ -0,0 +1,373 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+)
+
+// transmissionSessionHeader carries Transmission's CSRF token; requests without
+// the current value are rejected with 409 and the new value in the response
+const transmissionSessionHeader = "X-Transmission-Session-Id"
+
+// errTransmissionUnauthorized is returned when RPC authentication fails
+var errTransmissionUnauthorized = errors.New("Transmission authentication failed (401). Check username and password")
+
+// TransmissionClient handles communication with Transmission over its RPC interface
+type TransmissionClient struct {
+	rpcURL    string
+	username  string
+	password  string
+	client    *http.Client
+	sessionMu sync.Mutex // Protects sessionID
+	sessionID string
+}
+
+// TransmissionFile represents a file in a Transmission torrent
+// Transmission has no categories, so labels are reported as tags
+type TransmissionFile struct {
+	Path        string
+	Size        int64
+	TorrentHash string
+	TorrentName string
+	Tags        string
+	Seeding     SeedingHealth
+}
+
+// transmissionStates maps torrent-get status codes to names
+var transmissionStates = []string{"stopped", "check_wait", "checking", "download_wait", "downloading", "seed_wait", "seeding"}
+
+// NewTransmissionClient creates a new Transmission RPC client
+// The URL may be the web UI root (http://host:9091) or the full RPC endpoint
+func NewTransmissionClient(baseURL, username, password string, timeout time.Duration) *TransmissionClient {
+	rpcURL := strings.TrimSuffix(baseURL, "/")
+	if !strings.HasSuffix(rpcURL, "/rpc") {
+		rpcURL += "/transmission/rpc"
+	}
+
+	return &TransmissionClient{
+		rpcURL:   rpcURL,
+		username: username,
+		password: password,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// Test tests the connection to Transmission
+func (t *TransmissionClient) Test() error {
+	var session struct {
+		Version string `json:"version"`
+	}
+
+	if err := t.call(context.Background(), "session-get", map[string]interface{}{"fields": []string{"version"}}, &session); err != nil {
+		if errors.Is(err, errTransmissionUnauthorized) {
+			return err
+		}
+		return fmt.Errorf("failed to connect to Transmission at %s: %w. Check the URL is reachable", t.rpcURL, err)
+	}
+
+	return nil
+}
+
+// ForEachFilePage retrieves the files of all torrents, handing each torrent's files to fn
+// Transmission returns every torrent's file list in a single torrent-get call, so this can't page
+// the request itself. Returning an error from fn stops the listing
+func (t *TransmissionClient) ForEachFilePage(ctx context.Context, fn func([]TransmissionFile) error) error {
+	torrents, err := t.getTorrents(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get torrents: %w", err)
+	}
+
+	// Torrents following the global seeding settings need the session's ratio limit
+	var session struct {
+		SeedRatioLimit   float64 `json:"seedRatioLimit"`
+		SeedRatioLimited bool    `json:"seedRatioLimited"`
+	}
+	if err := t.call(ctx, "session-get", map[string]interface{}{"fields": []string{"seedRatioLimit", "seedRatioLimited"}}, &session); err != nil {
+		return fmt.Errorf("failed to get session settings: %w", err)
+	}
+	globalRatioLimit := -1.0
+	if session.SeedRatioLimited {
+		globalRatioLimit = session.SeedRatioLimit
+	}
+
+	for _, torrent := range torrents {
+		tags := strings.Join(torrent.Labels, ",")
+		seeding := torrent.seedingHealth(globalRatioLimit)
+		files := make([]TransmissionFile, 0, len(torrent.Files))
+		for _, f := range torrent.Files {
+			files = append(files, TransmissionFile{
+				Path:        filepath.Join(torrent.DownloadDir, f.Name),
+				Size:        f.Length,
+				TorrentHash: torrent.HashString,
+				TorrentName: torrent.Name,
+				Tags:        tags,
+				Seeding:     seeding,
+			})
+		}
+		if err := fn(files); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetSampleFiles returns up to limit file paths under pathPrefix, one per torrent
+// This is used for path mapping validation and discovery
+func (t *TransmissionClient) GetSampleFiles(ctx context.Context, pathPrefix string, limit int) ([]string, error) {
+	torrents, err := t.getTorrents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrents: %w", err)
+	}
+
+	var paths []string
+	for _, torrent := range torrents {
+		if len(paths) >= limit {
+			break
+		}
+		for _, f := range torrent.Files {
+			if path := filepath.Join(torrent.DownloadDir, f.Name); matchesPrefix(path, pathPrefix) {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+type transmissionTorrent struct {
+	HashString     string   `json:"hashString"`
+	Name           string   `json:"name"`
+	DownloadDir    string   `json:"downloadDir"`
+	Labels         []string `json:"labels"`
+	UploadRatio    float64  `json:"uploadRatio"`
+	SecondsSeeding int64    `json:"secondsSeeding"`
+	Status         int      `json:"status"`
+	AddedDate      int64    `json:"addedDate"`
+	SeedRatioLimit float64  `json:"seedRatioLimit"`
+	SeedRatioMode  int      `json:"seedRatioMode"` // 0 global setting, 1 per-torrent limit, 2 unlimited
+	IsFinished     bool     `json:"isFinished"`    // Stopped after reaching its seed ratio or idle limit
+	Files          []struct {
+		Name   string `json:"name"`
+		Length int64  `json:"length"`
+	} `json:"files"`
+	TrackerStats []struct {
+		Host                  string `json:"host"`
+		HasAnnounced          bool   `json:"hasAnnounced"`
+		LastAnnounceSucceeded bool   `json:"lastAnnounceSucceeded"`
+		LastAnnounceResult    string `json:"lastAnnounceResult"`
+	} `json:"trackerStats"`
+}
+
+// seedingHealth builds the torrent's seeding health
+// Transmission only has ratio goals (the idle limit is not a seeding time goal)
+func (t transmissionTorrent) seedingHealth(globalRatioLimit float64) SeedingHealth {
+	health := SeedingHealth{
+		Ratio:            t.UploadRatio,
+		SeedingTime:      t.SecondsSeeding,
+		AddedOn:          t.AddedDate,
+		RatioLimit:       -1,
+		SeedingTimeLimit: -1,
+		LimitReached:     t.IsFinished,
+	}
+	if t.Status >= 0 && t.Status < len(transmissionStates) {
+		health.State = transmissionStates[t.Status]
+	}
+
+	switch t.SeedRatioMode {
+	case 0:
+		health.RatioLimit = globalRatioLimit
+	case 1:
+		health.RatioLimit = t.SeedRatioLimit
+	}
+
+	for _, tracker := range t.TrackerStats {
+		switch {
+		case tracker.LastAnnounceSucceeded:
+			health.addTracker(tracker.Host, TrackerStatusWorking, tracker.LastAnnounceResult)
+		case tracker.HasAnnounced:
+			health.addTracker(tracker.Host, TrackerStatusNotWorking, tracker.LastAnnounceResult)
+		default:
+			health.addTracker(tracker.Host, TrackerStatusUpdating, tracker.LastAnnounceResult)
+		}
+	}
+
+	return health
+}
+
+func (t *TransmissionClient) getTorrents(ctx context.Context) ([]transmissionTorrent, error) {
+	var result struct {
+		Torrents []transmissionTorrent `json:"torrents"`
+	}
+
+	args := map[string]interface{}{
+		"fields": []string{
+			"hashString", "name", "downloadDir", "labels", "files",
+			"uploadRatio", "secondsSeeding", "status", "addedDate",
+			"seedRatioLimit", "seedRatioMode", "isFinished", "trackerStats",
+		},
+	}
+	if err := t.call(ctx, "torrent-get", args, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Torrents, nil
+}
+
+// call performs an RPC call, retrying once when Transmission hands out a new session ID
+func (t *TransmissionClient) call(ctx context.Context, method string, args interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"method":    method,
+		"arguments": args,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.post(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusConflict {
+		resp.Body.Close()
+
+		t.sessionMu.Lock()
+		t.sessionID = resp.Header.Get(transmissionSessionHeader)
+		t.sessionMu.Unlock()
+
+		if resp, err = t.post(ctx, body); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errTransmissionUnauthorized
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Transmission RPC returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var rpcResp struct {
+		Result    string          `json:"result"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("failed to parse Transmission response: %w", err)
+	}
+
+	if rpcResp.Result != "success" {
+		return fmt.Errorf("Transmission %s failed: %s", method, rpcResp.Result)
+	}
+
+	if err := json.Unmarshal(rpcResp.Arguments, result); err != nil {
+		return fmt.Errorf("failed to parse Transmission %s arguments: %w", method, err)
+	}
+
+	return nil
+}
+
+func (t *TransmissionClient) post(ctx context.Context, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", t.rpcURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if t.username != "" || t.password != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+
+	t.sessionMu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(transmissionSessionHeader, t.sessionID)
+	}
+	t.sessionMu.Unlock()
+
+	return t.client.Do(req)
+}
+
+// transmissionProvider registers Transmission with the provider registry
+type transmissionProvider struct{ providerInfo }
+
+func init() {
+	Register(&transmissionProvider{providerInfo{
+		name:        "transmission",
+		displayName: "Transmission",
+		serviceType: ServiceTypeDownloadClient,
+		fields: []ConfigField{
+			{
+				Key: "url", Label: "Transmission URL", Kind: FieldURL, Required: true,
+				Get: func(s *config.Services) string { return s.Transmission.URL },
+				Set: func(s *config.Services, v string) { s.Transmission.URL = v },
+			},
+			{
+				Key: "username", Label: "Transmission Username", Kind: FieldText,
+				Get: func(s *config.Services) string { return s.Transmission.Username },
+				Set: func(s *config.Services, v string) { s.Transmission.Username = v },
+			},
+			{
+				Key: "password", Label: "Transmission Password", Kind: FieldSecret,
+				Get: func(s *config.Services) string { return s.Transmission.Password },
+				Set: func(s *config.Services, v string) { s.Transmission.Password = v },
+			},
+		},
+	}})
+}
+
+func (p *transmissionProvider) newClient(cfg *config.Config, timeout time.Duration) *TransmissionClient {
+	tr := cfg.Services.Transmission
+	return NewTransmissionClient(tr.URL, tr.Username, tr.Password, timeout)
+}
+
+func (p *transmissionProvider) NewClient(cfg *config.Config, timeout time.Duration) ServiceClient {
+	return p.newClient(cfg, timeout)
+}
+
+func (p *transmissionProvider) Test(cfg *config.Config, timeout time.Duration) error {
+	return p.newClient(cfg, timeout).Test()
+}
+
+func (p *transmissionProvider) ListFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, page func([]ServiceFile) error) error {
+	client := p.newClient(cfg, timeout)
+	return pageFiles(ctx, func(fn func([]TransmissionFile) error) error {
+		return client.ForEachFilePage(ctx, fn)
+	}, func(f TransmissionFile) ServiceFile {
+		metadata := map[string]interface{}{
+			"torrent_hash": f.TorrentHash,
+			"torrent_name": f.TorrentName,
+			"category":     "",
+			"tags":         f.Tags,
+		}
+		f.Seeding.addMetadata(metadata)
+
+		return ServiceFile{
+			Path:     f.Path,
+			Size:     f.Size,
+			Group:    f.TorrentName,
+			GroupID:  f.TorrentHash,
+			Metadata: metadata,
+		}
+	}, page)
+}
+
+func (p *transmissionProvider) SampleFiles(ctx context.Context, cfg *config.Config, timeout time.Duration, pathPrefix string, limit int) ([]string, error) {
+	return p.newClient(cfg, timeout).GetSampleFiles(ctx, pathPrefix, limit)
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+)
+
+// UsenetJob is a completed download in a Usenet client's history
+type UsenetJob struct {
+	ID          string
+	Name        string
+	Category    string
+	Status      string
+	Storage     string // Final file or directory of the job, as the client reports it
+	CompletedAt int64  // Unix timestamp
+}
+
+// UsenetFile represents a file a completed Usenet job left on disk
+type UsenetFile struct {
+	Path string // Path as the client would report it, under the job's storage
+	Size int64
+	Job  UsenetJob
+}
+
+// usenetJobFiles lists the files a job left in its storage. Usenet clients only report where a job
+// was stored, so its files are read from disk through localPath, which turns a client path into one
+// media-finder can read. Jobs whose storage is gone (moved by an import or deleted) have no files
+func usenetJobFiles(job UsenetJob, localPath func(string) string) ([]UsenetFile, error) {
+	if job.Storage == "" {
+		return nil, nil
+	}
+
+	root := localPath(job.Storage)
+	info, err := os.Stat(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []UsenetFile{{Path: job.Storage, Size: info.Size(), Job: job}}, nil
+	}
+
+	var files []UsenetFile
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, UsenetFile{Path: filepath.Join(job.Storage, rel), Size: info.Size(), Job: job})
+		return nil
+	})
+	return files, err
+}
+
+// usenetLocalPath returns a function translating a Usenet client's paths to paths media-finder can
+// read, through the client's service path mappings and the local path mappings
+func usenetLocalPath(cfg *config.Config, service string) func(string) string {
+	return func(servicePath string) string {
+		return cfg.TranslatePathToContainer(cfg.TranslatePathToHost(servicePath, service))
+	}
+}
+
+// forEachUsenetFilePage hands the files each history job left on disk to fn, a job at a time
+func forEachUsenetFilePage(ctx context.Context, listJobs func(fn func([]UsenetJob) error) error, localPath func(string) string, fn func([]UsenetFile) error) error {
+	return listJobs(func(jobs []UsenetJob) error {
+		for _, job := range jobs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			files, err := usenetJobFiles(job, localPath)
+			if err != nil {
+				return err
+			}
+			if err := fn(files); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// sampleUsenetFiles returns up to limit paths under pathPrefix, the first file of each job
+func sampleUsenetFiles(ctx context.Context, listJobs func(fn func([]UsenetJob) error) error, localPath func(string) string, pathPrefix string, limit int) ([]string, error) {
+	var paths []string
+	err := listJobs(func(jobs []UsenetJob) error {
+		for _, job := range jobs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			files, err := usenetJobFiles(job, localPath)
+			if err != nil {
+				return err
+			}
+			for _, f := range files {
+				if matchesPrefix(f.Path, pathPrefix) {
+					paths = append(paths, f.Path)
+					break
+				}
+			}
+			if len(paths) >= limit {
+				return errSampleComplete
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errSampleComplete) {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// usenetServiceFile maps a Usenet job file to a ServiceFile
+func usenetServiceFile(f UsenetFile) ServiceFile {
+	return ServiceFile{
+		Path:    f.Path,
+		Size:    f.Size,
+		Group:   f.Job.Name,
+		GroupID: f.Job.ID,
+		Metadata: map[string]interface{}{
+			"job_id":       f.Job.ID,
+			"job_name":     f.Job.Name,
+			"category":     f.Job.Category,
+			"status":       f.Job.Status,
+			"completed_at": f.Job.CompletedAt,
+		},
+	}
+}
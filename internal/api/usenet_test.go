@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+)
+
+func TestSABnzbdListFiles(t *testing.T) {
+	local := t.TempDir()
+	writeFile(t, filepath.Join(local, "tv", "Show.S01E01", "Show.S01E01.mkv"), 10)
+	writeFile(t, filepath.Join(local, "tv", "Show.S01E01", "Subs", "Show.S01E01.en.srt"), 2)
+	writeFile(t, filepath.Join(local, "movies", "Movie.2020.mkv"), 5)
+
+	slots := []map[string]interface{}{
+		{"nzo_id": "SABnzbd_nzo_1", "name": "Show.S01E01", "category": "tv", "status": "Completed", "storage": "/downloads/complete/tv/Show.S01E01", "completed": 1700000000},
+		{"nzo_id": "SABnzbd_nzo_2", "name": "Broken", "category": "tv", "status": "Failed", "storage": "/downloads/incomplete/Broken"},
+		{"nzo_id": "SABnzbd_nzo_3", "name": "Imported", "category": "tv", "status": "Completed", "storage": "/downloads/complete/tv/Imported"},
+		{"nzo_id": "SABnzbd_nzo_4", "name": "Movie.2020", "category": "movies", "status": "Completed", "storage": "/downloads/complete/movies/Movie.2020.mkv"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("apikey") != "secret" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": false, "error": "API Key Incorrect"})
+			return
+		}
+		start, _ := strconv.Atoi(q.Get("start"))
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		end := min(start+limit, len(slots))
+		start = min(start, end)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"history": map[string]interface{}{"noofslots": len(slots), "slots": slots[start:end]},
+		})
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Services: config.Services{SABnzbd: config.SABnzbdConfig{URL: server.URL, APIKey: "secret"}},
+		ServicePathMappings: map[string][]config.PathMapping{
+			"sabnzbd": {{Service: "/downloads/complete", Local: local}},
+		},
+	}
+	provider := Lookup("sabnzbd")
+
+	var files []ServiceFile
+	err := provider.ListFiles(context.Background(), cfg, time.Second, func(page []ServiceFile) error {
+		files = append(files, page...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	sort.Strings(paths)
+	want := []string{
+		"/downloads/complete/movies/Movie.2020.mkv",
+		"/downloads/complete/tv/Show.S01E01/Show.S01E01.mkv",
+		"/downloads/complete/tv/Show.S01E01/Subs/Show.S01E01.en.srt",
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("paths[%d] = %s, want %s", i, paths[i], want[i])
+		}
+	}
+	for _, f := range files {
+		if f.Path == want[1] && (f.Size != 10 || f.GroupID != "SABnzbd_nzo_1" || f.Metadata["category"] != "tv") {
+			t.Errorf("unexpected file %+v", f)
+		}
+	}
+
+	samples, err := provider.SampleFiles(context.Background(), cfg, time.Second, "/downloads/complete/tv", 5)
+	if err != nil {
+		t.Fatalf("SampleFiles: %v", err)
+	}
+	if len(samples) != 1 || samples[0] != want[1] {
+		t.Errorf("samples = %v, want [%s]", samples, want[1])
+	}
+
+	cfg.Services.SABnzbd.APIKey = "wrong"
+	if err := provider.Test(cfg, time.Second); err == nil {
+		t.Error("Test with a wrong API key succeeded")
+	}
+}
+
+func writeFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Roles
+const (
+	RoleAdmin = "admin" // Full access, including destructive and admin routes
+	RoleUser  = "user"  // Read access plus non-destructive actions (scans, exports)
+)
+
+// Password hashing parameters (PBKDF2-HMAC-SHA256)
+const (
+	passwordScheme     = "pbkdf2-sha256"
+	passwordIterations = 600000 // OWASP 2023 recommendation for PBKDF2-HMAC-SHA256
+	passwordSaltLength = 16
+	passwordKeyLength  = 32
+
+	// MinPasswordLength is the minimum accepted password length
+	MinPasswordLength = 8
+)
+
+// APIKeyPrefix identifies media-finder API keys (helps secret scanners and users recognise them)
+const APIKeyPrefix = "mfk_"
+
+// ValidRole reports whether role is a known role
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleUser
+}
+
+// HashPassword hashes a password for storage
+// Format: pbkdf2-sha256$<iterations>$<base64 salt>$<base64 key>
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, passwordKeyLength)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return fmt.Sprintf("%s$%d$%s$%s",
+		passwordScheme,
+		passwordIterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPassword reports whether password matches a hash produced by HashPassword
+func CheckPassword(password, encoded string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != passwordScheme {
+		return false
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+// GenerateToken returns a random URL-safe token suitable for session cookies
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateAPIKey returns a new random API key
+func GenerateAPIKey() (string, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + token, nil
+}
+
+// HashToken hashes a session token or API key for storage and lookup
+// Tokens are high-entropy random values, so a fast unsalted hash is sufficient
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// DisplayPrefix returns the leading characters of an API key for identification in the UI
+func DisplayPrefix(key string) string {
+	const n = len(APIKeyPrefix) + 6
+	if len(key) <= n {
+		return key
+	}
+	return key[:n]
+}
@@ -2,11 +2,13 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/mmenanno/media-usage-finder/internal/cron"
 	"gopkg.in/yaml.v3"
 )
 
@@ -41,6 +43,30 @@ type Config struct {
 	DuplicateDetection     DuplicateDetectionConfig     `yaml:"duplicate_detection"`
 	DuplicateConsolidation DuplicateConsolidationConfig `yaml:"duplicate_consolidation"`
 
+	// Scheduled task configuration
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+
+	// Real-time filesystem watching
+	Watcher WatcherConfig `yaml:"watcher"`
+
+	// Inbound webhooks from services that trigger targeted rescans
+	Webhooks WebhookConfig `yaml:"webhooks"`
+
+	// Trash directory for files deleted from the filesystem
+	Quarantine QuarantineConfig `yaml:"quarantine"`
+
+	// Sanity checks on service file listings before they change orphaned status
+	Safeguards SafeguardConfig `yaml:"safeguards"`
+
+	// Retention policies for cleaning up orphaned files automatically
+	CleanupPolicies []CleanupPolicy `yaml:"cleanup_policies"`
+
+	// Outbound notifications for scan and cleanup events
+	Notifications NotificationConfig `yaml:"notifications"`
+
+	// Web UI / API authentication
+	Auth AuthConfig `yaml:"auth"`
+
 	// Internal caching (not serialized)
 	pathCache *PathCache `yaml:"-"`
 }
@@ -55,12 +81,20 @@ type PathMapping struct {
 
 // Services contains configuration for all external services
 type Services struct {
-	Plex        PlexConfig        `yaml:"plex"`
-	Sonarr      SonarrConfig      `yaml:"sonarr"`
-	Radarr      RadarrConfig      `yaml:"radarr"`
-	QBittorrent QBittorrentConfig `yaml:"qbittorrent"`
-	Stash       StashConfig       `yaml:"stash"`
-	Calibre     CalibreConfig     `yaml:"calibre"`
+	Plex         PlexConfig         `yaml:"plex"`
+	Jellyfin     JellyfinConfig     `yaml:"jellyfin"`
+	Emby         JellyfinConfig     `yaml:"emby"`
+	Sonarr       SonarrConfig       `yaml:"sonarr"`
+	Radarr       RadarrConfig       `yaml:"radarr"`
+	Lidarr       LidarrConfig       `yaml:"lidarr"`
+	Readarr      ReadarrConfig      `yaml:"readarr"`
+	QBittorrent  QBittorrentConfig  `yaml:"qbittorrent"`
+	Transmission TransmissionConfig `yaml:"transmission"`
+	Deluge       DelugeConfig       `yaml:"deluge"`
+	SABnzbd      SABnzbdConfig      `yaml:"sabnzbd"`
+	NZBGet       NZBGetConfig       `yaml:"nzbget"`
+	Stash        StashConfig        `yaml:"stash"`
+	Calibre      CalibreConfig      `yaml:"calibre"`
 }
 
 // PlexConfig contains Plex server configuration
@@ -70,6 +104,14 @@ type PlexConfig struct {
 	Libraries []string `yaml:"libraries"` // Library keys to scan (empty = all libraries)
 }
 
+// JellyfinConfig contains Jellyfin or Emby server configuration
+// Emby shares the Jellyfin API, so both services use the same settings
+type JellyfinConfig struct {
+	URL       string   `yaml:"url"`
+	APIKey    string   `yaml:"api_key"`
+	Libraries []string `yaml:"libraries"` // Library IDs to scan (empty = all libraries)
+}
+
 // SonarrConfig contains Sonarr configuration
 type SonarrConfig struct {
 	URL    string `yaml:"url"`
@@ -82,6 +124,18 @@ type RadarrConfig struct {
 	APIKey string `yaml:"api_key"`
 }
 
+// LidarrConfig contains Lidarr configuration
+type LidarrConfig struct {
+	URL    string `yaml:"url"`
+	APIKey string `yaml:"api_key"`
+}
+
+// ReadarrConfig contains Readarr configuration
+type ReadarrConfig struct {
+	URL    string `yaml:"url"`
+	APIKey string `yaml:"api_key"`
+}
+
 // QBittorrentConfig contains qBittorrent configuration
 type QBittorrentConfig struct {
 	URL         string `yaml:"url"`
@@ -90,6 +144,32 @@ type QBittorrentConfig struct {
 	QuiProxyURL string `yaml:"qui_proxy_url"`
 }
 
+// TransmissionConfig contains Transmission configuration
+type TransmissionConfig struct {
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"` // Optional, only needed when RPC authentication is enabled
+	Password string `yaml:"password"`
+}
+
+// DelugeConfig contains Deluge Web UI configuration
+type DelugeConfig struct {
+	URL      string `yaml:"url"`
+	Password string `yaml:"password"`
+}
+
+// SABnzbdConfig contains SABnzbd configuration
+type SABnzbdConfig struct {
+	URL    string `yaml:"url"`
+	APIKey string `yaml:"api_key"`
+}
+
+// NZBGetConfig contains NZBGet configuration
+type NZBGetConfig struct {
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"` // Control username and password, optional when NZBGet has none set
+	Password string `yaml:"password"`
+}
+
 // StashConfig contains Stash configuration
 type StashConfig struct {
 	URL    string `yaml:"url"`
@@ -129,6 +209,111 @@ type DuplicateConsolidationConfig struct {
 	RequireManualApproval bool  `yaml:"require_manual_approval"` // Require manual approval for each group
 	VerifyBeforeDelete   bool   `yaml:"verify_before_delete"`   // Re-hash files before deletion
 	Strategy             string `yaml:"strategy"`               // Consolidation strategy ("least_full_disk" or "preferred_disk")
+	CrossDiskMode        string `yaml:"cross_disk_mode"`        // How extra copies are removed ("delete" or "move_and_hardlink")
+	SameDiskMode         string `yaml:"same_disk_mode"`         // How same-disk duplicates share space ("hardlink" or "reflink")
+}
+
+// SchedulerConfig contains cron schedules for automatically triggered tasks
+// Each schedule is a standard 5-field cron expression; an empty schedule disables that task
+type SchedulerConfig struct {
+	Enabled             bool   `yaml:"enabled"`               // Master switch for all scheduled tasks
+	FullScanCron        string `yaml:"full_scan_cron"`        // Full filesystem scan (e.g., "0 3 * * 0")
+	IncrementalScanCron string `yaml:"incremental_scan_cron"` // Incremental filesystem scan (e.g., "0 */6 * * *")
+	ServiceUpdateCron   string `yaml:"service_update_cron"`   // Update usage from all configured services
+	HashScanCron        string `yaml:"hash_scan_cron"`        // Hash scan for duplicate detection
+	CleanupCron         string `yaml:"cleanup_cron"`          // Remove database entries for deleted files
+}
+
+// WatcherConfig contains configuration for real-time filesystem watching (Linux only)
+// The watcher updates the files table as scan paths change, between scheduled scans
+type WatcherConfig struct {
+	Enabled             bool          `yaml:"enabled"`              // Watch scan paths with inotify
+	DebounceInterval    time.Duration `yaml:"debounce_interval"`    // How long bursts of events are coalesced before being written
+	ReassociateInterval time.Duration `yaml:"reassociate_interval"` // How often changed files are matched against services again
+}
+
+// WebhookConfig contains configuration for the webhook endpoints services call when files change
+// Sonarr, Radarr, Plex and qBittorrent report the files they touched, and only those are rescanned
+type WebhookConfig struct {
+	Enabled          bool          `yaml:"enabled"`           // Accept webhooks at /api/webhooks/<service>
+	DebounceInterval time.Duration `yaml:"debounce_interval"` // How long reported paths are collected before being rescanned together
+}
+
+// QuarantineConfig contains configuration for holding deleted files in a trash directory
+// Files are moved to a trash directory at the root of their filesystem (a rename, never a copy)
+// and can be restored until they are purged
+type QuarantineConfig struct {
+	Enabled        bool          `yaml:"enabled"`          // Move deleted files to the trash instead of removing them
+	Retention      time.Duration `yaml:"retention"`        // How long quarantined files are kept before being purged
+	MinFreePercent float64       `yaml:"min_free_percent"` // Purge the oldest quarantined files on a filesystem with less free space than this
+}
+
+// SafeguardConfig contains the checks each full file listing from a service must pass before it
+// replaces the service's usage. A listing that fails is ignored and the service is marked stale:
+// its previous usage is kept and orphan-based deletes are blocked until it is acknowledged
+type SafeguardConfig struct {
+	Enabled          bool    `yaml:"enabled"`            // Check listings against the last accepted listing
+	MaxDropPercent   float64 `yaml:"max_drop_percent"`   // Fail a listing with this much smaller a file count (0 = only fail empty listings)
+	MinPreviousFiles int     `yaml:"min_previous_files"` // Only check the drop when the last accepted listing had at least this many files
+}
+
+// CleanupPolicy is a retention rule for orphaned files, evaluated after each completed scan
+// A file matches when it is orphaned and meets every condition that is set. Matches are only
+// reported until the policy is enforced
+type CleanupPolicy struct {
+	Name       string        `yaml:"name"`
+	Enforce    bool          `yaml:"enforce"`    // Act on matching files; otherwise they only appear in the report
+	Action     string        `yaml:"action"`     // "quarantine" or "delete"
+	Paths      []string      `yaml:"paths"`      // Path prefixes a file must be under (empty = anywhere)
+	Extensions []string      `yaml:"extensions"` // Extensions including the dot, e.g. ".nfo" (empty = any)
+	MinAge     time.Duration `yaml:"min_age"`    // Orphaned for at least this long (0 = any age)
+	MinSize    int64         `yaml:"min_size"`   // Minimum size in bytes (0 = no minimum)
+	MaxSize    int64         `yaml:"max_size"`   // Maximum size in bytes (0 = no maximum)
+}
+
+// NotificationConfig contains the sinks notified about scan and cleanup events
+type NotificationConfig struct {
+	OrphanThreshold int                `yaml:"orphan_threshold"` // Notify when a scan newly orphans at least this many files (0 = never)
+	Sinks           []NotificationSink `yaml:"sinks"`
+}
+
+// NotificationSink is a destination for notifications
+// Which fields apply depends on the type: url for webhook, discord, slack, ntfy and gotify,
+// token for ntfy and gotify, and the smtp_* fields, from and to for email
+type NotificationSink struct {
+	Name            string            `yaml:"name"`
+	Type            string            `yaml:"type"`             // webhook, discord, slack, ntfy, gotify or email
+	Events          []string          `yaml:"events"`           // Event types to send (empty = all)
+	URL             string            `yaml:"url"`              // Webhook URL, ntfy topic URL or Gotify server URL
+	Token           string            `yaml:"token"`            // ntfy access token or Gotify application token
+	Priority        int               `yaml:"priority"`         // ntfy (1-5) or Gotify message priority (0 = default)
+	Headers         map[string]string `yaml:"headers"`          // Extra HTTP headers
+	SMTPHost        string            `yaml:"smtp_host"`        // Email server
+	SMTPPort        int               `yaml:"smtp_port"`        // Email server port, usually 587
+	Username        string            `yaml:"username"`         // SMTP username (empty = no authentication)
+	Password        string            `yaml:"password"`         // SMTP password
+	From            string            `yaml:"from"`             // Sender address
+	To              []string          `yaml:"to"`               // Recipient addresses
+	TitleTemplate   string            `yaml:"title_template"`   // Go template for the title (empty = default)
+	MessageTemplate string            `yaml:"message_template"` // Go template for the message (empty = default)
+}
+
+// notificationEvents are the event types notification sinks can subscribe to
+var notificationEvents = map[string]bool{
+	"scan_completed":   true,
+	"scan_failed":      true,
+	"orphan_threshold": true,
+	"missing_files":    true,
+	"consolidation":    true,
+	"service_stale":    true,
+}
+
+// AuthConfig contains configuration for web UI and API authentication
+// Users and API keys are stored in the database, not in this file
+type AuthConfig struct {
+	Enabled       bool          `yaml:"enabled"`        // Require login / API key for all routes except /health and static assets
+	SessionTTL    time.Duration `yaml:"session_ttl"`    // How long a login session stays valid
+	SecureCookies bool          `yaml:"secure_cookies"` // Set the Secure flag on session cookies (enable when served over HTTPS)
 }
 
 // Default returns a default configuration
@@ -187,6 +372,34 @@ func Default() *Config {
 			RequireManualApproval: false,
 			VerifyBeforeDelete:   true,
 			Strategy:             "least_full_disk",
+			CrossDiskMode:        "delete",
+			SameDiskMode:         "hardlink",
+		},
+		Scheduler: SchedulerConfig{
+			Enabled: false, // Opt-in: nothing runs automatically until enabled
+		},
+		Watcher: WatcherConfig{
+			Enabled:             false, // Opt-in: each watched directory uses an inotify watch
+			DebounceInterval:    2 * time.Second,
+			ReassociateInterval: 5 * time.Minute,
+		},
+		Webhooks: WebhookConfig{
+			Enabled:          false, // Opt-in: services need a webhook pointed at this server anyway
+			DebounceInterval: 30 * time.Second,
+		},
+		Safeguards: SafeguardConfig{
+			Enabled:          true, // A service that comes back empty or half-empty shouldn't orphan its files
+			MaxDropPercent:   50,
+			MinPreviousFiles: 100,
+		},
+		Quarantine: QuarantineConfig{
+			Enabled:        true, // Deleted files can be restored until the retention period passes
+			Retention:      7 * 24 * time.Hour,
+			MinFreePercent: 5,
+		},
+		Auth: AuthConfig{
+			Enabled:    true, // New installs only; the first admin account needs the setup token from the server log
+			SessionTTL: 7 * 24 * time.Hour,
 		},
 	}
 }
@@ -205,6 +418,8 @@ func Load(path string) (*Config, error) {
 	}
 
 	cfg := Default()
+	// Config files written before authentication existed keep it off until it's enabled explicitly
+	cfg.Auth.Enabled = false
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
@@ -304,6 +519,74 @@ func (c *Config) TranslatePathToContainer(hostPath string) string {
 	return result
 }
 
+// LocalPathMappingFor returns the local path mapping TranslatePathToContainer applies to a host path,
+// or nil if none applies
+func (c *Config) LocalPathMappingFor(hostPath string) *PathMapping {
+	var bestMatch *PathMapping
+	for i, mapping := range c.LocalPathMappings {
+		if strings.HasPrefix(hostPath, mapping.Local) && (bestMatch == nil || len(mapping.Local) > len(bestMatch.Local)) {
+			bestMatch = &c.LocalPathMappings[i]
+		}
+	}
+	return bestMatch
+}
+
+// ServicePathMappingFor returns the path mapping TranslatePathToHost applies to a service path, or nil
+// if none applies
+func (c *Config) ServicePathMappingFor(servicePath, service string) *PathMapping {
+	var bestMatch *PathMapping
+	mappings := c.ServicePathMappings[service]
+	for i, mapping := range mappings {
+		if strings.HasPrefix(servicePath, mapping.Service) && (bestMatch == nil || len(mapping.Service) > len(bestMatch.Service)) {
+			bestMatch = &mappings[i]
+		}
+	}
+	return bestMatch
+}
+
+// TranslatePathToService translates a path in media-finder's database to the path a service would
+// report for it, the reverse of TranslatePathToHost. Returns the service path mapping that applied,
+// or nil if none did and the path is returned unchanged
+func (c *Config) TranslatePathToService(localPath, service string) (string, *PathMapping) {
+	var bestMatch *PathMapping
+	mappings := c.ServicePathMappings[service]
+	for i, mapping := range mappings {
+		if strings.HasPrefix(localPath, mapping.Local) && (bestMatch == nil || len(mapping.Local) > len(bestMatch.Local)) {
+			bestMatch = &mappings[i]
+		}
+	}
+	if bestMatch == nil {
+		return localPath, nil
+	}
+
+	remainder := strings.TrimPrefix(localPath, bestMatch.Local)
+	return filepath.Join(bestMatch.Service, remainder), bestMatch
+}
+
+// WithServicePathMapping returns a service's path mappings with mapping added, replacing any mapping
+// of the same service path. The configured mappings are left unchanged
+func (c *Config) WithServicePathMapping(service string, mapping PathMapping) []PathMapping {
+	var mappings []PathMapping
+	for _, m := range c.ServicePathMappings[service] {
+		if m.Service != mapping.Service {
+			mappings = append(mappings, m)
+		}
+	}
+	return append(mappings, mapping)
+}
+
+// WithLocalPathMapping returns the local path mappings with mapping added, replacing any mapping of the
+// same container path. The configured mappings are left unchanged
+func (c *Config) WithLocalPathMapping(mapping PathMapping) []PathMapping {
+	var mappings []PathMapping
+	for _, m := range c.LocalPathMappings {
+		if m.Service != mapping.Service {
+			mappings = append(mappings, m)
+		}
+	}
+	return append(mappings, mapping)
+}
+
 // ClearPathCache clears the path translation cache
 func (c *Config) ClearPathCache() {
 	if c.pathCache != nil {
@@ -321,6 +604,12 @@ func (c *Config) GetPathCacheStats() (hits, total, evictions uint64, size int, h
 
 // translatePath performs the actual path translation
 func (c *Config) translatePath(sourcePath string, mappings []PathMapping) string {
+	return ApplyPathMappings(sourcePath, mappings)
+}
+
+// ApplyPathMappings translates a service path with the longest matching mapping, returning it
+// unchanged if none matches. Lets callers try out mappings that aren't configured yet
+func ApplyPathMappings(sourcePath string, mappings []PathMapping) string {
 	// Find the longest matching service path
 	var bestMatch PathMapping
 	maxLen := 0
@@ -388,9 +677,172 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid path mappings: %w", err)
 	}
 
+	if c.Auth.Enabled && c.Auth.SessionTTL < time.Minute {
+		return fmt.Errorf("auth.session_ttl must be at least 1 minute")
+	}
+
+	if c.Watcher.Enabled {
+		if c.Watcher.DebounceInterval < 100*time.Millisecond {
+			return fmt.Errorf("watcher.debounce_interval must be at least 100ms")
+		}
+		if c.Watcher.ReassociateInterval < time.Minute {
+			return fmt.Errorf("watcher.reassociate_interval must be at least 1 minute")
+		}
+	}
+
+	if c.Webhooks.Enabled && c.Webhooks.DebounceInterval < time.Second {
+		return fmt.Errorf("webhooks.debounce_interval must be at least 1s")
+	}
+
+	if c.Quarantine.Enabled {
+		if c.Quarantine.Retention < time.Hour {
+			return fmt.Errorf("quarantine.retention must be at least 1 hour")
+		}
+		if c.Quarantine.MinFreePercent < 0 || c.Quarantine.MinFreePercent >= 100 {
+			return fmt.Errorf("quarantine.min_free_percent must be between 0 and 100")
+		}
+	}
+
+	if c.Safeguards.MaxDropPercent < 0 || c.Safeguards.MaxDropPercent >= 100 {
+		return fmt.Errorf("safeguards.max_drop_percent must be between 0 and 100")
+	}
+	if c.Safeguards.MinPreviousFiles < 0 {
+		return fmt.Errorf("safeguards.min_previous_files cannot be negative")
+	}
+
+	if err := c.validateCleanupPolicies(); err != nil {
+		return err
+	}
+
+	if err := c.validateNotifications(); err != nil {
+		return err
+	}
+
+	// Validate cron schedules (validated even when the scheduler is disabled so bad values aren't persisted)
+	if err := c.Scheduler.validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// validate checks that every configured cron expression parses
+func (s *SchedulerConfig) validate() error {
+	schedules := []struct {
+		name string
+		expr string
+	}{
+		{"scheduler.full_scan_cron", s.FullScanCron},
+		{"scheduler.incremental_scan_cron", s.IncrementalScanCron},
+		{"scheduler.service_update_cron", s.ServiceUpdateCron},
+		{"scheduler.hash_scan_cron", s.HashScanCron},
+		{"scheduler.cleanup_cron", s.CleanupCron},
+	}
+
+	for _, sched := range schedules {
+		if strings.TrimSpace(sched.expr) == "" {
+			continue
+		}
+		if err := cron.Validate(sched.expr); err != nil {
+			return fmt.Errorf("%s: %w", sched.name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateCleanupPolicies checks that every cleanup policy is named, has a known action and
+// narrows down which orphans it matches
+func (c *Config) validateCleanupPolicies() error {
+	names := make(map[string]bool)
+	for i, p := range c.CleanupPolicies {
+		context := fmt.Sprintf("cleanup_policies[%d]", i)
+		if strings.TrimSpace(p.Name) == "" {
+			return fmt.Errorf("%s: name is required", context)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("%s: duplicate policy name %q", context, p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Action != "quarantine" && p.Action != "delete" {
+			return fmt.Errorf("%s (%s): action must be \"quarantine\" or \"delete\"", context, p.Name)
+		}
+		for _, path := range p.Paths {
+			if !filepath.IsAbs(path) {
+				return fmt.Errorf("%s (%s): paths must be absolute (got: %s)", context, p.Name, path)
+			}
+		}
+		if p.MinAge < 0 || p.MinSize < 0 || p.MaxSize < 0 {
+			return fmt.Errorf("%s (%s): min_age, min_size and max_size cannot be negative", context, p.Name)
+		}
+		if p.MaxSize > 0 && p.MaxSize < p.MinSize {
+			return fmt.Errorf("%s (%s): max_size must be at least min_size", context, p.Name)
+		}
+
+		// A policy without conditions would match every orphan
+		if len(p.Paths) == 0 && len(p.Extensions) == 0 && p.MinAge == 0 && p.MinSize == 0 && p.MaxSize == 0 {
+			return fmt.Errorf("%s (%s): at least one of paths, extensions, min_age, min_size or max_size is required", context, p.Name)
+		}
+	}
+	return nil
+}
+
+// validateNotifications checks that every notification sink is named and has what its type needs
+func (c *Config) validateNotifications() error {
+	if c.Notifications.OrphanThreshold < 0 {
+		return fmt.Errorf("notifications.orphan_threshold cannot be negative")
+	}
+
+	names := make(map[string]bool)
+	for i, sink := range c.Notifications.Sinks {
+		context := fmt.Sprintf("notifications.sinks[%d]", i)
+		if strings.TrimSpace(sink.Name) == "" {
+			return fmt.Errorf("%s: name is required", context)
+		}
+		if names[sink.Name] {
+			return fmt.Errorf("%s: duplicate sink name %q", context, sink.Name)
+		}
+		names[sink.Name] = true
+
+		switch sink.Type {
+		case "webhook", "discord", "slack", "ntfy":
+			if !isHTTPURL(sink.URL) {
+				return fmt.Errorf("%s (%s): url must be an http:// or https:// URL", context, sink.Name)
+			}
+		case "gotify":
+			if !isHTTPURL(sink.URL) {
+				return fmt.Errorf("%s (%s): url must be an http:// or https:// URL", context, sink.Name)
+			}
+			if sink.Token == "" {
+				return fmt.Errorf("%s (%s): token is required", context, sink.Name)
+			}
+		case "email":
+			if sink.SMTPHost == "" || sink.SMTPPort <= 0 {
+				return fmt.Errorf("%s (%s): smtp_host and smtp_port are required", context, sink.Name)
+			}
+			if sink.From == "" || len(sink.To) == 0 {
+				return fmt.Errorf("%s (%s): from and to are required", context, sink.Name)
+			}
+		default:
+			return fmt.Errorf("%s (%s): type must be one of webhook, discord, slack, ntfy, gotify or email", context, sink.Name)
+		}
+
+		for _, event := range sink.Events {
+			if !notificationEvents[event] {
+				return fmt.Errorf("%s (%s): unknown event %q", context, sink.Name, event)
+			}
+		}
+	}
+	return nil
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
 // validatePathMappings validates all path mappings
 func (c *Config) validatePathMappings() error {
 	// Validate local path mappings
@@ -11,6 +11,12 @@ const (
 	// DefaultScansPerPage is the default number of scans per page
 	DefaultScansPerPage = 20
 
+	// DefaultQuarantineItemsPerPage is the default number of quarantined files per page
+	DefaultQuarantineItemsPerPage = 50
+
+	// RecentOperationRunsShown is the number of consolidation and hardlink runs listed on the quarantine page
+	RecentOperationRunsShown = 20
+
 	// MaxFilesPerPage is the maximum number of files that can be requested per page
 	MaxFilesPerPage = 1000
 
@@ -62,6 +68,18 @@ const (
 	// MaxAPITimeoutMultiplier is the maximum multiplier for API timeout
 	MaxAPITimeoutMultiplier = 2
 
+	// ServiceFilePageSize is the number of files a service provider hands to the scanner per page
+	ServiceFilePageSize = 5000
+
+	// JellyfinItemsPageSize is the number of items requested per page from Jellyfin and Emby
+	JellyfinItemsPageSize = 500
+
+	// PlexItemsPageSize is the number of items requested per page from a Plex library section
+	PlexItemsPageSize = 500
+
+	// SABnzbdHistoryPageSize is the number of history jobs requested per page from SABnzbd
+	SABnzbdHistoryPageSize = 500
+
 	// ProgressPollIntervalMS is the interval for polling progress updates (milliseconds)
 	ProgressPollIntervalMS = 2000
 
@@ -93,3 +111,75 @@ const (
 	// PathCacheCleanupThreshold is the percentage at which to trigger cleanup
 	PathCacheCleanupThreshold = 0.9
 )
+
+// Quarantine constants
+const (
+	// QuarantineDirName is the trash directory created at the root of each filesystem holding deleted files
+	// Directories with this name are never scanned or watched
+	QuarantineDirName = ".media-finder-trash"
+
+	// QuarantinePurgeIntervalMinutes is how often expired quarantined files are purged
+	QuarantinePurgeIntervalMinutes = 60
+)
+
+// Cleanup policy constants
+const (
+	// PolicyRunsKept is the number of cleanup policy runs kept for the report page
+	PolicyRunsKept = 10
+
+	// DefaultPolicyMatchesPerPage is the default number of policy matches per page
+	DefaultPolicyMatchesPerPage = 50
+)
+
+// Scan report constants
+const (
+	// ScanReportsKept is the number of scan reports kept; older reports and their changes are removed
+	ScanReportsKept = 30
+
+	// DefaultScanChangesPerPage is the default number of changes per page of a scan report
+	DefaultScanChangesPerPage = 100
+)
+
+// Service snapshot constants
+const (
+	// ServiceSnapshotsKept is the number of file listing snapshots kept per service
+	ServiceSnapshotsKept = 30
+
+	// DefaultSnapshotDiffPerPage is the default number of files per page of a snapshot diff
+	DefaultSnapshotDiffPerPage = 100
+)
+
+// Orphan explainer constants
+const (
+	// OrphanExplainerMatches is the number of closest reported paths shown per service
+	OrphanExplainerMatches = 5
+
+	// MinNameSimilarity is the share of a file name (0-1) a reported path's name must have in common
+	// with it to count as a near match
+	MinNameSimilarity = 0.6
+)
+
+// Reconciliation constants
+const (
+	// MappingProposalExamples is the number of example file pairs stored with a mapping proposal
+	MappingProposalExamples = 5
+
+	// DiscoverySampleSize is the number of paths sampled from a service's listing to discover mappings
+	DiscoverySampleSize = 300
+
+	// DiscoveryCandidatesPerPath is the number of scanned files ending like a sampled path compared with it
+	DiscoveryCandidatesPerPath = 20
+
+	// DiscoverySuffixComponents is the most trailing components of a sampled path looked up among the
+	// scanned files; shorter suffixes are tried when none match
+	DiscoverySuffixComponents = 3
+
+	// DiscoveryMinMatches is the number of sampled paths a discovered mapping must match to be proposed
+	DiscoveryMinMatches = 2
+)
+
+// Usage history constants
+const (
+	// FileUsageHistoryLimit is the number of usage events shown in a file's details
+	FileUsageHistoryLimit = 50
+)
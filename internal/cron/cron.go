@@ -0,0 +1,224 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed standard 5-field cron expression
+// (minute, hour, day of month, month, day of week)
+type Schedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+
+	// Standard cron semantics: when both day fields are restricted,
+	// a time matches if EITHER the day of month or the day of week matches
+	domRestricted bool
+	dowRestricted bool
+}
+
+// fieldBounds describes the valid range for a cron field
+type fieldBounds struct {
+	name string
+	min  int
+	max  int
+}
+
+var (
+	minuteBounds = fieldBounds{"minute", 0, 59}
+	hourBounds   = fieldBounds{"hour", 0, 23}
+	domBounds    = fieldBounds{"day of month", 1, 31}
+	monthBounds  = fieldBounds{"month", 1, 12}
+	dowBounds    = fieldBounds{"day of week", 0, 7} // 0 and 7 are both Sunday
+)
+
+// descriptors maps the supported @-shorthands to their 5-field equivalents
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// maxSearchYears bounds Next so an impossible schedule (e.g. "0 0 31 2 *") terminates
+const maxSearchYears = 5
+
+// Parse parses a cron expression
+// Supports *, lists (1,2,3), ranges (1-5), steps (*/15, 1-30/5) and the
+// @hourly, @daily, @weekly, @monthly, @yearly shorthands
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("cron expression is empty")
+	}
+
+	if strings.HasPrefix(expr, "@") {
+		expanded, ok := descriptors[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("unknown cron descriptor: %s", expr)
+		}
+		expr = expanded
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields (minute hour day month weekday), got %d", len(fields))
+	}
+
+	s := &Schedule{}
+	var err error
+
+	if s.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+
+	// Fold Sunday=7 onto Sunday=0 so time.Weekday can be used directly
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+		s.dow &^= 1 << 7
+	}
+
+	s.domRestricted = fields[2] != "*" && fields[2] != "?"
+	s.dowRestricted = fields[4] != "*" && fields[4] != "?"
+
+	return s, nil
+}
+
+// Validate reports whether expr is a valid cron expression
+func Validate(expr string) error {
+	_, err := Parse(expr)
+	return err
+}
+
+// parseField parses a single comma-separated cron field into a bitmask
+func parseField(field string, bounds fieldBounds) (uint64, error) {
+	var mask uint64
+
+	for _, part := range strings.Split(field, ",") {
+		if part == "" {
+			return 0, fmt.Errorf("invalid %s field %q: empty list element", bounds.name, field)
+		}
+
+		rangePart := part
+		step := 1
+
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			rangePart = part[:idx]
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid %s step in %q", bounds.name, part)
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			lo, hi = bounds.min, bounds.max
+		case strings.Contains(rangePart, "-"):
+			bits := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bits[0]); err != nil {
+				return 0, fmt.Errorf("invalid %s range start in %q", bounds.name, part)
+			}
+			if hi, err = strconv.Atoi(bits[1]); err != nil {
+				return 0, fmt.Errorf("invalid %s range end in %q", bounds.name, part)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s value %q", bounds.name, part)
+			}
+			lo = n
+			hi = n
+			// "5/10" means starting at 5, every 10 until the end of the range
+			if step > 1 {
+				hi = bounds.max
+			}
+		}
+
+		if lo < bounds.min || hi > bounds.max || lo > hi {
+			return 0, fmt.Errorf("%s value %q out of range (%d-%d)", bounds.name, part, bounds.min, bounds.max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+
+	return mask, nil
+}
+
+// Next returns the first time strictly after t that matches the schedule
+// Returns the zero time if no match exists within a few years (impossible schedule)
+func (s *Schedule) Next(t time.Time) time.Time {
+	// Cron has minute resolution - start at the next whole minute
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = startOfDay(t.Year(), t.Month()+1, 1, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = startOfDay(t.Year(), t.Month(), t.Day()+1, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			// Adding the minutes rather than building the next hour with time.Date keeps moving
+			// forward through a DST gap, where time.Date would return an earlier time
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// startOfDay returns the first minute of a day, normalizing the date like time.Date
+// When a DST change skips midnight, the day starts at the end of the gap
+func startOfDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	noon := time.Date(year, month, day, 12, 0, 0, 0, loc)
+	t := time.Date(noon.Year(), noon.Month(), noon.Day(), 0, 0, 0, 0, loc)
+	for t.Day() != noon.Day() {
+		t = t.Add(time.Hour)
+	}
+	return t
+}
+
+// dayMatches applies the day-of-month / day-of-week matching rules
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domRestricted && s.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
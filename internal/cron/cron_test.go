@@ -0,0 +1,195 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+// bits returns the mask with the given values set
+func bits(values ...int) uint64 {
+	var mask uint64
+	for _, v := range values {
+		mask |= 1 << uint(v)
+	}
+	return mask
+}
+
+func TestParseField(t *testing.T) {
+	tests := []struct {
+		field  string
+		bounds fieldBounds
+		want   uint64
+	}{
+		{"*/15", minuteBounds, bits(0, 15, 30, 45)},
+		{"1-5/2", minuteBounds, bits(1, 3, 5)},
+		{"5/20", minuteBounds, bits(5, 25, 45)},
+		{"1,2,10", hourBounds, bits(1, 2, 10)},
+		{"1-3,20-23", hourBounds, bits(1, 2, 3, 20, 21, 22, 23)},
+		{"*", monthBounds, bits(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12)},
+		{"?", dowBounds, bits(0, 1, 2, 3, 4, 5, 6, 7)},
+		{"31", domBounds, bits(31)},
+	}
+	for _, tt := range tests {
+		got, err := parseField(tt.field, tt.bounds)
+		if err != nil {
+			t.Errorf("parseField(%q, %s): %v", tt.field, tt.bounds.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseField(%q, %s) = %b, want %b", tt.field, tt.bounds.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"@fortnightly",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"1- * * * *",
+		"1,,2 * * * *",
+		"* * * JAN *", // Month and weekday names aren't supported
+		"* * * * MON",
+	}
+	for _, expr := range tests {
+		if err := Validate(expr); err == nil {
+			t.Errorf("Validate(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestParseSundayAndDescriptors(t *testing.T) {
+	s, err := Parse("0 0 * * 7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.dow != bits(0) {
+		t.Errorf("weekday 7 parsed as %b, want Sunday (0)", s.dow)
+	}
+
+	weekly, err := Parse("@WEEKLY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *weekly != *s {
+		t.Errorf("@weekly = %+v, want %+v", weekly, s)
+	}
+}
+
+func TestNext(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		// Strictly after, at minute resolution
+		{"*/15 * * * *", utc(2024, 9, 2, 10, 15), utc(2024, 9, 2, 10, 30)},
+		{"*/15 * * * *", time.Date(2024, 9, 2, 10, 14, 59, 0, time.UTC), utc(2024, 9, 2, 10, 15)},
+		{"0 3 * * *", utc(2024, 9, 2, 3, 0), utc(2024, 9, 3, 3, 0)},
+		{"0 9-17/4 * * *", utc(2024, 9, 2, 10, 0), utc(2024, 9, 2, 13, 0)},
+		{"30 6 * * 1,3,5", utc(2024, 9, 3, 0, 0), utc(2024, 9, 4, 6, 30)},
+		{"0 0 1 */3 *", utc(2024, 2, 15, 0, 0), utc(2024, 4, 1, 0, 0)},
+		{"@yearly", utc(2024, 9, 2, 0, 0), utc(2025, 1, 1, 0, 0)},
+		// 2024-09-07 is a Saturday: the 10th is a Tuesday and comes before Friday the 13th
+		{"0 0 10 * 5", utc(2024, 9, 7, 0, 0), utc(2024, 9, 10, 0, 0)},
+		// 2024-09-11 is a Wednesday: Friday the 13th matches either way
+		{"0 0 10 * 5", utc(2024, 9, 11, 0, 0), utc(2024, 9, 13, 0, 0)},
+		// A Friday that isn't the 10th matches when both day fields are restricted
+		{"0 0 10 * 5", utc(2024, 9, 14, 0, 0), utc(2024, 9, 20, 0, 0)},
+		// With only the day of month restricted, the weekday doesn't matter
+		{"0 0 10 * *", utc(2024, 9, 11, 0, 0), utc(2024, 10, 10, 0, 0)},
+		// With only the day of week restricted, the day of month doesn't matter
+		{"0 0 * * 5", utc(2024, 9, 14, 0, 0), utc(2024, 9, 20, 0, 0)},
+		// Leap days are found within the search limit
+		{"0 0 29 2 *", utc(2024, 3, 1, 0, 0), utc(2028, 2, 29, 0, 0)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%s) = %s, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestNextImpossibleSchedule(t *testing.T) {
+	for _, expr := range []string{"0 0 30 2 *", "0 0 31 4 *"} {
+		s, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", expr, err)
+		}
+		if got := s.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+			t.Errorf("%q.Next() = %s, want the zero time", expr, got)
+		}
+	}
+}
+
+func TestNextAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	local := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2024, month, day, hour, min, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		// Clocks go from 02:00 EST to 03:00 EDT on March 10
+		{"first hour after the gap", "0 3 * * *", local(time.March, 10, 1, 30), local(time.March, 10, 3, 0)},
+		{"skipped hour runs the next day", "30 2 * * *", local(time.March, 10, 1, 0), local(time.March, 11, 2, 30)},
+		{"every 15 minutes across the gap", "*/15 * * * *", local(time.March, 10, 1, 50), local(time.March, 10, 3, 0)},
+		// Clocks go from 02:00 EDT back to 01:00 EST on November 3
+		{"hourly across the repeated hour", "0 * * * *", time.Date(2024, time.November, 3, 5, 50, 0, 0, time.UTC), time.Date(2024, time.November, 3, 6, 0, 0, 0, time.UTC)},
+		{"daily after the change", "0 3 * * *", local(time.November, 3, 0, 30), local(time.November, 3, 3, 0)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		got := s.Next(tt.from.In(loc))
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: %q.Next(%s) = %s, want %s", tt.name, tt.expr, tt.from.In(loc), got, tt.want)
+		}
+	}
+}
+
+func TestNextWhenMidnightIsSkipped(t *testing.T) {
+	// Chile's clocks go from 00:00 to 01:00 on September 8, 2024
+	loc, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	s, err := Parse("0 12 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, time.September, 7, 13, 0, 0, 0, loc)
+	want := time.Date(2024, time.September, 8, 12, 0, 0, 0, loc)
+	if got := s.Next(from); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", from, got, want)
+	}
+	if got := startOfDay(2024, time.September, 8, loc); got.Day() != 8 || got.Hour() != 1 {
+		t.Errorf("startOfDay() = %s, want 01:00 on September 8", got)
+	}
+}
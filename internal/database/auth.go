@@ -0,0 +1,357 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// User represents a web UI user account
+type User struct {
+	ID           int64
+	Username     string
+	PasswordHash string
+	Role         string
+	CreatedAt    time.Time
+	LastLoginAt  *time.Time
+}
+
+// APIKey represents an API key used for automation
+// The key itself is never stored - only its hash and a short display prefix
+type APIKey struct {
+	ID         int64
+	Name       string
+	KeyPrefix  string
+	Role       string
+	CreatedBy  *int64
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// IsRevoked reports whether the key has been revoked
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// CountUsers returns the number of user accounts
+func (db *DB) CountUsers() (int, error) {
+	var count int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// CountAdmins returns the number of admin user accounts
+func (db *DB) CountAdmins() (int, error) {
+	var count int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM users WHERE role = 'admin'`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count admins: %w", err)
+	}
+	return count, nil
+}
+
+// CreateUser creates a new user account
+func (db *DB) CreateUser(username, passwordHash, role string) (*User, error) {
+	user := &User{
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         role,
+		CreatedAt:    time.Now(),
+	}
+
+	err := db.conn.QueryRow(`
+		INSERT INTO users (username, password_hash, role, created_at)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`, username, passwordHash, role, user.CreatedAt.Unix()).Scan(&user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// scanUser scans a user row (id, username, password_hash, role, created_at, last_login_at)
+func scanUser(row interface{ Scan(...interface{}) error }) (*User, error) {
+	user := &User{}
+	var createdAt int64
+	var lastLoginAt sql.NullInt64
+
+	if err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &createdAt, &lastLoginAt); err != nil {
+		return nil, err
+	}
+
+	user.CreatedAt = time.Unix(createdAt, 0)
+	if lastLoginAt.Valid {
+		t := time.Unix(lastLoginAt.Int64, 0)
+		user.LastLoginAt = &t
+	}
+
+	return user, nil
+}
+
+// GetUserByUsername returns a user by username (case-insensitive), or nil if not found
+func (db *DB) GetUserByUsername(username string) (*User, error) {
+	row := db.conn.QueryRow(`
+		SELECT id, username, password_hash, role, created_at, last_login_at
+		FROM users
+		WHERE username = ?
+	`, username)
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// GetUserByID returns a user by ID, or nil if not found
+func (db *DB) GetUserByID(id int64) (*User, error) {
+	row := db.conn.QueryRow(`
+		SELECT id, username, password_hash, role, created_at, last_login_at
+		FROM users
+		WHERE id = ?
+	`, id)
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// ListUsers returns all user accounts ordered by username
+func (db *DB) ListUsers() ([]*User, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, username, password_hash, role, created_at, last_login_at
+		FROM users
+		ORDER BY username
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// UpdateUserPassword replaces a user's password hash and signs out all of their sessions
+func (db *DB) UpdateUserPassword(userID int64, passwordHash string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE users SET password_hash = ? WHERE id = ?`, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to clear sessions: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// UpdateUserLastLogin records a successful login
+func (db *DB) UpdateUserLastLogin(userID int64) error {
+	_, err := db.conn.Exec(`UPDATE users SET last_login_at = ? WHERE id = ?`, time.Now().Unix(), userID)
+	return err
+}
+
+// DeleteUser deletes a user account and its sessions
+func (db *DB) DeleteUser(userID int64) error {
+	result, err := db.conn.Exec(`DELETE FROM users WHERE id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+// CreateSession stores a new login session
+func (db *DB) CreateSession(tokenHash string, userID int64, expiresAt time.Time) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO sessions (token_hash, user_id, expires_at)
+		VALUES (?, ?, ?)
+	`, tokenHash, userID, expiresAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetSessionUser returns the user for an unexpired session, or nil if the session is invalid
+func (db *DB) GetSessionUser(tokenHash string) (*User, error) {
+	row := db.conn.QueryRow(`
+		SELECT u.id, u.username, u.password_hash, u.role, u.created_at, u.last_login_at
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.token_hash = ? AND s.expires_at > ?
+	`, tokenHash, time.Now().Unix())
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return user, nil
+}
+
+// DeleteSession removes a session (logout)
+func (db *DB) DeleteSession(tokenHash string) error {
+	_, err := db.conn.Exec(`DELETE FROM sessions WHERE token_hash = ?`, tokenHash)
+	return err
+}
+
+// DeleteExpiredSessions removes expired sessions and returns how many were deleted
+func (db *DB) DeleteExpiredSessions() (int64, error) {
+	result, err := db.conn.Exec(`DELETE FROM sessions WHERE expires_at <= ?`, time.Now().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// CreateAPIKey stores a new API key hash
+func (db *DB) CreateAPIKey(name, keyPrefix, keyHash, role string, createdBy *int64) (*APIKey, error) {
+	key := &APIKey{
+		Name:      name,
+		KeyPrefix: keyPrefix,
+		Role:      role,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+
+	var createdByArg interface{}
+	if createdBy != nil {
+		createdByArg = *createdBy
+	}
+
+	err := db.conn.QueryRow(`
+		INSERT INTO api_keys (name, key_prefix, key_hash, role, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, name, keyPrefix, keyHash, role, createdByArg, key.CreatedAt.Unix()).Scan(&key.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return key, nil
+}
+
+// scanAPIKey scans an api_keys row (id, name, key_prefix, role, created_by, created_at, last_used_at, revoked_at)
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*APIKey, error) {
+	key := &APIKey{}
+	var createdAt int64
+	var createdBy, lastUsedAt, revokedAt sql.NullInt64
+
+	if err := row.Scan(&key.ID, &key.Name, &key.KeyPrefix, &key.Role, &createdBy, &createdAt, &lastUsedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+
+	key.CreatedAt = time.Unix(createdAt, 0)
+	if createdBy.Valid {
+		key.CreatedBy = &createdBy.Int64
+	}
+	if lastUsedAt.Valid {
+		t := time.Unix(lastUsedAt.Int64, 0)
+		key.LastUsedAt = &t
+	}
+	if revokedAt.Valid {
+		t := time.Unix(revokedAt.Int64, 0)
+		key.RevokedAt = &t
+	}
+
+	return key, nil
+}
+
+// GetAPIKeyByHash returns the API key matching a key hash, or nil if not found
+// Revoked keys are returned so callers can distinguish revoked from unknown keys
+func (db *DB) GetAPIKeyByHash(keyHash string) (*APIKey, error) {
+	row := db.conn.QueryRow(`
+		SELECT id, name, key_prefix, role, created_by, created_at, last_used_at, revoked_at
+		FROM api_keys
+		WHERE key_hash = ?
+	`, keyHash)
+
+	key, err := scanAPIKey(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	return key, nil
+}
+
+// ListAPIKeys returns all API keys, newest first
+func (db *DB) ListAPIKeys() ([]*APIKey, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, name, key_prefix, role, created_by, created_at, last_used_at, revoked_at
+		FROM api_keys
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey marks an API key as revoked
+func (db *DB) RevokeAPIKey(id int64) error {
+	result, err := db.conn.Exec(`
+		UPDATE api_keys SET revoked_at = ?
+		WHERE id = ? AND revoked_at IS NULL
+	`, time.Now().Unix(), id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("API key not found or already revoked")
+	}
+	return nil
+}
+
+// TouchAPIKey records that an API key was used
+func (db *DB) TouchAPIKey(id int64) error {
+	_, err := db.conn.Exec(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`, time.Now().Unix(), id)
+	return err
+}
@@ -790,6 +790,286 @@ func (db *DB) runMigrations() error {
 		}
 	}
 
+	// Migration 18: Add trigger_source column to scans table if it doesn't exist
+	// Note: scans table rebuilds above don't carry this column, so it is always checked last
+	var hasTriggerSource int
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*)
+		FROM pragma_table_info('scans')
+		WHERE name = 'trigger_source'
+	`).Scan(&hasTriggerSource)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for trigger_source column: %w", err)
+	}
+
+	if hasTriggerSource == 0 {
+		_, err = db.conn.Exec(migrateAddScanTriggerSource)
+		if err != nil {
+			return fmt.Errorf("failed to add trigger_source column: %w", err)
+		}
+	}
+
+	// Migration 19: Replace service CHECK constraints on usage and service_missing_files
+	// with foreign keys to the services table
+	var hasServicesForeignKey int
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*)
+		FROM pragma_foreign_key_list('usage')
+		WHERE "table" = 'services'
+	`).Scan(&hasServicesForeignKey)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for usage services foreign key: %w", err)
+	}
+
+	if hasServicesForeignKey == 0 {
+		// Disable foreign key constraints for migration
+		_, err = db.conn.Exec("PRAGMA foreign_keys = OFF")
+		if err != nil {
+			return fmt.Errorf("failed to disable foreign keys for services table migration: %w", err)
+		}
+
+		_, err = db.conn.Exec(migrateServicesTable)
+		if err != nil {
+			// Re-enable foreign keys before returning error
+			db.conn.Exec("PRAGMA foreign_keys = ON")
+			return fmt.Errorf("failed to migrate usage and service_missing_files to services table: %w", err)
+		}
+
+		// Re-enable foreign key constraints
+		_, err = db.conn.Exec("PRAGMA foreign_keys = ON")
+		if err != nil {
+			return fmt.Errorf("failed to re-enable foreign keys after services table migration: %w", err)
+		}
+	}
+
+	// Migration 20: Accept any service_update_<service> scan type
+	// Test if migration is needed by trying to insert a scan type no built-in provider uses
+	needsGenericServiceUpdateMigration := false
+
+	tx20, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction for generic service_update scan_type migration check: %w", err)
+	}
+	defer tx20.Rollback()
+
+	_, err = tx20.Exec(`
+		INSERT INTO scans (started_at, status, scan_type)
+		VALUES (?, 'completed', 'service_update_migration_test')
+	`, time.Now().Unix())
+
+	if err != nil {
+		// Check if the error is a CHECK constraint failure
+		if strings.Contains(err.Error(), "CHECK constraint failed") {
+			needsGenericServiceUpdateMigration = true
+		}
+	}
+
+	// Rollback the test transaction
+	tx20.Rollback()
+
+	if needsGenericServiceUpdateMigration {
+		// Disable foreign key constraints for migration
+		_, err = db.conn.Exec("PRAGMA foreign_keys = OFF")
+		if err != nil {
+			return fmt.Errorf("failed to disable foreign keys for generic service_update scan_type migration: %w", err)
+		}
+
+		_, err = db.conn.Exec(migrateGenericServiceUpdateScanType)
+		if err != nil {
+			// Re-enable foreign keys before returning error
+			db.conn.Exec("PRAGMA foreign_keys = ON")
+			return fmt.Errorf("failed to update scans table CHECK constraint for service_update types: %w", err)
+		}
+
+		// Re-enable foreign key constraints
+		_, err = db.conn.Exec("PRAGMA foreign_keys = ON")
+		if err != nil {
+			return fmt.Errorf("failed to re-enable foreign keys after generic service_update scan_type migration: %w", err)
+		}
+	}
+
+	// Migration 21: Add needs_service_update column to files table if it doesn't exist
+	var hasNeedsServiceUpdate int
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*)
+		FROM pragma_table_info('files')
+		WHERE name = 'needs_service_update'
+	`).Scan(&hasNeedsServiceUpdate)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for needs_service_update column: %w", err)
+	}
+
+	if hasNeedsServiceUpdate == 0 {
+		_, err = db.conn.Exec(migrateAddNeedsServiceUpdate)
+		if err != nil {
+			return fmt.Errorf("failed to add needs_service_update column: %w", err)
+		}
+	}
+
+	// Migration 22: Update audit_log table CHECK constraint to include 'move'
+	// Test if migration is needed by trying to insert a test record with action='move'
+	needsMoveAuditLogMigration := false
+	tx22, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction for move audit_log migration check: %w", err)
+	}
+
+	_, err = tx22.Exec(`
+		INSERT INTO audit_log (action, entity_type, entity_id, details)
+		VALUES ('move', 'test', 0, 'migration test')
+	`)
+
+	if err != nil {
+		// If we get a CHECK constraint error, we need the migration
+		if strings.Contains(err.Error(), "CHECK constraint failed") {
+			needsMoveAuditLogMigration = true
+		}
+	}
+
+	tx22.Rollback() // Always rollback since this is just a test
+
+	if needsMoveAuditLogMigration {
+		_, err = db.conn.Exec(migrateAddMoveToAuditLogAction)
+		if err != nil {
+			return fmt.Errorf("failed to add move to audit_log action constraint: %w", err)
+		}
+	}
+
+	// Migration 23: Update audit_log table CHECK constraint to include 'quarantine' and 'restore'
+	needsQuarantineAuditLogMigration := false
+	tx23, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction for quarantine audit_log migration check: %w", err)
+	}
+
+	_, err = tx23.Exec(`
+		INSERT INTO audit_log (action, entity_type, entity_id, details)
+		VALUES ('restore', 'test', 0, 'migration test')
+	`)
+
+	if err != nil {
+		// If we get a CHECK constraint error, we need the migration
+		if strings.Contains(err.Error(), "CHECK constraint failed") {
+			needsQuarantineAuditLogMigration = true
+		}
+	}
+
+	tx23.Rollback() // Always rollback since this is just a test
+
+	if needsQuarantineAuditLogMigration {
+		_, err = db.conn.Exec(migrateAddQuarantineToAuditLogAction)
+		if err != nil {
+			return fmt.Errorf("failed to add quarantine to audit_log action constraint: %w", err)
+		}
+	}
+
+	// Migration 24: Add linked_path and removed_path columns to operation_journal if they don't exist
+	var hasLinkedPath int
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*)
+		FROM pragma_table_info('operation_journal')
+		WHERE name = 'linked_path'
+	`).Scan(&hasLinkedPath)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for linked_path column: %w", err)
+	}
+
+	if hasLinkedPath == 0 {
+		_, err = db.conn.Exec(migrateAddJournalRelinkPaths)
+		if err != nil {
+			return fmt.Errorf("failed to add operation_journal relink columns: %w", err)
+		}
+	}
+
+	// Migration 25: Update audit_log table CHECK constraint to include 'reflink'
+	needsReflinkAuditLogMigration := false
+	tx25, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction for reflink audit_log migration check: %w", err)
+	}
+
+	_, err = tx25.Exec(`
+		INSERT INTO audit_log (action, entity_type, entity_id, details)
+		VALUES ('reflink', 'test', 0, 'migration test')
+	`)
+
+	if err != nil {
+		// If we get a CHECK constraint error, we need the migration
+		if strings.Contains(err.Error(), "CHECK constraint failed") {
+			needsReflinkAuditLogMigration = true
+		}
+	}
+
+	tx25.Rollback() // Always rollback since this is just a test
+
+	if needsReflinkAuditLogMigration {
+		_, err = db.conn.Exec(migrateAddReflinkToAuditLogAction)
+		if err != nil {
+			return fmt.Errorf("failed to add reflink to audit_log action constraint: %w", err)
+		}
+	}
+
+	// Migration 26: Add orphaned_since, last_used_by and used_by columns to files if they don't exist
+	var hasOrphanedSince int
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*)
+		FROM pragma_table_info('files')
+		WHERE name = 'orphaned_since'
+	`).Scan(&hasOrphanedSince)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for orphaned_since column: %w", err)
+	}
+
+	if hasOrphanedSince == 0 {
+		_, err = db.conn.Exec(migrateAddOrphanTracking)
+		if err != nil {
+			return fmt.Errorf("failed to add orphan tracking columns: %w", err)
+		}
+	}
+
+	// Migration 27: Keep the path on usage events and allow 'changed' events
+	var hasUsageEventPath int
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*)
+		FROM pragma_table_info('usage_events')
+		WHERE name = 'path'
+	`).Scan(&hasUsageEventPath)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for usage_events path column: %w", err)
+	}
+
+	if hasUsageEventPath == 0 {
+		_, err = db.conn.Exec(migrateUsageEventsKeepPath)
+		if err != nil {
+			return fmt.Errorf("failed to migrate usage_events table: %w", err)
+		}
+	}
+
+	// Migration 28: Drop the files_scan_modified trigger, scan reports compare snapshots instead
+	var hasScanModifiedTrigger int
+	err = db.conn.QueryRow(`
+		SELECT COUNT(*)
+		FROM sqlite_master
+		WHERE type = 'trigger' AND name = 'files_scan_modified'
+	`).Scan(&hasScanModifiedTrigger)
+
+	if err != nil {
+		return fmt.Errorf("failed to check for files_scan_modified trigger: %w", err)
+	}
+
+	if hasScanModifiedTrigger > 0 {
+		_, err = db.conn.Exec(migrateDropScanModifiedTrigger)
+		if err != nil {
+			return fmt.Errorf("failed to drop files_scan_modified trigger: %w", err)
+		}
+	}
+
 	return nil
 }
 
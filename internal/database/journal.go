@@ -0,0 +1,263 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Operation run types
+const (
+	OperationConsolidate = "consolidate"
+	OperationHardlink    = "hardlink"
+)
+
+// Operation run statuses
+const (
+	RunStatusRunning           = "running"
+	RunStatusCompleted         = "completed"
+	RunStatusReverted          = "reverted"
+	RunStatusPartiallyReverted = "partially_reverted"
+)
+
+// Journal entry statuses
+const (
+	JournalStatusApplied      = "applied"
+	JournalStatusReverted     = "reverted"
+	JournalStatusRevertFailed = "revert_failed"
+)
+
+// OperationRun is one consolidation or hardlink request and the files it changed
+type OperationRun struct {
+	ID          int64      `json:"id"`
+	Operation   string     `json:"operation"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	RevertedAt  *time.Time `json:"reverted_at,omitempty"`
+	Entries     int        `json:"entries"`
+	Reverted    int        `json:"reverted"`
+	Failed      int        `json:"failed"`
+	TotalSize   int64      `json:"total_size"`
+}
+
+// JournalEntry records one file replaced by an operation run
+// For consolidation the replaced file was deleted, or with move-and-hardlink its copy on another disk was
+// removed after LinkedPath was linked to the kept file; for hardlinks it was replaced by a link to the kept file
+type JournalEntry struct {
+	ID               int64     `json:"id"`
+	RunID            int64     `json:"run_id"`
+	GroupHash        string    `json:"group_hash"`
+	KeptPath         string    `json:"kept_path"`
+	KeptInode        int64     `json:"kept_inode"`
+	KeptDeviceID     int64     `json:"kept_device_id"`
+	ReplacedPath     string    `json:"replaced_path"`
+	OriginalInode    int64     `json:"original_inode"`
+	OriginalDeviceID int64     `json:"original_device_id"`
+	Size             int64     `json:"size"`
+	QuarantineID     *int64    `json:"quarantine_id,omitempty"` // Quarantined original, if one was kept
+	LinkedPath       string    `json:"linked_path,omitempty"`   // Hardlink created on the kept file's disk (move-and-hardlink)
+	RemovedPath      string    `json:"removed_path,omitempty"`  // Disk copy removed once LinkedPath resolved (move-and-hardlink)
+	Status           string    `json:"status"`
+	RevertError      string    `json:"revert_error,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// CreateOperationRun starts a new operation run
+func (db *DB) CreateOperationRun(operation string) (int64, error) {
+	var id int64
+	err := db.conn.QueryRow(`INSERT INTO operation_runs (operation) VALUES (?) RETURNING id`, operation).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create operation run: %w", err)
+	}
+	return id, nil
+}
+
+// CompleteOperationRun marks a run as completed and reports whether it was kept
+// A run that changed nothing is removed since there is nothing to revert
+func (db *DB) CompleteOperationRun(id int64) (bool, error) {
+	var entries int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM operation_journal WHERE run_id = ?`, id).Scan(&entries); err != nil {
+		return false, err
+	}
+	if entries == 0 {
+		_, err := db.conn.Exec(`DELETE FROM operation_runs WHERE id = ?`, id)
+		return false, err
+	}
+
+	_, err := db.conn.Exec(`
+		UPDATE operation_runs SET status = ?, completed_at = ? WHERE id = ?
+	`, RunStatusCompleted, time.Now().Unix(), id)
+	return err == nil, err
+}
+
+// AddJournalEntry records a file changed by an operation run
+func (db *DB) AddJournalEntry(entry *JournalEntry) error {
+	var quarantineID sql.NullInt64
+	if entry.QuarantineID != nil {
+		quarantineID = sql.NullInt64{Int64: *entry.QuarantineID, Valid: true}
+	}
+
+	return db.conn.QueryRow(`
+		INSERT INTO operation_journal (run_id, group_hash, kept_path, kept_inode, kept_device_id,
+			replaced_path, original_inode, original_device_id, size, quarantine_id, linked_path, removed_path)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))
+		RETURNING id
+	`,
+		entry.RunID, entry.GroupHash, entry.KeptPath, entry.KeptInode, entry.KeptDeviceID,
+		entry.ReplacedPath, entry.OriginalInode, entry.OriginalDeviceID, entry.Size, quarantineID,
+		entry.LinkedPath, entry.RemovedPath,
+	).Scan(&entry.ID)
+}
+
+// SetJournalEntryStatus records the outcome of reverting a journal entry
+func (db *DB) SetJournalEntryStatus(id int64, status, revertError string) error {
+	_, err := db.conn.Exec(`
+		UPDATE operation_journal SET status = ?, revert_error = NULLIF(?, '') WHERE id = ?
+	`, status, revertError, id)
+	return err
+}
+
+// LogHardlinkRevert records a hardlinked file that was turned back into a separate copy
+func (db *DB) LogHardlinkRevert(path, details string) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO audit_log (action, entity_type, entity_id, details)
+		VALUES ('restore', 'file', COALESCE((SELECT id FROM files WHERE path = ?), 0), ?)
+	`, path, details)
+	return err
+}
+
+// FinishOperationRunRevert sets a run's status from the state of its journal entries
+func (db *DB) FinishOperationRunRevert(id int64) (string, error) {
+	var pending int
+	err := db.conn.QueryRow(`
+		SELECT COUNT(*) FROM operation_journal WHERE run_id = ? AND status != ?
+	`, id, JournalStatusReverted).Scan(&pending)
+	if err != nil {
+		return "", err
+	}
+
+	status := RunStatusReverted
+	if pending > 0 {
+		status = RunStatusPartiallyReverted
+	}
+
+	_, err = db.conn.Exec(`
+		UPDATE operation_runs SET status = ?, reverted_at = ? WHERE id = ?
+	`, status, time.Now().Unix(), id)
+	return status, err
+}
+
+const operationRunColumns = `r.id, r.operation, r.status, r.started_at, r.completed_at, r.reverted_at,
+	COUNT(j.id),
+	COALESCE(SUM(CASE WHEN j.status = 'reverted' THEN 1 ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN j.status = 'revert_failed' THEN 1 ELSE 0 END), 0),
+	COALESCE(SUM(j.size), 0)`
+
+// scanOperationRunRow scans a single operation run row from a query result
+func scanOperationRunRow(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*OperationRun, error) {
+	run := &OperationRun{}
+	var startedAt int64
+	var completedAt, revertedAt sql.NullInt64
+
+	err := scanner.Scan(&run.ID, &run.Operation, &run.Status, &startedAt, &completedAt, &revertedAt,
+		&run.Entries, &run.Reverted, &run.Failed, &run.TotalSize)
+	if err != nil {
+		return nil, err
+	}
+
+	run.StartedAt = time.Unix(startedAt, 0)
+	if completedAt.Valid {
+		t := time.Unix(completedAt.Int64, 0)
+		run.CompletedAt = &t
+	}
+	if revertedAt.Valid {
+		t := time.Unix(revertedAt.Int64, 0)
+		run.RevertedAt = &t
+	}
+	return run, nil
+}
+
+// GetOperationRun retrieves an operation run by ID
+func (db *DB) GetOperationRun(id int64) (*OperationRun, error) {
+	return scanOperationRunRow(db.conn.QueryRow(`
+		SELECT `+operationRunColumns+`
+		FROM operation_runs r
+		LEFT JOIN operation_journal j ON j.run_id = r.id
+		WHERE r.id = ?
+		GROUP BY r.id
+	`, id))
+}
+
+// ListOperationRuns returns operation runs, most recent first, with the total count
+func (db *DB) ListOperationRuns(limit, offset int) ([]*OperationRun, int, error) {
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM operation_runs`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count operation runs: %w", err)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT `+operationRunColumns+`
+		FROM operation_runs r
+		LEFT JOIN operation_journal j ON j.run_id = r.id
+		GROUP BY r.id
+		ORDER BY r.started_at DESC, r.id DESC
+		LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query operation runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*OperationRun
+	for rows.Next() {
+		run, err := scanOperationRunRow(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan operation run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, total, rows.Err()
+}
+
+// GetJournalEntries returns the journal entries of an operation run in the order they were applied
+func (db *DB) GetJournalEntries(runID int64) ([]*JournalEntry, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, run_id, group_hash, kept_path, kept_inode, kept_device_id, replaced_path,
+			original_inode, original_device_id, size, quarantine_id, linked_path, removed_path, status, revert_error, created_at
+		FROM operation_journal
+		WHERE run_id = ?
+		ORDER BY id
+	`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query journal entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*JournalEntry
+	for rows.Next() {
+		entry := &JournalEntry{}
+		var quarantineID sql.NullInt64
+		var linkedPath, removedPath, revertError sql.NullString
+		var createdAt int64
+		err := rows.Scan(&entry.ID, &entry.RunID, &entry.GroupHash, &entry.KeptPath, &entry.KeptInode,
+			&entry.KeptDeviceID, &entry.ReplacedPath, &entry.OriginalInode, &entry.OriginalDeviceID,
+			&entry.Size, &quarantineID, &linkedPath, &removedPath, &entry.Status, &revertError, &createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan journal entry: %w", err)
+		}
+		if quarantineID.Valid {
+			entry.QuarantineID = &quarantineID.Int64
+		}
+		entry.LinkedPath = linkedPath.String
+		entry.RemovedPath = removedPath.String
+		entry.RevertError = revertError.String
+		entry.CreatedAt = time.Unix(createdAt, 0)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Mapping proposal kinds
+const (
+	ProposalMapping      = "mapping"       // A service path mapping that would match the files
+	ProposalNameMismatch = "name_mismatch" // The names differ by case or accents, which no mapping can fix
+)
+
+// MappingProposal is a fix for orphaned files that a service reports as missing under another path
+type MappingProposal struct {
+	ID            int64            `json:"id"`
+	ScanID        *int64           `json:"scan_id,omitempty"`
+	Service       string           `json:"service"`
+	Kind          string           `json:"kind"`
+	ServicePrefix string           `json:"service_prefix,omitempty"`
+	LocalPrefix   string           `json:"local_prefix,omitempty"`
+	FileCount     int              `json:"file_count"` // Orphaned files the proposal would match
+	TotalSize     int64            `json:"total_size"`
+	Examples      []MappingExample `json:"examples"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// MappingExample pairs the path a service reported as missing with the orphaned file it refers to
+type MappingExample struct {
+	ServicePath string `json:"service_path"`
+	LocalPath   string `json:"local_path"`
+}
+
+// ReplaceMappingProposals replaces the stored mapping proposals with the latest reconciliation's
+// scanID is the scan that triggered it, or 0
+func (db *DB) ReplaceMappingProposals(ctx context.Context, scanID int64, proposals []*MappingProposal) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mapping_proposals`); err != nil {
+		return fmt.Errorf("failed to clear mapping proposals: %w", err)
+	}
+	for _, p := range proposals {
+		examples, err := json.Marshal(p.Examples)
+		if err != nil {
+			return fmt.Errorf("failed to encode mapping proposal examples: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO mapping_proposals (scan_id, service, kind, service_prefix, local_prefix, file_count, total_size, examples)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, nullableScanID(scanID), p.Service, p.Kind, p.ServicePrefix, p.LocalPrefix, p.FileCount, p.TotalSize, string(examples)); err != nil {
+			return fmt.Errorf("failed to save mapping proposal: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListMappingProposals returns the stored mapping proposals, those matching the most files first
+func (db *DB) ListMappingProposals(ctx context.Context) ([]*MappingProposal, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, scan_id, service, kind, service_prefix, local_prefix, file_count, total_size, examples, created_at
+		FROM mapping_proposals
+		ORDER BY kind, file_count DESC, total_size DESC, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mapping proposals: %w", err)
+	}
+	defer rows.Close()
+
+	var proposals []*MappingProposal
+	for rows.Next() {
+		p, err := scanMappingProposal(rows)
+		if err != nil {
+			return nil, err
+		}
+		proposals = append(proposals, p)
+	}
+	return proposals, rows.Err()
+}
+
+// GetMappingProposal returns a mapping proposal
+func (db *DB) GetMappingProposal(ctx context.Context, id int64) (*MappingProposal, error) {
+	return scanMappingProposal(db.conn.QueryRowContext(ctx, `
+		SELECT id, scan_id, service, kind, service_prefix, local_prefix, file_count, total_size, examples, created_at
+		FROM mapping_proposals
+		WHERE id = ?
+	`, id))
+}
+
+// DeleteMappingProposal removes a mapping proposal once applied
+func (db *DB) DeleteMappingProposal(ctx context.Context, id int64) error {
+	_, err := db.conn.ExecContext(ctx, `DELETE FROM mapping_proposals WHERE id = ?`, id)
+	return err
+}
+
+// scanMappingProposal scans a mapping proposal row
+func scanMappingProposal(row interface {
+	Scan(dest ...interface{}) error
+}) (*MappingProposal, error) {
+	p := &MappingProposal{}
+	var scanID sql.NullInt64
+	var examples string
+	var createdAt int64
+	if err := row.Scan(&p.ID, &scanID, &p.Service, &p.Kind, &p.ServicePrefix, &p.LocalPrefix,
+		&p.FileCount, &p.TotalSize, &examples, &createdAt); err != nil {
+		return nil, err
+	}
+	if scanID.Valid {
+		p.ScanID = &scanID.Int64
+	}
+	if err := json.Unmarshal([]byte(examples), &p.Examples); err != nil {
+		return nil, fmt.Errorf("failed to decode examples of mapping proposal %d: %w", p.ID, err)
+	}
+	p.CreatedAt = time.Unix(createdAt, 0)
+	return p, nil
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// FileMove records a file's path changing while it stayed the same file on disk
+type FileMove struct {
+	ID      int64     `json:"id"`
+	FileID  int64     `json:"file_id"`
+	OldPath string    `json:"old_path"`
+	NewPath string    `json:"new_path"`
+	ScanID  *int64    `json:"scan_id,omitempty"`
+	MovedAt time.Time `json:"moved_at"`
+}
+
+// movedFile pairs a file that was not seen by a scan with the new file at its new path
+type movedFile struct {
+	oldID   int64
+	oldPath string
+	newID   int64
+	newPath string
+}
+
+// ApplyScanMoves detects files that were renamed or moved since the previous scan and keeps their rows
+// A file not seen by the scan is a move when exactly one file added by the scan has the same
+// device, inode, size and modification time, and the old path is gone from disk. The original row takes over the new path, keeping its
+// hash and history, and the duplicate row the scan inserted is removed. Returns the number of moves
+func (db *DB) ApplyScanMoves(ctx context.Context, scanID int64) (int, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT old.id, old.path, new.id, new.path
+		FROM files new
+		JOIN files old ON old.device_id = new.device_id
+			AND old.inode = new.inode
+			AND old.size = new.size
+			AND old.modified_time = new.modified_time
+			AND old.id != new.id
+		WHERE new.scan_id = ?
+			AND new.created_at >= (SELECT started_at FROM scans WHERE id = ?)
+			AND new.inode != 0
+			AND (old.scan_id IS NULL OR old.scan_id != ?)
+	`, scanID, scanID, scanID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query moved files: %w", err)
+	}
+
+	var candidates []movedFile
+	oldMatches := make(map[int64]int)
+	newMatches := make(map[int64]int)
+	for rows.Next() {
+		var m movedFile
+		if err := rows.Scan(&m.oldID, &m.oldPath, &m.newID, &m.newPath); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan moved file: %w", err)
+		}
+		candidates = append(candidates, m)
+		oldMatches[m.oldID]++
+		newMatches[m.newID]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Hardlinks share an inode, so only unambiguous one-to-one matches are treated as moves.
+	// A file the scan didn't revisit can still be on disk as a hardlink of the new one
+	var moves []movedFile
+	for _, m := range candidates {
+		if oldMatches[m.oldID] != 1 || newMatches[m.newID] != 1 {
+			continue
+		}
+		if _, err := os.Lstat(m.oldPath); !errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		moves = append(moves, m)
+	}
+	if len(moves) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	for _, m := range moves {
+		// The scan's row for the new path goes first so the original row can take its path
+		if _, err := tx.ExecContext(ctx, `DELETE FROM files WHERE id = ?`, m.newID); err != nil {
+			return 0, fmt.Errorf("failed to remove new row for %s: %w", m.newPath, err)
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			UPDATE files SET path = ?, extension = ?, scan_id = ?, last_verified = ?
+			WHERE id = ?
+		`, m.newPath, ExtractExtension(m.newPath), scanID, now, m.oldID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to move %s to %s: %w", m.oldPath, m.newPath, err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO file_moves (file_id, old_path, new_path, scan_id)
+			VALUES (?, ?, ?, ?)
+		`, m.oldID, m.oldPath, m.newPath, scanID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record move of %s: %w", m.oldPath, err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO audit_log (action, entity_type, entity_id, scan_id, details)
+			VALUES ('move', 'file', ?, ?, ?)
+		`, m.oldID, scanID, fmt.Sprintf("Moved %s to %s", m.oldPath, m.newPath))
+		if err != nil {
+			return 0, fmt.Errorf("failed to log move: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return len(moves), nil
+}
+
+// GetFileMoves returns a file's move history, most recent first
+func (db *DB) GetFileMoves(fileID int64) ([]*FileMove, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, file_id, old_path, new_path, scan_id, moved_at
+		FROM file_moves
+		WHERE file_id = ?
+		ORDER BY moved_at DESC, id DESC
+	`, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var moves []*FileMove
+	for rows.Next() {
+		move := &FileMove{}
+		var scanID sql.NullInt64
+		var movedAt int64
+		if err := rows.Scan(&move.ID, &move.FileID, &move.OldPath, &move.NewPath, &scanID, &movedAt); err != nil {
+			return nil, err
+		}
+		if scanID.Valid {
+			move.ScanID = &scanID.Int64
+		}
+		move.MovedAt = time.Unix(movedAt, 0)
+		moves = append(moves, move)
+	}
+
+	return moves, rows.Err()
+}
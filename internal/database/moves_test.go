@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestApplyScanMoves(t *testing.T) {
+	db := newTestDB(t)
+	dir := t.TempDir()
+	modified := time.Unix(1700000000, 0)
+
+	addFile := func(path string, scanID, inode int64) *File {
+		t.Helper()
+		file := &File{Path: path, Size: 100, Inode: inode, DeviceID: 1, ModifiedTime: modified, ScanID: scanID, LastVerified: time.Now()}
+		if err := db.UpsertFile(file); err != nil {
+			t.Fatalf("UpsertFile: %v", err)
+		}
+		return file
+	}
+
+	previous, err := db.CreateScan("full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed := addFile(filepath.Join(dir, "old.mkv"), previous.ID, 10)
+	linked := addFile(filepath.Join(dir, "linked.mkv"), previous.ID, 20)
+
+	// The hardlink's original path is still on disk, the renamed file's is not
+	if err := os.WriteFile(linked.Path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	scan, err := db.CreateScan("incremental")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addFile(filepath.Join(dir, "new.mkv"), scan.ID, 10)
+	addFile(filepath.Join(dir, "hardlink.mkv"), scan.ID, 20)
+
+	moved, err := db.ApplyScanMoves(context.Background(), scan.ID)
+	if err != nil {
+		t.Fatalf("ApplyScanMoves: %v", err)
+	}
+	if moved != 1 {
+		t.Fatalf("moved = %d, want 1", moved)
+	}
+
+	file, err := db.GetFileByPath(filepath.Join(dir, "new.mkv"))
+	if err != nil {
+		t.Fatalf("GetFileByPath: %v", err)
+	}
+	if file.ID != renamed.ID {
+		t.Errorf("moved file has id %d, want the original row %d", file.ID, renamed.ID)
+	}
+
+	moves, err := db.GetFileMoves(renamed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 1 || moves[0].OldPath != renamed.Path {
+		t.Errorf("moves = %+v, want one from %s", moves, renamed.Path)
+	}
+
+	// Both hardlinks keep their own rows
+	for _, path := range []string{linked.Path, filepath.Join(dir, "hardlink.mkv")} {
+		if _, err := db.GetFileByPath(path); err != nil {
+			t.Errorf("GetFileByPath(%s): %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Policy match outcomes
+const (
+	PolicyOutcomeReported = "reported" // Matched, but the policy is not enforced, the run was a dry run or a service was stale
+	PolicyOutcomeApplied  = "applied"
+	PolicyOutcomeFailed   = "failed"
+)
+
+// PolicyRun is one evaluation of the cleanup policies
+type PolicyRun struct {
+	ID            int64      `json:"id"`
+	ScanID        *int64     `json:"scan_id,omitempty"` // Scan that triggered the run, if any
+	TriggerSource string     `json:"trigger_source"`
+	DryRun        bool       `json:"dry_run"`
+	StartedAt     time.Time  `json:"started_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	Matched       int        `json:"matched"`
+	Applied       int        `json:"applied"`
+	Failed        int        `json:"failed"`
+	MatchedSize   int64      `json:"matched_size"`
+}
+
+// PolicyMatch is an orphaned file matched by a cleanup policy during a run
+type PolicyMatch struct {
+	ID           int64     `json:"id"`
+	RunID        int64     `json:"run_id"`
+	Policy       string    `json:"policy"`
+	Action       string    `json:"action"`
+	FileID       int64     `json:"file_id"`
+	Path         string    `json:"path"`
+	Size         int64     `json:"size"`
+	ModifiedTime time.Time `json:"modified_time"`
+	Outcome      string    `json:"outcome"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// PolicySummary totals the matches of one policy in a run
+type PolicySummary struct {
+	Policy      string `json:"policy"`
+	Matched     int    `json:"matched"`
+	Applied     int    `json:"applied"`
+	Failed      int    `json:"failed"`
+	MatchedSize int64  `json:"matched_size"`
+}
+
+// OrphanedFileFilter selects orphaned files for a cleanup policy
+// Zero values match every orphaned file
+type OrphanedFileFilter struct {
+	PathPrefixes   []string  // Directories the file must be under
+	Extensions     []string  // Lowercase extensions including the dot
+	OrphanedBefore time.Time // Orphaned since before this time
+	MinSize        int64
+	MaxSize        int64
+}
+
+// FindOrphanedFiles returns the orphaned files matching a filter, ordered by path
+// Files that gained usage since orphaned status was last calculated are left out
+func (db *DB) FindOrphanedFiles(ctx context.Context, filter OrphanedFileFilter) ([]*File, error) {
+	conditions := []string{
+		"is_orphaned = 1",
+		"NOT EXISTS (SELECT 1 FROM usage WHERE usage.file_id = files.id)",
+	}
+	var args []interface{}
+
+	if len(filter.PathPrefixes) > 0 {
+		var prefixes []string
+		for _, prefix := range filter.PathPrefixes {
+			prefix = strings.TrimSuffix(prefix, "/")
+			prefixes = append(prefixes, "(path = ? OR substr(path, 1, ?) = ?)")
+			args = append(args, prefix, len(prefix)+1, prefix+"/")
+		}
+		conditions = append(conditions, "("+strings.Join(prefixes, " OR ")+")")
+	}
+	if len(filter.Extensions) > 0 {
+		conditions = append(conditions, fmt.Sprintf("extension IN (%s)", buildInClause(len(filter.Extensions))))
+		for _, ext := range filter.Extensions {
+			args = append(args, ext)
+		}
+	}
+	if !filter.OrphanedBefore.IsZero() {
+		conditions = append(conditions, "orphaned_since < ?")
+		args = append(args, filter.OrphanedBefore.Unix())
+	}
+	if filter.MinSize > 0 {
+		conditions = append(conditions, "size >= ?")
+		args = append(args, filter.MinSize)
+	}
+	if filter.MaxSize > 0 {
+		conditions = append(conditions, "size <= ?")
+		args = append(args, filter.MaxSize)
+	}
+
+	query := `
+		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at, orphaned_since, last_used_by
+		FROM files
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY path
+	`
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orphaned files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*File
+	for rows.Next() {
+		file, err := scanFileRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
+
+// CreatePolicyRun starts a new policy run
+func (db *DB) CreatePolicyRun(scanID int64, triggerSource string, dryRun bool) (int64, error) {
+	var id int64
+	err := db.conn.QueryRow(`
+		INSERT INTO policy_runs (scan_id, trigger_source, dry_run) VALUES (?, ?, ?) RETURNING id
+	`, nullableScanID(scanID), triggerSource, dryRun).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create policy run: %w", err)
+	}
+	return id, nil
+}
+
+// CompletePolicyRun marks a policy run as completed and removes all but the most recent keep runs
+func (db *DB) CompletePolicyRun(id int64, keep int) error {
+	if _, err := db.conn.Exec(`UPDATE policy_runs SET completed_at = ? WHERE id = ?`, time.Now().Unix(), id); err != nil {
+		return err
+	}
+
+	_, err := db.conn.Exec(`
+		DELETE FROM policy_runs WHERE id NOT IN (
+			SELECT id FROM policy_runs ORDER BY started_at DESC, id DESC LIMIT ?
+		)
+	`, keep)
+	return err
+}
+
+// AddPolicyMatch records a file matched by a policy during a run
+func (db *DB) AddPolicyMatch(match *PolicyMatch) error {
+	return db.conn.QueryRow(`
+		INSERT INTO policy_matches (run_id, policy, action, file_id, path, size, modified_time, outcome, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''))
+		RETURNING id
+	`,
+		match.RunID, match.Policy, match.Action, match.FileID, match.Path, match.Size,
+		match.ModifiedTime.Unix(), match.Outcome, match.Error,
+	).Scan(&match.ID)
+}
+
+const policyRunColumns = `r.id, r.scan_id, r.trigger_source, r.dry_run, r.started_at, r.completed_at,
+	COUNT(m.id),
+	COALESCE(SUM(CASE WHEN m.outcome = 'applied' THEN 1 ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN m.outcome = 'failed' THEN 1 ELSE 0 END), 0),
+	COALESCE(SUM(m.size), 0)`
+
+// scanPolicyRunRow scans a single policy run row from a query result
+func scanPolicyRunRow(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*PolicyRun, error) {
+	run := &PolicyRun{}
+	var scanID, completedAt sql.NullInt64
+	var startedAt int64
+
+	err := scanner.Scan(&run.ID, &scanID, &run.TriggerSource, &run.DryRun, &startedAt, &completedAt,
+		&run.Matched, &run.Applied, &run.Failed, &run.MatchedSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if scanID.Valid {
+		run.ScanID = &scanID.Int64
+	}
+	run.StartedAt = time.Unix(startedAt, 0)
+	if completedAt.Valid {
+		t := time.Unix(completedAt.Int64, 0)
+		run.CompletedAt = &t
+	}
+	return run, nil
+}
+
+// GetPolicyRun retrieves a policy run by ID
+func (db *DB) GetPolicyRun(id int64) (*PolicyRun, error) {
+	return scanPolicyRunRow(db.conn.QueryRow(`
+		SELECT `+policyRunColumns+`
+		FROM policy_runs r
+		LEFT JOIN policy_matches m ON m.run_id = r.id
+		WHERE r.id = ?
+		GROUP BY r.id
+	`, id))
+}
+
+// ListPolicyRuns returns completed policy runs, most recent first
+func (db *DB) ListPolicyRuns(limit int) ([]*PolicyRun, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+policyRunColumns+`
+		FROM policy_runs r
+		LEFT JOIN policy_matches m ON m.run_id = r.id
+		WHERE r.completed_at IS NOT NULL
+		GROUP BY r.id
+		ORDER BY r.started_at DESC, r.id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policy runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*PolicyRun
+	for rows.Next() {
+		run, err := scanPolicyRunRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan policy run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// GetPolicySummaries totals a run's matches per policy
+func (db *DB) GetPolicySummaries(runID int64) (map[string]*PolicySummary, error) {
+	rows, err := db.conn.Query(`
+		SELECT policy, COUNT(*),
+			SUM(CASE WHEN outcome = 'applied' THEN 1 ELSE 0 END),
+			SUM(CASE WHEN outcome = 'failed' THEN 1 ELSE 0 END),
+			SUM(size)
+		FROM policy_matches
+		WHERE run_id = ?
+		GROUP BY policy
+	`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query policy summaries: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make(map[string]*PolicySummary)
+	for rows.Next() {
+		s := &PolicySummary{}
+		if err := rows.Scan(&s.Policy, &s.Matched, &s.Applied, &s.Failed, &s.MatchedSize); err != nil {
+			return nil, fmt.Errorf("failed to scan policy summary: %w", err)
+		}
+		summaries[s.Policy] = s
+	}
+
+	return summaries, rows.Err()
+}
+
+// GetPolicyMatches returns a run's matches, optionally for one policy, with the total count
+func (db *DB) GetPolicyMatches(runID int64, policy string, limit, offset int) ([]*PolicyMatch, int, error) {
+	where := "WHERE run_id = ?"
+	args := []interface{}{runID}
+	if policy != "" {
+		where += " AND policy = ?"
+		args = append(args, policy)
+	}
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM policy_matches `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count policy matches: %w", err)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT id, run_id, policy, action, file_id, path, size, modified_time, outcome, error
+		FROM policy_matches
+		`+where+`
+		ORDER BY id
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query policy matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []*PolicyMatch
+	for rows.Next() {
+		m := &PolicyMatch{}
+		var modifiedTime int64
+		var matchErr sql.NullString
+		err := rows.Scan(&m.ID, &m.RunID, &m.Policy, &m.Action, &m.FileID, &m.Path, &m.Size,
+			&modifiedTime, &m.Outcome, &matchErr)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan policy match: %w", err)
+		}
+		m.ModifiedTime = time.Unix(modifiedTime, 0)
+		m.Error = matchErr.String
+		matches = append(matches, m)
+	}
+
+	return matches, total, rows.Err()
+}
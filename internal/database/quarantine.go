@@ -0,0 +1,319 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// QuarantineItem is a deleted file held in a trash directory until it is restored or purged
+// It keeps the file's metadata, hash and usage so restoring recreates the original record
+type QuarantineItem struct {
+	ID            int64     `json:"id"`
+	FileID        int64     `json:"file_id"` // ID of the files row before quarantine
+	OriginalPath  string    `json:"original_path"`
+	TrashPath     string    `json:"trash_path"`
+	TrashRoot     string    `json:"trash_root"` // Filesystem root holding the trash directory
+	Size          int64     `json:"size"`
+	Inode         int64     `json:"inode"`
+	DeviceID      int64     `json:"device_id"`
+	ModifiedTime  time.Time `json:"modified_time"`
+	IsOrphaned    bool      `json:"is_orphaned"`
+	FileHash      string    `json:"file_hash,omitempty"`
+	HashAlgorithm string    `json:"hash_algorithm,omitempty"`
+	HashType      string    `json:"hash_type,omitempty"`
+	HashLevel     int       `json:"hash_level"`
+	Usage         []*Usage  `json:"usage"`
+	Reason        string    `json:"reason"`
+	QuarantinedAt time.Time `json:"quarantined_at"`
+}
+
+const quarantineColumns = `id, file_id, original_path, trash_path, trash_root, size, inode, device_id, modified_time,
+	is_orphaned, file_hash, hash_algorithm, hash_type, hash_level, usage_snapshot, reason, quarantined_at`
+
+// scanQuarantineRow scans a single quarantine row from a query result
+func scanQuarantineRow(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*QuarantineItem, error) {
+	item := &QuarantineItem{}
+	var modifiedTime, quarantinedAt int64
+	var fileHash, hashAlgorithm, hashType, usageSnapshot, reason sql.NullString
+
+	err := scanner.Scan(
+		&item.ID, &item.FileID, &item.OriginalPath, &item.TrashPath, &item.TrashRoot,
+		&item.Size, &item.Inode, &item.DeviceID, &modifiedTime,
+		&item.IsOrphaned, &fileHash, &hashAlgorithm, &hashType, &item.HashLevel,
+		&usageSnapshot, &reason, &quarantinedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	item.ModifiedTime = time.Unix(modifiedTime, 0)
+	item.QuarantinedAt = time.Unix(quarantinedAt, 0)
+	item.FileHash = fileHash.String
+	item.HashAlgorithm = hashAlgorithm.String
+	item.HashType = hashType.String
+	item.Reason = reason.String
+	if usageSnapshot.Valid {
+		if err := json.Unmarshal([]byte(usageSnapshot.String), &item.Usage); err != nil {
+			item.Usage = nil
+		}
+	}
+
+	return item, nil
+}
+
+// QuarantineFile records a file that was moved to trashPath and removes its files row
+// The file's metadata, hash and current usage are kept on the quarantine row for restoring
+func (db *DB) QuarantineFile(ctx context.Context, fileID int64, trashPath, trashRoot, reason string) (*QuarantineItem, error) {
+	return db.quarantineFile(ctx, fileID, nil, trashPath, trashRoot, reason, false)
+}
+
+// HoldQuarantineFile records a link to a file's original contents kept at trashPath, keeping its files row
+// Used before the file at the original path is replaced, so the replacement can be reverted
+func (db *DB) HoldQuarantineFile(ctx context.Context, fileID int64, trashPath, trashRoot, reason string) (*QuarantineItem, error) {
+	return db.quarantineFile(ctx, fileID, nil, trashPath, trashRoot, reason, true)
+}
+
+// SetAsideQuarantineFile records one disk's copy of a file that was moved to trashPath, keeping its files row
+// Used when the copy is removed while the file stays reachable through another disk
+func (db *DB) SetAsideQuarantineFile(ctx context.Context, fileID int64, diskCopy *FileDiskLocation, trashPath, trashRoot, reason string) (*QuarantineItem, error) {
+	return db.quarantineFile(ctx, fileID, diskCopy, trashPath, trashRoot, reason, true)
+}
+
+// quarantineFile records a quarantined file from its files row, or from diskCopy when one disk's copy was moved
+func (db *DB) quarantineFile(ctx context.Context, fileID int64, diskCopy *FileDiskLocation, trashPath, trashRoot, reason string, keepRecord bool) (*QuarantineItem, error) {
+	usage, err := db.GetUsageByFileID(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+	usageJSON, err := json.Marshal(usage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal usage: %w", err)
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var copyPath sql.NullString
+	var copySize, copyInode, copyDeviceID, copyModTime sql.NullInt64
+	if diskCopy != nil {
+		copyPath = sql.NullString{String: diskCopy.DiskPath, Valid: true}
+		copySize = sql.NullInt64{Int64: diskCopy.Size, Valid: true}
+		copyInode = sql.NullInt64{Int64: diskCopy.Inode, Valid: true}
+		copyDeviceID = sql.NullInt64{Int64: diskCopy.DiskDeviceID, Valid: true}
+		copyModTime = sql.NullInt64{Int64: diskCopy.ModifiedTime.Unix(), Valid: true}
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO quarantine (file_id, original_path, trash_path, trash_root, size, inode, device_id, modified_time,
+			is_orphaned, file_hash, hash_algorithm, hash_type, hash_level, usage_snapshot, reason)
+		SELECT id, COALESCE(?, path), ?, ?, COALESCE(?, size), COALESCE(?, inode), COALESCE(?, device_id), COALESCE(?, modified_time),
+			is_orphaned, file_hash, hash_algorithm, hash_type, COALESCE(hash_level, 0), ?, ?
+		FROM files
+		WHERE id = ?
+		RETURNING id
+	`, copyPath, trashPath, trashRoot, copySize, copyInode, copyDeviceID, copyModTime, string(usageJSON), reason, fileID).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record quarantined file: %w", err)
+	}
+
+	item, err := scanQuarantineRow(tx.QueryRowContext(ctx, `SELECT `+quarantineColumns+` FROM quarantine WHERE id = ?`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quarantined file: %w", err)
+	}
+
+	details := fmt.Sprintf("%s (moved %s to %s)", reason, item.OriginalPath, trashPath)
+	if keepRecord && diskCopy == nil {
+		details = fmt.Sprintf("%s (kept original of %s at %s)", reason, item.OriginalPath, trashPath)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_log (action, entity_type, entity_id, details) VALUES ('quarantine', 'file', ?, ?)`,
+		fileID, details,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log quarantine: %w", err)
+	}
+
+	if !keepRecord {
+		// Delete the file record (usage records will be cascade deleted)
+		if _, err := tx.ExecContext(ctx, `DELETE FROM files WHERE id = ?`, fileID); err != nil {
+			return nil, fmt.Errorf("failed to delete file record: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return item, nil
+}
+
+// RestoreQuarantineItem recreates the files row and usage of a file moved back to its original path
+// Returns the ID of the new files row
+func (db *DB) RestoreQuarantineItem(ctx context.Context, item *QuarantineItem) (int64, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var fileHash, hashAlgorithm, hashType sql.NullString
+	if item.FileHash != "" {
+		fileHash = sql.NullString{String: item.FileHash, Valid: true}
+		hashAlgorithm = sql.NullString{String: item.HashAlgorithm, Valid: item.HashAlgorithm != ""}
+		hashType = sql.NullString{String: item.HashType, Valid: item.HashType != ""}
+	}
+
+	var fileID int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO files (path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension,
+			file_hash, hash_algorithm, hash_calculated, hash_type, hash_level)
+		VALUES (?, ?, ?, ?, ?, NULL, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`,
+		item.OriginalPath, item.Size, item.Inode, item.DeviceID, item.ModifiedTime.Unix(),
+		time.Now().Unix(), item.IsOrphaned, ExtractExtension(item.OriginalPath),
+		fileHash, hashAlgorithm, fileHash.Valid, hashType, item.HashLevel,
+	).Scan(&fileID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to recreate file record: %w", err)
+	}
+
+	for _, usage := range item.Usage {
+		metadataJSON, err := json.Marshal(usage.Metadata)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO usage (file_id, service, reference_path, metadata)
+			VALUES (?, ?, ?, ?)
+		`, fileID, usage.Service, usage.ReferencePath, string(metadataJSON))
+		if err != nil {
+			return 0, fmt.Errorf("failed to restore %s usage: %w", usage.Service, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_log (action, entity_type, entity_id, details) VALUES ('restore', 'file', ?, ?)`,
+		fileID, fmt.Sprintf("Restored %s from quarantine", item.OriginalPath),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to log restore: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM quarantine WHERE id = ?`, item.ID); err != nil {
+		return 0, fmt.Errorf("failed to remove quarantine record: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return fileID, nil
+}
+
+// ReleaseQuarantineItem removes the quarantine record of a held file that was moved back over its original path
+// The files row was kept while the file was held, so only its inode is updated
+func (db *DB) ReleaseQuarantineItem(ctx context.Context, item *QuarantineItem, details string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM quarantine WHERE id = ?`, item.ID); err != nil {
+		return fmt.Errorf("failed to remove quarantine record: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE files SET inode = ?, device_id = ?, last_verified = ? WHERE path = ?
+	`, item.Inode, item.DeviceID, time.Now().Unix(), item.OriginalPath)
+	if err != nil {
+		return fmt.Errorf("failed to update file record: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_log (action, entity_type, entity_id, details) VALUES ('restore', 'file', ?, ?)`,
+		item.FileID, fmt.Sprintf("%s (moved %s back to %s)", details, item.TrashPath, item.OriginalPath),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to log restore: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// DeleteQuarantineItem removes a quarantine record once its file has been permanently deleted
+func (db *DB) DeleteQuarantineItem(ctx context.Context, item *QuarantineItem, details string) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM quarantine WHERE id = ?`, item.ID); err != nil {
+		return fmt.Errorf("failed to remove quarantine record: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO audit_log (action, entity_type, entity_id, details) VALUES ('delete', 'quarantine', ?, ?)`,
+		item.FileID, fmt.Sprintf("%s (deleted quarantined copy of %s)", details, item.OriginalPath),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to log purge: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetQuarantineItem retrieves a quarantined file by ID
+func (db *DB) GetQuarantineItem(id int64) (*QuarantineItem, error) {
+	return scanQuarantineRow(db.conn.QueryRow(`SELECT `+quarantineColumns+` FROM quarantine WHERE id = ?`, id))
+}
+
+// ListQuarantineItems returns quarantined files, most recently quarantined first, with the total count and size
+func (db *DB) ListQuarantineItems(limit, offset int) ([]*QuarantineItem, int, int64, error) {
+	var total int
+	var totalSize int64
+	err := db.conn.QueryRow(`SELECT COUNT(*), COALESCE(SUM(size), 0) FROM quarantine`).Scan(&total, &totalSize)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to count quarantined files: %w", err)
+	}
+
+	items, err := db.queryQuarantineItems(`SELECT `+quarantineColumns+` FROM quarantine
+		ORDER BY quarantined_at DESC, id DESC LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return items, total, totalSize, nil
+}
+
+// GetQuarantineItemsOldestFirst returns every quarantined file in the order they should be purged
+func (db *DB) GetQuarantineItemsOldestFirst() ([]*QuarantineItem, error) {
+	return db.queryQuarantineItems(`SELECT ` + quarantineColumns + ` FROM quarantine ORDER BY quarantined_at, id`)
+}
+
+func (db *DB) queryQuarantineItems(query string, args ...interface{}) ([]*QuarantineItem, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quarantined files: %w", err)
+	}
+	defer rows.Close()
+
+	var items []*QuarantineItem
+	for rows.Next() {
+		item, err := scanQuarantineRow(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan quarantined file: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
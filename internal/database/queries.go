@@ -9,6 +9,7 @@ import (
 	"os"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // File represents a file in the database
@@ -24,6 +25,18 @@ type File struct {
 	IsOrphaned   bool
 	Extension    string
 	CreatedAt    time.Time
+	// OrphanedSince is when the file last lost all service usage, nil while a service uses it
+	OrphanedSince *time.Time
+	// LastUsedBy lists the services that used the file before it was orphaned, comma-separated
+	LastUsedBy string
+}
+
+// OrphanedDays returns how many whole days the file has been orphaned
+func (f *File) OrphanedDays() int {
+	if f.OrphanedSince == nil {
+		return 0
+	}
+	return int(time.Since(*f.OrphanedSince) / (24 * time.Hour))
 }
 
 // scanFileRow scans a single file row from a query result
@@ -32,7 +45,8 @@ func scanFileRow(scanner interface {
 }) (*File, error) {
 	file := &File{}
 	var modTime, lastVerified, createdAt int64
-	var scanID sql.NullInt64
+	var scanID, orphanedSince sql.NullInt64
+	var lastUsedBy sql.NullString
 
 	err := scanner.Scan(
 		&file.ID,
@@ -46,6 +60,8 @@ func scanFileRow(scanner interface {
 		&file.IsOrphaned,
 		&file.Extension,
 		&createdAt,
+		&orphanedSince,
+		&lastUsedBy,
 	)
 	if err != nil {
 		return nil, err
@@ -54,6 +70,11 @@ func scanFileRow(scanner interface {
 	file.ModifiedTime = time.Unix(modTime, 0)
 	file.LastVerified = time.Unix(lastVerified, 0)
 	file.CreatedAt = time.Unix(createdAt, 0)
+	if orphanedSince.Valid {
+		t := time.Unix(orphanedSince.Int64, 0)
+		file.OrphanedSince = &t
+	}
+	file.LastUsedBy = lastUsedBy.String
 
 	// Handle NULL scan_id (can occur if scans are deleted)
 	if scanID.Valid {
@@ -89,6 +110,7 @@ type Scan struct {
 	LastProcessedPath *string
 	ResumeFromScanID  *int64
 	DeletedFilesCount int64
+	TriggerSource     string // What started the scan: "manual" or "scheduler"
 	CreatedAt         time.Time
 }
 
@@ -270,7 +292,7 @@ func (db *DB) batchUpsertFilesChunk(ctx context.Context, files []*File) error {
 			file.Inode,
 			file.DeviceID,
 			file.ModifiedTime.Unix(),
-			file.ScanID,
+			nullableScanID(file.ScanID),
 			file.LastVerified.Unix(),
 			file.IsOrphaned,
 			file.Extension,
@@ -289,10 +311,15 @@ func (db *DB) batchUpsertFilesChunk(ctx context.Context, files []*File) error {
 	return nil
 }
 
+// nullableScanID stores files recorded outside a scan (e.g. by the filesystem watcher) with a NULL scan_id
+func nullableScanID(scanID int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: scanID, Valid: scanID != 0}
+}
+
 // GetFileByID retrieves a file by its ID
 func (db *DB) GetFileByID(id int64) (*File, error) {
 	query := `
-		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at
+		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at, orphaned_since, last_used_by
 		FROM files
 		WHERE id = ?
 	`
@@ -303,7 +330,7 @@ func (db *DB) GetFileByID(id int64) (*File, error) {
 // GetFileByPath retrieves a file by its path
 func (db *DB) GetFileByPath(path string) (*File, error) {
 	query := `
-		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at
+		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at, orphaned_since, last_used_by
 		FROM files
 		WHERE path = ?
 	`
@@ -355,7 +382,7 @@ func (db *DB) GetFilesByPaths(ctx context.Context, paths []string) (map[string]*
 		}
 
 		query := fmt.Sprintf(`
-			SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at
+			SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at, orphaned_since, last_used_by
 			FROM files
 			WHERE path IN (%s)
 		`, buildInClause(len(batch)))
@@ -386,7 +413,7 @@ func (db *DB) GetFilesByPaths(ctx context.Context, paths []string) (map[string]*
 // GetFilesByService retrieves all files that are used by a specific service
 func (db *DB) GetFilesByService(ctx context.Context, service string) ([]*File, error) {
 	query := `
-		SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time, f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at
+		SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time, f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at, f.orphaned_since, f.last_used_by
 		FROM files f
 		INNER JOIN usage u ON f.id = u.file_id
 		WHERE u.service = ?
@@ -430,7 +457,7 @@ func (db *DB) GetFilesByExtensions(ctx context.Context, extensions []string) ([]
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at
+		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at, orphaned_since, last_used_by
 		FROM files
 		WHERE extension IN (%s)
 		ORDER BY path
@@ -462,7 +489,7 @@ func (db *DB) GetFilesByExtensions(ctx context.Context, extensions []string) ([]
 // This is useful for compound extensions like .!qb which can be .mkv.!qb, .mp4.!qb, etc.
 func (db *DB) GetFilesByExtensionSuffix(ctx context.Context, suffix string) ([]*File, error) {
 	query := `
-		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at
+		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at, orphaned_since, last_used_by
 		FROM files
 		WHERE extension LIKE ?
 		ORDER BY path
@@ -498,7 +525,7 @@ func (db *DB) GetFilesByExtensionSuffix(ctx context.Context, suffix string) ([]*
 // WARNING: This loads the entire files table into memory - use only when appropriate
 func (db *DB) GetAllFilesMap(ctx context.Context) (map[string]*File, error) {
 	query := `
-		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at
+		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at, orphaned_since, last_used_by
 		FROM files
 	`
 
@@ -531,21 +558,27 @@ func (db *DB) GetAllFilesMap(ctx context.Context) (map[string]*File, error) {
 	return fileMap, nil
 }
 
-// CreateScan creates a new scan record
+// CreateScan creates a new manually triggered scan record
 func (db *DB) CreateScan(scanType string) (*Scan, error) {
+	return db.CreateScanWithTrigger(scanType, "manual")
+}
+
+// CreateScanWithTrigger creates a new scan record, recording what triggered it
+func (db *DB) CreateScanWithTrigger(scanType, triggerSource string) (*Scan, error) {
 	query := `
-		INSERT INTO scans (started_at, status, scan_type)
-		VALUES (?, 'running', ?)
+		INSERT INTO scans (started_at, status, scan_type, trigger_source)
+		VALUES (?, 'running', ?, ?)
 		RETURNING id
 	`
 
 	scan := &Scan{
-		StartedAt: time.Now(),
-		Status:    "running",
-		ScanType:  scanType,
+		StartedAt:     time.Now(),
+		Status:        "running",
+		ScanType:      scanType,
+		TriggerSource: triggerSource,
 	}
 
-	err := db.conn.QueryRow(query, scan.StartedAt.Unix(), scanType).Scan(&scan.ID)
+	err := db.conn.QueryRow(query, scan.StartedAt.Unix(), scanType, triggerSource).Scan(&scan.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -706,7 +739,7 @@ func (db *DB) GetLastInterruptedScan() (*Scan, error) {
 // GetCurrentScan returns the currently running scan, if any
 func (db *DB) GetCurrentScan() (*Scan, error) {
 	query := `
-		SELECT id, started_at, completed_at, status, files_scanned, errors, scan_type, current_phase, last_processed_path, resume_from_scan_id, created_at
+		SELECT id, started_at, completed_at, status, files_scanned, errors, scan_type, current_phase, last_processed_path, resume_from_scan_id, trigger_source, created_at
 		FROM scans
 		WHERE status = 'running'
 		ORDER BY started_at DESC
@@ -732,6 +765,7 @@ func (db *DB) GetCurrentScan() (*Scan, error) {
 		&currentPhase,
 		&lastProcessedPath,
 		&resumeFromScanID,
+		&scan.TriggerSource,
 		&createdAt,
 	)
 
@@ -809,7 +843,7 @@ func (db *DB) ListScans(limit, offset int) ([]*Scan, int, error) {
 
 	// Get scans
 	query := `
-		SELECT id, started_at, completed_at, status, files_scanned, errors, scan_type, current_phase, last_processed_path, resume_from_scan_id, deleted_files_count, created_at
+		SELECT id, started_at, completed_at, status, files_scanned, errors, scan_type, current_phase, last_processed_path, resume_from_scan_id, deleted_files_count, trigger_source, created_at
 		FROM scans
 		ORDER BY started_at DESC
 		LIMIT ? OFFSET ?
@@ -843,6 +877,7 @@ func (db *DB) ListScans(limit, offset int) ([]*Scan, int, error) {
 			&lastProcessedPath,
 			&resumeFromScanID,
 			&scan.DeletedFilesCount,
+			&scan.TriggerSource,
 			&createdAt,
 		)
 		if err != nil {
@@ -1274,17 +1309,59 @@ func (db *DB) GetUsageByFileIDs(fileIDs []int64) (map[int64][]*Usage, error) {
 }
 
 // UpdateOrphanedStatus updates the orphaned status of all files
+// Files that lose all usage get orphaned_since set and last_used_by filled from the used_by snapshot;
+// both are cleared when usage returns. is_orphaned is rewritten during scans, so transitions are
+// detected from orphaned_since rather than from the previous is_orphaned value.
+// Files the watcher recorded but hasn't matched against services yet are left pending, not orphaned
 func (db *DB) UpdateOrphanedStatus(ctx context.Context) error {
-	query := `
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Newly orphaned files remember which services used them
+	_, err = tx.ExecContext(ctx, `
+		UPDATE files
+		SET orphaned_since = ?, last_used_by = used_by, used_by = NULL
+		WHERE orphaned_since IS NULL
+		  AND needs_service_update = 0
+		  AND NOT EXISTS (SELECT 1 FROM usage WHERE usage.file_id = files.id)
+	`, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to mark newly orphaned files: %w", err)
+	}
+
+	// Files in use keep an up to date snapshot of their services
+	_, err = tx.ExecContext(ctx, `
+		UPDATE files
+		SET orphaned_since = NULL, last_used_by = NULL, used_by = u.services
+		FROM (
+			SELECT file_id, group_concat(DISTINCT service ORDER BY service) AS services
+			FROM usage
+			GROUP BY file_id
+		) u
+		WHERE u.file_id = files.id
+		  AND (files.orphaned_since IS NOT NULL OR files.used_by IS NOT u.services)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to update file service snapshots: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
 		UPDATE files
 		SET is_orphaned = CASE
+			WHEN needs_service_update = 1 THEN 0
 			WHEN NOT EXISTS (SELECT 1 FROM usage WHERE usage.file_id = files.id)
 			THEN 1
 			ELSE 0
 		END
-	`
-	_, err := db.conn.ExecContext(ctx, query)
-	return err
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to update orphaned status: %w", err)
+	}
+
+	return tx.Commit()
 }
 
 // sanitizeFTS5Query escapes and quotes a search query for safe FTS5 usage.
@@ -1298,7 +1375,7 @@ func sanitizeFTS5Query(query string) string {
 }
 
 // SearchFiles searches for files by path using FTS
-func (db *DB) SearchFiles(searchQuery string, orphanedOnly bool, services []string, serviceFilterMode string, hardlinksOnly bool, extensions []string, deviceIDs []int64, limit, offset int, orderBy, direction string) ([]*File, int, error) {
+func (db *DB) SearchFiles(searchQuery string, orphanedOnly bool, orphanedDays int, services []string, serviceFilterMode string, hardlinksOnly, safeToDeleteOnly bool, extensions []string, deviceIDs []int64, limit, offset int, orderBy, direction string) ([]*File, int, error) {
 	var conditions []string
 	args := []interface{}{}
 
@@ -1311,6 +1388,12 @@ func (db *DB) SearchFiles(searchQuery string, orphanedOnly bool, services []stri
 		conditions = append(conditions, "f.is_orphaned = 1")
 	}
 
+	// Orphaned for at least orphanedDays days
+	if orphanedDays > 0 {
+		conditions = append(conditions, "f.orphaned_since <= ?")
+		args = append(args, time.Now().AddDate(0, 0, -orphanedDays).Unix())
+	}
+
 	// Filter by device IDs (for disk-based filtering)
 	// Use file_disk_locations table for accurate disk filtering (handles mergerfs setups)
 	if len(deviceIDs) > 0 {
@@ -1376,6 +1459,10 @@ func (db *DB) SearchFiles(searchQuery string, orphanedOnly bool, services []stri
 		)`)
 	}
 
+	if safeToDeleteOnly {
+		conditions = append(conditions, safeToDeleteCondition)
+	}
+
 	// Filter by file extensions using the extension column (much faster than GLOB!)
 	// Uses idx_files_extension or idx_files_orphaned_extension index
 	if len(extensions) > 0 {
@@ -1410,12 +1497,12 @@ func (db *DB) SearchFiles(searchQuery string, orphanedOnly bool, services []stri
 
 	query := fmt.Sprintf(`
 		SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time,
-		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at
+		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at, f.orphaned_since, f.last_used_by
 		FROM files f
 		%s
-		ORDER BY f.%s %s
+		ORDER BY %s
 		LIMIT ? OFFSET ?
-	`, whereClause, safeOrderBy, safeDirection)
+	`, whereClause, fileOrderClause(safeOrderBy, safeDirection))
 
 	args = append(args, limit, offset)
 	rows, err := db.conn.Query(query, args...)
@@ -1439,11 +1526,12 @@ func (db *DB) SearchFiles(searchQuery string, orphanedOnly bool, services []stri
 // ValidateOrderBy validates and returns a safe ORDER BY column name
 func ValidateOrderBy(orderBy string) string {
 	validColumns := map[string]bool{
-		"path":          true,
-		"size":          true,
-		"modified_time": true,
-		"last_verified": true,
-		"id":            true,
+		"path":           true,
+		"size":           true,
+		"modified_time":  true,
+		"last_verified":  true,
+		"id":             true,
+		"orphaned_since": true,
 	}
 
 	if validColumns[orderBy] {
@@ -1452,6 +1540,16 @@ func ValidateOrderBy(orderBy string) string {
 	return "path" // default
 }
 
+// fileOrderClause builds the ORDER BY clause for file listings from validated values
+// Files in use have no orphaned_since, so they sort after orphaned files in either direction
+func fileOrderClause(safeOrderBy, safeDirection string) string {
+	clause := fmt.Sprintf("f.%s %s", safeOrderBy, safeDirection)
+	if safeOrderBy == "orphaned_since" {
+		clause += " NULLS LAST"
+	}
+	return clause
+}
+
 // ValidateDirection ensures only valid SQL direction keywords are used
 func ValidateDirection(direction string) string {
 	if direction == "asc" || direction == "ASC" {
@@ -1529,7 +1627,7 @@ func (db *DB) GetFileExtensions(orphanedOnly bool, service string) ([]string, er
 }
 
 // ListFiles retrieves files with filtering and pagination
-func (db *DB) ListFiles(orphanedOnly bool, services []string, serviceFilterMode string, hardlinksOnly bool, extensions []string, deviceIDs []int64, limit, offset int, orderBy, direction string) ([]*File, int, error) {
+func (db *DB) ListFiles(orphanedOnly bool, orphanedDays int, services []string, serviceFilterMode string, hardlinksOnly, safeToDeleteOnly bool, extensions []string, deviceIDs []int64, limit, offset int, orderBy, direction string) ([]*File, int, error) {
 	var conditions []string
 	args := []interface{}{}
 
@@ -1537,6 +1635,12 @@ func (db *DB) ListFiles(orphanedOnly bool, services []string, serviceFilterMode
 		conditions = append(conditions, "f.is_orphaned = 1")
 	}
 
+	// Orphaned for at least orphanedDays days
+	if orphanedDays > 0 {
+		conditions = append(conditions, "f.orphaned_since <= ?")
+		args = append(args, time.Now().AddDate(0, 0, -orphanedDays).Unix())
+	}
+
 	// Filter by device IDs (for disk-based filtering)
 	// Use file_disk_locations table for accurate disk filtering (handles mergerfs setups)
 	if len(deviceIDs) > 0 {
@@ -1602,6 +1706,10 @@ func (db *DB) ListFiles(orphanedOnly bool, services []string, serviceFilterMode
 		)`)
 	}
 
+	if safeToDeleteOnly {
+		conditions = append(conditions, safeToDeleteCondition)
+	}
+
 	// Filter by file extensions using the extension column (much faster than GLOB!)
 	// Uses idx_files_extension or idx_files_orphaned_extension index
 	if len(extensions) > 0 {
@@ -1639,12 +1747,12 @@ func (db *DB) ListFiles(orphanedOnly bool, services []string, serviceFilterMode
 
 	query := fmt.Sprintf(`
 		SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time,
-		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at
+		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at, f.orphaned_since, f.last_used_by
 		FROM files f
 		%s
-		ORDER BY f.%s %s
+		ORDER BY %s
 		LIMIT ? OFFSET ?
-	`, whereClause, safeOrderBy, safeDirection)
+	`, whereClause, fileOrderClause(safeOrderBy, safeDirection))
 
 	args = append(args, limit, offset)
 	rows, err := db.conn.Query(query, args...)
@@ -1669,7 +1777,7 @@ func (db *DB) ListFiles(orphanedOnly bool, services []string, serviceFilterMode
 func (db *DB) GetHardlinkGroups() (map[string][]*File, error) {
 	query := `
 		SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time,
-		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at
+		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at, f.orphaned_since, f.last_used_by
 		FROM files f
 		WHERE (f.device_id, f.inode) IN (
 			SELECT device_id, inode
@@ -1764,7 +1872,7 @@ func (db *DB) GetHardlinkGroupsFiltered(search, orderBy, direction string, limit
 				LIMIT ? OFFSET ?
 			)
 			SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time,
-			       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at
+			       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at, f.orphaned_since, f.last_used_by
 			FROM files f
 			INNER JOIN hardlink_groups hg
 				ON f.device_id = hg.device_id AND f.inode = hg.inode
@@ -1793,7 +1901,7 @@ func (db *DB) GetHardlinkGroupsFiltered(search, orderBy, direction string, limit
 				LIMIT ? OFFSET ?
 			)
 			SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time,
-			       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at
+			       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at, f.orphaned_since, f.last_used_by
 			FROM files f
 			INNER JOIN ranked_groups rg
 				ON f.device_id = rg.device_id AND f.inode = rg.inode
@@ -1828,7 +1936,7 @@ func (db *DB) GetHardlinkGroupsFiltered(search, orderBy, direction string, limit
 func (db *DB) GetHardlinksByInodeDevice(inode, deviceID int64) ([]*File, error) {
 	query := `
 		SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time,
-		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at
+		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at, f.orphaned_since, f.last_used_by
 		FROM files f
 		WHERE f.device_id = ? AND f.inode = ?
 		ORDER BY f.path
@@ -1997,6 +2105,15 @@ func (db *DB) ClearScans() (int64, error) {
 		return 0, fmt.Errorf("failed to clear scan references: %w", err)
 	}
 
+	// Missing files are only kept for the latest scan of each service, so drop those of cleared scans
+	_, err = db.conn.Exec(`
+		DELETE FROM service_missing_files
+		WHERE scan_id IN (SELECT id FROM scans WHERE status != 'running')
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear missing files: %w", err)
+	}
+
 	// Now delete the scans
 	result, err := db.conn.Exec(`DELETE FROM scans WHERE status != 'running'`)
 	if err != nil {
@@ -2677,7 +2794,7 @@ func (db *DB) CountFileDiskLocations() (int64, error) {
 // GetFilesWithMultipleDiskLocations returns files that exist on multiple disks (cross-disk duplicates)
 func (db *DB) GetFilesWithMultipleDiskLocations() ([]*File, error) {
 	query := `
-		SELECT DISTINCT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time, f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at
+		SELECT DISTINCT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time, f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at, f.orphaned_since, f.last_used_by
 		FROM files f
 		JOIN file_disk_locations fdl ON f.id = fdl.file_id
 		GROUP BY f.id
@@ -2710,12 +2827,29 @@ func (db *DB) DeleteDiskLocationsByDisk(diskDeviceID int64) error {
 	return err
 }
 
+// DeleteFileDiskLocation deletes a file's location on one disk
+func (db *DB) DeleteFileDiskLocation(fileID, diskDeviceID int64) error {
+	query := `DELETE FROM file_disk_locations WHERE file_id = ? AND disk_device_id = ?`
+	_, err := db.conn.Exec(query, fileID, diskDeviceID)
+	return err
+}
+
+// GetDiskNameByDeviceID returns the disk name recorded for a disk device, or "" if no location is on it
+func (db *DB) GetDiskNameByDeviceID(diskDeviceID int64) (string, error) {
+	var name string
+	err := db.conn.QueryRow(`SELECT disk_name FROM file_disk_locations WHERE disk_device_id = ? LIMIT 1`, diskDeviceID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return name, err
+}
+
 // Hash Scanning Methods
 
 // GetFilesNeedingHash returns files that need hashing (optionally filtered by size)
 func (db *DB) GetFilesNeedingHash(minSize, maxSize int64, order string) ([]File, error) {
 	query := `
-		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at
+		SELECT id, path, size, inode, device_id, modified_time, scan_id, last_verified, is_orphaned, extension, created_at, orphaned_since, last_used_by
 		FROM files
 		WHERE hash_calculated = 0
 	`
@@ -2851,7 +2985,7 @@ func (db *DB) ClearAllHashes() error {
 func (db *DB) GetFilesWithQuickHashDuplicates(minSize int64, maxSize int64) ([]File, error) {
 	query := `
 		SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time,
-		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at
+		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at, f.orphaned_since, f.last_used_by
 		FROM files f
 		WHERE f.hash_type = 'quick'
 		  AND f.file_hash IN (
@@ -2921,7 +3055,7 @@ func (db *DB) GetQuickHashDuplicateCount() (int64, error) {
 func (db *DB) GetFilesWithQuickHashes(minSize int64, maxSize int64) ([]File, error) {
 	query := `
 		SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time,
-		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at
+		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at, f.orphaned_since, f.last_used_by
 		FROM files f
 		WHERE f.hash_type = 'quick'
 	`
@@ -2969,7 +3103,7 @@ func (db *DB) GetQuickHashCount() (int64, error) {
 func (db *DB) GetFilesWithHashDuplicatesAtLevel(level int, minSize int64, maxSize int64) ([]File, error) {
 	query := `
 		SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time,
-		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at
+		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at, f.orphaned_since, f.last_used_by
 		FROM files f
 		WHERE f.hash_level = ?
 		  AND f.hash_calculated = 1
@@ -3093,6 +3227,58 @@ func (db *DB) LogConsolidation(keepFile, deleteFile *DuplicateFile, reason strin
 	return err
 }
 
+// LogRelink logs a cross-disk duplicate that was hardlinked onto the kept file's disk to the audit log
+func (db *DB) LogRelink(keepFile, relinkedFile *DuplicateFile, linkPath, keptDisk string, removedCopies []string, reason string) error {
+	query := `
+		INSERT INTO audit_log (action, entity_type, entity_id, details, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	details := map[string]interface{}{
+		"operation":      "cross_disk_relink",
+		"kept_file":      keepFile.Path,
+		"kept_disk":      keptDisk,
+		"relinked_file":  relinkedFile.Path,
+		"link_path":      linkPath,
+		"removed_copies": removedCopies,
+		"size":           relinkedFile.Size,
+		"reason":         reason,
+	}
+
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal details: %w", err)
+	}
+
+	_, err = db.conn.Exec(query, "consolidate", "file", relinkedFile.ID, string(detailsJSON), time.Now().Unix())
+	return err
+}
+
+// LogReflink logs a same-disk duplicate that now shares its data with the primary file to the audit log
+func (db *DB) LogReflink(primaryFile, duplicateFile *DuplicateFile, bytesDeduped int64, reason string) error {
+	query := `
+		INSERT INTO audit_log (action, entity_type, entity_id, details, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	details := map[string]interface{}{
+		"operation":      "reflink_dedupe",
+		"primary_file":   primaryFile.Path,
+		"duplicate_file": duplicateFile.Path,
+		"disk":           primaryFile.DiskName,
+		"bytes_deduped":  bytesDeduped,
+		"reason":         reason,
+	}
+
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("failed to marshal details: %w", err)
+	}
+
+	_, err = db.conn.Exec(query, "reflink", "file", duplicateFile.ID, string(detailsJSON), time.Now().Unix())
+	return err
+}
+
 // LogHardlinkCreation logs a hardlink creation operation to the audit log
 func (db *DB) LogHardlinkCreation(primaryFile, duplicateFile *DuplicateFile, reason string) error {
 	query := `
@@ -3318,13 +3504,30 @@ func (db *DB) InsertMissingFile(ctx context.Context, missing *MissingFile) error
 
 // GetMissingFilesByScan retrieves all missing files for a specific scan
 func (db *DB) GetMissingFilesByScan(ctx context.Context, scanID int64) ([]*MissingFile, error) {
-	rows, err := db.conn.QueryContext(ctx, `
+	return db.queryMissingFiles(ctx, `
 		SELECT id, scan_id, service, service_path, translated_path,
 		       size, service_group, service_group_id, metadata, created_at
 		FROM service_missing_files
 		WHERE scan_id = ?
 		ORDER BY service, size DESC
 	`, scanID)
+}
+
+// GetLatestServiceMissingFiles retrieves each service's missing files from the latest scan that
+// recorded any for it, so a service update doesn't hide other services' missing files
+func (db *DB) GetLatestServiceMissingFiles(ctx context.Context) ([]*MissingFile, error) {
+	return db.queryMissingFiles(ctx, `
+		SELECT id, scan_id, service, service_path, translated_path,
+		       size, service_group, service_group_id, metadata, created_at
+		FROM service_missing_files m
+		WHERE scan_id = (SELECT MAX(scan_id) FROM service_missing_files WHERE service = m.service)
+		ORDER BY service, size DESC
+	`)
+}
+
+// queryMissingFiles runs a query selecting service_missing_files rows
+func (db *DB) queryMissingFiles(ctx context.Context, query string, args ...interface{}) ([]*MissingFile, error) {
+	rows, err := db.conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -3378,3 +3581,91 @@ func (db *DB) GetLatestMissingFiles(ctx context.Context) ([]*MissingFile, error)
 
 	return db.GetMissingFilesByScan(ctx, latestScanID)
 }
+
+// GetServiceReportedPaths returns the paths a service reported in its latest listing as recorded in
+// usage and missing files, for when no snapshot of the listing is stored
+func (db *DB) GetServiceReportedPaths(ctx context.Context, service string) ([]string, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT reference_path FROM usage WHERE service = ? AND reference_path != ''
+		UNION
+		SELECT service_path FROM service_missing_files
+		WHERE service = ? AND scan_id = (SELECT MAX(scan_id) FROM service_missing_files WHERE service = ?)
+	`, service, service, service)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+// GetMissingServicePaths returns which of the given service paths are among the missing files
+// recorded by the service's latest listing that had any
+func (db *DB) GetMissingServicePaths(ctx context.Context, service string, paths []string) (map[string]bool, error) {
+	missing := make(map[string]bool)
+	if len(paths) == 0 {
+		return missing, nil
+	}
+
+	args := []interface{}{service, service}
+	for _, path := range paths {
+		args = append(args, path)
+	}
+
+	rows, err := db.conn.QueryContext(ctx, fmt.Sprintf(`
+		SELECT DISTINCT service_path FROM service_missing_files
+		WHERE service = ? AND scan_id = (SELECT MAX(scan_id) FROM service_missing_files WHERE service = ?)
+		  AND service_path IN (%s)
+	`, buildInClause(len(paths))), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		missing[path] = true
+	}
+	return missing, rows.Err()
+}
+
+// FindFilePathsBySuffix returns the paths of up to limit scanned files ending in suffix, which
+// starts with a path separator, e.g. "/Movie/Movie.mkv"
+func (db *DB) FindFilePathsBySuffix(ctx context.Context, suffix string, limit int) ([]string, error) {
+	name := suffix[strings.LastIndex(suffix, "/")+1:]
+	if name == "" {
+		return nil, nil
+	}
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT path FROM files
+		WHERE id IN (SELECT rowid FROM files_fts WHERE files_fts MATCH ?)
+		  AND substr(path, -?) = ?
+		ORDER BY path
+		LIMIT ?
+	`, sanitizeFTS5Query(name), utf8.RuneCountInString(suffix), suffix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find files ending in %s: %w", suffix, err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
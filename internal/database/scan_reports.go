@@ -0,0 +1,307 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Scan change types
+const (
+	ScanChangeAdded      = "added"      // New on disk since the previous scan
+	ScanChangeRemoved    = "removed"    // No longer recorded, e.g. removed by the scan's cleanup of deleted files or the watcher
+	ScanChangeModified   = "modified"   // Size or modification time changed
+	ScanChangeOrphaned   = "orphaned"   // No longer used by any service
+	ScanChangeUnorphaned = "unorphaned" // Used by a service again
+	ScanChangeMissing    = "missing"    // Newly reported by a service but not found on disk
+)
+
+// ScanChangeTypes lists the scan change types in the order reports show them
+var ScanChangeTypes = []string{
+	ScanChangeAdded,
+	ScanChangeRemoved,
+	ScanChangeModified,
+	ScanChangeOrphaned,
+	ScanChangeUnorphaned,
+	ScanChangeMissing,
+}
+
+// ScanReport summarizes the changes since the end of the previous full or incremental scan
+type ScanReport struct {
+	ScanID           int64            `json:"scan_id"`
+	ScanType         string           `json:"scan_type"`
+	StartedAt        time.Time        `json:"started_at"`
+	ComparedToScanID *int64           `json:"compared_to_scan_id,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+	Counts           map[string]int   `json:"counts"` // Number of changes by change type
+	Sizes            map[string]int64 `json:"sizes"`  // Total size of the changed files by change type
+	MissingByService map[string]int   `json:"missing_by_service,omitempty"`
+}
+
+// Changes returns the total number of changes in the report
+func (r *ScanReport) Changes() int {
+	total := 0
+	for _, count := range r.Counts {
+		total += count
+	}
+	return total
+}
+
+// ScanChange is one file a scan found changed
+type ScanChange struct {
+	ID                   int64      `json:"id"`
+	ScanID               int64      `json:"scan_id"`
+	ChangeType           string     `json:"change_type"`
+	FileID               *int64     `json:"file_id,omitempty"`
+	Path                 string     `json:"path"`
+	Service              string     `json:"service,omitempty"`
+	Size                 int64      `json:"size"`
+	PreviousSize         *int64     `json:"previous_size,omitempty"`
+	ModifiedTime         *time.Time `json:"modified_time,omitempty"`
+	PreviousModifiedTime *time.Time `json:"previous_modified_time,omitempty"`
+}
+
+// CreateScanReport records what changed since the snapshot taken at the end of the previous full or
+// incremental scan, then replaces the snapshot with the files as they are now. Changes the watcher and
+// webhook rescans made in between are included. Files services newly report as missing are compared
+// with the missing files of the snapshot's scan. Only the most recent keep reports are kept
+// Returns nil if there was no snapshot to compare with, i.e. for the first scan
+func (db *DB) CreateScanReport(ctx context.Context, scanID int64, keep int) (*ScanReport, error) {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var comparedTo sql.NullInt64
+	if err := tx.QueryRowContext(ctx, `SELECT MAX(scan_id) FROM scan_snapshot_files`).Scan(&comparedTo); err != nil {
+		return nil, fmt.Errorf("failed to find the scan snapshot: %w", err)
+	}
+
+	if comparedTo.Valid {
+		if err := recordScanChanges(ctx, tx, scanID, comparedTo.Int64); err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR REPLACE INTO scan_reports (scan_id, compared_to_scan_id) VALUES (?, ?)
+		`, scanID, comparedTo.Int64); err != nil {
+			return nil, fmt.Errorf("failed to create scan report: %w", err)
+		}
+
+		// Changes of scans without a report (e.g. interrupted ones) are removed along with old reports
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM scan_reports WHERE scan_id NOT IN (
+				SELECT scan_id FROM scan_reports ORDER BY scan_id DESC LIMIT ?
+			)
+		`, keep); err != nil {
+			return nil, fmt.Errorf("failed to remove old scan reports: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM scan_changes WHERE scan_id < ? AND scan_id NOT IN (SELECT scan_id FROM scan_reports)
+		`, scanID); err != nil {
+			return nil, fmt.Errorf("failed to remove old scan changes: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scan_snapshot_files`); err != nil {
+		return nil, fmt.Errorf("failed to clear the scan snapshot: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO scan_snapshot_files (file_id, scan_id, path, size, modified_time, orphaned)
+		SELECT id, ?, path, size, modified_time, orphaned_since IS NOT NULL FROM files
+	`, scanID); err != nil {
+		return nil, fmt.Errorf("failed to snapshot files: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+
+	if !comparedTo.Valid {
+		return nil, nil
+	}
+	return db.GetScanReport(scanID)
+}
+
+// recordScanChanges records the differences between the files and the scan snapshot as changes of scanID
+// Moved files keep their row, so they are neither added nor removed
+func recordScanChanges(ctx context.Context, tx *sql.Tx, scanID, comparedTo int64) error {
+	changes := []struct {
+		changeType string
+		query      string
+		args       []interface{}
+	}{
+		{ScanChangeAdded, `
+			INSERT INTO scan_changes (scan_id, change_type, file_id, path, size, modified_time)
+			SELECT ?, 'added', f.id, f.path, f.size, f.modified_time
+			FROM files f
+			WHERE NOT EXISTS (SELECT 1 FROM scan_snapshot_files s WHERE s.file_id = f.id)
+		`, []interface{}{scanID}},
+		{ScanChangeRemoved, `
+			INSERT INTO scan_changes (scan_id, change_type, file_id, path, size, modified_time)
+			SELECT ?, 'removed', s.file_id, s.path, s.size, s.modified_time
+			FROM scan_snapshot_files s
+			WHERE NOT EXISTS (SELECT 1 FROM files f WHERE f.id = s.file_id)
+		`, []interface{}{scanID}},
+		{ScanChangeModified, `
+			INSERT INTO scan_changes (scan_id, change_type, file_id, path, size, previous_size, modified_time, previous_modified_time)
+			SELECT ?, 'modified', f.id, f.path, f.size, s.size, f.modified_time, s.modified_time
+			FROM files f
+			JOIN scan_snapshot_files s ON s.file_id = f.id
+			WHERE f.size != s.size OR f.modified_time != s.modified_time
+		`, []interface{}{scanID}},
+		{ScanChangeOrphaned, `
+			INSERT INTO scan_changes (scan_id, change_type, file_id, path, size, modified_time)
+			SELECT ?, 'orphaned', f.id, f.path, f.size, f.modified_time
+			FROM files f
+			LEFT JOIN scan_snapshot_files s ON s.file_id = f.id
+			WHERE f.orphaned_since IS NOT NULL AND (s.file_id IS NULL OR s.orphaned = 0)
+		`, []interface{}{scanID}},
+		// Removed files are no longer there, so they aren't reported as unorphaned
+		{ScanChangeUnorphaned, `
+			INSERT INTO scan_changes (scan_id, change_type, file_id, path, size, modified_time)
+			SELECT ?, 'unorphaned', f.id, f.path, f.size, f.modified_time
+			FROM files f
+			JOIN scan_snapshot_files s ON s.file_id = f.id
+			WHERE f.orphaned_since IS NULL AND s.orphaned = 1
+		`, []interface{}{scanID}},
+		{ScanChangeMissing, `
+			INSERT INTO scan_changes (scan_id, change_type, path, service, size)
+			SELECT ?, 'missing', m.translated_path, m.service, m.size
+			FROM service_missing_files m
+			WHERE m.scan_id = ?
+				AND NOT EXISTS (
+					SELECT 1 FROM service_missing_files p
+					WHERE p.scan_id = ? AND p.service = m.service AND p.translated_path = m.translated_path
+				)
+		`, []interface{}{scanID, scanID, comparedTo}},
+	}
+
+	for _, change := range changes {
+		if _, err := tx.ExecContext(ctx, change.query, change.args...); err != nil {
+			return fmt.Errorf("failed to record %s files: %w", change.changeType, err)
+		}
+	}
+	return nil
+}
+
+// GetScanReport returns a scan's report with its change totals
+// Returns sql.ErrNoRows if the scan has no report
+func (db *DB) GetScanReport(scanID int64) (*ScanReport, error) {
+	report := &ScanReport{
+		Counts:           make(map[string]int),
+		Sizes:            make(map[string]int64),
+		MissingByService: make(map[string]int),
+	}
+	var comparedTo sql.NullInt64
+	var startedAt, createdAt int64
+
+	err := db.conn.QueryRow(`
+		SELECT r.scan_id, s.scan_type, s.started_at, r.compared_to_scan_id, r.created_at
+		FROM scan_reports r
+		JOIN scans s ON s.id = r.scan_id
+		WHERE r.scan_id = ?
+	`, scanID).Scan(&report.ScanID, &report.ScanType, &startedAt, &comparedTo, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if comparedTo.Valid {
+		report.ComparedToScanID = &comparedTo.Int64
+	}
+	report.StartedAt = time.Unix(startedAt, 0)
+	report.CreatedAt = time.Unix(createdAt, 0)
+
+	rows, err := db.conn.Query(`
+		SELECT change_type, COALESCE(service, ''), COUNT(*), COALESCE(SUM(size), 0)
+		FROM scan_changes
+		WHERE scan_id = ?
+		GROUP BY change_type, service
+	`, scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count scan changes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var changeType, service string
+		var count int
+		var size int64
+		if err := rows.Scan(&changeType, &service, &count, &size); err != nil {
+			return nil, fmt.Errorf("failed to scan change totals: %w", err)
+		}
+		report.Counts[changeType] += count
+		report.Sizes[changeType] += size
+		if changeType == ScanChangeMissing {
+			report.MissingByService[service] = count
+		}
+	}
+
+	return report, rows.Err()
+}
+
+// ListScanChanges returns a scan's changes, optionally of one type and service, ordered by path
+// with the total count. A negative limit returns every change
+func (db *DB) ListScanChanges(scanID int64, changeType, service string, limit, offset int) ([]*ScanChange, int, error) {
+	where := "WHERE scan_id = ?"
+	args := []interface{}{scanID}
+	if changeType != "" {
+		where += " AND change_type = ?"
+		args = append(args, changeType)
+	}
+	if service != "" {
+		where += " AND service = ?"
+		args = append(args, service)
+	}
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM scan_changes `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count scan changes: %w", err)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT id, scan_id, change_type, file_id, path, service, size, previous_size, modified_time, previous_modified_time
+		FROM scan_changes
+		`+where+`
+		ORDER BY change_type, path, id
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query scan changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []*ScanChange
+	for rows.Next() {
+		c := &ScanChange{}
+		var fileID, size, previousSize, modifiedTime, previousModifiedTime sql.NullInt64
+		var svc sql.NullString
+		err := rows.Scan(&c.ID, &c.ScanID, &c.ChangeType, &fileID, &c.Path, &svc, &size,
+			&previousSize, &modifiedTime, &previousModifiedTime)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan scan change: %w", err)
+		}
+
+		if fileID.Valid {
+			c.FileID = &fileID.Int64
+		}
+		c.Service = svc.String
+		c.Size = size.Int64
+		if previousSize.Valid {
+			c.PreviousSize = &previousSize.Int64
+		}
+		if modifiedTime.Valid {
+			t := time.Unix(modifiedTime.Int64, 0)
+			c.ModifiedTime = &t
+		}
+		if previousModifiedTime.Valid {
+			t := time.Unix(previousModifiedTime.Int64, 0)
+			c.PreviousModifiedTime = &t
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, total, rows.Err()
+}
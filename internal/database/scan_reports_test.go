@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCreateScanReport(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	modified := time.Unix(1700000000, 0)
+
+	first, err := db.CreateScan("full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addFile := func(name string, scanID int64) *File {
+		t.Helper()
+		file := &File{Path: filepath.Join(dir, name), Size: 100, ModifiedTime: modified, ScanID: scanID, LastVerified: time.Now()}
+		if err := db.UpsertFile(file); err != nil {
+			t.Fatalf("UpsertFile: %v", err)
+		}
+		return file
+	}
+	addFile("kept.mkv", first.ID)
+	grown := addFile("grown.mkv", first.ID)
+	deleted := addFile("deleted.mkv", first.ID)
+	if err := db.UpdateOrphanedStatus(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := db.CreateScanReport(ctx, first.ID, 10)
+	if err != nil {
+		t.Fatalf("CreateScanReport: %v", err)
+	}
+	if report != nil {
+		t.Fatalf("the first scan has report %+v, want none", report)
+	}
+
+	// Changes between the scans, as the watcher would make them
+	grown.Size = 200
+	if err := db.UpsertFile(grown); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteFile(deleted.ID, "test", false); err != nil {
+		t.Fatal(err)
+	}
+	// Files without usage are orphaned, so only the new file becomes orphaned
+	addFile("new.mkv", first.ID)
+	if err := db.UpdateOrphanedStatus(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := db.CreateScan("incremental")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err = db.CreateScanReport(ctx, second.ID, 10)
+	if err != nil {
+		t.Fatalf("CreateScanReport: %v", err)
+	}
+	if report == nil || report.ComparedToScanID == nil || *report.ComparedToScanID != first.ID {
+		t.Fatalf("report = %+v, want one compared with scan %d", report, first.ID)
+	}
+
+	want := map[string]int{
+		ScanChangeAdded:    1,
+		ScanChangeRemoved:  1,
+		ScanChangeModified: 1,
+		ScanChangeOrphaned: 1,
+	}
+	for _, changeType := range ScanChangeTypes {
+		if report.Counts[changeType] != want[changeType] {
+			t.Errorf("%s changes = %d, want %d", changeType, report.Counts[changeType], want[changeType])
+		}
+	}
+
+	changes, _, err := db.ListScanChanges(second.ID, ScanChangeModified, "", -1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].PreviousSize == nil || *changes[0].PreviousSize != 100 || changes[0].Size != 200 {
+		t.Errorf("modified changes = %+v, want grown.mkv from 100 to 200 bytes", changes)
+	}
+
+	// The second scan replaced the snapshot, so a third one without changes has none
+	third, err := db.CreateScan("incremental")
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err = db.CreateScanReport(ctx, third.ID, 10)
+	if err != nil {
+		t.Fatalf("CreateScanReport: %v", err)
+	}
+	if report.Changes() != 0 {
+		t.Errorf("third scan has %d changes, want 0", report.Changes())
+	}
+}
@@ -49,11 +49,20 @@ CREATE TRIGGER IF NOT EXISTS files_au AFTER UPDATE ON files BEGIN
 	INSERT INTO files_fts(rowid, path) VALUES (new.id, new.path);
 END;
 
+-- Services table lists the registered service providers
+-- Rows are synced from the provider registry at startup, so new providers need no migration
+CREATE TABLE IF NOT EXISTS services (
+	name TEXT PRIMARY KEY,
+	display_name TEXT NOT NULL,
+	service_type TEXT NOT NULL DEFAULT '',
+	registered_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+);
+
 -- Usage table to track which services use each file
 CREATE TABLE IF NOT EXISTS usage (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
 	file_id INTEGER NOT NULL,
-	service TEXT NOT NULL CHECK(service IN ('plex', 'sonarr', 'radarr', 'qbittorrent', 'stash', 'calibre')),
+	service TEXT NOT NULL REFERENCES services(name),
 	reference_path TEXT NOT NULL,
 	metadata TEXT,
 	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
@@ -74,11 +83,12 @@ CREATE TABLE IF NOT EXISTS scans (
 	status TEXT NOT NULL CHECK(status IN ('running', 'completed', 'failed', 'interrupted', 'completed_with_errors')),
 	files_scanned INTEGER NOT NULL DEFAULT 0,
 	errors TEXT,
-	scan_type TEXT NOT NULL DEFAULT 'full' CHECK(scan_type IN ('full', 'incremental', 'disk_location', 'service_update_all', 'service_update_plex', 'service_update_sonarr', 'service_update_radarr', 'service_update_qbittorrent', 'service_update_stash', 'service_update_calibre', 'hash_scan', 'cleanup')),
+	scan_type TEXT NOT NULL DEFAULT 'full' CHECK(scan_type IN ('full', 'incremental', 'disk_location', 'service_update_all', 'hash_scan', 'cleanup', 'file_rescan') OR scan_type LIKE 'service_update_%'),
 	current_phase TEXT,
 	last_processed_path TEXT,
 	resume_from_scan_id INTEGER,
 	deleted_files_count INTEGER DEFAULT 0,
+	trigger_source TEXT NOT NULL DEFAULT 'manual',
 	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
 	FOREIGN KEY (resume_from_scan_id) REFERENCES scans(id)
 );
@@ -96,7 +106,7 @@ CREATE TABLE IF NOT EXISTS config (
 -- Audit log for tracking deletions and modifications
 CREATE TABLE IF NOT EXISTS audit_log (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	action TEXT NOT NULL CHECK(action IN ('delete', 'mark_rescan', 'config_change', 'consolidate', 'hardlink', 'cleanup', 'delete_failed')),
+	action TEXT NOT NULL CHECK(action IN ('delete', 'mark_rescan', 'config_change', 'consolidate', 'hardlink', 'cleanup', 'delete_failed', 'move', 'quarantine', 'restore', 'reflink')),
 	entity_type TEXT NOT NULL,
 	entity_id INTEGER,
 	details TEXT,
@@ -106,6 +116,223 @@ CREATE TABLE IF NOT EXISTS audit_log (
 CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
 CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action);
 
+-- File moves table records path changes of files that stayed the same file on disk
+-- (renames by Sonarr/Radarr detected during scans, and renames seen by the filesystem watcher)
+CREATE TABLE IF NOT EXISTS file_moves (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	file_id INTEGER NOT NULL,
+	old_path TEXT NOT NULL,
+	new_path TEXT NOT NULL,
+	scan_id INTEGER,
+	moved_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE CASCADE,
+	FOREIGN KEY (scan_id) REFERENCES scans(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_moves_file_id ON file_moves(file_id);
+
+-- Quarantine table tracks deleted files moved to a trash directory so they can be restored
+-- The files row is removed on quarantine; this row keeps what is needed to recreate it
+CREATE TABLE IF NOT EXISTS quarantine (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	file_id INTEGER NOT NULL,
+	original_path TEXT NOT NULL,
+	trash_path TEXT NOT NULL UNIQUE,
+	trash_root TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	inode INTEGER NOT NULL,
+	device_id INTEGER NOT NULL,
+	modified_time INTEGER NOT NULL,
+	is_orphaned INTEGER NOT NULL DEFAULT 0,
+	file_hash TEXT,
+	hash_algorithm TEXT,
+	hash_type TEXT,
+	hash_level INTEGER NOT NULL DEFAULT 0,
+	usage_snapshot TEXT,
+	reason TEXT,
+	quarantined_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_quarantine_quarantined_at ON quarantine(quarantined_at);
+CREATE INDEX IF NOT EXISTS idx_quarantine_original_path ON quarantine(original_path);
+
+-- Operation runs group the changes made by one consolidation or hardlink request so it can be reverted
+CREATE TABLE IF NOT EXISTS operation_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	operation TEXT NOT NULL CHECK(operation IN ('consolidate', 'hardlink')),
+	status TEXT NOT NULL DEFAULT 'running' CHECK(status IN ('running', 'completed', 'reverted', 'partially_reverted')),
+	started_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	completed_at INTEGER,
+	reverted_at INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_operation_runs_started_at ON operation_runs(started_at);
+
+-- Operation journal records each file replaced by a run with the state needed to put it back
+-- quarantine_id points at the quarantined original (deleted duplicate or pre-hardlink backup) when one was kept
+CREATE TABLE IF NOT EXISTS operation_journal (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id INTEGER NOT NULL,
+	group_hash TEXT NOT NULL,
+	kept_path TEXT NOT NULL,
+	kept_inode INTEGER NOT NULL,
+	kept_device_id INTEGER NOT NULL,
+	replaced_path TEXT NOT NULL,
+	original_inode INTEGER NOT NULL,
+	original_device_id INTEGER NOT NULL,
+	size INTEGER NOT NULL,
+	quarantine_id INTEGER,
+	linked_path TEXT,
+	removed_path TEXT,
+	status TEXT NOT NULL DEFAULT 'applied' CHECK(status IN ('applied', 'reverted', 'revert_failed')),
+	revert_error TEXT,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (run_id) REFERENCES operation_runs(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_operation_journal_run_id ON operation_journal(run_id);
+
+-- Policy runs record each evaluation of the cleanup policies, after a scan or on request
+CREATE TABLE IF NOT EXISTS policy_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	scan_id INTEGER,
+	trigger_source TEXT NOT NULL DEFAULT 'manual',
+	dry_run INTEGER NOT NULL DEFAULT 0,
+	started_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	completed_at INTEGER,
+	FOREIGN KEY (scan_id) REFERENCES scans(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_policy_runs_started_at ON policy_runs(started_at);
+
+-- Policy matches record each orphaned file a policy matched and what was done with it
+-- file_id is not a foreign key since the file record is removed when the policy acts on it
+CREATE TABLE IF NOT EXISTS policy_matches (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id INTEGER NOT NULL,
+	policy TEXT NOT NULL,
+	action TEXT NOT NULL,
+	file_id INTEGER NOT NULL,
+	path TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	modified_time INTEGER NOT NULL,
+	outcome TEXT NOT NULL CHECK(outcome IN ('reported', 'applied', 'failed')),
+	error TEXT,
+	FOREIGN KEY (run_id) REFERENCES policy_runs(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_policy_matches_run_policy ON policy_matches(run_id, policy);
+
+-- Usage events record when a service started or stopped referencing a file, or changed how it does
+-- metadata_diff holds the usage metadata fields that were added, removed or changed, keyed by field
+-- path is kept so the history outlives the file, whose file_id is cleared when it is deleted
+CREATE TABLE IF NOT EXISTS usage_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	file_id INTEGER,
+	path TEXT NOT NULL,
+	service TEXT NOT NULL,
+	event_type TEXT NOT NULL CHECK(event_type IN ('added', 'removed', 'changed')),
+	scan_id INTEGER,
+	reference_path TEXT,
+	metadata_diff TEXT,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE SET NULL,
+	FOREIGN KEY (scan_id) REFERENCES scans(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_events_file_id ON usage_events(file_id);
+CREATE INDEX IF NOT EXISTS idx_usage_events_service ON usage_events(service, event_type, created_at);
+
+-- Scan reports mark the scans whose changes were compared with the previous completed scan
+CREATE TABLE IF NOT EXISTS scan_reports (
+	scan_id INTEGER PRIMARY KEY,
+	compared_to_scan_id INTEGER,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (scan_id) REFERENCES scans(id) ON DELETE CASCADE,
+	FOREIGN KEY (compared_to_scan_id) REFERENCES scans(id) ON DELETE SET NULL
+);
+
+-- Scan changes list the files a scan added, removed, modified or changed the orphaned status of,
+-- and the files services newly reported as missing. file_id is not a foreign key since removed
+-- files no longer exist, and is NULL for missing files
+CREATE TABLE IF NOT EXISTS scan_changes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	scan_id INTEGER NOT NULL,
+	change_type TEXT NOT NULL CHECK(change_type IN ('added', 'removed', 'modified', 'orphaned', 'unorphaned', 'missing')),
+	file_id INTEGER,
+	path TEXT NOT NULL,
+	service TEXT,
+	size INTEGER,
+	previous_size INTEGER,
+	modified_time INTEGER,
+	previous_modified_time INTEGER,
+	FOREIGN KEY (scan_id) REFERENCES scans(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_scan_changes_scan_type ON scan_changes(scan_id, change_type);
+
+-- The scan snapshot holds every file as it was at the end of the last full or incremental scan
+-- The next scan's report is the difference with it, so it includes the changes the watcher and
+-- webhook rescans made in between. Only the latest snapshot is kept
+CREATE TABLE IF NOT EXISTS scan_snapshot_files (
+	file_id INTEGER PRIMARY KEY,
+	scan_id INTEGER NOT NULL,
+	path TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	modified_time INTEGER NOT NULL,
+	orphaned INTEGER NOT NULL
+);
+
+-- Service checks compare each full file listing from a service with the last accepted listing
+-- A stale listing failed a safeguard and was not applied; orphan-based deletes are blocked until
+-- it is acknowledged. Only the latest ok check of each service is kept
+CREATE TABLE IF NOT EXISTS service_checks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	service TEXT NOT NULL,
+	scan_id INTEGER,
+	status TEXT NOT NULL CHECK(status IN ('ok', 'stale')),
+	file_count INTEGER NOT NULL,
+	previous_count INTEGER,
+	reason TEXT,
+	acknowledged_at INTEGER,
+	acknowledged_by TEXT,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (scan_id) REFERENCES scans(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_checks_service ON service_checks(service, status);
+CREATE INDEX IF NOT EXISTS idx_service_checks_scan_id ON service_checks(scan_id);
+
+-- Service snapshots keep the file listings services returned on full refreshes, as gzip-compressed
+-- JSON, so listings can be compared and usage rebuilt without contacting the service
+CREATE TABLE IF NOT EXISTS service_snapshots (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	service TEXT NOT NULL,
+	scan_id INTEGER,
+	file_count INTEGER NOT NULL,
+	files BLOB NOT NULL,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (scan_id) REFERENCES scans(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_snapshots_service ON service_snapshots(service, id);
+
+-- Mapping proposals pair orphaned files with files services report as missing and suggest the
+-- service path mapping that would match them. Replaced each time reconciliation runs
+CREATE TABLE IF NOT EXISTS mapping_proposals (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	scan_id INTEGER,
+	service TEXT NOT NULL,
+	kind TEXT NOT NULL CHECK(kind IN ('mapping', 'name_mismatch')),
+	service_prefix TEXT NOT NULL DEFAULT '',
+	local_prefix TEXT NOT NULL DEFAULT '',
+	file_count INTEGER NOT NULL,
+	total_size INTEGER NOT NULL,
+	examples TEXT NOT NULL,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (scan_id) REFERENCES scans(id) ON DELETE SET NULL
+);
+
 -- Scan logs table for persistent logging of scan activity
 CREATE TABLE IF NOT EXISTS scan_logs (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -151,7 +378,7 @@ CREATE INDEX IF NOT EXISTS idx_disk_locations_inode ON file_disk_locations(disk_
 CREATE TABLE IF NOT EXISTS service_missing_files (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
 	scan_id INTEGER NOT NULL,
-	service TEXT NOT NULL CHECK(service IN ('plex', 'sonarr', 'radarr', 'qbittorrent', 'stash', 'calibre')),
+	service TEXT NOT NULL REFERENCES services(name),
 	service_path TEXT NOT NULL,
 	translated_path TEXT NOT NULL,
 	size INTEGER,
@@ -166,6 +393,43 @@ CREATE INDEX IF NOT EXISTS idx_missing_files_scan_id ON service_missing_files(sc
 CREATE INDEX IF NOT EXISTS idx_missing_files_service ON service_missing_files(service);
 CREATE INDEX IF NOT EXISTS idx_missing_files_size ON service_missing_files(size DESC);
 CREATE INDEX IF NOT EXISTS idx_missing_files_scan_service ON service_missing_files(scan_id, service);
+
+-- Users for web UI login
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+	password_hash TEXT NOT NULL,
+	role TEXT NOT NULL DEFAULT 'user' CHECK(role IN ('admin', 'user')),
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	last_login_at INTEGER
+);
+
+-- Login sessions (only a hash of the session token is stored)
+CREATE TABLE IF NOT EXISTS sessions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	token_hash TEXT NOT NULL UNIQUE,
+	user_id INTEGER NOT NULL,
+	expires_at INTEGER NOT NULL,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+
+-- API keys for automation (only a hash of the key is stored; the key is shown once on creation)
+CREATE TABLE IF NOT EXISTS api_keys (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	key_prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	role TEXT NOT NULL DEFAULT 'user' CHECK(role IN ('admin', 'user')),
+	created_by INTEGER,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	last_used_at INTEGER,
+	revoked_at INTEGER,
+	FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
 `
 
 // GetSchema returns the database schema
@@ -715,7 +979,7 @@ DROP TABLE IF EXISTS audit_log_new;
 -- Create new audit_log table with scan_id column
 CREATE TABLE audit_log_new (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	action TEXT NOT NULL CHECK(action IN ('delete', 'mark_rescan', 'config_change', 'consolidate', 'hardlink', 'cleanup', 'delete_failed')),
+	action TEXT NOT NULL CHECK(action IN ('delete', 'mark_rescan', 'config_change', 'consolidate', 'hardlink', 'cleanup', 'delete_failed', 'move', 'quarantine', 'restore', 'reflink')),
 	entity_type TEXT NOT NULL,
 	entity_id INTEGER,
 	scan_id INTEGER,
@@ -782,3 +1046,300 @@ ALTER TABLE scans_new RENAME TO scans;
 CREATE INDEX idx_scans_status ON scans(status);
 CREATE INDEX idx_scans_started_at ON scans(started_at);
 `
+
+// Migration to add trigger_source column to scans table
+const migrateAddScanTriggerSource = `
+-- Record what started each scan ('manual' for UI/API/CLI, 'scheduler' for cron-triggered runs)
+ALTER TABLE scans ADD COLUMN trigger_source TEXT NOT NULL DEFAULT 'manual';
+`
+
+// Migration to replace the hardcoded service CHECK constraints on usage and service_missing_files
+// with foreign keys to the services table
+const migrateServicesTable = `
+-- Register every service already referenced so existing rows satisfy the new foreign keys
+INSERT OR IGNORE INTO services (name, display_name) SELECT DISTINCT service, service FROM usage;
+INSERT OR IGNORE INTO services (name, display_name) SELECT DISTINCT service, service FROM service_missing_files;
+
+-- Rebuild usage table
+DROP TABLE IF EXISTS usage_new;
+CREATE TABLE usage_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	file_id INTEGER NOT NULL,
+	service TEXT NOT NULL REFERENCES services(name),
+	reference_path TEXT NOT NULL,
+	metadata TEXT,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	updated_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE CASCADE,
+	UNIQUE(file_id, service)
+);
+
+INSERT INTO usage_new (id, file_id, service, reference_path, metadata, created_at, updated_at)
+SELECT id, file_id, service, reference_path, metadata, created_at, updated_at
+FROM usage;
+
+DROP INDEX IF EXISTS idx_usage_file_id;
+DROP INDEX IF EXISTS idx_usage_service;
+DROP INDEX IF EXISTS idx_usage_reference_path;
+DROP TABLE usage;
+
+ALTER TABLE usage_new RENAME TO usage;
+
+CREATE INDEX idx_usage_file_id ON usage(file_id);
+CREATE INDEX idx_usage_service ON usage(service);
+CREATE INDEX idx_usage_reference_path ON usage(reference_path);
+
+-- Rebuild service_missing_files table
+DROP TABLE IF EXISTS service_missing_files_new;
+CREATE TABLE service_missing_files_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	scan_id INTEGER NOT NULL,
+	service TEXT NOT NULL REFERENCES services(name),
+	service_path TEXT NOT NULL,
+	translated_path TEXT NOT NULL,
+	size INTEGER,
+	service_group TEXT,
+	service_group_id TEXT,
+	metadata TEXT,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (scan_id) REFERENCES scans(id)
+);
+
+INSERT INTO service_missing_files_new (id, scan_id, service, service_path, translated_path, size, service_group, service_group_id, metadata, created_at)
+SELECT id, scan_id, service, service_path, translated_path, size, service_group, service_group_id, metadata, created_at
+FROM service_missing_files;
+
+DROP INDEX IF EXISTS idx_missing_files_scan_id;
+DROP INDEX IF EXISTS idx_missing_files_service;
+DROP INDEX IF EXISTS idx_missing_files_size;
+DROP INDEX IF EXISTS idx_missing_files_scan_service;
+DROP INDEX IF EXISTS idx_service_missing_files_scan_id;
+DROP INDEX IF EXISTS idx_service_missing_files_service;
+DROP TABLE service_missing_files;
+
+ALTER TABLE service_missing_files_new RENAME TO service_missing_files;
+
+CREATE INDEX idx_missing_files_scan_id ON service_missing_files(scan_id);
+CREATE INDEX idx_missing_files_service ON service_missing_files(service);
+CREATE INDEX idx_missing_files_size ON service_missing_files(size DESC);
+CREATE INDEX idx_missing_files_scan_service ON service_missing_files(scan_id, service);
+`
+
+// Migration to accept any service_update_<service> scan type so new providers need no migration
+const migrateGenericServiceUpdateScanType = `
+-- Drop scans_new if it exists from a previous failed migration
+DROP TABLE IF EXISTS scans_new;
+
+-- Create new scans table with a pattern-based CHECK for per-service updates
+CREATE TABLE scans_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	started_at INTEGER NOT NULL,
+	completed_at INTEGER,
+	status TEXT NOT NULL CHECK(status IN ('running', 'completed', 'failed', 'interrupted', 'completed_with_errors')),
+	files_scanned INTEGER NOT NULL DEFAULT 0,
+	errors TEXT,
+	scan_type TEXT NOT NULL DEFAULT 'full' CHECK(scan_type IN ('full', 'incremental', 'disk_location', 'service_update_all', 'hash_scan', 'cleanup', 'file_rescan') OR scan_type LIKE 'service_update_%'),
+	current_phase TEXT,
+	last_processed_path TEXT,
+	resume_from_scan_id INTEGER,
+	deleted_files_count INTEGER DEFAULT 0,
+	trigger_source TEXT NOT NULL DEFAULT 'manual',
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (resume_from_scan_id) REFERENCES scans(id)
+);
+
+-- Copy data from old table (use COALESCE to handle NULL values)
+INSERT INTO scans_new (id, started_at, completed_at, status, files_scanned, errors, scan_type, current_phase, last_processed_path, resume_from_scan_id, deleted_files_count, trigger_source, created_at)
+SELECT id, started_at, completed_at, status, files_scanned, errors, scan_type, current_phase, last_processed_path, resume_from_scan_id, COALESCE(deleted_files_count, 0), trigger_source, COALESCE(created_at, started_at)
+FROM scans;
+
+-- Drop old table and indexes
+DROP INDEX IF EXISTS idx_scans_status;
+DROP INDEX IF EXISTS idx_scans_started_at;
+DROP TABLE scans;
+
+-- Rename new table
+ALTER TABLE scans_new RENAME TO scans;
+
+-- Recreate indexes
+CREATE INDEX idx_scans_status ON scans(status);
+CREATE INDEX idx_scans_started_at ON scans(started_at);
+`
+
+// Migration to flag files whose service usage must be re-checked after a filesystem watcher change
+const migrateAddNeedsServiceUpdate = `
+ALTER TABLE files ADD COLUMN needs_service_update INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_files_needs_service_update ON files(needs_service_update) WHERE needs_service_update = 1;
+`
+
+// Migration to add 'move' to audit_log action CHECK constraint
+const migrateAddMoveToAuditLogAction = `
+-- Drop audit_log_new if it exists from a previous failed migration
+DROP TABLE IF EXISTS audit_log_new;
+
+-- Create new audit_log table with updated CHECK constraint including 'move'
+CREATE TABLE audit_log_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	action TEXT NOT NULL CHECK(action IN ('delete', 'mark_rescan', 'config_change', 'consolidate', 'hardlink', 'cleanup', 'delete_failed', 'move')),
+	entity_type TEXT NOT NULL,
+	entity_id INTEGER,
+	scan_id INTEGER,
+	details TEXT,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (scan_id) REFERENCES scans(id)
+);
+
+-- Copy data from old table
+INSERT INTO audit_log_new (id, action, entity_type, entity_id, scan_id, details, created_at)
+SELECT id, action, entity_type, entity_id, scan_id, details, created_at
+FROM audit_log;
+
+-- Drop old table and indexes
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP INDEX IF EXISTS idx_audit_log_action;
+DROP INDEX IF EXISTS idx_audit_log_scan_id;
+DROP TABLE audit_log;
+
+-- Rename new table
+ALTER TABLE audit_log_new RENAME TO audit_log;
+
+-- Recreate indexes
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_action ON audit_log(action);
+CREATE INDEX idx_audit_log_scan_id ON audit_log(scan_id);
+`
+
+// Migration to add 'quarantine' and 'restore' to audit_log action CHECK constraint
+const migrateAddQuarantineToAuditLogAction = `
+-- Drop audit_log_new if it exists from a previous failed migration
+DROP TABLE IF EXISTS audit_log_new;
+
+-- Create new audit_log table with updated CHECK constraint including 'quarantine' and 'restore'
+CREATE TABLE audit_log_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	action TEXT NOT NULL CHECK(action IN ('delete', 'mark_rescan', 'config_change', 'consolidate', 'hardlink', 'cleanup', 'delete_failed', 'move', 'quarantine', 'restore')),
+	entity_type TEXT NOT NULL,
+	entity_id INTEGER,
+	scan_id INTEGER,
+	details TEXT,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (scan_id) REFERENCES scans(id)
+);
+
+-- Copy data from old table
+INSERT INTO audit_log_new (id, action, entity_type, entity_id, scan_id, details, created_at)
+SELECT id, action, entity_type, entity_id, scan_id, details, created_at
+FROM audit_log;
+
+-- Drop old table and indexes
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP INDEX IF EXISTS idx_audit_log_action;
+DROP INDEX IF EXISTS idx_audit_log_scan_id;
+DROP TABLE audit_log;
+
+-- Rename new table
+ALTER TABLE audit_log_new RENAME TO audit_log;
+
+-- Recreate indexes
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_action ON audit_log(action);
+CREATE INDEX idx_audit_log_scan_id ON audit_log(scan_id);
+`
+
+// Migration to add the move-and-hardlink consolidation paths to operation_journal
+const migrateAddJournalRelinkPaths = `
+ALTER TABLE operation_journal ADD COLUMN linked_path TEXT;
+ALTER TABLE operation_journal ADD COLUMN removed_path TEXT;
+`
+
+// Migration to add 'reflink' to audit_log action CHECK constraint
+const migrateAddReflinkToAuditLogAction = `
+-- Drop audit_log_new if it exists from a previous failed migration
+DROP TABLE IF EXISTS audit_log_new;
+
+-- Create new audit_log table with updated CHECK constraint including 'reflink'
+CREATE TABLE audit_log_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	action TEXT NOT NULL CHECK(action IN ('delete', 'mark_rescan', 'config_change', 'consolidate', 'hardlink', 'cleanup', 'delete_failed', 'move', 'quarantine', 'restore', 'reflink')),
+	entity_type TEXT NOT NULL,
+	entity_id INTEGER,
+	scan_id INTEGER,
+	details TEXT,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (scan_id) REFERENCES scans(id)
+);
+
+-- Copy data from old table
+INSERT INTO audit_log_new (id, action, entity_type, entity_id, scan_id, details, created_at)
+SELECT id, action, entity_type, entity_id, scan_id, details, created_at
+FROM audit_log;
+
+-- Drop old table and indexes
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP INDEX IF EXISTS idx_audit_log_action;
+DROP INDEX IF EXISTS idx_audit_log_scan_id;
+DROP TABLE audit_log;
+
+-- Rename new table
+ALTER TABLE audit_log_new RENAME TO audit_log;
+
+-- Recreate indexes
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_action ON audit_log(action);
+CREATE INDEX idx_audit_log_scan_id ON audit_log(scan_id);
+`
+
+// Migration to track when files became orphaned and which services used them before
+// used_by is a snapshot of the services using a file, kept so last_used_by can be filled in
+// after the usage rows are gone. Files that are already orphaned are treated as orphaned from now
+const migrateAddOrphanTracking = `
+ALTER TABLE files ADD COLUMN orphaned_since INTEGER;
+ALTER TABLE files ADD COLUMN last_used_by TEXT;
+ALTER TABLE files ADD COLUMN used_by TEXT;
+CREATE INDEX IF NOT EXISTS idx_files_orphaned_since ON files(orphaned_since) WHERE orphaned_since IS NOT NULL;
+
+UPDATE files SET orphaned_since = strftime('%s', 'now') WHERE is_orphaned = 1;
+UPDATE files SET used_by = (
+	SELECT group_concat(DISTINCT service ORDER BY service) FROM usage WHERE usage.file_id = files.id
+) WHERE EXISTS (SELECT 1 FROM usage WHERE usage.file_id = files.id);
+`
+
+// Migration to keep usage events for deleted files and record usage changes
+// The path is copied onto each event and file_id is cleared instead of cascading the delete
+const migrateUsageEventsKeepPath = `
+DROP TABLE IF EXISTS usage_events_new;
+
+CREATE TABLE usage_events_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	file_id INTEGER,
+	path TEXT NOT NULL,
+	service TEXT NOT NULL,
+	event_type TEXT NOT NULL CHECK(event_type IN ('added', 'removed', 'changed')),
+	scan_id INTEGER,
+	reference_path TEXT,
+	metadata_diff TEXT,
+	created_at INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+	FOREIGN KEY (file_id) REFERENCES files(id) ON DELETE SET NULL,
+	FOREIGN KEY (scan_id) REFERENCES scans(id) ON DELETE SET NULL
+);
+
+INSERT INTO usage_events_new (id, file_id, path, service, event_type, scan_id, reference_path, metadata_diff, created_at)
+SELECT e.id, e.file_id, f.path, e.service, e.event_type, e.scan_id, e.reference_path, e.metadata_diff, e.created_at
+FROM usage_events e
+JOIN files f ON f.id = e.file_id;
+
+DROP INDEX IF EXISTS idx_usage_events_file_id;
+DROP INDEX IF EXISTS idx_usage_events_service;
+DROP TABLE usage_events;
+
+ALTER TABLE usage_events_new RENAME TO usage_events;
+
+CREATE INDEX idx_usage_events_file_id ON usage_events(file_id);
+CREATE INDEX idx_usage_events_service ON usage_events(service, event_type, created_at);
+`
+
+// Migration to drop the trigger that recorded modified files while a scan ran
+// Scan reports now compare the files with the snapshot taken at the end of the previous scan
+const migrateDropScanModifiedTrigger = `
+DROP TRIGGER IF EXISTS files_scan_modified;
+`
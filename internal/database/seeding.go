@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// safeToDeleteCondition matches files held only by torrents that no longer need to seed:
+// at least one download client uses the file, every download client usage has met its
+// ratio/seeding time goal or was unregistered by its tracker, and no media server uses it.
+// Download client usages without seeding metadata (e.g. incomplete files) count as still needed.
+// The metadata keys and values are written by the torrent clients in internal/api
+const safeToDeleteCondition = `EXISTS (
+		SELECT 1 FROM usage u JOIN services s ON s.name = u.service
+		WHERE u.file_id = f.id AND s.service_type = 'download-client'
+	) AND NOT EXISTS (
+		SELECT 1 FROM usage u JOIN services s ON s.name = u.service
+		WHERE u.file_id = f.id AND s.service_type = 'download-client'
+		AND COALESCE(json_extract(u.metadata, '$.seeding_goal_met'), 0) != 1
+		AND COALESCE(json_extract(u.metadata, '$.tracker_status'), '') != 'unregistered'
+	) AND NOT EXISTS (
+		SELECT 1 FROM usage u JOIN services s ON s.name = u.service
+		WHERE u.file_id = f.id AND s.service_type = 'media-server'
+	)`
+
+// SafeToDeleteFile is a torrent file that is safe to delete, with the torrent that holds it
+type SafeToDeleteFile struct {
+	*File
+	Service       string  `json:"service"`
+	TorrentName   string  `json:"torrent_name"`
+	TorrentHash   string  `json:"torrent_hash"`
+	Reason        string  `json:"reason"` // "tracker_unregistered" or "seeding_goal_met"
+	Ratio         float64 `json:"ratio"`
+	SeedingTime   int64   `json:"seeding_time"`
+	TrackerStatus string  `json:"tracker_status"`
+}
+
+// ListSafeToDeleteFiles returns torrent files that are safe to delete, ordered by path
+// When several download clients hold a file, the first recorded usage describes the torrent
+func (db *DB) ListSafeToDeleteFiles(limit, offset int) ([]*SafeToDeleteFile, error) {
+	query := fmt.Sprintf(`
+		SELECT f.id, f.path, f.size, f.inode, f.device_id, f.modified_time,
+		       f.scan_id, f.last_verified, f.is_orphaned, f.extension, f.created_at,
+		       u.service,
+		       json_extract(u.metadata, '$.torrent_name'),
+		       json_extract(u.metadata, '$.torrent_hash'),
+		       CASE WHEN json_extract(u.metadata, '$.tracker_status') = 'unregistered'
+		            THEN 'tracker_unregistered' ELSE 'seeding_goal_met' END,
+		       json_extract(u.metadata, '$.ratio'),
+		       json_extract(u.metadata, '$.seeding_time'),
+		       json_extract(u.metadata, '$.tracker_status')
+		FROM files f
+		JOIN usage u ON u.id = (
+			SELECT MIN(u2.id) FROM usage u2 JOIN services s ON s.name = u2.service
+			WHERE u2.file_id = f.id AND s.service_type = 'download-client'
+		)
+		WHERE %s
+		ORDER BY f.path
+		LIMIT ? OFFSET ?
+	`, safeToDeleteCondition)
+
+	rows, err := db.conn.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list safe to delete files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []*SafeToDeleteFile
+	for rows.Next() {
+		var file File
+		var safe SafeToDeleteFile
+		var modTime, lastVerified, createdAt int64
+		var scanID sql.NullInt64
+		var torrentName, torrentHash, trackerStatus sql.NullString
+		var ratio sql.NullFloat64
+		var seedingTime sql.NullInt64
+
+		err := rows.Scan(
+			&file.ID, &file.Path, &file.Size, &file.Inode, &file.DeviceID, &modTime,
+			&scanID, &lastVerified, &file.IsOrphaned, &file.Extension, &createdAt,
+			&safe.Service, &torrentName, &torrentHash, &safe.Reason,
+			&ratio, &seedingTime, &trackerStatus,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		file.ModifiedTime = time.Unix(modTime, 0)
+		file.LastVerified = time.Unix(lastVerified, 0)
+		file.CreatedAt = time.Unix(createdAt, 0)
+		file.ScanID = scanID.Int64
+
+		safe.File = &file
+		safe.TorrentName = torrentName.String
+		safe.TorrentHash = torrentHash.String
+		safe.Ratio = ratio.Float64
+		safe.SeedingTime = seedingTime.Int64
+		safe.TrackerStatus = trackerStatus.String
+		files = append(files, &safe)
+	}
+
+	return files, rows.Err()
+}
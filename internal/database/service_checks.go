@@ -0,0 +1,194 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Service check statuses
+const (
+	ServiceCheckOK    = "ok"    // The listing was applied
+	ServiceCheckStale = "stale" // The listing failed a safeguard and the previous usage was kept
+)
+
+// ServiceCheck records a full file listing from a service and whether it passed the safeguards
+type ServiceCheck struct {
+	ID             int64      `json:"id"`
+	Service        string     `json:"service"`
+	ScanID         *int64     `json:"scan_id,omitempty"`
+	Status         string     `json:"status"`
+	FileCount      int        `json:"file_count"`
+	PreviousCount  *int       `json:"previous_count,omitempty"` // Files in the last accepted listing, if there was one
+	Reason         string     `json:"reason,omitempty"`         // Why a stale listing failed
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// RecordServiceCheck stores a service check
+// An ok check replaces the service's previous ok check, since only the latest is needed as a baseline
+func (db *DB) RecordServiceCheck(ctx context.Context, check *ServiceCheck) error {
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if check.Status == ServiceCheckOK {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM service_checks WHERE service = ? AND status = 'ok'`, check.Service); err != nil {
+			return fmt.Errorf("failed to replace %s service check: %w", check.Service, err)
+		}
+	}
+
+	var scanID int64
+	if check.ScanID != nil {
+		scanID = *check.ScanID
+	}
+	var previousCount sql.NullInt64
+	if check.PreviousCount != nil {
+		previousCount = sql.NullInt64{Int64: int64(*check.PreviousCount), Valid: true}
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO service_checks (service, scan_id, status, file_count, previous_count, reason)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, check.Service, nullableScanID(scanID), check.Status, check.FileCount, previousCount,
+		sql.NullString{String: check.Reason, Valid: check.Reason != ""})
+	if err != nil {
+		return fmt.Errorf("failed to record %s service check: %w", check.Service, err)
+	}
+	if check.ID, err = result.LastInsertId(); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetServiceBaseline returns the number of files in a service's last accepted listing: the latest
+// ok check, or a stale check that was acknowledged. Services checked before service checks were
+// recorded fall back to their usage count. ok is false when there is nothing to compare with
+func (db *DB) GetServiceBaseline(ctx context.Context, service string) (count int, ok bool, err error) {
+	err = db.conn.QueryRowContext(ctx, `
+		SELECT file_count
+		FROM service_checks
+		WHERE service = ? AND (status = 'ok' OR acknowledged_at IS NOT NULL)
+		ORDER BY id DESC
+		LIMIT 1
+	`, service).Scan(&count)
+	if err == nil {
+		return count, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, fmt.Errorf("failed to get %s baseline: %w", service, err)
+	}
+
+	if err := db.conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM usage WHERE service = ?`, service).Scan(&count); err != nil {
+		return 0, false, fmt.Errorf("failed to count %s usage: %w", service, err)
+	}
+	return count, count > 0, nil
+}
+
+// ListStaleServices returns the latest unacknowledged stale check of each service, ordered by service
+// Orphaned status can't be trusted while any are returned
+func (db *DB) ListStaleServices(ctx context.Context) ([]*ServiceCheck, error) {
+	return db.queryServiceChecks(ctx, `
+		WHERE id IN (
+			SELECT MAX(id) FROM service_checks
+			WHERE status = 'stale' AND acknowledged_at IS NULL
+			GROUP BY service
+		)
+		ORDER BY service
+	`)
+}
+
+// GetScanStaleServices returns the services whose listings were stale in each of the given scans
+func (db *DB) GetScanStaleServices(ctx context.Context, scanIDs []int64) (map[int64][]string, error) {
+	result := make(map[int64][]string)
+	if len(scanIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]interface{}, len(scanIDs))
+	for i, id := range scanIDs {
+		args[i] = id
+	}
+	rows, err := db.conn.QueryContext(ctx, fmt.Sprintf(`
+		SELECT DISTINCT scan_id, service
+		FROM service_checks
+		WHERE status = 'stale' AND scan_id IN (%s)
+		ORDER BY service
+	`, buildInClause(len(scanIDs))), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stale services of scans: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var scanID int64
+		var service string
+		if err := rows.Scan(&scanID, &service); err != nil {
+			return nil, err
+		}
+		result[scanID] = append(result[scanID], service)
+	}
+	return result, rows.Err()
+}
+
+// AcknowledgeStaleService acknowledges a service's stale checks, which unblocks orphan-based deletes
+// once no other service is stale. The latest stale listing becomes the service's baseline, so the
+// next listing of a similar size is applied. Returns the number of checks acknowledged
+func (db *DB) AcknowledgeStaleService(ctx context.Context, service, acknowledgedBy string) (int64, error) {
+	result, err := db.conn.ExecContext(ctx, `
+		UPDATE service_checks
+		SET acknowledged_at = strftime('%s', 'now'), acknowledged_by = ?
+		WHERE service = ? AND status = 'stale' AND acknowledged_at IS NULL
+	`, acknowledgedBy, service)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acknowledge %s: %w", service, err)
+	}
+	return result.RowsAffected()
+}
+
+// queryServiceChecks selects service checks with the given WHERE and ORDER BY clauses
+func (db *DB) queryServiceChecks(ctx context.Context, clauses string, args ...interface{}) ([]*ServiceCheck, error) {
+	rows, err := db.conn.QueryContext(ctx, `
+		SELECT id, service, scan_id, status, file_count, previous_count, reason,
+			acknowledged_at, acknowledged_by, created_at
+		FROM service_checks
+	`+strings.TrimSpace(clauses), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service checks: %w", err)
+	}
+	defer rows.Close()
+
+	var checks []*ServiceCheck
+	for rows.Next() {
+		check := &ServiceCheck{}
+		var scanID, previousCount, acknowledgedAt sql.NullInt64
+		var reason, acknowledgedBy sql.NullString
+		var createdAt int64
+		if err := rows.Scan(&check.ID, &check.Service, &scanID, &check.Status, &check.FileCount, &previousCount,
+			&reason, &acknowledgedAt, &acknowledgedBy, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan service check: %w", err)
+		}
+		if scanID.Valid {
+			check.ScanID = &scanID.Int64
+		}
+		if previousCount.Valid {
+			count := int(previousCount.Int64)
+			check.PreviousCount = &count
+		}
+		if acknowledgedAt.Valid {
+			t := time.Unix(acknowledgedAt.Int64, 0)
+			check.AcknowledgedAt = &t
+		}
+		check.Reason = reason.String
+		check.AcknowledgedBy = acknowledgedBy.String
+		check.CreatedAt = time.Unix(createdAt, 0)
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
+}
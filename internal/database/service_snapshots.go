@@ -0,0 +1,221 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ServiceSnapshot describes a stored file listing from a service; its files are loaded separately
+type ServiceSnapshot struct {
+	ID             int64     `json:"id"`
+	Service        string    `json:"service"`
+	ScanID         *int64    `json:"scan_id,omitempty"`
+	FileCount      int       `json:"file_count"`
+	CompressedSize int64     `json:"compressed_size"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SnapshotFile is a file in a service snapshot, as the service reported it (before path mapping)
+type SnapshotFile struct {
+	Path     string                 `json:"path"`
+	Size     int64                  `json:"size,omitempty"`
+	Group    string                 `json:"group,omitempty"`
+	GroupID  string                 `json:"group_id,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// SnapshotDiff lists the files a service started and stopped reporting between two snapshots
+type SnapshotDiff struct {
+	From    *ServiceSnapshot `json:"from"`
+	To      *ServiceSnapshot `json:"to"`
+	Added   []SnapshotFile   `json:"added"`   // In the later snapshot only
+	Removed []SnapshotFile   `json:"removed"` // In the earlier snapshot only
+}
+
+// SaveServiceSnapshot stores a service's file listing, keeping only the service's latest keep snapshots
+// scanID is the scan that fetched the listing, or 0
+func (db *DB) SaveServiceSnapshot(ctx context.Context, service string, scanID int64, files []SnapshotFile, keep int) (int64, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(files); err != nil {
+		return 0, fmt.Errorf("failed to encode %s snapshot: %w", service, err)
+	}
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("failed to compress %s snapshot: %w", service, err)
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO service_snapshots (service, scan_id, file_count, files)
+		VALUES (?, ?, ?, ?)
+	`, service, nullableScanID(scanID), len(files), buf.Bytes())
+	if err != nil {
+		return 0, fmt.Errorf("failed to save %s snapshot: %w", service, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM service_snapshots
+		WHERE service = ? AND id NOT IN (
+			SELECT id FROM service_snapshots WHERE service = ? ORDER BY id DESC LIMIT ?
+		)
+	`, service, service, keep); err != nil {
+		return 0, fmt.Errorf("failed to prune %s snapshots: %w", service, err)
+	}
+
+	return id, tx.Commit()
+}
+
+// ListServiceSnapshots returns the stored snapshots, newest first, optionally for one service
+func (db *DB) ListServiceSnapshots(ctx context.Context, service string) ([]*ServiceSnapshot, error) {
+	query := `
+		SELECT id, service, scan_id, file_count, length(files), created_at
+		FROM service_snapshots
+	`
+	var args []interface{}
+	if service != "" {
+		query += " WHERE service = ?"
+		args = append(args, service)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := db.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []*ServiceSnapshot
+	for rows.Next() {
+		snapshot, err := scanServiceSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
+
+// GetServiceSnapshot returns a snapshot without its files
+func (db *DB) GetServiceSnapshot(ctx context.Context, id int64) (*ServiceSnapshot, error) {
+	return scanServiceSnapshot(db.conn.QueryRowContext(ctx, `
+		SELECT id, service, scan_id, file_count, length(files), created_at
+		FROM service_snapshots
+		WHERE id = ?
+	`, id))
+}
+
+// GetLatestServiceSnapshot returns a service's latest snapshot without its files
+// Returns sql.ErrNoRows if the service has none
+func (db *DB) GetLatestServiceSnapshot(ctx context.Context, service string) (*ServiceSnapshot, error) {
+	return scanServiceSnapshot(db.conn.QueryRowContext(ctx, `
+		SELECT id, service, scan_id, file_count, length(files), created_at
+		FROM service_snapshots
+		WHERE service = ?
+		ORDER BY id DESC
+		LIMIT 1
+	`, service))
+}
+
+// GetServiceSnapshotFiles returns the files of a snapshot
+func (db *DB) GetServiceSnapshotFiles(ctx context.Context, id int64) ([]SnapshotFile, error) {
+	var data []byte
+	if err := db.conn.QueryRowContext(ctx, `SELECT files FROM service_snapshots WHERE id = ?`, id).Scan(&data); err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot %d: %w", id, err)
+	}
+	defer zr.Close()
+
+	var files []SnapshotFile
+	if err := json.NewDecoder(zr).Decode(&files); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to decode snapshot %d: %w", id, err)
+	}
+	return files, nil
+}
+
+// DiffServiceSnapshots compares two snapshots of the same service by the paths the service reported
+// Added and removed files are sorted by path
+func (db *DB) DiffServiceSnapshots(ctx context.Context, fromID, toID int64) (*SnapshotDiff, error) {
+	from, err := db.GetServiceSnapshot(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := db.GetServiceSnapshot(ctx, toID)
+	if err != nil {
+		return nil, err
+	}
+	if from.Service != to.Service {
+		return nil, fmt.Errorf("snapshots %d and %d are of different services (%s and %s)", fromID, toID, from.Service, to.Service)
+	}
+
+	fromFiles, err := db.GetServiceSnapshotFiles(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	toFiles, err := db.GetServiceSnapshotFiles(ctx, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &SnapshotDiff{
+		From:    from,
+		To:      to,
+		Added:   subtractSnapshotFiles(toFiles, fromFiles),
+		Removed: subtractSnapshotFiles(fromFiles, toFiles),
+	}
+	return diff, nil
+}
+
+// subtractSnapshotFiles returns the files in a whose paths aren't in b, sorted by path
+func subtractSnapshotFiles(a, b []SnapshotFile) []SnapshotFile {
+	paths := make(map[string]bool, len(b))
+	for _, f := range b {
+		paths[f.Path] = true
+	}
+
+	result := []SnapshotFile{}
+	for _, f := range a {
+		if !paths[f.Path] {
+			paths[f.Path] = true // A service can report a path twice
+			result = append(result, f)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result
+}
+
+// scanServiceSnapshot scans a service snapshot row without its files
+func scanServiceSnapshot(row interface {
+	Scan(dest ...interface{}) error
+}) (*ServiceSnapshot, error) {
+	snapshot := &ServiceSnapshot{}
+	var scanID sql.NullInt64
+	var createdAt int64
+	if err := row.Scan(&snapshot.ID, &snapshot.Service, &scanID, &snapshot.FileCount, &snapshot.CompressedSize, &createdAt); err != nil {
+		return nil, err
+	}
+	if scanID.Valid {
+		snapshot.ScanID = &scanID.Int64
+	}
+	snapshot.CreatedAt = time.Unix(createdAt, 0)
+	return snapshot, nil
+}
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// Service represents a registered service provider
+// Usage and missing-file rows reference services by name
+type Service struct {
+	Name         string
+	DisplayName  string
+	Type         string
+	RegisteredAt time.Time
+}
+
+// RegisterServices upserts the given services so usage rows can reference them
+// Services that are no longer registered are kept so historical rows remain valid
+func (db *DB) RegisterServices(services []Service) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO services (name, display_name, service_type)
+		VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			display_name = excluded.display_name,
+			service_type = excluded.service_type
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare service upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, service := range services {
+		if _, err := stmt.Exec(service.Name, service.DisplayName, service.Type); err != nil {
+			return fmt.Errorf("failed to register service %s: %w", service.Name, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListServices returns all registered services ordered by name
+func (db *DB) ListServices() ([]*Service, error) {
+	rows, err := db.conn.Query(`
+		SELECT name, display_name, service_type, registered_at
+		FROM services
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	defer rows.Close()
+
+	var services []*Service
+	for rows.Next() {
+		service := &Service{}
+		var registeredAt int64
+		if err := rows.Scan(&service.Name, &service.DisplayName, &service.Type, &registeredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan service: %w", err)
+		}
+		service.RegisteredAt = time.Unix(registeredAt, 0)
+		services = append(services, service)
+	}
+
+	return services, rows.Err()
+}
@@ -0,0 +1,323 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Usage event types
+const (
+	UsageEventAdded   = "added"   // The service started referencing the file
+	UsageEventRemoved = "removed" // The service stopped referencing the file
+	UsageEventChanged = "changed" // The service still references the file with a different reference path or metadata
+)
+
+// usageReferencePathKey is the metadata diff key of a changed reference path
+const usageReferencePathKey = "reference_path"
+
+// UsageEvent records a service starting or stopping to reference a file, or changing how it does
+// FileID is zero once the file has been deleted, Path still names it
+type UsageEvent struct {
+	ID            int64                     `json:"id"`
+	FileID        int64                     `json:"file_id,omitempty"`
+	Path          string                    `json:"path"`
+	Service       string                    `json:"service"`
+	EventType     string                    `json:"event_type"`
+	ScanID        *int64                    `json:"scan_id,omitempty"`
+	ReferencePath string                    `json:"reference_path,omitempty"`
+	MetadataDiff  map[string]MetadataChange `json:"metadata_diff,omitempty"`
+	CreatedAt     time.Time                 `json:"created_at"`
+}
+
+// MetadataChange is the before and after value of one usage metadata field
+type MetadataChange struct {
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// UsageEventFilter selects usage events; zero values match everything
+type UsageEventFilter struct {
+	FileID       int64
+	Service      string
+	EventType    string
+	Since        time.Time
+	Until        time.Time
+	StillRemoved bool // Only removals the service has not reverted, i.e. it still doesn't use the file
+}
+
+// usageState is a usage record as it was when a snapshot was taken
+type usageState struct {
+	referencePath string
+	metadata      string
+}
+
+// UsageSnapshot holds one service's usage records keyed by file ID
+// Snapshots taken before and after a usage refresh are compared to record usage events
+type UsageSnapshot map[int64]usageState
+
+// SnapshotServiceUsage returns a service's current usage records
+// When fileIDs is not nil only those files are included
+func (db *DB) SnapshotServiceUsage(ctx context.Context, service string, fileIDs []int64) (UsageSnapshot, error) {
+	snapshot := make(UsageSnapshot)
+
+	load := func(query string, args ...interface{}) error {
+		rows, err := db.conn.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var fileID int64
+			var referencePath, metadata sql.NullString
+			if err := rows.Scan(&fileID, &referencePath, &metadata); err != nil {
+				return err
+			}
+			snapshot[fileID] = usageState{referencePath: referencePath.String, metadata: metadata.String}
+		}
+		return rows.Err()
+	}
+
+	if fileIDs == nil {
+		if err := load(`SELECT file_id, reference_path, metadata FROM usage WHERE service = ?`, service); err != nil {
+			return nil, fmt.Errorf("failed to snapshot %s usage: %w", service, err)
+		}
+		return snapshot, nil
+	}
+
+	const batchSize = 900
+	for i := 0; i < len(fileIDs); i += batchSize {
+		end := i + batchSize
+		if end > len(fileIDs) {
+			end = len(fileIDs)
+		}
+		batch := fileIDs[i:end]
+
+		args := make([]interface{}, 0, len(batch)+1)
+		args = append(args, service)
+		for _, id := range batch {
+			args = append(args, id)
+		}
+		query := fmt.Sprintf(`SELECT file_id, reference_path, metadata FROM usage WHERE service = ? AND file_id IN (%s)`, buildInClause(len(batch)))
+		if err := load(query, args...); err != nil {
+			return nil, fmt.Errorf("failed to snapshot %s usage: %w", service, err)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// UsageChanges counts the usage events recorded for a service
+type UsageChanges struct {
+	Added   int
+	Removed int
+	Changed int
+}
+
+// Total returns the number of events recorded
+func (c UsageChanges) Total() int {
+	return c.Added + c.Removed + c.Changed
+}
+
+// RecordUsageChanges compares a snapshot taken before a usage refresh with the service's usage now
+// and records an event for every file the service started or stopped referencing, or now references
+// with a different reference path or metadata. fileIDs must match the set the snapshot was taken for
+func (db *DB) RecordUsageChanges(ctx context.Context, service string, scanID int64, before UsageSnapshot, fileIDs []int64) (UsageChanges, error) {
+	var counts UsageChanges
+	after, err := db.SnapshotServiceUsage(ctx, service, fileIDs)
+	if err != nil {
+		return counts, err
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return counts, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Files removed since the snapshot have no history to record
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO usage_events (file_id, path, service, event_type, scan_id, reference_path, metadata_diff)
+		SELECT id, path, ?, ?, ?, NULLIF(?, ''), ? FROM files WHERE id = ?
+	`)
+	if err != nil {
+		return counts, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	record := func(fileID int64, eventType, referencePath string, changes map[string]MetadataChange) error {
+		var diff sql.NullString
+		if len(changes) > 0 {
+			data, err := json.Marshal(changes)
+			if err != nil {
+				return fmt.Errorf("failed to marshal metadata diff: %w", err)
+			}
+			diff = sql.NullString{String: string(data), Valid: true}
+		}
+		_, err := stmt.ExecContext(ctx, service, eventType, nullableScanID(scanID), referencePath, diff, fileID)
+		return err
+	}
+
+	for fileID, state := range after {
+		old, ok := before[fileID]
+		if !ok {
+			if err := record(fileID, UsageEventAdded, state.referencePath, diffMetadata("", state.metadata)); err != nil {
+				return UsageChanges{}, fmt.Errorf("failed to record usage event: %w", err)
+			}
+			counts.Added++
+			continue
+		}
+
+		changes := diffMetadata(old.metadata, state.metadata)
+		if old.referencePath != state.referencePath {
+			changes[usageReferencePathKey] = MetadataChange{Old: nullIfEmpty(old.referencePath), New: nullIfEmpty(state.referencePath)}
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		if err := record(fileID, UsageEventChanged, state.referencePath, changes); err != nil {
+			return UsageChanges{}, fmt.Errorf("failed to record usage event: %w", err)
+		}
+		counts.Changed++
+	}
+	for fileID, state := range before {
+		if _, ok := after[fileID]; ok {
+			continue
+		}
+		if err := record(fileID, UsageEventRemoved, state.referencePath, diffMetadata(state.metadata, "")); err != nil {
+			return UsageChanges{}, fmt.Errorf("failed to record usage event: %w", err)
+		}
+		counts.Removed++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return UsageChanges{}, err
+	}
+	return counts, nil
+}
+
+// nullIfEmpty returns nil for an empty string so it is left out of a metadata change
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// diffMetadata returns the fields that differ between two usage metadata JSON documents
+func diffMetadata(oldJSON, newJSON string) map[string]MetadataChange {
+	var oldFields, newFields map[string]interface{}
+	if oldJSON != "" {
+		json.Unmarshal([]byte(oldJSON), &oldFields)
+	}
+	if newJSON != "" {
+		json.Unmarshal([]byte(newJSON), &newFields)
+	}
+
+	changes := make(map[string]MetadataChange)
+	for key, oldValue := range oldFields {
+		if newValue, ok := newFields[key]; !ok || !reflect.DeepEqual(oldValue, newValue) {
+			changes[key] = MetadataChange{Old: oldValue, New: newFields[key]}
+		}
+	}
+	for key, newValue := range newFields {
+		if _, ok := oldFields[key]; !ok {
+			changes[key] = MetadataChange{New: newValue}
+		}
+	}
+	return changes
+}
+
+// ListUsageEvents returns usage events matching a filter, most recent first, with the total count
+func (db *DB) ListUsageEvents(filter UsageEventFilter, limit, offset int) ([]*UsageEvent, int, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.FileID > 0 {
+		conditions = append(conditions, "e.file_id = ?")
+		args = append(args, filter.FileID)
+	}
+	if filter.Service != "" {
+		conditions = append(conditions, "e.service = ?")
+		args = append(args, filter.Service)
+	}
+	if filter.EventType != "" {
+		conditions = append(conditions, "e.event_type = ?")
+		args = append(args, filter.EventType)
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "e.created_at >= ?")
+		args = append(args, filter.Since.Unix())
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "e.created_at < ?")
+		args = append(args, filter.Until.Unix())
+	}
+	if filter.StillRemoved {
+		// A deleted file is no longer used by anything
+		conditions = append(conditions,
+			"e.event_type = 'removed'",
+			"NOT EXISTS (SELECT 1 FROM usage u WHERE u.file_id = e.file_id AND u.service = e.service)")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM usage_events e `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count usage events: %w", err)
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT e.id, e.file_id, COALESCE(f.path, e.path), e.service, e.event_type, e.scan_id, e.reference_path, e.metadata_diff, e.created_at
+		FROM usage_events e
+		LEFT JOIN files f ON f.id = e.file_id
+		`+where+`
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query usage events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*UsageEvent
+	for rows.Next() {
+		event := &UsageEvent{}
+		var fileID, scanID sql.NullInt64
+		var referencePath, metadataDiff sql.NullString
+		var createdAt int64
+		err := rows.Scan(&event.ID, &fileID, &event.Path, &event.Service, &event.EventType,
+			&scanID, &referencePath, &metadataDiff, &createdAt)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan usage event: %w", err)
+		}
+		event.FileID = fileID.Int64
+		if scanID.Valid {
+			event.ScanID = &scanID.Int64
+		}
+		event.ReferencePath = referencePath.String
+		if metadataDiff.Valid {
+			if err := json.Unmarshal([]byte(metadataDiff.String), &event.MetadataDiff); err != nil {
+				return nil, 0, fmt.Errorf("failed to unmarshal metadata diff: %w", err)
+			}
+		}
+		event.CreatedAt = time.Unix(createdAt, 0)
+		events = append(events, event)
+	}
+
+	return events, total, rows.Err()
+}
+
+// GetUsageEvents returns a file's usage history, most recent first
+func (db *DB) GetUsageEvents(fileID int64, limit int) ([]*UsageEvent, error) {
+	events, _, err := db.ListUsageEvents(UsageEventFilter{FileID: fileID}, limit, 0)
+	return events, err
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordUsageChanges(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	scan, err := db.CreateScan("full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addFile := func(name string) *File {
+		t.Helper()
+		file := &File{Path: filepath.Join(dir, name), Size: 100, ModifiedTime: time.Now(), ScanID: scan.ID, LastVerified: time.Now()}
+		if err := db.UpsertFile(file); err != nil {
+			t.Fatalf("UpsertFile: %v", err)
+		}
+		return file
+	}
+	kept := addFile("kept.mkv")
+	upgraded := addFile("upgraded.mkv")
+	dropped := addFile("dropped.mkv")
+
+	if err := db.RegisterServices([]Service{{Name: "sonarr", DisplayName: "Sonarr", Type: "arr"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, usage := range []*Usage{
+		{FileID: kept.ID, Service: "sonarr", ReferencePath: "/tv/kept.mkv", Metadata: map[string]interface{}{"quality": "HDTV-720p"}},
+		{FileID: upgraded.ID, Service: "sonarr", ReferencePath: "/tv/upgraded.mkv", Metadata: map[string]interface{}{"quality": "HDTV-720p"}},
+		{FileID: dropped.ID, Service: "sonarr", ReferencePath: "/tv/dropped.mkv", Metadata: map[string]interface{}{"quality": "HDTV-720p"}},
+	} {
+		if err := db.UpsertUsage(usage); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before, err := db.SnapshotServiceUsage(ctx, "sonarr", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upgradedUsage := &Usage{FileID: upgraded.ID, Service: "sonarr", ReferencePath: "/tv/upgraded.mkv", Metadata: map[string]interface{}{"quality": "Bluray-1080p"}}
+	if err := db.UpsertUsage(upgradedUsage); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`DELETE FROM usage WHERE file_id = ?`, dropped.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := db.RecordUsageChanges(ctx, "sonarr", scan.ID, before, nil)
+	if err != nil {
+		t.Fatalf("RecordUsageChanges: %v", err)
+	}
+	if changes != (UsageChanges{Removed: 1, Changed: 1}) {
+		t.Fatalf("changes = %+v, want one removed and one changed", changes)
+	}
+
+	events, err := db.GetUsageEvents(upgraded.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].EventType != UsageEventChanged {
+		t.Fatalf("events for the upgraded file = %+v, want one changed event", events)
+	}
+	if change := events[0].MetadataDiff["quality"]; change.Old != "HDTV-720p" || change.New != "Bluray-1080p" {
+		t.Errorf("quality change = %+v, want HDTV-720p to Bluray-1080p", change)
+	}
+
+	// The removal stays listed once the file itself is deleted
+	if err := db.DeleteFile(dropped.ID, "test", false); err != nil {
+		t.Fatal(err)
+	}
+	events, total, err := db.ListUsageEvents(UsageEventFilter{EventType: UsageEventRemoved, StillRemoved: true}, 10, 0)
+	if err != nil {
+		t.Fatalf("ListUsageEvents: %v", err)
+	}
+	if total != 1 || len(events) != 1 {
+		t.Fatalf("removed events = %d, want 1", total)
+	}
+	if events[0].Path != dropped.Path || events[0].FileID != 0 {
+		t.Errorf("removed event = %+v, want the deleted file's path without a file ID", events[0])
+	}
+}
@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// underPathCondition matches rows whose path is inside a directory
+// Paths under "dir/" sort between "dir/" and "dir0" ('0' follows '/'), so this is a range scan on the path index
+const underPathCondition = `(path > ? || '/' AND path < ? || '0')`
+
+// GetFilePathsUnderPath returns the paths of all files inside a directory
+func (db *DB) GetFilePathsUnderPath(ctx context.Context, dir string) ([]string, error) {
+	dir = strings.TrimSuffix(dir, "/")
+	rows, err := db.conn.QueryContext(ctx, `SELECT path FROM files WHERE `+underPathCondition, dir, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query paths under %s: %w", dir, err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan path: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
+
+// DeleteFilesByPaths removes files, and any files inside them when they were directories
+// A single audit log entry records the deletion. Returns the number of files removed
+func (db *DB) DeleteFilesByPaths(ctx context.Context, paths []string, details string) (int64, error) {
+	if len(paths) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `DELETE FROM files WHERE path = ? OR `+underPathCondition)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	var deleted int64
+	for _, path := range paths {
+		path = strings.TrimSuffix(path, "/")
+		result, err := stmt.ExecContext(ctx, path, path, path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete %s: %w", path, err)
+		}
+		affected, _ := result.RowsAffected()
+		deleted += affected
+	}
+
+	if deleted > 0 {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO audit_log (action, entity_type, entity_id, details) VALUES ('delete', 'file', NULL, ?)`,
+			fmt.Sprintf("%s (%d files)", details, deleted),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to log deletion: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return deleted, nil
+}
+
+// RenameFilePath moves a file, or every file inside a directory, to a new path
+// Moved files keep their usage records and move history and are flagged for a service update,
+// since services still reference the old path. A file already recorded at the destination is replaced
+func (db *DB) RenameFilePath(ctx context.Context, oldPath, newPath string) (int64, error) {
+	oldPath = strings.TrimSuffix(oldPath, "/")
+	newPath = strings.TrimSuffix(newPath, "/")
+
+	tx, err := db.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Clear the destination so the moved paths don't collide with the UNIQUE path constraint
+	if _, err := tx.ExecContext(ctx, `DELETE FROM files WHERE path = ? OR `+underPathCondition, newPath, newPath, newPath); err != nil {
+		return 0, fmt.Errorf("failed to clear rename destination %s: %w", newPath, err)
+	}
+
+	// Keep the move history of every file being renamed
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO file_moves (file_id, old_path, new_path)
+		SELECT id, path, ? || substr(path, length(?) + 1)
+		FROM files
+		WHERE path = ? OR `+underPathCondition,
+		newPath, oldPath,
+		oldPath, oldPath, oldPath,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record move of %s: %w", oldPath, err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE files
+		SET path = ? || substr(path, length(?) + 1),
+			extension = CASE WHEN path = ? THEN ? ELSE extension END,
+			needs_service_update = 1
+		WHERE path = ? OR `+underPathCondition,
+		newPath, oldPath,
+		oldPath, ExtractExtension(newPath),
+		oldPath, oldPath, oldPath,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rename %s to %s: %w", oldPath, newPath, err)
+	}
+
+	renamed, _ := result.RowsAffected()
+	if renamed > 0 {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO audit_log (action, entity_type, entity_id, details) VALUES ('move', 'file', NULL, ?)`,
+			fmt.Sprintf("Moved %s to %s (%d files, filesystem watcher)", oldPath, newPath, renamed),
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to log move: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit: %w", err)
+	}
+	return renamed, nil
+}
+
+// MarkFilesForServiceUpdate flags files so their service usage is re-checked
+func (db *DB) MarkFilesForServiceUpdate(ctx context.Context, paths []string) error {
+	for start := 0; start < len(paths); start += 900 {
+		end := min(start+900, len(paths))
+		chunk := paths[start:end]
+
+		args := make([]interface{}, len(chunk))
+		for i, path := range chunk {
+			args[i] = path
+		}
+
+		query := fmt.Sprintf(`UPDATE files SET needs_service_update = 1 WHERE path IN (%s)`, buildInClause(len(chunk)))
+		if _, err := db.conn.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to mark files for service update: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetFilesNeedingServiceUpdate returns the paths of files flagged for a service update
+func (db *DB) GetFilesNeedingServiceUpdate(ctx context.Context, limit int) ([]string, error) {
+	rows, err := db.conn.QueryContext(ctx, `SELECT path FROM files WHERE needs_service_update = 1 ORDER BY path LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query files needing service update: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan path: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
+
+// ClearServiceUpdateMarks removes the service update flag from files
+func (db *DB) ClearServiceUpdateMarks(ctx context.Context, paths []string) error {
+	for start := 0; start < len(paths); start += 900 {
+		end := min(start+900, len(paths))
+		chunk := paths[start:end]
+
+		args := make([]interface{}, len(chunk))
+		for i, path := range chunk {
+			args[i] = path
+		}
+
+		query := fmt.Sprintf(`UPDATE files SET needs_service_update = 0 WHERE path IN (%s)`, buildInClause(len(chunk)))
+		if _, err := db.conn.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to clear service update marks: %w", err)
+		}
+	}
+	return nil
+}
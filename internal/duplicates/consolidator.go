@@ -1,6 +1,8 @@
 package duplicates
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -9,6 +11,8 @@ import (
 
 	"github.com/mmenanno/media-usage-finder/internal/config"
 	"github.com/mmenanno/media-usage-finder/internal/database"
+	"github.com/mmenanno/media-usage-finder/internal/notify"
+	"github.com/mmenanno/media-usage-finder/internal/quarantine"
 	"github.com/mmenanno/media-usage-finder/internal/scanner"
 )
 
@@ -17,17 +21,45 @@ type Consolidator struct {
 	db     *database.DB
 	config *config.DuplicateConsolidationConfig
 	hasher *scanner.FileHasher
+	trash  *quarantine.Manager
+
+	notifier *notify.Notifier // Receives consolidation results
+
+	// Devices found not to support reflinks, which fall back to hardlinks
+	reflinkUnsupported map[uint64]bool
 }
 
 // NewConsolidator creates a new consolidator
-func NewConsolidator(db *database.DB, cfg *config.DuplicateConsolidationConfig, hasher *scanner.FileHasher) *Consolidator {
+func NewConsolidator(db *database.DB, cfg *config.DuplicateConsolidationConfig, hasher *scanner.FileHasher, trash *quarantine.Manager) *Consolidator {
 	return &Consolidator{
 		db:     db,
 		config: cfg,
 		hasher: hasher,
+		trash:  trash,
 	}
 }
 
+// SetNotifier sets the notifier that receives consolidation results
+func (c *Consolidator) SetNotifier(n *notify.Notifier) {
+	c.notifier = n
+}
+
+// notifyResult sends the consolidation notification for a run that changed files
+func (c *Consolidator) notifyResult(operation string, result *ConsolidationResult) {
+	if result.DryRun || (result.GroupsProcessed == 0 && len(result.Errors) == 0) {
+		return
+	}
+	c.notifier.Notify(notify.ConsolidationFinished(notify.ConsolidationResult{
+		Operation:       operation,
+		GroupsProcessed: result.GroupsProcessed,
+		Files:           result.FilesDeleted + result.FilesReflinked,
+		SpaceFreed:      result.SpaceFreed,
+		SpaceHeld:       result.SpaceHeld,
+		Errors:          len(result.Errors),
+		RunID:           result.RunID,
+	}))
+}
+
 // ConsolidationResult contains the results of a consolidation operation
 type ConsolidationResult struct {
 	GroupsProcessed int
@@ -35,6 +67,28 @@ type ConsolidationResult struct {
 	SpaceFreed      int64
 	Errors          []ConsolidationError
 	DryRun          bool
+	RunID           int64 // Operation run that can be reverted, 0 when nothing changed
+	FilesReflinked  int   // Same-disk duplicates deduplicated with reflinks instead of hardlinks
+	SpaceReflinked  int64 // Bytes the kernel deduplicated for reflinked files, included in SpaceFreed
+	SpaceHeld       int64 // Bytes of replaced originals kept in quarantine, only freed once it is purged
+}
+
+// addReflinked counts a duplicate that now shares its data with the kept file
+func (r *ConsolidationResult) addReflinked(deduped int64) {
+	r.FilesReflinked++
+	r.SpaceReflinked += deduped
+	r.SpaceFreed += deduped
+}
+
+// addRemoved counts a duplicate deleted or replaced by a hardlink. When its original is held in
+// quarantine the space is not freed until the quarantine is purged
+func (r *ConsolidationResult) addRemoved(size int64, held bool) {
+	r.FilesDeleted++
+	if held {
+		r.SpaceHeld += size
+	} else {
+		r.SpaceFreed += size
+	}
 }
 
 // ConsolidationError represents an error during consolidation
@@ -50,8 +104,13 @@ func (c *Consolidator) ConsolidateCrossDisk(plans []*ConsolidationPlan, dryRun b
 		DryRun: dryRun,
 	}
 
+	runID, err := c.startRun(database.OperationConsolidate, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, plan := range plans {
-		if err := c.processGroup(plan, dryRun); err != nil {
+		if err := c.processGroup(plan, dryRun, runID, result); err != nil {
 			result.Errors = append(result.Errors, ConsolidationError{
 				GroupHash: plan.Group.FileHash,
 				FilePath:  plan.KeepFile.Path,
@@ -61,15 +120,39 @@ func (c *Consolidator) ConsolidateCrossDisk(plans []*ConsolidationPlan, dryRun b
 		}
 
 		result.GroupsProcessed++
-		result.FilesDeleted += len(plan.DeleteFiles)
-		result.SpaceFreed += plan.SpaceSavings
 	}
 
+	result.RunID = c.completeRun(runID)
+	c.notifyResult(database.OperationConsolidate, result)
 	return result, nil
 }
 
-// processGroup handles one duplicate group
-func (c *Consolidator) processGroup(plan *ConsolidationPlan, dryRun bool) error {
+// startRun creates the operation run that journals the changes, unless this is a dry run
+func (c *Consolidator) startRun(operation string, dryRun bool) (int64, error) {
+	if dryRun {
+		return 0, nil
+	}
+	return c.db.CreateOperationRun(operation)
+}
+
+// completeRun finishes an operation run and returns its ID, or 0 if it changed nothing
+func (c *Consolidator) completeRun(runID int64) int64 {
+	if runID == 0 {
+		return 0
+	}
+	kept, err := c.db.CompleteOperationRun(runID)
+	if err != nil {
+		log.Printf("WARNING: Failed to complete operation run %d: %v", runID, err)
+		return runID
+	}
+	if !kept {
+		return 0
+	}
+	return runID
+}
+
+// processGroup handles one duplicate group, counting each duplicate it removes in result
+func (c *Consolidator) processGroup(plan *ConsolidationPlan, dryRun bool, runID int64, result *ConsolidationResult) error {
 	// Step 1: Verify kept file exists and is readable
 	if err := c.verifyFileSafety(plan.KeepFile); err != nil {
 		return fmt.Errorf("keep file verification failed: %w", err)
@@ -82,10 +165,26 @@ func (c *Consolidator) processGroup(plan *ConsolidationPlan, dryRun bool) error
 		}
 	}
 
-	// Step 3: Process each file to delete
-	for _, deleteFile := range plan.DeleteFiles {
+	var keepStat syscall.Stat_t
+	if err := syscall.Stat(plan.KeepFile.Path, &keepStat); err != nil {
+		return fmt.Errorf("failed to stat keep file: %w", err)
+	}
+
+	// Step 3: In move-and-hardlink mode, keep the paths services reference as hardlinks on the kept disk
+	deleteFiles := plan.DeleteFiles
+	if c.config.CrossDiskMode == CrossDiskModeMoveAndHardlink {
+		var err error
+		deleteFiles, err = c.relinkReferencedFiles(plan, dryRun, runID, result)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Step 4: Process each file to delete
+	for _, deleteFile := range deleteFiles {
 		if dryRun {
 			log.Printf("[DRY-RUN] Would delete: %s (save %d bytes)", deleteFile.Path, deleteFile.Size)
+			result.addRemoved(deleteFile.Size, c.trash.Enabled())
 			continue
 		}
 
@@ -103,16 +202,36 @@ func (c *Consolidator) processGroup(plan *ConsolidationPlan, dryRun bool) error
 			}
 		}
 
-		// Delete the file atomically
-		if err := os.Remove(deleteFile.Path); err != nil {
-			return fmt.Errorf("failed to delete %s: %w", deleteFile.Path, err)
+		var deleteStat syscall.Stat_t
+		if err := syscall.Stat(deleteFile.Path, &deleteStat); err != nil {
+			log.Printf("WARNING: Skipping %s: %v", deleteFile.Path, err)
+			continue
 		}
 
-		// Update database - remove file from files table
+		// Delete the file, or move it to quarantine when enabled, and remove it from the files table
 		details := fmt.Sprintf("Cross-disk consolidation: kept %s on %s, deleted duplicate on %s",
 			plan.KeepFile.Path, plan.KeepFile.DiskName, deleteFile.DiskName)
-		if err := c.db.DeleteFileByPath(deleteFile.Path, details, false); err != nil {
-			log.Printf("WARNING: Failed to update database after deleting %s: %v", deleteFile.Path, err)
+		item, err := c.trash.Remove(context.Background(), deleteFile.ID, details)
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %w", deleteFile.Path, err)
+		}
+
+		entry := &database.JournalEntry{
+			RunID:            runID,
+			GroupHash:        plan.Group.FileHash,
+			KeptPath:         plan.KeepFile.Path,
+			KeptInode:        int64(keepStat.Ino),
+			KeptDeviceID:     int64(keepStat.Dev),
+			ReplacedPath:     deleteFile.Path,
+			OriginalInode:    int64(deleteStat.Ino),
+			OriginalDeviceID: int64(deleteStat.Dev),
+			Size:             deleteFile.Size,
+		}
+		if item != nil {
+			entry.QuarantineID = &item.ID
+		}
+		if err := c.db.AddJournalEntry(entry); err != nil {
+			log.Printf("WARNING: Failed to journal deletion of %s: %v", deleteFile.Path, err)
 		}
 
 		// Log consolidation to audit log
@@ -120,7 +239,12 @@ func (c *Consolidator) processGroup(plan *ConsolidationPlan, dryRun bool) error
 			log.Printf("WARNING: Failed to log consolidation for %s: %v", deleteFile.Path, err)
 		}
 
-		log.Printf("Deleted: %s (freed %d bytes)", deleteFile.Path, deleteFile.Size)
+		result.addRemoved(deleteFile.Size, item != nil)
+		if item != nil {
+			log.Printf("Deleted: %s (%d bytes held in quarantine)", deleteFile.Path, deleteFile.Size)
+		} else {
+			log.Printf("Deleted: %s (freed %d bytes)", deleteFile.Path, deleteFile.Size)
+		}
 	}
 
 	return nil
@@ -133,9 +257,14 @@ func (c *Consolidator) CreateHardlinks(plans []*ConsolidationPlan, dryRun bool)
 		DryRun: dryRun,
 	}
 
+	runID, err := c.startRun(database.OperationHardlink, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
 	for i, plan := range plans {
 		log.Printf("CreateHardlinks: Processing plan %d/%d (group: %s)", i+1, len(plans), plan.Group.FileHash[:16])
-		if err := c.processHardlinkGroup(plan, dryRun); err != nil {
+		if err := c.processHardlinkGroup(plan, dryRun, runID, result); err != nil {
 			result.Errors = append(result.Errors, ConsolidationError{
 				GroupHash: plan.Group.FileHash,
 				FilePath:  plan.KeepFile.Path,
@@ -145,15 +274,15 @@ func (c *Consolidator) CreateHardlinks(plans []*ConsolidationPlan, dryRun bool)
 		}
 
 		result.GroupsProcessed++
-		result.FilesDeleted += len(plan.DeleteFiles) // Actually hardlinked, not deleted
-		result.SpaceFreed += plan.SpaceSavings
 	}
 
+	result.RunID = c.completeRun(runID)
+	c.notifyResult(database.OperationHardlink, result)
 	return result, nil
 }
 
 // processHardlinkGroup creates hardlinks for one duplicate group
-func (c *Consolidator) processHardlinkGroup(plan *ConsolidationPlan, dryRun bool) error {
+func (c *Consolidator) processHardlinkGroup(plan *ConsolidationPlan, dryRun bool, runID int64, result *ConsolidationResult) error {
 	// Verify primary file exists
 	if err := c.verifyFileSafety(plan.KeepFile); err != nil {
 		return fmt.Errorf("primary file verification failed: %w", err)
@@ -195,9 +324,15 @@ func (c *Consolidator) processHardlinkGroup(plan *ConsolidationPlan, dryRun bool
 			continue
 		}
 
+		if dryRun && c.config.SameDiskMode == SameDiskModeReflink {
+			log.Printf("[DRY-RUN] Would reflink: %s -> %s (save up to %d bytes)", dupFile.Path, plan.KeepFile.Path, dupFile.Size)
+			result.addReflinked(dupFile.Size)
+			continue
+		}
 		if dryRun {
 			log.Printf("[DRY-RUN] Would hardlink: %s -> %s (save %d bytes, inode %d -> %d)",
 				dupFile.Path, plan.KeepFile.Path, dupFile.Size, int64(dupStat.Ino), int64(primaryInode))
+			result.addRemoved(dupFile.Size, c.trash.Enabled())
 			continue
 		}
 
@@ -209,9 +344,51 @@ func (c *Consolidator) processHardlinkGroup(plan *ConsolidationPlan, dryRun bool
 			continue
 		}
 
+		// Reflink mode shares the data with the primary while the duplicate stays a separate file
+		if c.config.SameDiskMode == SameDiskModeReflink && !c.reflinkUnsupported[uint64(dupStat.Dev)] {
+			deduped, err := reflinkDedupe(plan.KeepFile.Path, dupFile.Path)
+			switch {
+			case err == nil:
+				result.addReflinked(deduped)
+				if err := c.db.LogReflink(plan.KeepFile, dupFile, deduped, plan.ReasonToKeep); err != nil {
+					log.Printf("WARNING: Failed to log reflink for %s: %v", dupFile.Path, err)
+				}
+				log.Printf("Reflinked: %s -> %s (deduplicated %d bytes)", dupFile.Path, plan.KeepFile.Path, deduped)
+				continue
+			case errors.Is(err, errReflinkUnsupported):
+				log.Printf("INFO: Falling back to hardlinks for %s: %v", filepath.Dir(dupFile.Path), err)
+				if c.reflinkUnsupported == nil {
+					c.reflinkUnsupported = make(map[uint64]bool)
+				}
+				c.reflinkUnsupported[uint64(dupStat.Dev)] = true
+			case errors.Is(err, errContentsDiffer):
+				log.Printf("WARNING: Skipping %s: kernel reports its contents differ from %s", dupFile.Path, plan.KeepFile.Path)
+				continue
+			default:
+				log.Printf("WARNING: Failed to reflink %s: %v", dupFile.Path, err)
+				continue
+			}
+		}
+
+		// Keep the original contents in quarantine so the hardlink can be reverted
+		var quarantineID *int64
+		if c.trash.Enabled() {
+			item, err := c.trash.Hold(context.Background(), dupFile.ID, "Replaced by hardlink to "+plan.KeepFile.Path)
+			if err != nil {
+				log.Printf("WARNING: Skipping %s: %v", dupFile.Path, err)
+				continue
+			}
+			quarantineID = &item.ID
+		}
+
 		// Create hardlink atomically
 		if err := c.createHardlinkAtomic(dupFile.Path, plan.KeepFile.Path); err != nil {
 			log.Printf("WARNING: Failed to create hardlink for %s: %v", dupFile.Path, err)
+			if quarantineID != nil {
+				if err := c.trash.Purge(context.Background(), *quarantineID, "Hardlink was not created"); err != nil {
+					log.Printf("WARNING: Failed to remove kept original of %s: %v", dupFile.Path, err)
+				}
+			}
 			continue
 		}
 
@@ -219,12 +396,14 @@ func (c *Consolidator) processHardlinkGroup(plan *ConsolidationPlan, dryRun bool
 		var newStat syscall.Stat_t
 		if err := syscall.Stat(dupFile.Path, &newStat); err != nil {
 			log.Printf("WARNING: Failed to verify hardlink for %s: %v", dupFile.Path, err)
+			c.reinstateOriginal(dupFile, quarantineID)
 			continue
 		}
 
 		if newStat.Ino != primaryInode {
 			log.Printf("ERROR: Hardlink verification failed for %s (inode mismatch: expected %d, got %d)",
 				dupFile.Path, primaryInode, newStat.Ino)
+			c.reinstateOriginal(dupFile, quarantineID)
 			continue
 		}
 
@@ -239,13 +418,46 @@ func (c *Consolidator) processHardlinkGroup(plan *ConsolidationPlan, dryRun bool
 			log.Printf("WARNING: Failed to log hardlink creation for %s: %v", dupFile.Path, err)
 		}
 
-		log.Printf("Hardlinked: %s -> %s (saved %d bytes, inode %d -> %d)",
-			dupFile.Path, plan.KeepFile.Path, dupFile.Size, int64(dupStat.Ino), int64(primaryInode))
+		err := c.db.AddJournalEntry(&database.JournalEntry{
+			RunID:            runID,
+			GroupHash:        plan.Group.FileHash,
+			KeptPath:         plan.KeepFile.Path,
+			KeptInode:        int64(primaryInode),
+			KeptDeviceID:     int64(primaryStat.Dev),
+			ReplacedPath:     dupFile.Path,
+			OriginalInode:    int64(dupStat.Ino),
+			OriginalDeviceID: int64(dupStat.Dev),
+			Size:             dupFile.Size,
+			QuarantineID:     quarantineID,
+		})
+		if err != nil {
+			log.Printf("WARNING: Failed to journal hardlink of %s: %v", dupFile.Path, err)
+		}
+
+		result.addRemoved(dupFile.Size, quarantineID != nil)
+		if quarantineID != nil {
+			log.Printf("Hardlinked: %s -> %s (%d bytes held in quarantine, inode %d -> %d)",
+				dupFile.Path, plan.KeepFile.Path, dupFile.Size, int64(dupStat.Ino), int64(primaryInode))
+		} else {
+			log.Printf("Hardlinked: %s -> %s (saved %d bytes, inode %d -> %d)",
+				dupFile.Path, plan.KeepFile.Path, dupFile.Size, int64(dupStat.Ino), int64(primaryInode))
+		}
 	}
 
 	return nil
 }
 
+// reinstateOriginal puts the original held in quarantine back over a hardlink that failed verification
+// Without quarantine the original is already gone and the hardlink stays
+func (c *Consolidator) reinstateOriginal(dupFile *database.DuplicateFile, quarantineID *int64) {
+	if quarantineID == nil {
+		return
+	}
+	if err := c.trash.Reinstate(context.Background(), *quarantineID, "Hardlink failed verification"); err != nil {
+		log.Printf("ERROR: Failed to put the original of %s back from quarantine: %v", dupFile.Path, err)
+	}
+}
+
 // createHardlinkAtomic creates a hardlink atomically using temp file + rename
 func (c *Consolidator) createHardlinkAtomic(oldPath, newPath string) error {
 	// Create temp file in same directory as target
@@ -0,0 +1,162 @@
+package duplicates
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+	"github.com/mmenanno/media-usage-finder/internal/quarantine"
+)
+
+func TestHardlinkDryRunCountsEachFileOnce(t *testing.T) {
+	dir := t.TempDir()
+	var files []*database.DuplicateFile
+	for _, name := range []string{"keep.mkv", "dup1.mkv", "dup2.mkv"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, &database.DuplicateFile{Path: path, Size: 100})
+	}
+	plan := &ConsolidationPlan{
+		Group:        &database.DuplicateGroup{FileHash: "abc123"},
+		KeepFile:     files[0],
+		DeleteFiles:  files[1:],
+		SpaceSavings: 200,
+	}
+
+	for _, mode := range []string{"hardlink", SameDiskModeReflink} {
+		t.Run(mode, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.DuplicateConsolidation.SameDiskMode = mode
+			cfg.Quarantine.Enabled = false
+			c := NewConsolidator(nil, &cfg.DuplicateConsolidation, nil, quarantine.New(nil, cfg))
+
+			result := &ConsolidationResult{DryRun: true}
+			if err := c.processHardlinkGroup(plan, true, 0, result); err != nil {
+				t.Fatalf("processHardlinkGroup: %v", err)
+			}
+			if result.SpaceFreed != 200 {
+				t.Errorf("SpaceFreed = %d, want 200", result.SpaceFreed)
+			}
+			if linked := result.FilesDeleted + result.FilesReflinked; linked != 2 {
+				t.Errorf("files linked or reflinked = %d, want 2", linked)
+			}
+		})
+	}
+}
+
+func TestCrossDiskCountsEachFileRemoved(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	scan, err := db.CreateScan("full")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	var files []*database.DuplicateFile
+	for _, name := range []string{"keep.mkv", "dup.mkv", "gone.mkv"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+		file := &database.File{Path: path, Size: 100, ScanID: scan.ID, ModifiedTime: time.Now(), LastVerified: time.Now()}
+		if err := db.UpsertFile(file); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, &database.DuplicateFile{ID: file.ID, Path: path, Size: 100})
+	}
+	plan := &ConsolidationPlan{
+		Group:        &database.DuplicateGroup{FileHash: "abc123"},
+		KeepFile:     files[0],
+		DeleteFiles:  files[1:],
+		SpaceSavings: 200,
+	}
+
+	cfg := config.Default()
+	cfg.DuplicateConsolidation.VerifyBeforeDelete = false
+
+	// A dry run with quarantine enabled holds the space instead of freeing it
+	c := NewConsolidator(db, &cfg.DuplicateConsolidation, nil, quarantine.New(db, cfg))
+	result, err := c.ConsolidateCrossDisk([]*ConsolidationPlan{plan}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FilesDeleted != 2 || result.SpaceFreed != 0 || result.SpaceHeld != 200 {
+		t.Errorf("dry run deleted %d files, freed %d, held %d, want 2 files and 200 bytes held",
+			result.FilesDeleted, result.SpaceFreed, result.SpaceHeld)
+	}
+
+	// A duplicate that fails verification is skipped and not counted
+	if err := os.Remove(files[2].Path); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Quarantine.Enabled = false
+	c = NewConsolidator(db, &cfg.DuplicateConsolidation, nil, quarantine.New(db, cfg))
+	result, err = c.ConsolidateCrossDisk([]*ConsolidationPlan{plan}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.GroupsProcessed != 1 || result.FilesDeleted != 1 || result.SpaceFreed != 100 || result.SpaceHeld != 0 {
+		t.Errorf("processed %d groups, deleted %d files, freed %d, held %d, want 1 group and 1 file of 100 bytes freed",
+			result.GroupsProcessed, result.FilesDeleted, result.SpaceFreed, result.SpaceHeld)
+	}
+	if _, err := os.Stat(files[1].Path); !os.IsNotExist(err) {
+		t.Errorf("dup.mkv still exists: %v", err)
+	}
+}
+
+func TestReinstateOriginalAfterFailedHardlink(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	scan, err := db.CreateScan("full")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "dup.mkv")
+	if err := os.WriteFile(path, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := &database.File{Path: path, Size: 8, ScanID: scan.ID, ModifiedTime: time.Now(), LastVerified: time.Now()}
+	if err := db.UpsertFile(file); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.Quarantine.Enabled = true
+	trash := quarantine.New(db, cfg)
+	c := NewConsolidator(db, &cfg.DuplicateConsolidation, nil, trash)
+
+	item, err := trash.Hold(context.Background(), file.ID, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The path now holds something other than the kept file, as after a failed verification
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("wrong link"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c.reinstateOriginal(&database.DuplicateFile{ID: file.ID, Path: path}, &item.ID)
+
+	if contents, err := os.ReadFile(path); err != nil || string(contents) != "original" {
+		t.Errorf("%s holds %q (%v), want the original contents back", path, contents, err)
+	}
+	if _, err := db.GetQuarantineItem(item.ID); err == nil {
+		t.Error("the held original is still recorded in quarantine")
+	}
+}
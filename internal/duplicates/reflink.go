@@ -0,0 +1,17 @@
+package duplicates
+
+import "errors"
+
+// SameDiskModeReflink deduplicates same-disk duplicates by sharing their data instead of hardlinking them
+const SameDiskModeReflink = "reflink"
+
+// reflinkChunkSize is how much data one dedupe request covers; filesystems cap the length of a single request
+const reflinkChunkSize = 16 * 1024 * 1024
+
+var (
+	// errReflinkUnsupported means the filesystem cannot share data between files
+	errReflinkUnsupported = errors.New("filesystem does not support reflinks")
+
+	// errContentsDiffer means the kernel found the files are not byte-for-byte identical
+	errContentsDiffer = errors.New("contents differ")
+)
@@ -0,0 +1,80 @@
+//go:build linux
+
+package duplicates
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflinkDedupe makes dest share the data of src with the FIDEDUPERANGE ioctl and returns the bytes deduplicated
+// The kernel compares the ranges itself and only shares them when they are identical, so both files
+// keep their own inode, permissions and timestamps
+func reflinkDedupe(srcPath, destPath string) (int64, error) {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	dest, err := os.OpenFile(destPath, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer dest.Close()
+
+	srcInfo, err := src.Stat()
+	if err != nil {
+		return 0, err
+	}
+	destInfo, err := dest.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if srcInfo.Size() != destInfo.Size() {
+		return 0, errContentsDiffer
+	}
+
+	var deduped int64
+	for offset := int64(0); offset < srcInfo.Size(); {
+		length := min(int64(reflinkChunkSize), srcInfo.Size()-offset)
+		arg := &unix.FileDedupeRange{
+			Src_offset: uint64(offset),
+			Src_length: uint64(length),
+			Info:       []unix.FileDedupeRangeInfo{{Dest_fd: int64(dest.Fd()), Dest_offset: uint64(offset)}},
+		}
+
+		if err := unix.IoctlFileDedupeRange(int(src.Fd()), arg); err != nil {
+			return deduped, dedupeError(err)
+		}
+
+		info := arg.Info[0]
+		switch {
+		case info.Status == unix.FILE_DEDUPE_RANGE_DIFFERS:
+			return deduped, errContentsDiffer
+		case info.Status < 0:
+			return deduped, dedupeError(syscall.Errno(-info.Status))
+		case info.Bytes_deduped == 0:
+			return deduped, fmt.Errorf("kernel deduplicated nothing at offset %d", offset)
+		}
+
+		deduped += int64(info.Bytes_deduped)
+		offset += int64(info.Bytes_deduped)
+	}
+
+	return deduped, nil
+}
+
+// dedupeError reports the errors filesystems without reflink support return as errReflinkUnsupported
+// Only EOPNOTSUPP (no FIDEDUPERANGE support) and EXDEV (files on different filesystems) say so; other
+// errors such as EINVAL come from the files themselves and fail that file only
+func dedupeError(err error) error {
+	if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EXDEV) {
+		return fmt.Errorf("%w: %v", errReflinkUnsupported, err)
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+//go:build linux
+
+package duplicates
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestDedupeError(t *testing.T) {
+	tests := []struct {
+		err         error
+		unsupported bool
+	}{
+		{unix.EOPNOTSUPP, true},
+		{unix.EXDEV, true},
+		{fmt.Errorf("ioctl: %w", unix.EOPNOTSUPP), true},
+		{unix.EINVAL, false},
+		{unix.ENOTTY, false},
+		{unix.EIO, false},
+		{unix.EPERM, false},
+	}
+	for _, tt := range tests {
+		if got := errors.Is(dedupeError(tt.err), errReflinkUnsupported); got != tt.unsupported {
+			t.Errorf("dedupeError(%v) unsupported = %v, want %v", tt.err, got, tt.unsupported)
+		}
+	}
+}
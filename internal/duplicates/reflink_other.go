@@ -0,0 +1,8 @@
+//go:build !linux
+
+package duplicates
+
+// reflinkDedupe is only implemented with FIDEDUPERANGE on Linux
+func reflinkDedupe(srcPath, destPath string) (int64, error) {
+	return 0, errReflinkUnsupported
+}
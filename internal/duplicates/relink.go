@@ -0,0 +1,371 @@
+package duplicates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// CrossDiskModeMoveAndHardlink keeps every referenced path of a cross-disk duplicate as a hardlink on the kept disk
+const CrossDiskModeMoveAndHardlink = "move_and_hardlink"
+
+// relinkedFile is a referenced duplicate linked to the kept file on the kept disk
+type relinkedFile struct {
+	file     *database.DuplicateFile
+	linkPath string                       // Path of the duplicate on the kept disk
+	created  bool                         // Whether the link was created by this run
+	copies   []*database.FileDiskLocation // Copies on other disks, removed once every path resolves without them
+}
+
+// relinkReferencedFiles consolidates the duplicates that services reference without breaking their paths
+// Each referenced path is hardlinked to the kept file on the kept disk and the copies on the other disks
+// are moved aside. Only once every referenced path resolves through the user share without them are they
+// removed; otherwise the whole group is rolled back. Each relinked duplicate is counted in result by the
+// copies removed from other disks. Returns the duplicates nothing references, which are deleted as usual
+func (c *Consolidator) relinkReferencedFiles(plan *ConsolidationPlan, dryRun bool, runID int64, result *ConsolidationResult) ([]*database.DuplicateFile, error) {
+	var referenced, remaining []*database.DuplicateFile
+	for _, file := range plan.DeleteFiles {
+		if len(file.ServiceUsage) > 0 && file.Path != plan.KeepFile.Path {
+			referenced = append(referenced, file)
+		} else {
+			remaining = append(remaining, file)
+		}
+	}
+	if len(referenced) == 0 {
+		return remaining, nil
+	}
+
+	keepLoc, mount, err := c.keptDiskLocation(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		for _, file := range referenced {
+			log.Printf("[DRY-RUN] Would hardlink %s to %s on %s and remove its copies on other disks",
+				filepath.Join(mount, file.Path), keepLoc.DiskPath, keepLoc.DiskName)
+			result.addRemoved(file.Size, c.trash.Enabled())
+		}
+		return remaining, nil
+	}
+
+	var keepStat syscall.Stat_t
+	if err := syscall.Stat(keepLoc.DiskPath, &keepStat); err != nil {
+		return nil, fmt.Errorf("failed to stat kept copy %s: %w", keepLoc.DiskPath, err)
+	}
+
+	// Step 1: Link each referenced path to the kept file on the kept disk
+	var linked []*relinkedFile
+	for _, file := range referenced {
+		if err := c.verifyFileSafety(file); err != nil {
+			log.Printf("WARNING: Skipping %s: %v", file.Path, err)
+			continue
+		}
+		if c.config.VerifyBeforeDelete {
+			if err := c.verifyFileHash(file.Path, plan.Group.FileHash); err != nil {
+				log.Printf("WARNING: Hash mismatch for %s, skipping: %v", file.Path, err)
+				continue
+			}
+		}
+
+		rf, err := c.linkOnKeptDisk(file, mount, keepLoc, &keepStat)
+		if err != nil {
+			log.Printf("WARNING: Skipping %s: %v", file.Path, err)
+			continue
+		}
+		linked = append(linked, rf)
+	}
+
+	// Step 2: Move the copies on the other disks aside, so the user share can only resolve to the kept disk
+	var aside []*asideCopy
+	for _, rf := range linked {
+		for _, diskCopy := range rf.copies {
+			ac, err := c.setAsideCopy(plan, rf, diskCopy, keepLoc)
+			if err != nil {
+				c.rollbackRelink(linked, aside)
+				return nil, fmt.Errorf("no copies were removed: %w", err)
+			}
+			if ac != nil {
+				aside = append(aside, ac)
+			}
+		}
+	}
+
+	// Step 3: Every referenced path must still resolve through the user share
+	paths := []string{plan.KeepFile.Path}
+	for _, rf := range linked {
+		paths = append(paths, rf.file.Path)
+	}
+	for _, path := range paths {
+		if err := resolvesThroughShare(path, plan.KeepFile.Size); err != nil {
+			c.rollbackRelink(linked, aside)
+			return nil, fmt.Errorf("%s does not resolve through the user share without its other copies, no copies were removed: %w", path, err)
+		}
+	}
+
+	// Step 4: Remove the copies set aside. Copies in quarantine stay there until it is purged
+	for _, ac := range aside {
+		if ac.tempPath == "" {
+			continue
+		}
+		if err := os.Remove(ac.tempPath); err != nil {
+			c.rollbackRelink(linked, aside)
+			return nil, fmt.Errorf("failed to remove copy %s, no copies were removed: %w", ac.loc.DiskPath, err)
+		}
+		ac.tempPath = ""
+	}
+
+	for _, rf := range linked {
+		c.recordRelink(plan, rf, aside, keepLoc, &keepStat, runID, result)
+	}
+
+	return remaining, nil
+}
+
+// asideCopy is a copy on another disk moved out of the user share while a group is relinked
+type asideCopy struct {
+	rf           *relinkedFile
+	loc          *database.FileDiskLocation
+	stat         syscall.Stat_t
+	quarantineID *int64 // Set when the copy was moved to quarantine
+	tempPath     string // Set when the copy was renamed next to itself, until it is removed
+}
+
+// setAsideCopy moves a copy out of the user share, into quarantine when enabled so the run can be
+// reverted. Returns nil if the copy is already gone
+func (c *Consolidator) setAsideCopy(plan *ConsolidationPlan, rf *relinkedFile, diskCopy *database.FileDiskLocation, keepLoc *database.FileDiskLocation) (*asideCopy, error) {
+	ac := &asideCopy{rf: rf, loc: diskCopy}
+	if err := syscall.Stat(diskCopy.DiskPath, &ac.stat); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to stat copy %s: %w", diskCopy.DiskPath, err)
+	}
+
+	if c.trash.Enabled() {
+		reason := fmt.Sprintf("Cross-disk consolidation: %s hardlinked to %s on %s", rf.file.Path, plan.KeepFile.Path, keepLoc.DiskName)
+		item, err := c.trash.SetAside(context.Background(), rf.file.ID, diskCopy, reason)
+		if err != nil {
+			return nil, err
+		}
+		ac.quarantineID = &item.ID
+		return ac, nil
+	}
+
+	ac.tempPath = filepath.Join(filepath.Dir(diskCopy.DiskPath), ".consolidate-"+filepath.Base(diskCopy.DiskPath))
+	if err := os.Rename(diskCopy.DiskPath, ac.tempPath); err != nil {
+		return nil, fmt.Errorf("failed to move copy %s aside: %w", diskCopy.DiskPath, err)
+	}
+	return ac, nil
+}
+
+// rollbackRelink puts the copies set aside back and removes the links created for a group that
+// could not be consolidated
+func (c *Consolidator) rollbackRelink(linked []*relinkedFile, aside []*asideCopy) {
+	for _, ac := range aside {
+		switch {
+		case ac.quarantineID != nil:
+			if err := c.trash.Reinstate(context.Background(), *ac.quarantineID, "Cross-disk consolidation rolled back"); err != nil {
+				log.Printf("ERROR: Failed to move copy %s back from quarantine: %v", ac.loc.DiskPath, err)
+			}
+		case ac.tempPath != "":
+			if err := os.Rename(ac.tempPath, ac.loc.DiskPath); err != nil {
+				log.Printf("ERROR: Failed to move copy %s back from %s: %v", ac.loc.DiskPath, ac.tempPath, err)
+			}
+		}
+	}
+	unlinkCreated(linked)
+}
+
+// keptDiskLocation returns the kept file's location on the kept disk and that disk's mount path
+// The mount path is what the disk path has in front of the file's user share path
+func (c *Consolidator) keptDiskLocation(plan *ConsolidationPlan) (*database.FileDiskLocation, string, error) {
+	locations, err := c.db.GetDiskLocationsForFile(plan.KeepFile.ID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get disk locations of %s: %w", plan.KeepFile.Path, err)
+	}
+	if len(locations) == 0 {
+		return nil, "", fmt.Errorf("%s has no disk location, run a disk location scan first", plan.KeepFile.Path)
+	}
+
+	keepLoc := locations[0]
+	for _, loc := range locations {
+		if plan.KeepDisk != nil && loc.DiskDeviceID == plan.KeepDisk.DeviceID {
+			keepLoc = loc
+			break
+		}
+	}
+
+	mount := strings.TrimSuffix(keepLoc.DiskPath, plan.KeepFile.Path)
+	if mount == "" || mount == keepLoc.DiskPath {
+		return nil, "", fmt.Errorf("cannot tell the disk mount of %s from %s", plan.KeepFile.Path, keepLoc.DiskPath)
+	}
+	return keepLoc, mount, nil
+}
+
+// linkOnKeptDisk creates the duplicate's path on the kept disk as a hardlink to the kept file
+func (c *Consolidator) linkOnKeptDisk(file *database.DuplicateFile, mount string, keepLoc *database.FileDiskLocation, keepStat *syscall.Stat_t) (*relinkedFile, error) {
+	locations, err := c.db.GetDiskLocationsForFile(file.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk locations: %w", err)
+	}
+
+	rf := &relinkedFile{file: file, linkPath: filepath.Join(mount, file.Path)}
+	for _, loc := range locations {
+		if loc.DiskDeviceID != keepLoc.DiskDeviceID {
+			rf.copies = append(rf.copies, loc)
+		}
+	}
+	if len(rf.copies) == 0 {
+		return nil, fmt.Errorf("no copy on another disk is recorded, run a disk location scan first")
+	}
+
+	var st syscall.Stat_t
+	err = syscall.Stat(rf.linkPath, &st)
+	switch {
+	case err == nil:
+		if st.Ino != keepStat.Ino || st.Dev != keepStat.Dev {
+			return nil, fmt.Errorf("a different file already exists at %s", rf.linkPath)
+		}
+		return rf, nil // Already linked
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("failed to stat %s: %w", rf.linkPath, err)
+	}
+
+	// Match the permissions of the directory the copy is in now
+	dirMode := os.FileMode(0755)
+	if info, err := os.Stat(filepath.Dir(rf.copies[0].DiskPath)); err == nil {
+		dirMode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(rf.linkPath), dirMode); err != nil {
+		return nil, fmt.Errorf("failed to create directory on %s: %w", keepLoc.DiskName, err)
+	}
+	if err := os.Link(keepLoc.DiskPath, rf.linkPath); err != nil {
+		return nil, fmt.Errorf("failed to hardlink %s to %s: %w", rf.linkPath, keepLoc.DiskPath, err)
+	}
+	rf.created = true
+
+	// The link must be the kept file itself, on the kept disk
+	if err := syscall.Stat(rf.linkPath, &st); err != nil || st.Ino != keepStat.Ino || st.Dev != keepStat.Dev {
+		unlinkCreated([]*relinkedFile{rf})
+		return nil, fmt.Errorf("hardlink %s is not the kept file on %s", rf.linkPath, keepLoc.DiskName)
+	}
+	return rf, nil
+}
+
+// recordRelink journals the copies of a relinked duplicate that were removed, records its new location
+// and counts it in result
+func (c *Consolidator) recordRelink(plan *ConsolidationPlan, rf *relinkedFile, aside []*asideCopy, keepLoc *database.FileDiskLocation, keepStat *syscall.Stat_t, runID int64, result *ConsolidationResult) {
+	var removed []string
+	held := false
+	linkPath := ""
+	if rf.created {
+		linkPath = rf.linkPath
+	}
+
+	for _, ac := range aside {
+		if ac.rf != rf {
+			continue
+		}
+
+		if err := c.db.DeleteFileDiskLocation(rf.file.ID, ac.loc.DiskDeviceID); err != nil {
+			log.Printf("WARNING: Failed to remove disk location %s: %v", ac.loc.DiskPath, err)
+		}
+
+		err := c.db.AddJournalEntry(&database.JournalEntry{
+			RunID:            runID,
+			GroupHash:        plan.Group.FileHash,
+			KeptPath:         plan.KeepFile.Path,
+			KeptInode:        int64(keepStat.Ino),
+			KeptDeviceID:     int64(keepStat.Dev),
+			ReplacedPath:     rf.file.Path,
+			OriginalInode:    int64(ac.stat.Ino),
+			OriginalDeviceID: int64(ac.stat.Dev),
+			Size:             rf.file.Size,
+			QuarantineID:     ac.quarantineID,
+			LinkedPath:       linkPath,
+			RemovedPath:      ac.loc.DiskPath,
+		})
+		if err != nil {
+			log.Printf("WARNING: Failed to journal removal of %s: %v", ac.loc.DiskPath, err)
+		}
+
+		// Only the first entry owns the link, so reverting removes it after the last copy is back
+		linkPath = ""
+		removed = append(removed, ac.loc.DiskPath)
+		held = held || ac.quarantineID != nil
+	}
+
+	err := c.db.UpsertFileDiskLocation(&database.FileDiskLocation{
+		FileID:       rf.file.ID,
+		DiskName:     keepLoc.DiskName,
+		DiskDeviceID: keepLoc.DiskDeviceID,
+		DiskPath:     rf.linkPath,
+		Size:         keepLoc.Size,
+		Inode:        int64(keepStat.Ino),
+		ModifiedTime: keepLoc.ModifiedTime,
+		LastVerified: time.Now(),
+	})
+	if err != nil {
+		log.Printf("WARNING: Failed to record disk location %s: %v", rf.linkPath, err)
+	}
+
+	// The user share reports its own inode for the file, so record what it shows now
+	var shareStat syscall.Stat_t
+	if err := syscall.Stat(rf.file.Path, &shareStat); err == nil {
+		if err := c.db.UpdateFileInode(rf.file.Path, uint64(shareStat.Dev), uint64(shareStat.Ino)); err != nil {
+			log.Printf("WARNING: Failed to update database inode for %s: %v", rf.file.Path, err)
+		}
+	}
+
+	if len(removed) == 0 {
+		return
+	}
+
+	if err := c.db.LogRelink(plan.KeepFile, rf.file, rf.linkPath, keepLoc.DiskName, removed, plan.ReasonToKeep); err != nil {
+		log.Printf("WARNING: Failed to log consolidation for %s: %v", rf.file.Path, err)
+	}
+
+	size := rf.file.Size * int64(len(removed))
+	result.addRemoved(size, held)
+	if held {
+		log.Printf("Relinked: %s -> %s on %s (%d bytes held in quarantine)", rf.linkPath, keepLoc.DiskPath, keepLoc.DiskName, size)
+	} else {
+		log.Printf("Relinked: %s -> %s on %s (freed %d bytes)", rf.linkPath, keepLoc.DiskPath, keepLoc.DiskName, size)
+	}
+}
+
+// unlinkCreated removes the links created for a group that could not be consolidated
+func unlinkCreated(linked []*relinkedFile) {
+	for _, rf := range linked {
+		if !rf.created {
+			continue
+		}
+		if err := os.Remove(rf.linkPath); err != nil {
+			log.Printf("WARNING: Failed to remove hardlink %s: %v", rf.linkPath, err)
+		}
+	}
+}
+
+// resolvesThroughShare checks that a path opens through the user share as a regular file of the expected size
+func resolvesThroughShare(path string, size int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file")
+	}
+	if info.Size() != size {
+		return fmt.Errorf("size is %d bytes, expected %d", info.Size(), size)
+	}
+	return nil
+}
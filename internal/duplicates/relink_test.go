@@ -0,0 +1,176 @@
+package duplicates
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+	"github.com/mmenanno/media-usage-finder/internal/quarantine"
+)
+
+// relinkFixture is a kept file on disk A and a referenced duplicate with its copy on disk B
+// Disks are plain directories: a file's path on a disk is the disk directory followed by its share path
+type relinkFixture struct {
+	consolidator *Consolidator
+	db           *database.DB
+	plan         *ConsolidationPlan
+	runID        int64
+	diskA, diskB string
+	dup          *database.DuplicateFile
+}
+
+func newRelinkFixture(t *testing.T) *relinkFixture {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	f := &relinkFixture{db: db, diskA: t.TempDir(), diskB: t.TempDir()}
+	share := t.TempDir()
+	contents := []byte("same contents")
+
+	scan, err := db.CreateScan("full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addFile := func(path, diskName string, deviceID int64, diskRoot string) *database.DuplicateFile {
+		t.Helper()
+		diskPath := filepath.Join(diskRoot, path)
+		if err := os.MkdirAll(filepath.Dir(diskPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(diskPath, contents, 0644); err != nil {
+			t.Fatal(err)
+		}
+		file := &database.File{Path: path, Size: int64(len(contents)), ScanID: scan.ID, ModifiedTime: time.Now(), LastVerified: time.Now()}
+		if err := db.UpsertFile(file); err != nil {
+			t.Fatal(err)
+		}
+		err := db.UpsertFileDiskLocation(&database.FileDiskLocation{
+			FileID: file.ID, DiskName: diskName, DiskDeviceID: deviceID, DiskPath: diskPath,
+			Size: file.Size, ModifiedTime: time.Now(), LastVerified: time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &database.DuplicateFile{ID: file.ID, Path: path, Size: file.Size, ServiceUsage: []string{"sonarr"}}
+	}
+
+	keep := addFile(filepath.Join(share, "movies", "keep.mkv"), "disk1", 1, f.diskA)
+	f.dup = addFile(filepath.Join(share, "tv", "dup.mkv"), "disk2", 2, f.diskB)
+
+	// The share path of the kept file resolves to disk A
+	if err := os.MkdirAll(filepath.Dir(keep.Path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(f.diskA, keep.Path), keep.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	f.plan = &ConsolidationPlan{
+		Group:       &database.DuplicateGroup{FileHash: "abc123"},
+		KeepFile:    keep,
+		DeleteFiles: []*database.DuplicateFile{f.dup},
+	}
+	cfg := config.Default()
+	cfg.DuplicateConsolidation.CrossDiskMode = CrossDiskModeMoveAndHardlink
+	cfg.DuplicateConsolidation.VerifyBeforeDelete = false
+	f.consolidator = NewConsolidator(db, &cfg.DuplicateConsolidation, nil, quarantine.New(db, cfg))
+
+	f.runID, err = db.CreateOperationRun(database.OperationConsolidate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestRelinkReferencedFiles(t *testing.T) {
+	f := newRelinkFixture(t)
+
+	// The share path of the duplicate is a file of its own, so it resolves without the disk B copy
+	if err := os.MkdirAll(filepath.Dir(f.dup.Path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(f.dup.Path, []byte("same contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := &ConsolidationResult{}
+	remaining, err := f.consolidator.relinkReferencedFiles(f.plan, false, f.runID, result)
+	if err != nil {
+		t.Fatalf("relinkReferencedFiles: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("remaining = %d files, want 0", len(remaining))
+	}
+	// The copy on disk B went to quarantine, so its space is held rather than freed
+	if result.FilesDeleted != 1 || result.SpaceHeld != f.dup.Size || result.SpaceFreed != 0 {
+		t.Errorf("deleted %d files, held %d, freed %d, want 1 file of %d bytes held",
+			result.FilesDeleted, result.SpaceHeld, result.SpaceFreed, f.dup.Size)
+	}
+
+	if _, err := os.Stat(filepath.Join(f.diskB, f.dup.Path)); !os.IsNotExist(err) {
+		t.Errorf("copy on disk B still exists: %v", err)
+	}
+
+	var keepStat, linkStat syscall.Stat_t
+	if err := syscall.Stat(filepath.Join(f.diskA, f.plan.KeepFile.Path), &keepStat); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Stat(filepath.Join(f.diskA, f.dup.Path), &linkStat); err != nil {
+		t.Fatalf("no link on disk A: %v", err)
+	}
+	if linkStat.Ino != keepStat.Ino {
+		t.Errorf("link on disk A has inode %d, want the kept file's %d", linkStat.Ino, keepStat.Ino)
+	}
+
+	locations, err := f.db.GetDiskLocationsForFile(f.dup.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 1 || locations[0].DiskDeviceID != 1 {
+		t.Errorf("duplicate locations = %+v, want only disk A", locations)
+	}
+}
+
+func TestRelinkReferencedFilesRollsBack(t *testing.T) {
+	f := newRelinkFixture(t)
+
+	// The share path of the duplicate only resolves through the disk B copy
+	if err := os.MkdirAll(filepath.Dir(f.dup.Path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(f.diskB, f.dup.Path), f.dup.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	result := &ConsolidationResult{}
+	if _, err := f.consolidator.relinkReferencedFiles(f.plan, false, f.runID, result); err == nil {
+		t.Fatal("relinkReferencedFiles succeeded although the share path no longer resolves")
+	}
+	if result.FilesDeleted != 0 || result.SpaceHeld != 0 || result.SpaceFreed != 0 {
+		t.Errorf("rolled back group counted %d files, %d bytes held and %d freed, want none",
+			result.FilesDeleted, result.SpaceHeld, result.SpaceFreed)
+	}
+
+	if _, err := os.Stat(filepath.Join(f.diskB, f.dup.Path)); err != nil {
+		t.Errorf("copy on disk B was not put back: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(f.diskA, f.dup.Path)); !os.IsNotExist(err) {
+		t.Errorf("link on disk A was not removed: %v", err)
+	}
+
+	locations, err := f.db.GetDiskLocationsForFile(f.dup.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 1 || locations[0].DiskDeviceID != 2 {
+		t.Errorf("duplicate locations = %+v, want only disk B", locations)
+	}
+}
@@ -0,0 +1,255 @@
+package duplicates
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// RevertResult contains the results of reverting an operation run
+type RevertResult struct {
+	RunID    int64
+	Status   string
+	Reverted int
+	Failed   []RevertFailure
+}
+
+// RevertFailure describes a file that could not be put back
+type RevertFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// RevertRun puts back the files changed by a consolidation or hardlink run where possible
+// Deleted duplicates are restored from quarantine. Hardlinked files get their original contents back
+// from quarantine, or become a separate copy again when no original was kept. Files that changed
+// since the run are left alone and reported. Entries that already reverted are skipped, so a
+// partially reverted run can be retried
+func (c *Consolidator) RevertRun(ctx context.Context, runID int64) (*RevertResult, error) {
+	run, err := c.db.GetOperationRun(runID)
+	if err != nil {
+		return nil, fmt.Errorf("operation run not found: %w", err)
+	}
+	if run.Status == database.RunStatusReverted {
+		return nil, fmt.Errorf("operation run %d was already reverted", runID)
+	}
+
+	entries, err := c.db.GetJournalEntries(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RevertResult{RunID: runID}
+
+	// Newest first, so a file changed twice in one run ends in its original state
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Status == database.JournalStatusReverted {
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var revertErr error
+		switch run.Operation {
+		case database.OperationConsolidate:
+			if entry.RemovedPath != "" {
+				revertErr = c.revertRelink(ctx, entry)
+			} else {
+				revertErr = c.revertDeletion(ctx, entry)
+			}
+		case database.OperationHardlink:
+			revertErr = c.revertHardlink(ctx, entry)
+		default:
+			revertErr = fmt.Errorf("unknown operation %q", run.Operation)
+		}
+
+		if revertErr != nil {
+			log.Printf("WARNING: Failed to revert %s: %v", entry.ReplacedPath, revertErr)
+			result.Failed = append(result.Failed, RevertFailure{Path: entry.ReplacedPath, Error: revertErr.Error()})
+			if err := c.db.SetJournalEntryStatus(entry.ID, database.JournalStatusRevertFailed, revertErr.Error()); err != nil {
+				log.Printf("WARNING: Failed to update journal entry %d: %v", entry.ID, err)
+			}
+			continue
+		}
+
+		result.Reverted++
+		if err := c.db.SetJournalEntryStatus(entry.ID, database.JournalStatusReverted, ""); err != nil {
+			log.Printf("WARNING: Failed to update journal entry %d: %v", entry.ID, err)
+		}
+	}
+
+	result.Status, err = c.db.FinishOperationRunRevert(runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update operation run: %w", err)
+	}
+
+	log.Printf("Reverted operation run %d: %d files restored, %d could not be reverted", runID, result.Reverted, len(result.Failed))
+	return result, nil
+}
+
+// revertDeletion restores a duplicate deleted by cross-disk consolidation from quarantine
+func (c *Consolidator) revertDeletion(ctx context.Context, entry *database.JournalEntry) error {
+	if entry.QuarantineID == nil {
+		return fmt.Errorf("file was deleted without quarantine")
+	}
+	if _, err := c.db.GetQuarantineItem(*entry.QuarantineID); errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("quarantined copy was already purged or restored")
+	}
+
+	_, err := c.trash.Restore(ctx, *entry.QuarantineID)
+	return err
+}
+
+// revertRelink moves a disk copy removed by move-and-hardlink consolidation back from quarantine
+// The hardlink created on the kept disk is removed afterwards, so the path resolves the whole time
+func (c *Consolidator) revertRelink(ctx context.Context, entry *database.JournalEntry) error {
+	if entry.QuarantineID == nil {
+		return fmt.Errorf("copy %s was deleted without quarantine", entry.RemovedPath)
+	}
+	if _, err := c.db.GetQuarantineItem(*entry.QuarantineID); errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("quarantined copy was already purged or restored")
+	}
+	if _, err := os.Lstat(entry.RemovedPath); err == nil {
+		return fmt.Errorf("a file already exists at %s", entry.RemovedPath)
+	}
+
+	if entry.LinkedPath != "" {
+		var st syscall.Stat_t
+		if err := syscall.Stat(entry.LinkedPath, &st); err == nil &&
+			(int64(st.Ino) != entry.KeptInode || int64(st.Dev) != entry.KeptDeviceID) {
+			return fmt.Errorf("%s is no longer linked to %s", entry.LinkedPath, entry.KeptPath)
+		}
+	}
+
+	if err := c.trash.Reinstate(ctx, *entry.QuarantineID, "Reverted move-and-hardlink consolidation of "+entry.ReplacedPath); err != nil {
+		return err
+	}
+
+	var linkErr error
+	if entry.LinkedPath != "" {
+		if err := os.Remove(entry.LinkedPath); err != nil && !os.IsNotExist(err) {
+			linkErr = fmt.Errorf("restored %s but failed to remove hardlink %s: %w", entry.RemovedPath, entry.LinkedPath, err)
+		}
+	}
+
+	file, err := c.db.GetFileByPath(entry.ReplacedPath)
+	if err != nil {
+		log.Printf("WARNING: Failed to look up %s to update its disk locations: %v", entry.ReplacedPath, err)
+		return linkErr
+	}
+
+	if entry.LinkedPath != "" && linkErr == nil {
+		if err := c.db.DeleteFileDiskLocation(file.ID, entry.KeptDeviceID); err != nil {
+			log.Printf("WARNING: Failed to remove disk location %s: %v", entry.LinkedPath, err)
+		}
+	}
+
+	diskName, err := c.db.GetDiskNameByDeviceID(entry.OriginalDeviceID)
+	if err != nil || diskName == "" {
+		diskName = fmt.Sprintf("Device %d", entry.OriginalDeviceID)
+	}
+	err = c.db.UpsertFileDiskLocation(&database.FileDiskLocation{
+		FileID:       file.ID,
+		DiskName:     diskName,
+		DiskDeviceID: entry.OriginalDeviceID,
+		DiskPath:     entry.RemovedPath,
+		Size:         entry.Size,
+		Inode:        entry.OriginalInode,
+		ModifiedTime: file.ModifiedTime,
+		LastVerified: time.Now(),
+	})
+	if err != nil {
+		log.Printf("WARNING: Failed to record disk location %s: %v", entry.RemovedPath, err)
+	}
+
+	return linkErr
+}
+
+// revertHardlink turns a hardlinked duplicate back into a separate file
+func (c *Consolidator) revertHardlink(ctx context.Context, entry *database.JournalEntry) error {
+	var st syscall.Stat_t
+	if err := syscall.Stat(entry.ReplacedPath, &st); err != nil {
+		return fmt.Errorf("file no longer exists: %w", err)
+	}
+	if int64(st.Ino) != entry.KeptInode || int64(st.Dev) != entry.KeptDeviceID {
+		return fmt.Errorf("file is no longer linked to %s", entry.KeptPath)
+	}
+
+	if entry.QuarantineID != nil {
+		_, err := c.db.GetQuarantineItem(*entry.QuarantineID)
+		if err == nil {
+			return c.trash.Reinstate(ctx, *entry.QuarantineID, "Reverted hardlink to "+entry.KeptPath)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		// The kept original was purged, so fall back to a copy
+	}
+
+	// The files had the same contents, so a fresh copy restores a separate file with the same data
+	newInode, err := copyOverLink(entry.ReplacedPath)
+	if err != nil {
+		return err
+	}
+	if err := c.db.UpdateFileInode(entry.ReplacedPath, uint64(st.Dev), newInode); err != nil {
+		log.Printf("WARNING: Failed to update database inode for %s: %v", entry.ReplacedPath, err)
+	}
+	if err := c.db.LogHardlinkRevert(entry.ReplacedPath, fmt.Sprintf("Reverted hardlink to %s by copying %s (original contents were not kept)", entry.KeptPath, entry.ReplacedPath)); err != nil {
+		log.Printf("WARNING: Failed to log hardlink revert for %s: %v", entry.ReplacedPath, err)
+	}
+	return nil
+}
+
+// copyOverLink replaces a hardlink with a separate copy of its contents and returns the new inode
+// The copy is written next to the file and renamed over it so the path is never missing
+func copyOverLink(path string) (uint64, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	tempPath := filepath.Join(filepath.Dir(path), fmt.Sprintf(".unlink-temp-%d", os.Getpid()))
+	dst, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return 0, fmt.Errorf("failed to create copy: %w", err)
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(tempPath)
+		return 0, fmt.Errorf("failed to copy contents: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tempPath)
+		return 0, fmt.Errorf("failed to write copy: %w", err)
+	}
+	os.Chtimes(tempPath, info.ModTime(), info.ModTime())
+
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return 0, fmt.Errorf("failed to replace hardlink: %w", err)
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Ino), nil
+}
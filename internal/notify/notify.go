@@ -0,0 +1,302 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/stats"
+)
+
+// Event types sinks can subscribe to
+const (
+	EventScanCompleted   = "scan_completed"   // A scan finished, possibly with errors
+	EventScanFailed      = "scan_failed"      // A scan stopped because of an error
+	EventOrphanThreshold = "orphan_threshold" // A scan newly orphaned at least notifications.orphan_threshold files
+	EventMissingFiles    = "missing_files"    // Services newly report files that aren't on disk
+	EventConsolidation   = "consolidation"    // Duplicate consolidation or hardlinking finished
+	EventServiceStale    = "service_stale"    // A service's file listing failed a safeguard and was ignored
+	EventTest            = "test"             // Sent from the configuration page, delivered to every sink
+)
+
+// EventTypes lists the event types sinks can subscribe to
+var EventTypes = []string{
+	EventScanCompleted,
+	EventScanFailed,
+	EventOrphanThreshold,
+	EventMissingFiles,
+	EventConsolidation,
+	EventServiceStale,
+}
+
+// sendTimeout bounds how long delivering one event to one sink may take
+const sendTimeout = 30 * time.Second
+
+// Event is something sinks can be notified about
+// Title and Message are the default text; sinks can replace them with their own templates,
+// which are executed with the event
+type Event struct {
+	Type    string                 `json:"type"`
+	Title   string                 `json:"title"`
+	Message string                 `json:"message"`
+	Time    time.Time              `json:"time"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// Message is an event rendered for one sink
+type Message struct {
+	Event *Event
+	Title string
+	Body  string
+}
+
+// Notifier delivers events to the sinks configured under notifications
+// Sinks are read from the config on every event, so changes apply without a restart
+type Notifier struct {
+	config *config.Config
+}
+
+// New creates a notifier for the configured sinks
+func New(cfg *config.Config) *Notifier {
+	return &Notifier{config: cfg}
+}
+
+// Notify delivers an event in the background to every sink subscribed to its type
+// A nil notifier does nothing, so emitters don't need to check whether one is set
+func (n *Notifier) Notify(ev Event) {
+	if n == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	for _, sinkCfg := range n.config.Notifications.Sinks {
+		if !subscribed(sinkCfg, ev.Type) {
+			continue
+		}
+		go func(sinkCfg config.NotificationSink) {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			if err := send(ctx, sinkCfg, &ev); err != nil {
+				log.Printf("WARNING: Failed to send %s notification to %s: %v", ev.Type, sinkCfg.Name, err)
+			}
+		}(sinkCfg)
+	}
+}
+
+// Test sends a test event to the named sink and waits for the result
+func (n *Notifier) Test(ctx context.Context, name string) error {
+	for _, sinkCfg := range n.config.Notifications.Sinks {
+		if sinkCfg.Name != name {
+			continue
+		}
+		return send(ctx, sinkCfg, &Event{
+			Type:    EventTest,
+			Title:   "Media Usage Finder test notification",
+			Message: fmt.Sprintf("Notifications to %s are working.", name),
+			Time:    time.Now(),
+		})
+	}
+	return fmt.Errorf("no notification sink named %q", name)
+}
+
+// subscribed reports whether a sink receives an event type; sinks without events receive all of them
+func subscribed(sinkCfg config.NotificationSink, eventType string) bool {
+	if len(sinkCfg.Events) == 0 || eventType == EventTest {
+		return true
+	}
+	for _, t := range sinkCfg.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// send renders an event with the sink's templates and delivers it
+func send(ctx context.Context, sinkCfg config.NotificationSink, ev *Event) error {
+	sink, err := newSink(sinkCfg)
+	if err != nil {
+		return err
+	}
+
+	msg := &Message{Event: ev, Title: ev.Title, Body: ev.Message}
+	if sinkCfg.TitleTemplate != "" {
+		if msg.Title, err = render(sinkCfg.TitleTemplate, ev); err != nil {
+			return fmt.Errorf("title template: %w", err)
+		}
+	}
+	if sinkCfg.MessageTemplate != "" {
+		if msg.Body, err = render(sinkCfg.MessageTemplate, ev); err != nil {
+			return fmt.Errorf("message template: %w", err)
+		}
+	}
+
+	return sink.Send(ctx, msg)
+}
+
+// templateFuncs are available to sink templates in addition to the text/template builtins
+var templateFuncs = template.FuncMap{
+	"formatSize": stats.FormatSize,
+	"upper":      strings.ToUpper,
+}
+
+// render executes a sink template with an event
+func render(text string, ev *Event) (string, error) {
+	tmpl, err := template.New("notification").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, ev); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// ScanResult describes a finished scan for ScanFinished
+type ScanResult struct {
+	ScanID       int64
+	ScanType     string
+	Trigger      string
+	Status       string // completed, completed_with_errors or failed
+	FilesScanned int64
+	Duration     time.Duration
+	Error        string // Failure reason, or the errors a scan completed with
+}
+
+// ScanFinished builds the scan_completed or scan_failed event for a scan
+func ScanFinished(r ScanResult) Event {
+	ev := Event{
+		Type: EventScanCompleted,
+		Data: map[string]interface{}{
+			"scan_id":       r.ScanID,
+			"scan_type":     r.ScanType,
+			"trigger":       r.Trigger,
+			"status":        r.Status,
+			"files_scanned": r.FilesScanned,
+			"duration":      r.Duration.Round(time.Second).String(),
+			"error":         r.Error,
+		},
+	}
+
+	name := strings.ReplaceAll(r.ScanType, "_", " ")
+	duration := r.Duration.Round(time.Second)
+	summary := fmt.Sprintf("The %s scan finished in %s", name, duration)
+	if r.FilesScanned > 0 {
+		summary = fmt.Sprintf("The %s scan processed %d files in %s", name, r.FilesScanned, duration)
+	}
+
+	switch r.Status {
+	case "failed":
+		ev.Type = EventScanFailed
+		ev.Title = fmt.Sprintf("Scan #%d failed", r.ScanID)
+		ev.Message = fmt.Sprintf("The %s scan failed after %s: %s", name, duration, r.Error)
+	case "completed_with_errors":
+		ev.Title = fmt.Sprintf("Scan #%d completed with errors", r.ScanID)
+		ev.Message = summary + " but reported errors."
+	default:
+		ev.Title = fmt.Sprintf("Scan #%d completed", r.ScanID)
+		ev.Message = summary + "."
+	}
+	return ev
+}
+
+// OrphansIncreased builds the orphan_threshold event for a scan that newly orphaned count files
+func OrphansIncreased(scanID int64, count int, size int64, threshold int) Event {
+	return Event{
+		Type:    EventOrphanThreshold,
+		Title:   fmt.Sprintf("Scan #%d orphaned %d files", scanID, count),
+		Message: fmt.Sprintf("%d files (%s) are no longer used by any service, above the threshold of %d.", count, stats.FormatSize(size), threshold),
+		Data: map[string]interface{}{
+			"scan_id":   scanID,
+			"count":     count,
+			"size":      size,
+			"threshold": threshold,
+		},
+	}
+}
+
+// NewMissingFiles builds the missing_files event for files services newly report but aren't on disk
+func NewMissingFiles(scanID int64, byService map[string]int) Event {
+	services := make([]string, 0, len(byService))
+	total := 0
+	for service, count := range byService {
+		services = append(services, fmt.Sprintf("%s: %d", service, count))
+		total += count
+	}
+	sort.Strings(services)
+
+	return Event{
+		Type:    EventMissingFiles,
+		Title:   fmt.Sprintf("Scan #%d found %d missing files", scanID, total),
+		Message: fmt.Sprintf("Services reference %d files that aren't on disk (%s).", total, strings.Join(services, ", ")),
+		Data: map[string]interface{}{
+			"scan_id":    scanID,
+			"count":      total,
+			"by_service": byService,
+		},
+	}
+}
+
+// ConsolidationResult describes a finished consolidation for ConsolidationFinished
+type ConsolidationResult struct {
+	Operation       string // "consolidate" or "hardlink"
+	GroupsProcessed int
+	Files           int   // Files deleted, hardlinked or reflinked
+	SpaceFreed      int64 // Bytes freed
+	SpaceHeld       int64 // Bytes of replaced files kept in quarantine, freed once it is purged
+	Errors          int
+	RunID           int64 // Operation run that can be reverted
+}
+
+// ConsolidationFinished builds the consolidation event for a finished consolidation
+func ConsolidationFinished(r ConsolidationResult) Event {
+	verb := "consolidated"
+	if r.Operation == "hardlink" {
+		verb = "hardlinked"
+	}
+
+	ev := Event{
+		Type:    EventConsolidation,
+		Title:   fmt.Sprintf("Duplicates %s, %s freed", verb, stats.FormatSize(r.SpaceFreed)),
+		Message: fmt.Sprintf("%d files in %d duplicate groups were %s, freeing %s.", r.Files, r.GroupsProcessed, verb, stats.FormatSize(r.SpaceFreed)),
+		Data: map[string]interface{}{
+			"operation":        r.Operation,
+			"groups_processed": r.GroupsProcessed,
+			"files":            r.Files,
+			"space_freed":      r.SpaceFreed,
+			"space_held":       r.SpaceHeld,
+			"errors":           r.Errors,
+			"run_id":           r.RunID,
+		},
+	}
+	if r.SpaceHeld > 0 {
+		ev.Message += fmt.Sprintf(" %s more is held in quarantine until it is purged.", stats.FormatSize(r.SpaceHeld))
+	}
+	if r.Errors > 0 {
+		ev.Message += fmt.Sprintf(" %d groups failed.", r.Errors)
+	}
+	return ev
+}
+
+// ServiceStale builds the service_stale event for a service listing that failed a safeguard
+func ServiceStale(scanID int64, service, reason string) Event {
+	return Event{
+		Type:    EventServiceStale,
+		Title:   fmt.Sprintf("Ignored %s file listing", service),
+		Message: fmt.Sprintf("%s %s. Its previous usage was kept and orphan-based deletes are blocked until this is acknowledged.", service, reason),
+		Data: map[string]interface{}{
+			"scan_id": scanID,
+			"service": service,
+			"reason":  reason,
+		},
+	}
+}
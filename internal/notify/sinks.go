@@ -0,0 +1,243 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+)
+
+// Sink types
+const (
+	SinkWebhook = "webhook" // Generic JSON POST of the event
+	SinkDiscord = "discord" // Discord webhook
+	SinkSlack   = "slack"   // Slack incoming webhook (also accepted by Mattermost, Rocket.Chat...)
+	SinkNtfy    = "ntfy"    // ntfy topic URL
+	SinkGotify  = "gotify"  // Gotify server with an application token
+	SinkEmail   = "email"   // SMTP, with STARTTLS when the server offers it
+)
+
+// SinkTypes lists the supported sink types
+var SinkTypes = []string{SinkWebhook, SinkDiscord, SinkSlack, SinkNtfy, SinkGotify, SinkEmail}
+
+// discordContentLimit is the maximum length of a Discord message
+const discordContentLimit = 2000
+
+// Sink delivers rendered messages to a notification service
+type Sink interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// newSink creates the sink for a configured notification sink
+func newSink(cfg config.NotificationSink) (Sink, error) {
+	switch cfg.Type {
+	case SinkWebhook:
+		return &webhookSink{cfg: cfg}, nil
+	case SinkDiscord:
+		return &discordSink{cfg: cfg}, nil
+	case SinkSlack:
+		return &slackSink{cfg: cfg}, nil
+	case SinkNtfy:
+		return &ntfySink{cfg: cfg}, nil
+	case SinkGotify:
+		return &gotifySink{cfg: cfg}, nil
+	case SinkEmail:
+		return &emailSink{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+	}
+}
+
+// httpClient is shared by the HTTP sinks; requests are bounded by their context
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+// post sends a request body to url and fails on a non-2xx response
+func post(ctx context.Context, url, contentType string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// postJSON sends a JSON body to url
+func postJSON(ctx context.Context, url string, payload interface{}, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return post(ctx, url, "application/json", body, headers)
+}
+
+// webhookSink posts the event as JSON, with the rendered title and message
+type webhookSink struct{ cfg config.NotificationSink }
+
+func (s *webhookSink) Send(ctx context.Context, msg *Message) error {
+	return postJSON(ctx, s.cfg.URL, map[string]interface{}{
+		"type":    msg.Event.Type,
+		"title":   msg.Title,
+		"message": msg.Body,
+		"time":    msg.Event.Time,
+		"data":    msg.Event.Data,
+	}, s.cfg.Headers)
+}
+
+// discordSink posts to a Discord webhook
+type discordSink struct{ cfg config.NotificationSink }
+
+func (s *discordSink) Send(ctx context.Context, msg *Message) error {
+	content := truncate(fmt.Sprintf("**%s**\n%s", msg.Title, msg.Body), discordContentLimit)
+	return postJSON(ctx, s.cfg.URL, map[string]string{"content": content}, s.cfg.Headers)
+}
+
+// truncate shortens s to at most limit characters, ending it with an ellipsis when it is cut
+// It cuts on a character boundary so multi-byte characters aren't split into invalid UTF-8
+func truncate(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit-3]) + "..."
+}
+
+// slackSink posts to a Slack-compatible incoming webhook
+type slackSink struct{ cfg config.NotificationSink }
+
+func (s *slackSink) Send(ctx context.Context, msg *Message) error {
+	return postJSON(ctx, s.cfg.URL, map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", msg.Title, msg.Body),
+	}, s.cfg.Headers)
+}
+
+// ntfySink publishes to an ntfy topic URL, e.g. https://ntfy.sh/my-topic
+type ntfySink struct{ cfg config.NotificationSink }
+
+func (s *ntfySink) Send(ctx context.Context, msg *Message) error {
+	headers := map[string]string{
+		"Title": msg.Title,
+		"Tags":  msg.Event.Type,
+	}
+	if s.cfg.Priority > 0 {
+		headers["Priority"] = strconv.Itoa(s.cfg.Priority)
+	}
+	if s.cfg.Token != "" {
+		headers["Authorization"] = "Bearer " + s.cfg.Token
+	}
+	for k, v := range s.cfg.Headers {
+		headers[k] = v
+	}
+	return post(ctx, s.cfg.URL, "text/plain; charset=utf-8", []byte(msg.Body), headers)
+}
+
+// gotifySink sends a message to a Gotify server with an application token
+type gotifySink struct{ cfg config.NotificationSink }
+
+func (s *gotifySink) Send(ctx context.Context, msg *Message) error {
+	headers := map[string]string{"X-Gotify-Key": s.cfg.Token}
+	for k, v := range s.cfg.Headers {
+		headers[k] = v
+	}
+	return postJSON(ctx, strings.TrimSuffix(s.cfg.URL, "/")+"/message", map[string]interface{}{
+		"title":    msg.Title,
+		"message":  msg.Body,
+		"priority": s.cfg.Priority,
+	}, headers)
+}
+
+// emailSink sends a plain text email over SMTP
+type emailSink struct{ cfg config.NotificationSink }
+
+func (s *emailSink) Send(ctx context.Context, msg *Message) error {
+	addr := net.JoinHostPort(s.cfg.SMTPHost, strconv.Itoa(s.cfg.SMTPPort))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.cfg.SMTPHost}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	if s.cfg.Username != "" {
+		// PlainAuth refuses to send credentials without TLS, except to localhost
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.SMTPHost)); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range s.cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(emailBody(s.cfg.From, s.cfg.To, msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// emailBody formats a message as a plain text email
+// The subject is Q-encoded, since headers may only contain ASCII
+func emailBody(from string, to []string, msg *Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.NewReplacer("\r", " ", "\n", " ").Replace(msg.Title)))
+	fmt.Fprintf(&b, "Date: %s\r\n", msg.Event.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
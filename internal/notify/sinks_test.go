@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+)
+
+// captureJSON starts a server that decodes the JSON body of every request into the returned channel
+func captureJSON(t *testing.T) (*httptest.Server, chan map[string]interface{}) {
+	t.Helper()
+	bodies := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("X-Token") != "secret" {
+			http.Error(w, "missing header", http.StatusUnauthorized)
+			return
+		}
+		bodies <- body
+	}))
+	t.Cleanup(server.Close)
+	return server, bodies
+}
+
+func TestWebhookSink(t *testing.T) {
+	server, bodies := captureJSON(t)
+	sinkCfg := config.NotificationSink{
+		Name:            "hook",
+		Type:            SinkWebhook,
+		URL:             server.URL,
+		Headers:         map[string]string{"X-Token": "secret"},
+		MessageTemplate: "{{.Data.files}} files, {{formatSize .Data.size}}",
+	}
+	ev := &Event{
+		Type:    EventConsolidation,
+		Title:   "Consolidation finished",
+		Message: "default message",
+		Time:    time.Now(),
+		Data:    map[string]interface{}{"files": 3, "size": int64(2048)},
+	}
+
+	if err := send(context.Background(), sinkCfg, ev); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	body := <-bodies
+	if body["type"] != EventConsolidation || body["title"] != "Consolidation finished" {
+		t.Errorf("body = %v, want the event type and title", body)
+	}
+	if body["message"] != "3 files, 2.00 KB" {
+		t.Errorf("message = %q, want the rendered template", body["message"])
+	}
+
+	sinkCfg.Headers = nil
+	if err := send(context.Background(), sinkCfg, ev); err == nil {
+		t.Error("send succeeded although the server rejected the request")
+	}
+}
+
+func TestDiscordSinkTruncates(t *testing.T) {
+	server, bodies := captureJSON(t)
+	sinkCfg := config.NotificationSink{
+		Name:    "discord",
+		Type:    SinkDiscord,
+		URL:     server.URL,
+		Headers: map[string]string{"X-Token": "secret"},
+	}
+	ev := &Event{Type: EventMissingFiles, Title: "Missing files", Message: strings.Repeat("é", 3000)}
+
+	if err := send(context.Background(), sinkCfg, ev); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	content, _ := (<-bodies)["content"].(string)
+	if !utf8.ValidString(content) {
+		t.Error("content is not valid UTF-8")
+	}
+	if n := utf8.RuneCountInString(content); n != discordContentLimit {
+		t.Errorf("content has %d characters, want %d", n, discordContentLimit)
+	}
+	if !strings.HasSuffix(content, "é...") {
+		t.Errorf("content ends with %q, want an ellipsis", content[len(content)-8:])
+	}
+}
+
+// fakeSMTP accepts one message and returns the data it received
+func fakeSMTP(t *testing.T) (string, int, chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb, _, _ := strings.Cut(line, " "); strings.ToUpper(verb) {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 Go ahead")
+				data, err := tp.ReadDotBytes()
+				if err != nil {
+					return
+				}
+				messages <- string(data)
+				tp.PrintfLine("250 Queued")
+			case "QUIT":
+				tp.PrintfLine("221 Bye")
+				return
+			default:
+				tp.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	return host, portNum, messages
+}
+
+func TestEmailSink(t *testing.T) {
+	host, port, messages := fakeSMTP(t)
+	sinkCfg := config.NotificationSink{
+		Name:     "email",
+		Type:     SinkEmail,
+		SMTPHost: host,
+		SMTPPort: port,
+		From:     "finder@example.com",
+		To:       []string{"admin@example.com"},
+	}
+	ev := &Event{Type: EventScanCompleted, Title: "Scan terminé", Message: "line one\nline two", Time: time.Now()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := send(ctx, sinkCfg, ev); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	msg, err := textproto.NewReader(bufio.NewReader(strings.NewReader(<-messages))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("invalid headers: %v", err)
+	}
+	if subject := msg.Get("Subject"); subject != "=?utf-8?q?Scan_termin=C3=A9?=" {
+		t.Errorf("Subject = %q, want it Q-encoded", subject)
+	}
+	if to := msg.Get("To"); to != "admin@example.com" {
+		t.Errorf("To = %q", to)
+	}
+}
+
+func TestEmailBodyPlainSubject(t *testing.T) {
+	msg := &Message{Event: &Event{Time: time.Now()}, Title: "Scan\r\ncompleted", Body: "done"}
+	body := string(emailBody("a@example.com", []string{"b@example.com"}, msg))
+	if !strings.Contains(body, "Subject: Scan  completed\r\n") {
+		t.Errorf("body = %q, want an ASCII subject on one line, unencoded", body)
+	}
+}
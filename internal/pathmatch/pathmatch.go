@@ -0,0 +1,218 @@
+package pathmatch
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Match is a path ranked by how alike it is to a target path
+type Match struct {
+	Path string `json:"path"`
+	// Score is the number of trailing path components shared with the target plus the name similarity
+	Score float64 `json:"score"`
+	// SharedComponents is the number of trailing path components (file name first) shared with the target
+	SharedComponents int `json:"shared_components"`
+}
+
+// Similarity compares the file names of two paths, returning 1 for names that are equal ignoring case
+// and otherwise the share of the longer name covered by their common prefix and suffix,
+// e.g. "Movie (2020).mkv" and "Movie (2020) 1080p.mkv" share 16 of 22 characters
+func Similarity(a, b string) float64 {
+	a = strings.ToLower(baseName(a))
+	b = strings.ToLower(baseName(b))
+	if a == b {
+		return 1
+	}
+
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	if longest == 0 {
+		return 0
+	}
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return float64(prefix+suffix) / float64(longest)
+}
+
+// SharedComponents returns the number of trailing path components two paths have in common, ignoring
+// case and the path separator, e.g. 3 for "/data/tv/Show/S01E01.mkv" and "/tv/Show/S01E01.mkv"
+func SharedComponents(a, b string) int {
+	ac := components(a)
+	bc := components(b)
+	shared := 0
+	for shared < len(ac) && shared < len(bc) && strings.EqualFold(ac[len(ac)-1-shared], bc[len(bc)-1-shared]) {
+		shared++
+	}
+	return shared
+}
+
+// Closest ranks paths by how alike they are to target and returns the best limit of them
+// Paths whose names are less similar than minSimilarity are left out
+func Closest(target string, paths []string, minSimilarity float64, limit int) []Match {
+	var matches []Match
+	for _, path := range paths {
+		similarity := Similarity(target, path)
+		if similarity < minSimilarity {
+			continue
+		}
+		shared := SharedComponents(target, path)
+		matches = append(matches, Match{
+			Path:             path,
+			Score:            float64(shared) + similarity,
+			SharedComponents: shared,
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Path < matches[j].Path
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// Rewrite replaces the From prefix of a path with To
+type Rewrite struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// InferRewrite returns the prefix rewrite that turns from into to, keeping the trailing components
+// the paths share exactly, e.g. "/movies" to "/mnt/user/movies" for "/movies/Film/Film.mkv" and
+// "/mnt/user/movies/Film/Film.mkv". Each prefix keeps at least one component; returns false if the
+// paths don't share their file name
+func InferRewrite(from, to string) (Rewrite, bool) {
+	rewrites := Rewrites(from, to)
+	if len(rewrites) == 0 {
+		return Rewrite{}, false
+	}
+	return rewrites[0], true
+}
+
+// Rewrites returns every prefix rewrite that turns from into to, from the one keeping all trailing
+// components the paths share (as InferRewrite) to the one keeping only the file name
+func Rewrites(from, to string) []Rewrite {
+	fc := components(from)
+	tc := components(to)
+	shared := 0
+	for shared < len(fc)-1 && shared < len(tc)-1 && fc[len(fc)-1-shared] == tc[len(tc)-1-shared] {
+		shared++
+	}
+
+	var rewrites []Rewrite
+	for kept := shared; kept > 0; kept-- {
+		rewrites = append(rewrites, Rewrite{From: trimComponents(from, kept), To: trimComponents(to, kept)})
+	}
+	return rewrites
+}
+
+// NormalizedMismatch reports whether the last component two paths don't share exactly is equal
+// after NormalizeName, meaning the paths differ by case or accents rather than by location
+func NormalizedMismatch(a, b string) bool {
+	ac := components(a)
+	bc := components(b)
+	for i := 1; i <= len(ac) && i <= len(bc); i++ {
+		x, y := ac[len(ac)-i], bc[len(bc)-i]
+		if x != y {
+			return NormalizeName(x) == NormalizeName(y)
+		}
+	}
+	return false
+}
+
+// NormalizeName lower-cases a name and strips accents from Latin letters, so names that differ only
+// by case or Unicode form (precomposed or combining accents) compare equal
+func NormalizeName(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	for _, r := range name {
+		if unicode.Is(unicode.Mn, r) {
+			continue // Combining accent of a decomposed letter
+		}
+		r = unicode.ToLower(r)
+		if folded, ok := latinFolds[r]; ok {
+			b.WriteString(folded)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// latinFolds maps lower-case precomposed Latin letters to their unaccented form
+var latinFolds = func() map[rune]string {
+	folds := map[string]string{
+		"a":  "àáâãäåāăą",
+		"c":  "çćĉċč",
+		"d":  "ďđ",
+		"e":  "èéêëēĕėęě",
+		"g":  "ĝğġģ",
+		"h":  "ĥħ",
+		"i":  "ìíîïĩīĭįı",
+		"j":  "ĵ",
+		"k":  "ķ",
+		"l":  "ĺļľŀł",
+		"n":  "ñńņňŉ",
+		"o":  "òóôõöøōŏő",
+		"r":  "ŕŗř",
+		"s":  "śŝşš",
+		"t":  "ţťŧ",
+		"u":  "ùúûüũūŭůűų",
+		"w":  "ŵ",
+		"y":  "ýÿŷ",
+		"z":  "źżž",
+		"ae": "æ",
+		"oe": "œ",
+		"ss": "ß",
+	}
+	table := make(map[rune]string)
+	for base, letters := range folds {
+		for _, r := range letters {
+			table[r] = base
+		}
+	}
+	return table
+}()
+
+// trimComponents removes the last n components from a path
+func trimComponents(path string, n int) string {
+	isSeparator := func(r rune) bool { return r == '/' || r == '\\' }
+	for ; n > 0; n-- {
+		path = strings.TrimRightFunc(path, isSeparator)
+		i := strings.LastIndexFunc(path, isSeparator)
+		if i < 0 {
+			return ""
+		}
+		path = path[:i]
+	}
+	return path
+}
+
+// components splits a path into its components, accepting both separators since services on
+// Windows report paths with backslashes
+func components(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' })
+}
+
+// baseName returns the last component of a path
+func baseName(path string) string {
+	c := components(path)
+	if len(c) == 0 {
+		return ""
+	}
+	return c[len(c)-1]
+}
@@ -0,0 +1,107 @@
+package pathmatch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"/movies/Movie (2020).mkv", `D:\Movies\movie (2020).MKV`, 1},
+		{"/a/Movie (2020).mkv", "/b/Movie (2020) 1080p.mkv", 16.0 / 22.0},
+		{"/a/abc", "/b/xyz", 0},
+		{"/", "/", 1},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSharedComponents(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"/data/tv/Show/S01E01.mkv", "/tv/Show/S01E01.mkv", 3},
+		{"/data/TV/show/S01E01.mkv", `C:\tv\Show\S01E01.mkv`, 3},
+		{"/data/tv/Show/S01E01.mkv", "/data/tv/Show/S01E02.mkv", 0},
+	}
+	for _, tt := range tests {
+		if got := SharedComponents(tt.a, tt.b); got != tt.want {
+			t.Errorf("SharedComponents(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	paths := []string{
+		"/media/movies/Other/Film.mkv",
+		"/media/movies/Film/Film.mkv",
+		"/media/movies/Film/Film 1080p.mkv",
+		"/media/movies/Else/Unrelated.mkv",
+	}
+	matches := Closest("/movies/Film/Film.mkv", paths, 0.5, 2)
+
+	want := []Match{
+		{Path: "/media/movies/Film/Film.mkv", Score: 4, SharedComponents: 3},
+		{Path: "/media/movies/Other/Film.mkv", Score: 2, SharedComponents: 1},
+	}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("Closest() = %+v, want %+v", matches, want)
+	}
+}
+
+func TestRewrites(t *testing.T) {
+	from, to := "/movies/Film/Film.mkv", "/mnt/user/movies/Film/Film.mkv"
+	want := []Rewrite{
+		{From: "/movies", To: "/mnt/user/movies"},
+		{From: "/movies/Film", To: "/mnt/user/movies/Film"},
+	}
+	if got := Rewrites(from, to); !reflect.DeepEqual(got, want) {
+		t.Errorf("Rewrites() = %+v, want %+v", got, want)
+	}
+
+	rewrite, ok := InferRewrite(from, to)
+	if !ok || rewrite != want[0] {
+		t.Errorf("InferRewrite() = %+v, %v, want %+v", rewrite, ok, want[0])
+	}
+	if _, ok := InferRewrite("/movies/Film/Film.mkv", "/movies/Film/Other.mkv"); ok {
+		t.Error("InferRewrite() found a rewrite for paths with different file names")
+	}
+}
+
+func TestNormalizedMismatch(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"/movies/Amélie/Amélie.mkv", "/data/movies/amelie/Amélie.mkv", true},
+		{"/movies/Ame\u0301lie.mkv", "/movies/Am\u00e9lie.mkv", true},
+		{"/movies/Film/Film.mkv", "/data/movies/Film/Film.mkv", false},
+		{"/movies/Film.mkv", "/movies/Film.mkv", false},
+	}
+	for _, tt := range tests {
+		if got := NormalizedMismatch(tt.a, tt.b); got != tt.want {
+			t.Errorf("NormalizedMismatch(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := map[string]string{
+		"Amélie":          "amelie",
+		"Ame\u0301lie":    "amelie",
+		"Œuvre Straße":    "oeuvre strasse",
+		"ÇA N'EST PAS ÇA": "ca n'est pas ca",
+	}
+	for name, want := range tests {
+		if got := NormalizeName(name); got != want {
+			t.Errorf("NormalizeName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
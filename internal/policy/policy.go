@@ -0,0 +1,240 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+	"github.com/mmenanno/media-usage-finder/internal/quarantine"
+	"github.com/mmenanno/media-usage-finder/internal/stats"
+)
+
+// Cleanup policy actions
+const (
+	ActionQuarantine = "quarantine" // Move the file to the quarantine trash directory
+	ActionDelete     = "delete"     // Delete the file like the web UI does, through the quarantine when it is enabled
+)
+
+// Policy run trigger sources
+const (
+	TriggerScan   = "scan"   // Evaluated after a completed scan
+	TriggerManual = "manual" // Started from the web UI or API
+)
+
+// Engine evaluates the configured cleanup policies against orphaned files
+// Every match is recorded on a policy run for the report page; only enforced policies act on files
+type Engine struct {
+	db     *database.DB
+	config *config.Config
+	trash  *quarantine.Manager
+
+	mu sync.Mutex // Serializes runs so a file is never acted on twice
+}
+
+// New creates a cleanup policy engine
+func New(db *database.DB, cfg *config.Config, trash *quarantine.Manager) *Engine {
+	return &Engine{
+		db:     db,
+		config: cfg,
+		trash:  trash,
+	}
+}
+
+// Configured reports whether any cleanup policies are configured
+func (e *Engine) Configured() bool {
+	return len(e.config.CleanupPolicies) > 0
+}
+
+// Run evaluates every policy and acts on the matches of enforced policies unless dryRun is set
+// A file matched by several policies is handled by the first one. scanID is the scan that
+// triggered the run, or 0. Returns the completed run
+func (e *Engine) Run(ctx context.Context, scanID int64, trigger string, dryRun bool) (*database.PolicyRun, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Orphaned status can't be trusted while a service's file listing is stale,
+	// so enforced policies only report until it is acknowledged
+	stale, err := e.db.ListStaleServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var blocked string
+	if len(stale) > 0 {
+		services := make([]string, len(stale))
+		for i, check := range stale {
+			services[i] = check.Service
+		}
+		blocked = fmt.Sprintf("not applied: stale file listing from %s not acknowledged", strings.Join(services, ", "))
+		log.Printf("WARNING: Cleanup policies are only reporting matches, %s", blocked)
+	}
+
+	runID, err := e.db.CreatePolicyRun(scanID, trigger, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]bool)
+	for _, p := range e.config.CleanupPolicies {
+		if ctx.Err() != nil {
+			break
+		}
+
+		files, err := e.db.FindOrphanedFiles(ctx, filterFor(p))
+		if err != nil {
+			log.Printf("WARNING: Failed to evaluate cleanup policy %q: %v", p.Name, err)
+			continue
+		}
+
+		applied, failed := 0, 0
+		for _, file := range files {
+			if seen[file.ID] {
+				continue
+			}
+			seen[file.ID] = true
+
+			match := &database.PolicyMatch{
+				RunID:        runID,
+				Policy:       p.Name,
+				Action:       p.Action,
+				FileID:       file.ID,
+				Path:         file.Path,
+				Size:         file.Size,
+				ModifiedTime: file.ModifiedTime,
+				Outcome:      database.PolicyOutcomeReported,
+			}
+
+			if p.Enforce && !dryRun && blocked != "" {
+				match.Error = blocked
+			} else if p.Enforce && !dryRun && ctx.Err() == nil {
+				if err := e.apply(ctx, p, file); err != nil {
+					log.Printf("WARNING: Cleanup policy %q failed to %s %s: %v", p.Name, p.Action, file.Path, err)
+					if logErr := e.db.LogDeletionError(file.ID, file.Path, fmt.Errorf("cleanup policy %q: %w", p.Name, err)); logErr != nil {
+						log.Printf("WARNING: Failed to log failed deletion of %s: %v", file.Path, logErr)
+					}
+					match.Outcome = database.PolicyOutcomeFailed
+					match.Error = err.Error()
+					failed++
+				} else {
+					match.Outcome = database.PolicyOutcomeApplied
+					applied++
+				}
+			}
+
+			if err := e.db.AddPolicyMatch(match); err != nil {
+				log.Printf("WARNING: Failed to record cleanup policy match for %s: %v", file.Path, err)
+			}
+		}
+
+		if applied > 0 || failed > 0 {
+			log.Printf("Cleanup policy %q: %d files matched, %d %s, %d failed", p.Name, len(files), applied, pastTense(p.Action), failed)
+		} else if len(files) > 0 {
+			log.Printf("Cleanup policy %q: %d files matched (report only)", p.Name, len(files))
+		}
+	}
+
+	if err := e.db.CompletePolicyRun(runID, constants.PolicyRunsKept); err != nil {
+		log.Printf("WARNING: Failed to complete policy run %d: %v", runID, err)
+	}
+
+	return e.db.GetPolicyRun(runID)
+}
+
+// apply performs a policy's action on a matched file
+// The audit log entry written by the quarantine or delete carries the policy as its reason
+func (e *Engine) apply(ctx context.Context, p config.CleanupPolicy, file *database.File) error {
+	// Usage can come back between the query and now (e.g. from the filesystem watcher)
+	usage, err := e.db.GetUsageByFileID(file.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check usage: %w", err)
+	}
+	if len(usage) > 0 {
+		return fmt.Errorf("file is no longer orphaned")
+	}
+
+	reason := fmt.Sprintf("Cleanup policy %q (%s)", p.Name, Describe(p))
+	switch p.Action {
+	case ActionQuarantine:
+		_, err = e.trash.Quarantine(ctx, file.ID, reason)
+	case ActionDelete:
+		_, err = e.trash.Remove(ctx, file.ID, reason)
+	default:
+		err = fmt.Errorf("unknown action %q", p.Action)
+	}
+	return err
+}
+
+// filterFor converts a policy's conditions into an orphaned file filter
+func filterFor(p config.CleanupPolicy) database.OrphanedFileFilter {
+	filter := database.OrphanedFileFilter{
+		PathPrefixes: p.Paths,
+		MinSize:      p.MinSize,
+		MaxSize:      p.MaxSize,
+	}
+	for _, ext := range p.Extensions {
+		filter.Extensions = append(filter.Extensions, normalizeExtension(ext))
+	}
+	if p.MinAge > 0 {
+		filter.OrphanedBefore = time.Now().Add(-p.MinAge)
+	}
+	return filter
+}
+
+// normalizeExtension lowercases an extension and adds the leading dot the files table stores
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// Describe summarizes a policy's conditions, e.g. "orphaned for 14 days under /downloads, .nfo or .txt"
+func Describe(p config.CleanupPolicy) string {
+	parts := []string{"orphaned"}
+	if p.MinAge > 0 {
+		parts[0] += " for " + formatAge(p.MinAge)
+	}
+	if len(p.Paths) > 0 {
+		parts[0] += " under " + strings.Join(p.Paths, " or ")
+	}
+	if len(p.Extensions) > 0 {
+		exts := make([]string, len(p.Extensions))
+		for i, ext := range p.Extensions {
+			exts[i] = normalizeExtension(ext)
+		}
+		parts = append(parts, strings.Join(exts, " or "))
+	}
+	if p.MinSize > 0 {
+		parts = append(parts, "at least "+stats.FormatSize(p.MinSize))
+	}
+	if p.MaxSize > 0 {
+		parts = append(parts, "at most "+stats.FormatSize(p.MaxSize))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatAge formats a policy age in days when it is a whole number of days
+func formatAge(d time.Duration) string {
+	day := 24 * time.Hour
+	if d%day == 0 {
+		if d == day {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", d/day)
+	}
+	return d.String()
+}
+
+// pastTense returns the verb used in log messages for an action
+func pastTense(action string) string {
+	if action == ActionQuarantine {
+		return "quarantined"
+	}
+	return "deleted"
+}
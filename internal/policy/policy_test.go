@@ -0,0 +1,119 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+	"github.com/mmenanno/media-usage-finder/internal/quarantine"
+)
+
+func TestDescribe(t *testing.T) {
+	p := config.CleanupPolicy{
+		Paths:      []string{"/downloads"},
+		Extensions: []string{"NFO", ".txt"},
+		MinAge:     14 * 24 * time.Hour,
+		MaxSize:    1024,
+	}
+	want := "orphaned for 14 days under /downloads, .nfo or .txt, at most 1.00 KB"
+	if got := Describe(p); got != want {
+		t.Errorf("Describe() = %q, want %q", got, want)
+	}
+}
+
+// newTestEngine returns an engine over a database holding orphaned files written to dir
+// The files were last modified years ago but only just became orphaned
+func newTestEngine(t *testing.T, cfg *config.Config, names ...string) (*Engine, *database.DB, string) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	scan, err := db.CreateScan("full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("leftover"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		file := &database.File{
+			Path:         path,
+			Size:         8,
+			ScanID:       scan.ID,
+			ModifiedTime: time.Now().AddDate(-2, 0, 0),
+			LastVerified: time.Now(),
+			IsOrphaned:   true,
+			Extension:    database.ExtractExtension(path),
+		}
+		if err := db.UpsertFile(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.UpdateOrphanedStatus(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return New(db, cfg, quarantine.New(db, cfg)), db, dir
+}
+
+func TestRunMinAgeUsesOrphanedSince(t *testing.T) {
+	cfg := config.Default()
+	cfg.CleanupPolicies = []config.CleanupPolicy{
+		{Name: "old-orphans", Action: ActionDelete, MinAge: time.Hour},
+	}
+	engine, _, _ := newTestEngine(t, cfg, "a.nfo")
+
+	run, err := engine.Run(context.Background(), 0, TriggerManual, true)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if run.Matched != 0 {
+		t.Errorf("matched %d files orphaned just now, want 0", run.Matched)
+	}
+
+	cfg.CleanupPolicies[0].MinAge = 0
+	cfg.CleanupPolicies[0].Extensions = []string{".nfo"}
+	run, err = engine.Run(context.Background(), 0, TriggerManual, true)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if run.Matched != 1 {
+		t.Errorf("matched %d files, want 1", run.Matched)
+	}
+}
+
+func TestRunDeleteUsesQuarantineManager(t *testing.T) {
+	cfg := config.Default()
+	cfg.Quarantine.Enabled = false
+	cfg.CleanupPolicies = []config.CleanupPolicy{
+		{Name: "nfo", Action: ActionDelete, Extensions: []string{".nfo"}, Enforce: true},
+	}
+	engine, db, dir := newTestEngine(t, cfg, "a.nfo", "b.mkv")
+
+	run, err := engine.Run(context.Background(), 0, TriggerManual, false)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if run.Applied != 1 || run.Failed != 0 {
+		t.Fatalf("applied %d, failed %d, want 1 applied", run.Applied, run.Failed)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "a.nfo")); !os.IsNotExist(err) {
+		t.Errorf("a.nfo still exists: %v", err)
+	}
+	if _, err := db.GetFileByPath(filepath.Join(dir, "a.nfo")); err == nil {
+		t.Error("a.nfo is still recorded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.mkv")); err != nil {
+		t.Errorf("b.mkv was touched: %v", err)
+	}
+}
@@ -0,0 +1,365 @@
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+	"github.com/mmenanno/media-usage-finder/internal/disk"
+)
+
+// Manager moves deleted files to a trash directory and restores or purges them
+// The trash directory sits at the root of the file's own filesystem, so quarantining is a
+// rename and never copies data
+type Manager struct {
+	db     *database.DB
+	config *config.Config
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a quarantine manager
+func New(db *database.DB, cfg *config.Config) *Manager {
+	return &Manager{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// Enabled reports whether deleted files are moved to the trash instead of being removed
+func (m *Manager) Enabled() bool {
+	return m.config.Quarantine.Enabled
+}
+
+// DeleteFile deletes a file record and optionally the file itself, like DB.DeleteFile
+// When quarantine is enabled the file is moved to the trash instead of being removed
+func (m *Manager) DeleteFile(fileID int64, details string, deleteFromFilesystem bool) error {
+	if !deleteFromFilesystem {
+		return m.db.DeleteFile(fileID, details, false)
+	}
+	_, err := m.Remove(context.Background(), fileID, details)
+	return err
+}
+
+// Remove deletes a file and its record, moving the file to the trash when quarantine is enabled
+// Returns the quarantine item, or nil when the file was deleted outright
+func (m *Manager) Remove(ctx context.Context, fileID int64, details string) (*database.QuarantineItem, error) {
+	if !m.Enabled() {
+		return nil, m.db.DeleteFile(fileID, details, true)
+	}
+	return m.Quarantine(ctx, fileID, details)
+}
+
+// Quarantine moves a file to the trash directory of its filesystem and removes its file record
+func (m *Manager) Quarantine(ctx context.Context, fileID int64, reason string) (*database.QuarantineItem, error) {
+	file, err := m.db.GetFileByID(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+
+	root, itemDir, trashPath, err := trashLocation(fileID, file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move file to quarantine (%s): %w", file.Path, err)
+	}
+
+	if err := os.Rename(file.Path, trashPath); err != nil {
+		os.Remove(itemDir)
+		if errors.Is(err, syscall.EXDEV) {
+			return nil, fmt.Errorf("failed to move file to quarantine (%s): trash directory %s is on a different filesystem", file.Path, root)
+		}
+		return nil, fmt.Errorf("failed to move file to quarantine (%s): %w", file.Path, err)
+	}
+
+	item, err := m.db.QuarantineFile(ctx, fileID, trashPath, root, reason)
+	if err != nil {
+		// Put the file back so it stays tracked
+		if restoreErr := os.Rename(trashPath, file.Path); restoreErr != nil {
+			log.Printf("ERROR: Failed to move %s back from quarantine: %v", file.Path, restoreErr)
+		} else {
+			os.Remove(itemDir)
+		}
+		return nil, err
+	}
+
+	log.Printf("Quarantined %s to %s", file.Path, trashPath)
+	return item, nil
+}
+
+// Hold keeps a link to a file's current contents in the trash directory without moving it
+// The file record stays in place; call this before the file at the path is replaced so the
+// original can be put back with Reinstate
+func (m *Manager) Hold(ctx context.Context, fileID int64, reason string) (*database.QuarantineItem, error) {
+	file, err := m.db.GetFileByID(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+
+	root, itemDir, trashPath, err := trashLocation(fileID, file.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to keep original of %s: %w", file.Path, err)
+	}
+
+	if err := os.Link(file.Path, trashPath); err != nil {
+		os.Remove(itemDir)
+		return nil, fmt.Errorf("failed to keep original of %s: %w", file.Path, err)
+	}
+
+	item, err := m.db.HoldQuarantineFile(ctx, fileID, trashPath, root, reason)
+	if err != nil {
+		os.Remove(trashPath)
+		os.Remove(itemDir)
+		return nil, err
+	}
+	return item, nil
+}
+
+// SetAside moves one disk's copy of a file to the trash directory of that disk, keeping the file record
+// Used when the file stays reachable through a copy on another disk
+func (m *Manager) SetAside(ctx context.Context, fileID int64, diskCopy *database.FileDiskLocation, reason string) (*database.QuarantineItem, error) {
+	root, itemDir, trashPath, err := trashLocation(fileID, diskCopy.DiskPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move copy to quarantine (%s): %w", diskCopy.DiskPath, err)
+	}
+
+	if err := os.Rename(diskCopy.DiskPath, trashPath); err != nil {
+		os.Remove(itemDir)
+		return nil, fmt.Errorf("failed to move copy to quarantine (%s): %w", diskCopy.DiskPath, err)
+	}
+
+	item, err := m.db.SetAsideQuarantineFile(ctx, fileID, diskCopy, trashPath, root, reason)
+	if err != nil {
+		if restoreErr := os.Rename(trashPath, diskCopy.DiskPath); restoreErr != nil {
+			log.Printf("ERROR: Failed to move %s back from quarantine: %v", diskCopy.DiskPath, restoreErr)
+		} else {
+			os.Remove(itemDir)
+		}
+		return nil, err
+	}
+
+	log.Printf("Quarantined copy %s to %s", diskCopy.DiskPath, trashPath)
+	return item, nil
+}
+
+// Reinstate moves a held file back over its original path, replacing whatever is there now
+func (m *Manager) Reinstate(ctx context.Context, id int64, details string) error {
+	item, err := m.db.GetQuarantineItem(id)
+	if err != nil {
+		return fmt.Errorf("quarantined file not found: %w", err)
+	}
+
+	if err := os.Rename(item.TrashPath, item.OriginalPath); err != nil {
+		return fmt.Errorf("failed to move %s back from quarantine: %w", item.OriginalPath, err)
+	}
+	os.Remove(filepath.Dir(item.TrashPath))
+
+	if err := m.db.ReleaseQuarantineItem(ctx, item, details); err != nil {
+		return err
+	}
+
+	log.Printf("Reinstated %s from quarantine", item.OriginalPath)
+	return nil
+}
+
+// Restore moves a quarantined file back to its original path and recreates its file record and usage
+// Returns the ID of the restored file
+func (m *Manager) Restore(ctx context.Context, id int64) (int64, error) {
+	item, err := m.db.GetQuarantineItem(id)
+	if err != nil {
+		return 0, fmt.Errorf("quarantined file not found: %w", err)
+	}
+
+	if _, err := os.Lstat(item.OriginalPath); err == nil {
+		return 0, fmt.Errorf("a file already exists at %s", item.OriginalPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(item.OriginalPath), 0755); err != nil {
+		return 0, fmt.Errorf("failed to recreate directory for %s: %w", item.OriginalPath, err)
+	}
+	if err := os.Rename(item.TrashPath, item.OriginalPath); err != nil {
+		return 0, fmt.Errorf("failed to move %s back from quarantine: %w", item.OriginalPath, err)
+	}
+
+	// Held files and disk copies kept their file record, so only the file itself comes back
+	if _, err := m.db.GetFileByID(item.FileID); err == nil {
+		os.Remove(filepath.Dir(item.TrashPath))
+		if err := m.db.ReleaseQuarantineItem(ctx, item, "Restored from quarantine"); err != nil {
+			return 0, err
+		}
+		log.Printf("Restored %s from quarantine", item.OriginalPath)
+		return item.FileID, nil
+	}
+
+	fileID, err := m.db.RestoreQuarantineItem(ctx, item)
+	if err != nil {
+		// Keep the file in the trash so the quarantine record stays accurate
+		if moveErr := os.Rename(item.OriginalPath, item.TrashPath); moveErr != nil {
+			log.Printf("ERROR: Failed to move %s back to quarantine: %v", item.OriginalPath, moveErr)
+		}
+		return 0, err
+	}
+
+	os.Remove(filepath.Dir(item.TrashPath))
+	log.Printf("Restored %s from quarantine", item.OriginalPath)
+	return fileID, nil
+}
+
+// Purge permanently deletes a quarantined file
+func (m *Manager) Purge(ctx context.Context, id int64, reason string) error {
+	item, err := m.db.GetQuarantineItem(id)
+	if err != nil {
+		return fmt.Errorf("quarantined file not found: %w", err)
+	}
+	return m.purge(ctx, item, reason)
+}
+
+func (m *Manager) purge(ctx context.Context, item *database.QuarantineItem, reason string) error {
+	if err := os.Remove(item.TrashPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete %s: %w", item.TrashPath, err)
+	}
+	os.Remove(filepath.Dir(item.TrashPath))
+
+	return m.db.DeleteQuarantineItem(ctx, item, reason)
+}
+
+// PurgeExpired deletes quarantined files older than the retention period, then the oldest files on
+// any filesystem whose free space is below the configured minimum. Returns the number purged
+func (m *Manager) PurgeExpired(ctx context.Context) (int, error) {
+	items, err := m.db.GetQuarantineItemsOldestFirst()
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	cutoff := time.Now().Add(-m.config.Quarantine.Retention)
+	byRoot := make(map[string][]*database.QuarantineItem)
+	for _, item := range items {
+		if m.config.Quarantine.Retention > 0 && item.QuarantinedAt.Before(cutoff) {
+			if err := m.purge(ctx, item, "Quarantine retention expired"); err != nil {
+				log.Printf("WARNING: Failed to purge %s: %v", item.TrashPath, err)
+				continue
+			}
+			purged++
+			continue
+		}
+		byRoot[item.TrashRoot] = append(byRoot[item.TrashRoot], item)
+	}
+
+	if m.config.Quarantine.MinFreePercent <= 0 {
+		return purged, nil
+	}
+
+	for root, items := range byRoot {
+		for _, item := range items {
+			space, err := disk.GetDiskSpace(root)
+			if err != nil || space.TotalBytes == 0 {
+				break
+			}
+			if float64(space.FreeBytes)/float64(space.TotalBytes)*100 >= m.config.Quarantine.MinFreePercent {
+				break
+			}
+			if err := m.purge(ctx, item, "Low free space"); err != nil {
+				log.Printf("WARNING: Failed to purge %s: %v", item.TrashPath, err)
+				continue
+			}
+			purged++
+		}
+	}
+
+	return purged, nil
+}
+
+// Start purges expired quarantined files in the background
+func (m *Manager) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stop != nil {
+		return
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.run(m.stop, m.done)
+}
+
+// Stop halts background purging
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	stop, done := m.stop, m.done
+	m.stop = nil
+	m.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (m *Manager) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(constants.QuarantinePurgeIntervalMinutes * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if purged, err := m.PurgeExpired(context.Background()); err != nil {
+			log.Printf("WARNING: Failed to purge quarantined files: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d quarantined files", purged)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// trashLocation creates the trash directory for a file and returns the filesystem root, the file's own
+// directory in the trash and the path the file is kept at
+func trashLocation(fileID int64, path string) (root, itemDir, trashPath string, err error) {
+	root, err = filesystemRoot(path)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	// Each file gets its own directory so files with the same name never collide
+	itemDir = filepath.Join(root, constants.QuarantineDirName, fmt.Sprintf("%d-%d", fileID, time.Now().Unix()))
+	if err := os.MkdirAll(itemDir, 0755); err != nil {
+		return "", "", "", fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	return root, itemDir, filepath.Join(itemDir, filepath.Base(path)), nil
+}
+
+// filesystemRoot returns the top directory of the filesystem holding path
+// Walking up stops at the last parent on the same device, which is the mount point
+func filesystemRoot(path string) (string, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Dir(path)
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+		var parentStat syscall.Stat_t
+		if err := syscall.Stat(parent, &parentStat); err != nil || parentStat.Dev != st.Dev {
+			return dir, nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,282 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mmenanno/media-usage-finder/internal/api"
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+	"github.com/mmenanno/media-usage-finder/internal/pathmatch"
+)
+
+// DiscoveredMapping is a service path mapping that places sampled service paths on scanned files
+type DiscoveredMapping struct {
+	ServicePrefix string `json:"service_prefix"`
+	LocalPrefix   string `json:"local_prefix"`
+	Matched       int    `json:"matched"` // Sampled paths under the service prefix the mapping places on a scanned file
+	Sampled       int    `json:"sampled"` // Sampled paths under the service prefix
+	// Confidence (0-1) is the share of sampled paths under the service prefix the mapping matches,
+	// counting paths found in several places among the scanned files only in part
+	Confidence float64                   `json:"confidence"`
+	Configured bool                      `json:"configured"` // Already one of the service's mappings
+	Examples   []database.MappingExample `json:"examples"`
+}
+
+// DiscoveredLocalMapping is a local path mapping proposed from a discovered service mapping whose
+// service prefix doesn't exist inside media-finder's container. Such a service sees another
+// filesystem, and if it runs on the host rather than in a container of its own, its prefix is the
+// host path of media-finder's prefix
+type DiscoveredLocalMapping struct {
+	ContainerPrefix string `json:"container_prefix"` // media-finder's path, the mapping's local prefix
+	HostPrefix      string `json:"host_prefix"`      // The service prefix
+	Configured      bool   `json:"configured"`       // Already one of the local path mappings
+}
+
+// Discovery is the set of mappings proposed for a service from a sample of its files
+type Discovery struct {
+	Service  string              `json:"service"`
+	Sampled  int                 `json:"sampled"` // Distinct paths the service returned as samples
+	Found    int                 `json:"found"`   // Sampled paths found among the scanned files
+	Mappings []DiscoveredMapping `json:"mappings"`
+	// LocalMappings are proposed for the service mappings whose service prefix media-finder can't see
+	LocalMappings []DiscoveredLocalMapping `json:"local_mappings"`
+	// Unmatched lists a few sampled paths none of the mappings places on a scanned file
+	Unmatched []string `json:"unmatched"`
+}
+
+// discoverySample is a sampled service path with the scanned files ending like it
+type discoverySample struct {
+	path       string
+	candidates map[string]bool
+	rewrites   [][]pathmatch.Rewrite // Per candidate, the rewrites that turn the path into it, shortest prefix first
+}
+
+// Discover samples a service's files, finds the sampled paths among the scanned files and proposes
+// the fewest service path mappings that place the samples on them, those matching the most samples first
+func (e *Engine) Discover(ctx context.Context, provider api.Provider) (*Discovery, error) {
+	paths, err := e.samplePaths(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	discovery := &Discovery{
+		Service:       provider.Name(),
+		Mappings:      []DiscoveredMapping{},
+		LocalMappings: []DiscoveredLocalMapping{},
+		Unmatched:     []string{},
+	}
+
+	var samples []*discoverySample
+	for _, path := range paths {
+		candidates, err := e.findCandidates(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		sample := &discoverySample{path: path, candidates: make(map[string]bool, len(candidates))}
+		for _, candidate := range candidates {
+			sample.candidates[candidate] = true
+			if rewrites := pathmatch.Rewrites(path, candidate); len(rewrites) > 0 {
+				sample.rewrites = append(sample.rewrites, rewrites)
+			}
+		}
+		if len(candidates) > 0 {
+			discovery.Found++
+		}
+		samples = append(samples, sample)
+	}
+	discovery.Sampled = len(samples)
+
+	// Repeatedly take the rewrite suggested by the most samples no chosen mapping accounts for yet.
+	// Samples suggest their shortest service prefix that isn't taken, so a directory mapped elsewhere
+	// than its parent gets a mapping of its own
+	explained := make([]bool, len(samples))
+	taken := make(map[string]bool)
+	var chosen []pathmatch.Rewrite
+	for {
+		best, votes := bestRewrite(samples, explained, taken)
+		if votes < constants.DiscoveryMinMatches {
+			break
+		}
+		taken[best.From] = true
+		chosen = append(chosen, best)
+		for i, sample := range samples {
+			for _, rewrites := range sample.rewrites {
+				for _, rewrite := range rewrites {
+					if rewrite == best {
+						explained[i] = true
+					}
+				}
+			}
+		}
+	}
+
+	// Score each mapping alongside the configured mappings it doesn't replace
+	var effective []config.PathMapping
+	for _, m := range e.config.ServicePathMappings[provider.Name()] {
+		if !taken[m.Service] {
+			effective = append(effective, m)
+		}
+	}
+	for _, rewrite := range chosen {
+		effective = append(effective, config.PathMapping{Service: rewrite.From, Local: rewrite.To})
+	}
+
+	for _, rewrite := range chosen {
+		mapping := DiscoveredMapping{ServicePrefix: rewrite.From, LocalPrefix: rewrite.To}
+		var weight float64
+		for _, sample := range samples {
+			if applied, ok := longestMapping(sample.path, effective); !ok || applied.Service != rewrite.From {
+				continue
+			}
+			mapping.Sampled++
+			local := config.ApplyPathMappings(sample.path, effective)
+			if !sample.candidates[local] {
+				continue
+			}
+			mapping.Matched++
+			weight += 1 / float64(len(sample.candidates))
+			if len(mapping.Examples) < constants.MappingProposalExamples {
+				mapping.Examples = append(mapping.Examples, database.MappingExample{ServicePath: sample.path, LocalPath: local})
+			}
+		}
+		if mapping.Sampled > 0 {
+			mapping.Confidence = weight / float64(mapping.Sampled)
+		}
+		for _, m := range e.config.ServicePathMappings[provider.Name()] {
+			if m.Service == rewrite.From && m.Local == rewrite.To {
+				mapping.Configured = true
+			}
+		}
+		discovery.Mappings = append(discovery.Mappings, mapping)
+
+		if mapping.Matched > 0 {
+			if local, ok := e.localMapping(rewrite); ok {
+				discovery.LocalMappings = append(discovery.LocalMappings, local)
+			}
+		}
+	}
+
+	for i, sample := range samples {
+		if !explained[i] && len(discovery.Unmatched) < constants.MappingProposalExamples {
+			discovery.Unmatched = append(discovery.Unmatched, sample.path)
+		}
+	}
+	return discovery, nil
+}
+
+// findCandidates returns the scanned files ending in the longest suffix of a service path any of them
+// ends in, trying at most DiscoverySuffixComponents trailing components
+func (e *Engine) findCandidates(ctx context.Context, servicePath string) ([]string, error) {
+	c := strings.FieldsFunc(servicePath, func(r rune) bool { return r == '/' || r == '\\' })
+	n := constants.DiscoverySuffixComponents
+	if n > len(c)-1 {
+		n = len(c) - 1
+	}
+	for ; n > 0; n-- {
+		candidates, err := e.db.FindFilePathsBySuffix(ctx, "/"+strings.Join(c[len(c)-n:], "/"), constants.DiscoveryCandidatesPerPath)
+		if err != nil || len(candidates) > 0 {
+			return candidates, err
+		}
+	}
+	return nil, nil
+}
+
+// samplePaths returns the distinct paths of a sample of the service's files, spread across its
+// libraries by the provider, sorted
+func (e *Engine) samplePaths(ctx context.Context, provider api.Provider) ([]string, error) {
+	sampled, err := provider.SampleFiles(ctx, e.config, e.config.APITimeout, "", constants.DiscoverySampleSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample %s files: %w", provider.DisplayName(), err)
+	}
+
+	seen := make(map[string]bool, len(sampled))
+	var paths []string
+	for _, path := range sampled {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// localMapping proposes the local path mapping of a discovered service mapping whose service prefix
+// doesn't exist inside media-finder's container. Nothing is proposed when the service prefix exists,
+// since the service then shares media-finder's paths, or when a local path mapping already covers
+// the local prefix with another host path
+func (e *Engine) localMapping(rewrite pathmatch.Rewrite) (DiscoveredLocalMapping, bool) {
+	if _, err := os.Stat(rewrite.From); !errors.Is(err, fs.ErrNotExist) {
+		return DiscoveredLocalMapping{}, false
+	}
+
+	local := DiscoveredLocalMapping{ContainerPrefix: rewrite.To, HostPrefix: rewrite.From}
+	for _, m := range e.config.LocalPathMappings {
+		if m.Service == rewrite.To && m.Local == rewrite.From {
+			local.Configured = true
+			return local, true
+		}
+	}
+	for _, m := range e.config.LocalPathMappings {
+		if strings.HasPrefix(rewrite.To, m.Service) {
+			return DiscoveredLocalMapping{}, false
+		}
+	}
+	return local, true
+}
+
+// bestRewrite returns the rewrite suggested by the most unexplained samples, preferring shorter
+// service prefixes on ties. Each sample suggests, per candidate, its shortest rewrite whose service
+// prefix isn't taken
+func bestRewrite(samples []*discoverySample, explained []bool, taken map[string]bool) (pathmatch.Rewrite, int) {
+	votes := make(map[pathmatch.Rewrite]int)
+	for i, sample := range samples {
+		if explained[i] {
+			continue
+		}
+		voted := make(map[pathmatch.Rewrite]bool)
+		for _, rewrites := range sample.rewrites {
+			for _, rewrite := range rewrites {
+				if taken[rewrite.From] {
+					continue
+				}
+				if !voted[rewrite] {
+					voted[rewrite] = true
+					votes[rewrite]++
+				}
+				break
+			}
+		}
+	}
+
+	var best pathmatch.Rewrite
+	bestVotes := 0
+	for rewrite, n := range votes {
+		switch {
+		case n > bestVotes,
+			n == bestVotes && len(rewrite.From) < len(best.From),
+			n == bestVotes && len(rewrite.From) == len(best.From) && rewrite.From+rewrite.To < best.From+best.To:
+			best, bestVotes = rewrite, n
+		}
+	}
+	return best, bestVotes
+}
+
+// longestMapping returns the mapping ApplyPathMappings applies to a path
+func longestMapping(path string, mappings []config.PathMapping) (config.PathMapping, bool) {
+	var best config.PathMapping
+	found := false
+	for _, m := range mappings {
+		if strings.HasPrefix(path, m.Service) && (!found || len(m.Service) > len(best.Service)) {
+			best, found = m, true
+		}
+	}
+	return best, found
+}
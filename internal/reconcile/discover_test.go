@@ -0,0 +1,40 @@
+package reconcile
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/pathmatch"
+)
+
+func TestLocalMapping(t *testing.T) {
+	cfg := config.Default()
+	cfg.LocalPathMappings = nil
+	engine := New(nil, cfg)
+	visible := t.TempDir()
+	host := filepath.Join(t.TempDir(), "missing", "movies")
+
+	// A service prefix media-finder sees is shared, so it implies no local mapping
+	if _, ok := engine.localMapping(pathmatch.Rewrite{From: visible, To: "/media/movies"}); ok {
+		t.Error("proposed a local mapping for a service path that exists inside media-finder")
+	}
+
+	local, ok := engine.localMapping(pathmatch.Rewrite{From: host, To: "/media/movies"})
+	want := DiscoveredLocalMapping{ContainerPrefix: "/media/movies", HostPrefix: host}
+	if !ok || local != want {
+		t.Fatalf("localMapping() = %+v, %v, want %+v", local, ok, want)
+	}
+
+	cfg.LocalPathMappings = []config.PathMapping{{Service: "/media/movies", Local: host}}
+	local, ok = engine.localMapping(pathmatch.Rewrite{From: host, To: "/media/movies"})
+	if !ok || !local.Configured {
+		t.Errorf("localMapping() = %+v, %v, want it marked configured", local, ok)
+	}
+
+	// Another host path already covers the container path
+	cfg.LocalPathMappings = []config.PathMapping{{Service: "/media", Local: "/mnt/user/media"}}
+	if local, ok := engine.localMapping(pathmatch.Rewrite{From: host, To: "/media/movies"}); ok {
+		t.Errorf("localMapping() = %+v, want none under an existing local mapping", local)
+	}
+}
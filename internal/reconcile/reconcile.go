@@ -0,0 +1,182 @@
+package reconcile
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+	"github.com/mmenanno/media-usage-finder/internal/pathmatch"
+)
+
+// Engine proposes service path mappings, from orphaned files paired with the files services report
+// as missing by size and normalized file name, or from samples of a service's listing
+type Engine struct {
+	db     *database.DB
+	config *config.Config
+
+	mu sync.Mutex // Serializes runs so proposals are replaced as a whole
+}
+
+// New creates a reconciliation engine
+func New(db *database.DB, cfg *config.Config) *Engine {
+	return &Engine{
+		db:     db,
+		config: cfg,
+	}
+}
+
+// pairKey identifies the files that may be the same file under different paths
+type pairKey struct {
+	size int64
+	name string
+}
+
+// Run replaces the stored mapping proposals with ones for the current orphaned and missing files
+// scanID is the scan that triggered the run, or 0. Returns the stored proposals
+func (e *Engine) Run(ctx context.Context, scanID int64) ([]*database.MappingProposal, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	orphans, err := e.db.FindOrphanedFiles(ctx, database.OrphanedFileFilter{})
+	if err != nil {
+		return nil, err
+	}
+	missing, err := e.db.GetLatestServiceMissingFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.db.ReplaceMappingProposals(ctx, scanID, e.propose(orphans, missing)); err != nil {
+		return nil, err
+	}
+	return e.db.ListMappingProposals(ctx)
+}
+
+// propose pairs missing files with orphans and groups the pairs by the mapping that would match them
+// A mapping is counted by every orphan it would match, not only the pairs it was inferred from
+func (e *Engine) propose(orphans []*database.File, missing []*database.MissingFile) []*database.MappingProposal {
+	candidates := make(map[pairKey][]*database.File)
+	orphanPaths := make(map[string]*database.File, len(orphans))
+	for _, f := range orphans {
+		orphanPaths[f.Path] = f
+		if f.Size > 0 {
+			key := pairKey{f.Size, pathmatch.NormalizeName(filepath.Base(f.Path))}
+			candidates[key] = append(candidates[key], f)
+		}
+	}
+
+	var proposals []*database.MappingProposal
+	mappings := make(map[string]*database.MappingProposal)
+	mismatches := make(map[string]*database.MappingProposal)
+	byService := make(map[string][]*database.MissingFile)
+
+	for _, m := range missing {
+		byService[m.Service] = append(byService[m.Service], m)
+		if m.Size <= 0 {
+			continue // Services that don't report sizes can't be paired reliably
+		}
+		f := closestOrphan(m.ServicePath, candidates[pairKey{m.Size, pathmatch.NormalizeName(baseName(m.ServicePath))}])
+		if f == nil {
+			continue
+		}
+
+		if pathmatch.NormalizedMismatch(m.ServicePath, f.Path) {
+			p, ok := mismatches[m.Service]
+			if !ok {
+				p = &database.MappingProposal{Service: m.Service, Kind: database.ProposalNameMismatch}
+				mismatches[m.Service] = p
+				proposals = append(proposals, p)
+			}
+			addMatch(p, m.ServicePath, f)
+			continue
+		}
+
+		rewrite, ok := pathmatch.InferRewrite(m.ServicePath, f.Path)
+		if !ok {
+			continue
+		}
+		if current := e.config.ServicePathMappingFor(m.ServicePath, m.Service); current != nil && len(current.Service) > len(rewrite.From) {
+			// Correct the configured mapping rather than add one it would override
+			remainder := strings.TrimPrefix(m.ServicePath, current.Service)
+			if !strings.HasSuffix(f.Path, remainder) {
+				continue
+			}
+			rewrite = pathmatch.Rewrite{From: current.Service, To: strings.TrimSuffix(f.Path, remainder)}
+		}
+		key := m.Service + "\x00" + rewrite.From + "\x00" + rewrite.To
+		if _, ok := mappings[key]; !ok {
+			p := &database.MappingProposal{
+				Service:       m.Service,
+				Kind:          database.ProposalMapping,
+				ServicePrefix: rewrite.From,
+				LocalPrefix:   rewrite.To,
+			}
+			mappings[key] = p
+			proposals = append(proposals, p)
+		}
+	}
+
+	// Count every orphan each mapping would match alongside the service's other mappings, dropping
+	// mappings a longer configured one would override
+	var result []*database.MappingProposal
+	for _, p := range proposals {
+		if p.Kind == database.ProposalMapping {
+			serviceMappings := e.config.WithServicePathMapping(p.Service, config.PathMapping{Service: p.ServicePrefix, Local: p.LocalPrefix})
+			matched := make(map[string]bool)
+			for _, m := range byService[p.Service] {
+				local := config.ApplyPathMappings(m.ServicePath, serviceMappings)
+				if f, ok := orphanPaths[local]; ok && !matched[local] {
+					matched[local] = true
+					addMatch(p, m.ServicePath, f)
+				}
+			}
+		}
+		if p.FileCount > 0 {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// closestOrphan picks the candidate sharing the most trailing path components with a missing file,
+// or nil if there is none or it's a tie
+func closestOrphan(servicePath string, candidates []*database.File) *database.File {
+	var best *database.File
+	bestShared, tied := -1, false
+	for _, f := range candidates {
+		shared := pathmatch.SharedComponents(servicePath, f.Path)
+		switch {
+		case shared > bestShared:
+			best, bestShared, tied = f, shared, false
+		case shared == bestShared:
+			tied = true
+		}
+	}
+	if tied {
+		return nil
+	}
+	return best
+}
+
+// addMatch counts an orphan a proposal would match, keeping the first few as examples
+func addMatch(p *database.MappingProposal, servicePath string, f *database.File) {
+	p.FileCount++
+	p.TotalSize += f.Size
+	if len(p.Examples) < constants.MappingProposalExamples {
+		p.Examples = append(p.Examples, database.MappingExample{ServicePath: servicePath, LocalPath: f.Path})
+	}
+}
+
+// baseName returns the file name of a service path, which may use either separator
+func baseName(path string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' || path[i] == '\\' {
+			return path[i+1:]
+		}
+	}
+	return path
+}
@@ -146,8 +146,11 @@ func (ds *DiskScanner) scanDisk(diskCfg config.DiskConfig, fileChan chan<- diskF
 			return nil
 		}
 
-		// Skip directories
+		// Skip directories, and the files held in quarantine
 		if d.IsDir() {
+			if isQuarantineDir(d) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
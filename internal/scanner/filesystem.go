@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"sync"
 	"syscall"
+
+	"github.com/mmenanno/media-usage-finder/internal/constants"
 )
 
 // FileInfo represents information about a file from the filesystem
@@ -45,8 +47,11 @@ func WalkFiles(ctx context.Context, paths []string, out chan<- FileInfo, progres
 					return nil // Continue walking
 				}
 
-				// Skip directories
+				// Skip directories, and the files held in quarantine
 				if d.IsDir() {
+					if isQuarantineDir(d) {
+						return filepath.SkipDir
+					}
 					return nil
 				}
 
@@ -110,6 +115,11 @@ func WalkFiles(ctx context.Context, paths []string, out chan<- FileInfo, progres
 	return nil
 }
 
+// isQuarantineDir reports whether an entry is a quarantine trash directory, whose files are never scanned
+func isQuarantineDir(d fs.DirEntry) bool {
+	return d.IsDir() && d.Name() == constants.QuarantineDirName
+}
+
 // CountFiles counts the total number of files in the given paths
 func CountFiles(ctx context.Context, paths []string) (int64, error) {
 	var count int64
@@ -127,6 +137,10 @@ func CountFiles(ctx context.Context, paths []string) (int64, error) {
 				return nil // Continue counting
 			}
 
+			if isQuarantineDir(d) {
+				return filepath.SkipDir
+			}
+
 			// Count only regular files (skip directories and symlinks)
 			if !d.IsDir() && d.Type()&fs.ModeSymlink == 0 {
 				count++
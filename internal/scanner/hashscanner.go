@@ -11,6 +11,7 @@ import (
 	"github.com/mmenanno/media-usage-finder/internal/config"
 	"github.com/mmenanno/media-usage-finder/internal/database"
 	"github.com/mmenanno/media-usage-finder/internal/disk"
+	"github.com/mmenanno/media-usage-finder/internal/notify"
 )
 
 // HashScanner handles file hashing operations
@@ -22,6 +23,8 @@ type HashScanner struct {
 	cancel   context.CancelFunc
 	scanCtx  context.Context
 
+	notifier *notify.Notifier // Receives scan completed and failed events
+
 	// Stats for rate limiting
 	mu              sync.Mutex
 	bytesProcessed  int64
@@ -45,6 +48,32 @@ func NewHashScanner(db *database.DB, cfg *config.DuplicateDetectionConfig) *Hash
 	}
 }
 
+// SetNotifier sets the notifier that receives scan completed and failed events
+func (hs *HashScanner) SetNotifier(n *notify.Notifier) {
+	hs.notifier = n
+}
+
+// completeScan records a hash scan's final status and notifies about it
+// Interrupted scans were cancelled on purpose and aren't notified
+func (hs *HashScanner) completeScan(ctx context.Context, scanID int64, status, errorMsg string) {
+	if err := hs.db.CompleteScan(scanID, status, errorMsg); err != nil {
+		log.Printf("Warning: failed to complete scan: %v", err)
+	}
+	if status == "interrupted" {
+		return
+	}
+
+	hs.notifier.Notify(notify.ScanFinished(notify.ScanResult{
+		ScanID:       scanID,
+		ScanType:     "hash_scan",
+		Trigger:      TriggerFromContext(ctx),
+		Status:       status,
+		FilesScanned: hs.progress.ProcessedFiles,
+		Duration:     time.Since(hs.progress.StartTime),
+		Error:        errorMsg,
+	}))
+}
+
 // Start begins the hash scanning process
 func (hs *HashScanner) Start(ctx context.Context, minSize, maxSize int64) error {
 	// Check if hashing is already running
@@ -57,7 +86,7 @@ func (hs *HashScanner) Start(ctx context.Context, minSize, maxSize int64) error
 	}
 
 	// Create scan record
-	scan, err := hs.db.CreateScan("hash_scan")
+	scan, err := hs.db.CreateScanWithTrigger("hash_scan", TriggerFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to create scan record: %w", err)
 	}
@@ -86,7 +115,7 @@ func (hs *HashScanner) Start(ctx context.Context, minSize, maxSize int64) error
 	if err != nil {
 		hs.progress.AddError(fmt.Sprintf("Failed to get files: %v", err))
 		hs.progress.Stop()
-		hs.db.CompleteScan(scan.ID, "failed", fmt.Sprintf("Failed to get files: %v", err))
+		hs.completeScan(ctx, scan.ID, "failed", fmt.Sprintf("Failed to get files: %v", err))
 		return fmt.Errorf("failed to get files: %w", err)
 	}
 
@@ -130,7 +159,7 @@ func (hs *HashScanner) VerifyDuplicates(ctx context.Context, minSize int64, maxS
 	}
 
 	// Create scan record
-	scan, err := hs.db.CreateScan("hash_scan")
+	scan, err := hs.db.CreateScanWithTrigger("hash_scan", TriggerFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to create scan record: %w", err)
 	}
@@ -155,7 +184,7 @@ func (hs *HashScanner) VerifyDuplicates(ctx context.Context, minSize int64, maxS
 	if err != nil {
 		hs.progress.AddError(fmt.Sprintf("Failed to get files: %v", err))
 		hs.progress.Stop()
-		hs.db.CompleteScan(scan.ID, "failed", fmt.Sprintf("Failed to get files: %v", err))
+		hs.completeScan(ctx, scan.ID, "failed", fmt.Sprintf("Failed to get files: %v", err))
 		return fmt.Errorf("failed to get files: %w", err)
 	}
 
@@ -187,7 +216,7 @@ func (hs *HashScanner) UpgradeAllQuickHashes(ctx context.Context, minSize int64,
 	}
 
 	// Create scan record
-	scan, err := hs.db.CreateScan("hash_scan")
+	scan, err := hs.db.CreateScanWithTrigger("hash_scan", TriggerFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to create scan record: %w", err)
 	}
@@ -212,7 +241,7 @@ func (hs *HashScanner) UpgradeAllQuickHashes(ctx context.Context, minSize int64,
 	if err != nil {
 		hs.progress.AddError(fmt.Sprintf("Failed to get files: %v", err))
 		hs.progress.Stop()
-		hs.db.CompleteScan(scan.ID, "failed", fmt.Sprintf("Failed to get files: %v", err))
+		hs.completeScan(ctx, scan.ID, "failed", fmt.Sprintf("Failed to get files: %v", err))
 		return fmt.Errorf("failed to get files: %w", err)
 	}
 
@@ -236,7 +265,7 @@ func (hs *HashScanner) UpgradeAllQuickHashes(ctx context.Context, minSize int64,
 // Starts at level 1 and progressively upgrades to higher levels only for files that remain duplicates
 func (hs *HashScanner) VerifyDuplicatesProgressive(ctx context.Context, minSize int64, maxSize int64) error {
 	// Create scan record first
-	scan, err := hs.db.CreateScan("hash_scan")
+	scan, err := hs.db.CreateScanWithTrigger("hash_scan", TriggerFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to create scan: %w", err)
 	}
@@ -268,7 +297,7 @@ func (hs *HashScanner) VerifyDuplicatesProgressive(ctx context.Context, minSize
 		if err != nil {
 			hs.progress.AddError(fmt.Sprintf("Failed to get level %d duplicates: %v", prevLevel, err))
 			hs.progress.Stop()
-			hs.db.CompleteScan(scan.ID, "failed", fmt.Sprintf("Failed to get duplicates: %v", err))
+			hs.completeScan(ctx, scan.ID, "failed", fmt.Sprintf("Failed to get duplicates: %v", err))
 			return fmt.Errorf("failed to get duplicates at level %d: %w", prevLevel, err)
 		}
 
@@ -318,9 +347,7 @@ func (hs *HashScanner) VerifyDuplicatesProgressive(ctx context.Context, minSize
 		status = "completed"
 	}
 
-	if err := hs.db.CompleteScan(scan.ID, status, errorMsg); err != nil {
-		log.Printf("Warning: failed to complete scan: %v", err)
-	}
+	hs.completeScan(ctx, scan.ID, status, errorMsg)
 
 	hs.progress.Stop()
 	return nil
@@ -497,9 +524,7 @@ func (hs *HashScanner) verifyFiles(ctx context.Context, files []database.File, s
 	}
 
 	// Update scan record
-	if err := hs.db.CompleteScan(scanID, status, errorMsg); err != nil {
-		log.Printf("Warning: failed to complete scan: %v", err)
-	}
+	hs.completeScan(ctx, scanID, status, errorMsg)
 }
 
 // verifyWorker processes files for full hash verification
@@ -656,9 +681,7 @@ func (hs *HashScanner) processFiles(ctx context.Context, files []database.File,
 	}
 
 	// Update scan record
-	if err := hs.db.CompleteScan(scanID, status, errorMsg); err != nil {
-		log.Printf("Warning: failed to complete scan: %v", err)
-	}
+	hs.completeScan(ctx, scanID, status, errorMsg)
 }
 
 // worker processes files from the work channel
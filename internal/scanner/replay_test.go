@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+func TestReplayServiceSnapshotKeepsBaseline(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	if err := db.RegisterServices([]database.Service{{Name: "sonarr", DisplayName: "Sonarr", Type: "arr"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	check := &database.ServiceCheck{Service: "sonarr", Status: database.ServiceCheckOK, FileCount: 200}
+	if err := db.RecordServiceCheck(ctx, check); err != nil {
+		t.Fatal(err)
+	}
+	saveSnapshot := func(count int) int64 {
+		t.Helper()
+		files := make([]database.SnapshotFile, count)
+		for i := range files {
+			files[i] = database.SnapshotFile{Path: fmt.Sprintf("/tv/%d.mkv", i)}
+		}
+		id, err := db.SaveServiceSnapshot(ctx, "sonarr", 0, files, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	baseline := func() int {
+		t.Helper()
+		count, _, err := db.GetServiceBaseline(ctx, "sonarr")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	cfg := config.Default()
+	s := NewScanner(db, cfg)
+
+	// An older listing within the safeguards is replayed without becoming the baseline
+	if err := s.ReplayServiceSnapshot(ctx, saveSnapshot(150)); err != nil {
+		t.Fatalf("ReplayServiceSnapshot: %v", err)
+	}
+	if got := baseline(); got != 200 {
+		t.Errorf("baseline = %d after the replay, want 200", got)
+	}
+
+	// One the safeguards reject isn't replayed, and doesn't mark the service stale either
+	if err := s.ReplayServiceSnapshot(ctx, saveSnapshot(50)); err == nil {
+		t.Error("replayed a snapshot with a quarter of the baseline's files")
+	}
+	if got := baseline(); got != 200 {
+		t.Errorf("baseline = %d after the refused replay, want 200", got)
+	}
+	stale, err := db.ListStaleServices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 0 {
+		t.Errorf("stale services = %+v, want none", stale)
+	}
+}
@@ -0,0 +1,272 @@
+package scanner
+
+import (
+	"context"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/api"
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// RescanQueue collects the paths services report through webhooks and rescans them with
+// RescanFilesForServices, only querying the service that reported them. Paths reported within the
+// debounce interval are rescanned together, and a batch waits while another scan is running since
+// scans update service usage themselves
+type RescanQueue struct {
+	scanner *Scanner
+	db      *database.DB
+	config  *config.Config
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	pending map[string]map[string]bool // Host paths of files or directories to rescan, by reporting service
+	timer   *time.Timer                // Armed while paths are pending
+	busy    bool                       // A batch is being rescanned
+}
+
+// NewRescanQueue creates a queue that rescans reported paths with the scanner
+func NewRescanQueue(s *Scanner) *RescanQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RescanQueue{
+		scanner: s,
+		db:      s.db,
+		config:  s.config,
+		ctx:     ctx,
+		cancel:  cancel,
+		pending: make(map[string]map[string]bool),
+	}
+}
+
+// Stop discards pending paths and cancels a rescan in progress
+func (q *RescanQueue) Stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	clear(q.pending)
+	q.cancel()
+}
+
+// Add queues files or directories a service reported for a rescan
+// Paths are translated with the service's path mappings. Paths that end up outside the scan paths
+// are returned instead of queued, as they usually mean a path mapping is missing
+func (q *RescanQueue) Add(service string, paths ...string) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var skipped []string
+	for _, p := range paths {
+		hostPath, ok := q.hostPath(service, p)
+		if !ok {
+			skipped = append(skipped, p)
+			continue
+		}
+		if q.pending[service] == nil {
+			q.pending[service] = make(map[string]bool)
+		}
+		q.pending[service][hostPath] = true
+	}
+
+	q.schedule()
+	return skipped
+}
+
+// Rename moves the recorded files of a path a service renamed and queues the new path,
+// so the moved files are matched against services again. If the move can't be applied,
+// both paths are rescanned instead. Returns the paths outside the scan paths
+func (q *RescanQueue) Rename(ctx context.Context, service, from, to string) []string {
+	oldPath, oldOK := q.hostPath(service, from)
+	newPath, newOK := q.hostPath(service, to)
+	if !oldOK || !newOK {
+		// Whichever side is inside the scan paths is still rescanned
+		return q.Add(service, from, to)
+	}
+
+	renamed, err := q.db.RenameFilePath(ctx, oldPath, newPath)
+	if err != nil {
+		log.Printf("Webhooks: Failed to rename %s to %s, rescanning instead: %v", oldPath, newPath, err)
+		return q.Add(service, from, to)
+	}
+	if renamed > 0 {
+		log.Printf("Webhooks: Moved %d files from %s to %s", renamed, oldPath, newPath)
+	}
+
+	return q.Add(service, to)
+}
+
+// Pending returns the number of paths waiting to be rescanned
+func (q *RescanQueue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending := 0
+	for _, paths := range q.pending {
+		pending += len(paths)
+	}
+	return pending
+}
+
+// hostPath translates a service path to a host path and reports whether it is inside a scan path
+func (q *RescanQueue) hostPath(service, servicePath string) (string, bool) {
+	if servicePath == "" {
+		return "", false
+	}
+	hostPath := filepath.Clean(q.config.TranslatePathToHost(servicePath, service))
+	for _, scanPath := range q.config.ScanPaths {
+		scanPath = filepath.Clean(scanPath)
+		if hostPath == scanPath || strings.HasPrefix(hostPath, scanPath+string(filepath.Separator)) {
+			return hostPath, true
+		}
+	}
+	return hostPath, false
+}
+
+// schedule arms the timer for pending paths; must be called with mu held
+func (q *RescanQueue) schedule() {
+	if q.timer != nil || len(q.pending) == 0 || q.ctx.Err() != nil {
+		return
+	}
+	q.timer = time.AfterFunc(q.config.Webhooks.DebounceInterval, q.process)
+}
+
+// process rescans the pending paths, or waits for another interval while a scan is running
+func (q *RescanQueue) process() {
+	q.mu.Lock()
+	q.timer = nil
+	if q.busy || len(q.pending) == 0 {
+		q.schedule()
+		q.mu.Unlock()
+		return
+	}
+	if current, err := q.db.GetCurrentScan(); err != nil || current != nil {
+		q.schedule()
+		q.mu.Unlock()
+		return
+	}
+
+	batch := q.pending
+	q.pending = make(map[string]map[string]bool)
+	q.busy = true
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		q.busy = false
+		q.schedule()
+		q.mu.Unlock()
+	}()
+
+	for service, pending := range batch {
+		if q.ctx.Err() != nil {
+			return
+		}
+		q.rescan(service, pending)
+	}
+}
+
+// rescan rescans the paths one service reported, only querying that service for their usage
+// Other services pick the files up with their next update or webhook
+func (q *RescanQueue) rescan(service string, pending map[string]bool) {
+	paths := make([]string, 0, len(pending))
+	for p := range pending {
+		paths = append(paths, p)
+	}
+
+	files, err := q.expand(q.ctx, paths)
+	if err != nil {
+		log.Printf("Webhooks: Failed to list files to rescan: %v", err)
+		return
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	// Files of a service that isn't configured are still updated, without querying any service
+	var providers []api.Provider
+	if provider := api.Lookup(service); provider != nil && provider.Configured(&q.config.Services) {
+		providers = append(providers, provider)
+	}
+
+	log.Printf("Webhooks: Rescanning %d files from %d paths reported by %s", len(files), len(paths), service)
+	if err := q.scanner.RescanFilesForServices(WithTrigger(q.ctx, TriggerWebhook), files, providers); err != nil {
+		log.Printf("Webhooks: Failed to rescan files reported by %s: %v", service, err)
+	}
+}
+
+// expand turns reported paths into the files to rescan
+// Directories contribute the files on disk and the files recorded under them, so a deleted
+// directory takes its recorded files with it. Deleted files are only kept if they are recorded
+func (q *RescanQueue) expand(ctx context.Context, paths []string) ([]string, error) {
+	files := make(map[string]bool)
+	var gone []string
+
+	for _, p := range paths {
+		info, err := os.Lstat(p)
+		if err == nil && !info.IsDir() {
+			files[p] = true
+			continue
+		}
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("Webhooks: Failed to stat %s: %v", p, err)
+			continue
+		}
+
+		recorded, err := q.db.GetFilePathsUnderPath(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range recorded {
+			files[path] = true
+		}
+
+		if info == nil {
+			gone = append(gone, p)
+			continue
+		}
+
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // Keep walking, unreadable entries are picked up by the next scan
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if isQuarantineDir(d) {
+				return filepath.SkipDir
+			}
+			if d.Type().IsRegular() {
+				files[path] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := q.db.GetFilesByPaths(ctx, gone)
+	if err != nil {
+		return nil, err
+	}
+	for path := range existing {
+		files[path] = true
+	}
+
+	result := make([]string, 0, len(files))
+	for path := range files {
+		result = append(result, path)
+	}
+	return result, nil
+}
@@ -0,0 +1,34 @@
+package scanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+)
+
+func TestRescanQueueAdd(t *testing.T) {
+	cfg := &config.Config{
+		ScanPaths: []string{"/mnt/user/media"},
+		ServicePathMappings: map[string][]config.PathMapping{
+			"sonarr": {{Service: "/tv", Local: "/mnt/user/media/tv"}},
+			"radarr": {{Service: "/movies", Local: "/mnt/user/media/movies"}},
+		},
+	}
+	cfg.Webhooks.DebounceInterval = time.Hour
+	q := NewRescanQueue(&Scanner{config: cfg})
+	t.Cleanup(q.Stop)
+
+	if skipped := q.Add("sonarr", "/tv/Show/e1.mkv", "/elsewhere/e2.mkv"); len(skipped) != 1 || skipped[0] != "/elsewhere/e2.mkv" {
+		t.Errorf("skipped = %v, want the path outside the scan paths", skipped)
+	}
+	q.Add("radarr", "/movies/Movie/movie.mkv")
+	q.Add("sonarr", "/tv/Show/e1.mkv")
+
+	if got := q.Pending(); got != 2 {
+		t.Errorf("Pending() = %d, want 2", got)
+	}
+	if !q.pending["sonarr"]["/mnt/user/media/tv/Show/e1.mkv"] || !q.pending["radarr"]["/mnt/user/media/movies/Movie/movie.mkv"] {
+		t.Errorf("pending = %v, want each path under the service that reported it", q.pending)
+	}
+}
@@ -3,6 +3,7 @@ package scanner
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -17,6 +18,7 @@ import (
 	"github.com/mmenanno/media-usage-finder/internal/constants"
 	"github.com/mmenanno/media-usage-finder/internal/database"
 	"github.com/mmenanno/media-usage-finder/internal/disk"
+	"github.com/mmenanno/media-usage-finder/internal/notify"
 )
 
 // Scanner coordinates the entire scanning process
@@ -28,6 +30,8 @@ type Scanner struct {
 	cancel            context.CancelFunc
 	scanCtx           context.Context     // Current scan context for cancellation
 	onScanComplete    func()              // Callback when scan completes
+	onOrphansUpdated  func(scanID int64)  // Callback when a completed scan leaves orphaned status current
+	notifier          *notify.Notifier    // Receives scan, orphan and missing file events
 }
 
 // NewScanner creates a new scanner
@@ -68,6 +72,38 @@ func (s *Scanner) SetOnScanComplete(callback func()) {
 	s.onScanComplete = callback
 }
 
+// SetOnOrphansUpdated sets the callback to be called after a filesystem scan or update of all
+// services completes without errors, once orphaned status reflects every service
+func (s *Scanner) SetOnOrphansUpdated(callback func(scanID int64)) {
+	s.onOrphansUpdated = callback
+}
+
+// SetNotifier sets the notifier that receives scan, orphan threshold and missing file events
+func (s *Scanner) SetNotifier(n *notify.Notifier) {
+	s.notifier = n
+}
+
+// notifyScanFinished sends the scan completed or failed notification for a scan
+// Interrupted scans were stopped on purpose and aren't notified
+func (s *Scanner) notifyScanFinished(ctx context.Context, scan *database.Scan, status string, filesScanned int64, errorMsg *string) {
+	if status == "interrupted" {
+		return
+	}
+
+	result := notify.ScanResult{
+		ScanID:       scan.ID,
+		ScanType:     scan.ScanType,
+		Trigger:      TriggerFromContext(ctx),
+		Status:       status,
+		FilesScanned: filesScanned,
+		Duration:     time.Since(scan.StartedAt),
+	}
+	if errorMsg != nil {
+		result.Error = *errorMsg
+	}
+	s.notifier.Notify(notify.ScanFinished(result))
+}
+
 // Cancel gracefully stops the current scan
 func (s *Scanner) Cancel() bool {
 	if s.cancel != nil {
@@ -101,12 +137,24 @@ func (s *Scanner) ForceStop() bool {
 // RescanFiles rescans specific files immediately
 // This checks if files still exist, updates their metadata, queries all services, and recalculates orphaned status
 func (s *Scanner) RescanFiles(ctx context.Context, paths []string) error {
+	return s.rescanFiles(ctx, paths, api.ConfiguredProviders(&s.config.Services), true)
+}
+
+// RescanFilesForServices rescans specific files, only querying the given services for their usage
+// Files still waiting for the watcher's service update stay pending, as other services may use them
+func (s *Scanner) RescanFilesForServices(ctx context.Context, paths []string, providers []api.Provider) error {
+	return s.rescanFiles(ctx, paths, providers, false)
+}
+
+// rescanFiles rescans paths against providers. When the providers cover every service that can use
+// the paths, settle clears their pending service update marks once all of them were queried
+func (s *Scanner) rescanFiles(ctx context.Context, paths []string, providers []api.Provider, settle bool) error {
 	if len(paths) == 0 {
 		return fmt.Errorf("no paths provided for rescan")
 	}
 
 	// Create scan record
-	scan, err := s.db.CreateScan("file_rescan")
+	scan, err := s.db.CreateScanWithTrigger("file_rescan", TriggerFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to create scan record: %w", err)
 	}
@@ -192,12 +240,22 @@ func (s *Scanner) RescanFiles(ctx context.Context, paths []string) error {
 		}
 	}
 
-	// Query all configured services
+	// Query the services
 	if len(validPaths) > 0 {
-		if err := s.updateAllServicesForPaths(ctx, scan.ID, validPaths); err != nil {
+		if err := s.updateServicesForPaths(ctx, providers, validPaths); err != nil {
 			errMsg := fmt.Sprintf("Failed to update service usage: %v", err)
 			errors = append(errors, errMsg)
 			s.progress.Log(errMsg)
+		} else if settle {
+			settled := make([]string, 0, len(validPaths))
+			for path := range validPaths {
+				settled = append(settled, path)
+			}
+			if err := s.db.ClearServiceUpdateMarks(ctx, settled); err != nil {
+				errMsg := fmt.Sprintf("Failed to clear service update marks: %v", err)
+				errors = append(errors, errMsg)
+				s.progress.Log(errMsg)
+			}
 		}
 	}
 
@@ -262,7 +320,7 @@ func (s *Scanner) Scan(ctx context.Context, incremental bool) error {
 		scanType = "incremental"
 	}
 
-	scan, err := s.db.CreateScan(scanType)
+	scan, err := s.db.CreateScanWithTrigger(scanType, TriggerFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to create scan record: %w", err)
 	}
@@ -312,11 +370,15 @@ func (s *Scanner) Scan(ctx context.Context, incremental bool) error {
 	if err := s.db.UpdateScan(scan.ID, status, s.progress.ProcessedFiles, errorMsg); err != nil {
 		log.Printf("Failed to update scan status: %v", err)
 	}
+	s.notifyScanFinished(ctx, scan, status, s.progress.ProcessedFiles, errorMsg)
 
 	// Call completion callback if set
 	if s.onScanComplete != nil && status == "completed" {
 		s.onScanComplete()
 	}
+	if s.onOrphansUpdated != nil && status == "completed" {
+		s.onOrphansUpdated(scan.ID)
+	}
 
 	// Clear progress object so GetProgress() returns nil
 	// This prevents the UI from showing stale progress after scan completes
@@ -407,11 +469,15 @@ func (s *Scanner) ResumeScan(ctx context.Context) error {
 	if err := s.db.UpdateScan(scan.ID, status, s.progress.ProcessedFiles, errorMsg); err != nil {
 		log.Printf("Failed to update scan status: %v", err)
 	}
+	s.notifyScanFinished(ctx, scan, status, s.progress.ProcessedFiles, errorMsg)
 
 	// Call completion callback if set
 	if s.onScanComplete != nil && status == "completed" {
 		s.onScanComplete()
 	}
+	if s.onOrphansUpdated != nil && status == "completed" {
+		s.onOrphansUpdated(scan.ID)
+	}
 
 	// Clear progress object so GetProgress() returns nil
 	// This prevents the UI from showing stale progress after scan completes
@@ -457,6 +523,9 @@ func (s *Scanner) runScan(ctx context.Context, scanID int64, incremental bool) e
 		return fmt.Errorf("filesystem scan failed: %w", err)
 	}
 
+	// Phase 2.25: Keep the rows of renamed and moved files before missing files are cleaned up
+	s.detectMovedFiles(ctx, scanID)
+
 	// Phase 2.5: Clean up deleted files (only during full scans if auto-cleanup is enabled)
 	if !incremental && s.config.AutoCleanupDeletedFiles {
 		s.updatePhase(scanID, "Cleaning Up Deleted Files")
@@ -477,93 +546,7 @@ func (s *Scanner) runScan(ctx context.Context, scanID int64, incremental bool) e
 	}
 
 	// Phase 3: Update service usage
-	// Count configured services for progress tracking
-	totalServices := 0
-	if s.config.Services.Plex.URL != "" {
-		totalServices++
-	}
-	if s.config.Services.Sonarr.URL != "" {
-		totalServices++
-	}
-	if s.config.Services.Radarr.URL != "" {
-		totalServices++
-	}
-	if s.config.Services.QBittorrent.URL != "" || s.config.Services.QBittorrent.QuiProxyURL != "" {
-		totalServices++
-	}
-	if s.config.Services.Stash.URL != "" {
-		totalServices++
-	}
-	if s.config.Services.Calibre.LibraryPath != "" && s.config.Services.Calibre.DBPath != "" {
-		totalServices++
-	}
-	currentService := 0
-
-	// Update Plex if configured
-	if s.config.Services.Plex.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking Plex")
-		s.progress.Log("Querying Plex for tracked files...")
-		if err := s.updatePlexUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update Plex usage: %v", err))
-		}
-	}
-
-	// Update Sonarr if configured
-	if s.config.Services.Sonarr.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking Sonarr")
-		s.progress.Log("Querying Sonarr for tracked files...")
-		if err := s.updateSonarrUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update Sonarr usage: %v", err))
-		}
-	}
-
-	// Update Radarr if configured
-	if s.config.Services.Radarr.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking Radarr")
-		s.progress.Log("Querying Radarr for tracked files...")
-		if err := s.updateRadarrUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update Radarr usage: %v", err))
-		}
-	}
-
-	// Update qBittorrent if configured
-	if s.config.Services.QBittorrent.URL != "" || s.config.Services.QBittorrent.QuiProxyURL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking qBittorrent")
-		s.progress.Log("Querying qBittorrent for tracked files...")
-		if err := s.updateQBittorrentUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update qBittorrent usage: %v", err))
-		}
-	}
-
-	// Update Stash if configured
-	if s.config.Services.Stash.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking Stash")
-		s.progress.Log("Querying Stash for tracked files...")
-		if err := s.updateStashUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update Stash usage: %v", err))
-		}
-	}
-
-	// Update Calibre if configured
-	if s.config.Services.Calibre.LibraryPath != "" && s.config.Services.Calibre.DBPath != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking Calibre")
-		s.progress.Log("Querying Calibre for tracked files...")
-		if err := s.updateCalibreUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update Calibre usage: %v", err))
-		}
-	}
+	s.updateConfiguredServices(scanID)
 
 	// Phase 4: Update orphaned status
 	s.updatePhase(scanID, "Updating orphaned status")
@@ -573,6 +556,9 @@ func (s *Scanner) runScan(ctx context.Context, scanID int64, incremental bool) e
 		return fmt.Errorf("failed to update orphaned status: %w", err)
 	}
 
+	// Phase 5: Compare with the previous scan
+	s.createScanReport(ctx, scanID)
+
 	// Log path cache performance statistics
 	s.logCacheStats()
 
@@ -648,192 +634,271 @@ func (s *Scanner) runScanWithResume(ctx context.Context, scanID int64, increment
 	}
 	close(checkpointTicker)
 
+	// Moves aren't detected here: files seen before the interruption belong to the interrupted
+	// scan, so they would look missing to this one and a hardlink of theirs like a move
+
 	// Phase 3: Update service usage
-	// Count configured services for progress tracking
-	totalServices := 0
-	if s.config.Services.Plex.URL != "" {
-		totalServices++
-	}
-	if s.config.Services.Sonarr.URL != "" {
-		totalServices++
+	s.updateConfiguredServices(scanID)
+
+	// Phase 4: Update orphaned status
+	s.updatePhase(scanID, "Updating orphaned status")
+	s.progress.Log("Calculating orphaned file status...")
+
+	if err := s.db.UpdateOrphanedStatus(ctx); err != nil {
+		return fmt.Errorf("failed to update orphaned status: %w", err)
 	}
-	if s.config.Services.Radarr.URL != "" {
-		totalServices++
+
+	// Phase 5: Compare with the previous scan
+	s.createScanReport(ctx, scanID)
+
+	// Log path cache performance statistics
+	s.logCacheStats()
+
+	s.updatePhase(scanID, "Completed")
+	s.progress.Log("Scan completed successfully!")
+
+	return nil
+}
+
+// createScanReport records what changed since the end of the previous full or incremental scan
+func (s *Scanner) createScanReport(ctx context.Context, scanID int64) {
+	s.updatePhase(scanID, "Creating scan report")
+
+	report, err := s.db.CreateScanReport(ctx, scanID, constants.ScanReportsKept)
+	if err != nil {
+		s.progress.Log(fmt.Sprintf("Warning: Failed to create scan report: %v", err))
+		return
 	}
-	if s.config.Services.QBittorrent.URL != "" || s.config.Services.QBittorrent.QuiProxyURL != "" {
-		totalServices++
+	if report == nil {
+		return
 	}
-	if s.config.Services.Stash.URL != "" {
-		totalServices++
+
+	s.progress.Log(fmt.Sprintf("Changes since scan #%d: %d added, %d removed, %d modified, %d newly orphaned, %d no longer orphaned, %d newly missing",
+		*report.ComparedToScanID,
+		report.Counts[database.ScanChangeAdded],
+		report.Counts[database.ScanChangeRemoved],
+		report.Counts[database.ScanChangeModified],
+		report.Counts[database.ScanChangeOrphaned],
+		report.Counts[database.ScanChangeUnorphaned],
+		report.Counts[database.ScanChangeMissing]))
+
+	threshold := s.config.Notifications.OrphanThreshold
+	if orphaned := report.Counts[database.ScanChangeOrphaned]; threshold > 0 && orphaned >= threshold {
+		s.notifier.Notify(notify.OrphansIncreased(scanID, orphaned, report.Sizes[database.ScanChangeOrphaned], threshold))
 	}
-	if s.config.Services.Calibre.LibraryPath != "" && s.config.Services.Calibre.DBPath != "" {
-		totalServices++
+	if report.Counts[database.ScanChangeMissing] > 0 {
+		s.notifier.Notify(notify.NewMissingFiles(scanID, report.MissingByService))
 	}
-	currentService := 0
+}
 
-	// Update Plex if configured
-	if s.config.Services.Plex.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking Plex")
-		s.progress.Log("Querying Plex for tracked files...")
-		if err := s.updatePlexUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update Plex usage: %v", err))
-		}
-	}
+// serviceUsageHooks run after a service's usage has been refreshed to associate related
+// files the service doesn't report directly (subtitles, partial downloads, gallery images)
+// They receive the files the service listed and must not contact the service, so that usage can
+// be rebuilt from a stored snapshot
+var serviceUsageHooks = map[string]func(*Scanner, []api.ServiceFile) error{
+	"plex": func(s *Scanner, _ []api.ServiceFile) error {
+		return s.associatePlexSubtitles()
+	},
+	"qbittorrent": func(s *Scanner, _ []api.ServiceFile) error {
+		return s.associateQBittorrentIncompleteFiles()
+	},
+	"transmission": func(s *Scanner, _ []api.ServiceFile) error {
+		return s.associateTransmissionIncompleteFiles()
+	},
+	"stash": (*Scanner).associateStashGalleryImages,
+}
 
-	// Update Sonarr if configured
-	if s.config.Services.Sonarr.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking Sonarr")
-		s.progress.Log("Querying Sonarr for tracked files...")
-		if err := s.updateSonarrUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update Sonarr usage: %v", err))
+// updateConfiguredServices refreshes usage from every configured service as part of a scan
+// Failures are logged and do not abort the scan
+func (s *Scanner) updateConfiguredServices(scanID int64) {
+	providers := api.ConfiguredProviders(&s.config.Services)
+	for i, provider := range providers {
+		s.progress.SetServiceProgress(i+1, len(providers))
+		s.updatePhase(scanID, fmt.Sprintf("Checking %s", provider.DisplayName()))
+		s.progress.Log(fmt.Sprintf("Querying %s for tracked files...", provider.DisplayName()))
+		if err := s.updateProviderUsage(provider); err != nil {
+			s.progress.Log(fmt.Sprintf("Warning: Failed to update %s usage: %v", provider.DisplayName(), err))
 		}
 	}
+}
 
-	// Update Radarr if configured
-	if s.config.Services.Radarr.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking Radarr")
-		s.progress.Log("Querying Radarr for tracked files...")
-		if err := s.updateRadarrUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update Radarr usage: %v", err))
-		}
-	}
+// updateProviderUsage replaces all usage records for a service with its current file list
+// The listing is stored as a snapshot, and the files the service started or stopped using are
+// recorded in the usage history
+func (s *Scanner) updateProviderUsage(provider api.Provider) error {
+	return s.refreshServiceUsage(provider.Name(), true, func(ctx context.Context) ([]api.ServiceFile, error) {
+		return s.listServiceFiles(ctx, provider)
+	})
+}
 
-	// Update qBittorrent if configured
-	if s.config.Services.QBittorrent.URL != "" || s.config.Services.QBittorrent.QuiProxyURL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking qBittorrent")
-		s.progress.Log("Querying qBittorrent for tracked files...")
-		if err := s.updateQBittorrentUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update qBittorrent usage: %v", err))
-		}
+// refreshServiceUsage replaces all usage records for a service with the files getFiles returns and
+// runs the service's usage hook. A live listing must pass the listing safeguards, which record it as
+// the new baseline, and is stored as a snapshot once applied; a replayed snapshot is applied as is
+func (s *Scanner) refreshServiceUsage(service string, live bool, getFiles func(context.Context) ([]api.ServiceFile, error)) error {
+	before, err := s.db.SnapshotServiceUsage(context.Background(), service, nil)
+	if err != nil {
+		log.Printf("WARNING: Failed to snapshot %s usage, its changes will not be recorded: %v", service, err)
+	} else {
+		defer s.recordUsageChanges(service, before, nil)
 	}
 
-	// Update Stash if configured
-	if s.config.Services.Stash.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking Stash")
-		s.progress.Log("Querying Stash for tracked files...")
-		if err := s.updateStashUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update Stash usage: %v", err))
+	var listed []api.ServiceFile
+	err = s.updateServiceUsageWithTimeout(service, func(ctx context.Context) ([]api.ServiceFile, error) {
+		files, err := getFiles(ctx)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	// Update Calibre if configured
-	if s.config.Services.Calibre.LibraryPath != "" && s.config.Services.Calibre.DBPath != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.updatePhase(scanID, "Checking Calibre")
-		s.progress.Log("Querying Calibre for tracked files...")
-		if err := s.updateCalibreUsage(); err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to update Calibre usage: %v", err))
+		if live {
+			if err := s.checkServiceListing(ctx, service, len(files)); err != nil {
+				return nil, err
+			}
 		}
+		listed = files
+		return files, nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Phase 4: Update orphaned status
-	s.updatePhase(scanID, "Updating orphaned status")
-	s.progress.Log("Calculating orphaned file status...")
-
-	if err := s.db.UpdateOrphanedStatus(ctx); err != nil {
-		return fmt.Errorf("failed to update orphaned status: %w", err)
+	if live && len(listed) > 0 {
+		s.saveServiceSnapshot(service, listed)
 	}
 
-	// Log path cache performance statistics
-	s.logCacheStats()
-
-	s.updatePhase(scanID, "Completed")
-	s.progress.Log("Scan completed successfully!")
-
+	if hook, ok := serviceUsageHooks[service]; ok {
+		return hook(s, listed)
+	}
 	return nil
 }
 
-// serviceFile is a generic interface for files from different services
-type serviceFile interface {
-	GetPath() string
-	GetMetadata() map[string]interface{}
-}
+// saveServiceSnapshot stores a service's file listing for later diffing and replay
+// Failures are logged; a missing snapshot doesn't affect the scan
+func (s *Scanner) saveServiceSnapshot(service string, files []api.ServiceFile) {
+	var scanID int64
+	if s.progress != nil {
+		scanID = s.progress.GetScanID()
+	}
 
-// Implement serviceFile for each service type
-type plexServiceFile struct{ api.PlexFile }
+	snapshotFiles := make([]database.SnapshotFile, len(files))
+	for i, f := range files {
+		snapshotFiles[i] = database.SnapshotFile{
+			Path:     f.Path,
+			Size:     f.Size,
+			Group:    f.Group,
+			GroupID:  f.GroupID,
+			Metadata: f.Metadata,
+		}
+	}
 
-func (f plexServiceFile) GetPath() string { return f.Path }
-func (f plexServiceFile) GetMetadata() map[string]interface{} {
-	return map[string]interface{}{
-		"size":         f.Size,
-		"library_name": f.LibraryName,
-		"title":        f.Title,
+	if _, err := s.db.SaveServiceSnapshot(context.Background(), service, scanID, snapshotFiles, constants.ServiceSnapshotsKept); err != nil {
+		log.Printf("WARNING: Failed to save %s snapshot: %v", service, err)
 	}
 }
 
-type sonarrServiceFile struct{ api.SonarrFile }
+// recordUsageChanges records the files a service started, stopped or changed using since before was taken
+// fileIDs limits the comparison to the files the snapshot was taken for. A full refresh of a service
+// that had no usage yet is its first and only sets the baseline, so nothing is recorded for it
+func (s *Scanner) recordUsageChanges(service string, before database.UsageSnapshot, fileIDs []int64) {
+	if fileIDs == nil && len(before) == 0 {
+		return
+	}
+
+	var scanID int64
+	if s.progress != nil {
+		scanID = s.progress.GetScanID()
+	}
+
+	changes, err := s.db.RecordUsageChanges(context.Background(), service, scanID, before, fileIDs)
+	if err != nil {
+		log.Printf("WARNING: Failed to record %s usage history: %v", service, err)
+		return
+	}
 
-func (f sonarrServiceFile) GetPath() string { return f.Path }
-func (f sonarrServiceFile) GetMetadata() map[string]interface{} {
-	return map[string]interface{}{
-		"series_title":   f.SeriesTitle,
-		"season_number":  f.SeasonNumber,
-		"episode_number": f.EpisodeNumber,
-		"episode_id":     f.EpisodeID,
+	if changes.Total() > 0 && s.progress != nil {
+		s.progress.Log(fmt.Sprintf("%s: started using %d files, stopped using %d files, changed %d files",
+			service, changes.Added, changes.Removed, changes.Changed))
 	}
 }
 
-type radarrServiceFile struct{ api.RadarrFile }
+// listServiceFiles collects every page of a service's file listing
+func (s *Scanner) listServiceFiles(ctx context.Context, provider api.Provider) ([]api.ServiceFile, error) {
+	var files []api.ServiceFile
+	err := provider.ListFiles(ctx, s.config, s.config.APITimeout, func(page []api.ServiceFile) error {
+		files = append(files, page...)
+		return nil
+	})
 
-func (f radarrServiceFile) GetPath() string { return f.Path }
-func (f radarrServiceFile) GetMetadata() map[string]interface{} {
-	return map[string]interface{}{
-		"movie_title": f.MovieTitle,
-		"movie_year":  f.MovieYear,
-		"movie_id":    f.MovieID,
+	// Cross-seeded torrents report the same file, which has a single usage record per service
+	if err == nil && provider.Type() == api.ServiceTypeDownloadClient {
+		files = api.MergeCrossSeeds(files)
 	}
+	return files, err
 }
 
-type qbittorrentServiceFile struct{ api.QBittorrentFile }
+// checkServiceListing compares the number of files a service listed with its last accepted listing
+// and records the check. An error means the listing failed a safeguard and the service's usage must
+// be left as it is; the service stays stale until the check is acknowledged
+func (s *Scanner) checkServiceListing(ctx context.Context, serviceName string, count int) error {
+	baseline, hasBaseline, err := s.db.GetServiceBaseline(ctx, serviceName)
+	if err != nil {
+		return err
+	}
 
-func (f qbittorrentServiceFile) GetPath() string { return f.Path }
-func (f qbittorrentServiceFile) GetMetadata() map[string]interface{} {
-	return map[string]interface{}{
-		"torrent_hash": f.TorrentHash,
-		"torrent_name": f.TorrentName,
-		"category":     f.Category,
-		"tags":         f.Tags,
+	var scanID int64
+	if s.progress != nil {
+		scanID = s.progress.GetScanID()
 	}
-}
 
-type stashServiceFile struct{ api.StashFile }
+	check := &database.ServiceCheck{
+		Service:   serviceName,
+		ScanID:    &scanID,
+		Status:    database.ServiceCheckOK,
+		FileCount: count,
+	}
+	if hasBaseline {
+		check.PreviousCount = &baseline
+		check.Reason = safeguardReason(s.config.Safeguards, count, baseline)
+	}
 
-func (f stashServiceFile) GetPath() string { return f.Path }
-func (f stashServiceFile) GetMetadata() map[string]interface{} {
-	return map[string]interface{}{
-		"scene_id": f.SceneID,
-		"title":    f.Title,
-		"studio":   f.Studio,
+	if check.Reason == "" {
+		// Empty listings are never applied, so they don't replace the baseline
+		if count == 0 {
+			return nil
+		}
+		if err := s.db.RecordServiceCheck(ctx, check); err != nil {
+			log.Printf("WARNING: Failed to record %s service check: %v", serviceName, err)
+		}
+		return nil
 	}
-}
 
-type calibreServiceFile struct{ api.CalibreFile }
+	check.Status = database.ServiceCheckStale
+	if err := s.db.RecordServiceCheck(ctx, check); err != nil {
+		return fmt.Errorf("%s %s, and marking it stale failed: %w", serviceName, check.Reason, err)
+	}
+	log.Printf("WARNING: %s %s; keeping its previous usage and blocking orphan-based deletes until this is acknowledged", serviceName, check.Reason)
+	s.notifier.Notify(notify.ServiceStale(scanID, serviceName, check.Reason))
+	return fmt.Errorf("%s %s; keeping its previous usage until this is acknowledged", serviceName, check.Reason)
+}
 
-func (f calibreServiceFile) GetPath() string { return f.Path }
-func (f calibreServiceFile) GetMetadata() map[string]interface{} {
-	return map[string]interface{}{
-		"book_id":      f.BookID,
-		"title":        f.Title,
-		"author":       f.Author,
-		"series":       f.Series,
-		"series_index": f.SeriesIndex,
-		"format":       f.Format,
+// safeguardReason returns why a listing of count files fails the safeguards, given the number of
+// files in the last accepted listing, or "" if it passes
+func safeguardReason(cfg config.SafeguardConfig, count, baseline int) string {
+	if !cfg.Enabled || baseline == 0 {
+		return ""
+	}
+	if count == 0 {
+		return fmt.Sprintf("returned no files, down from %d", baseline)
+	}
+	if cfg.MaxDropPercent > 0 && baseline >= cfg.MinPreviousFiles && count < baseline {
+		drop := float64(baseline-count) / float64(baseline) * 100
+		if drop >= cfg.MaxDropPercent {
+			return fmt.Sprintf("returned %d files, %.0f%% fewer than the %d it last returned", count, drop, baseline)
+		}
 	}
+	return ""
 }
 
 // updateServiceUsage is a generic method to update usage information for any service
-func (s *Scanner) updateServiceUsage(ctx context.Context, serviceName string, files []serviceFile) error {
+func (s *Scanner) updateServiceUsage(ctx context.Context, serviceName string, files []api.ServiceFile) error {
 	if len(files) == 0 {
 		log.Printf("%s: No files returned from service", serviceName)
 		return nil
@@ -849,7 +914,7 @@ func (s *Scanner) updateServiceUsage(ctx context.Context, serviceName string, fi
 
 	// Translate all paths and collect for batch lookup
 	hostPaths := make([]string, 0, len(files))
-	pathToFile := make(map[string]serviceFile)
+	pathToFile := make(map[string]api.ServiceFile)
 
 	for i, file := range files {
 		// Check for context cancellation
@@ -859,7 +924,7 @@ func (s *Scanner) updateServiceUsage(ctx context.Context, serviceName string, fi
 		default:
 		}
 
-		originalPath := file.GetPath()
+		originalPath := file.Path
 		hostPath := s.config.TranslatePathToHost(originalPath, serviceName)
 
 		// Log first few path translations for debugging
@@ -908,69 +973,15 @@ func (s *Scanner) updateServiceUsage(ctx context.Context, serviceName string, fi
 
 			// Track missing file in database if we have a scan ID
 			if scanID > 0 {
-				metadata := file.GetMetadata()
-
-				// Extract service-specific grouping information
-				var serviceGroup, serviceGroupID string
-				var size int64
-
-				switch serviceName {
-				case "qbittorrent":
-					if name, ok := metadata["torrent_name"].(string); ok {
-						serviceGroup = name
-					}
-					if hash, ok := metadata["torrent_hash"].(string); ok {
-						serviceGroupID = hash
-					}
-					if s, ok := metadata["size"].(int64); ok {
-						size = s
-					}
-				case "stash":
-					if title, ok := metadata["title"].(string); ok {
-						serviceGroup = title
-					}
-					if sceneID, ok := metadata["scene_id"].(string); ok {
-						serviceGroupID = sceneID
-					}
-					if s, ok := metadata["size"].(int64); ok {
-						size = s
-					}
-				case "sonarr":
-					if seriesTitle, ok := metadata["series_title"].(string); ok {
-						serviceGroup = seriesTitle
-					}
-					if episodeID, ok := metadata["episode_id"].(float64); ok {
-						serviceGroupID = fmt.Sprintf("%d", int64(episodeID))
-					}
-					if s, ok := metadata["size"].(int64); ok {
-						size = s
-					}
-				case "radarr":
-					if movieTitle, ok := metadata["movie_title"].(string); ok {
-						serviceGroup = movieTitle
-					}
-					if movieID, ok := metadata["movie_id"].(float64); ok {
-						serviceGroupID = fmt.Sprintf("%d", int64(movieID))
-					}
-					if s, ok := metadata["size"].(int64); ok {
-						size = s
-					}
-				case "plex":
-					// Plex doesn't have grouping info in metadata typically
-					if s, ok := metadata["size"].(int64); ok {
-						size = s
-					}
-				}
-
 				missingFile := &database.MissingFile{
 					ScanID:         scanID,
 					Service:        serviceName,
-					ServicePath:    file.GetPath(),
+					ServicePath:    file.Path,
 					TranslatedPath: hostPath,
-					Size:           size,
-					ServiceGroup:   serviceGroup,
-					ServiceGroupID: serviceGroupID,
-					Metadata:       metadata,
+					Size:           file.Size,
+					ServiceGroup:   file.Group,
+					ServiceGroupID: file.GroupID,
+					Metadata:       file.Metadata,
 				}
 
 				if err := s.db.InsertMissingFile(ctx, missingFile); err != nil {
@@ -984,8 +995,8 @@ func (s *Scanner) updateServiceUsage(ctx context.Context, serviceName string, fi
 		usages = append(usages, &database.Usage{
 			FileID:        dbFile.ID,
 			Service:       serviceName,
-			ReferencePath: file.GetPath(),
-			Metadata:      file.GetMetadata(),
+			ReferencePath: file.Path,
+			Metadata:      file.Metadata,
 		})
 	}
 
@@ -1008,7 +1019,10 @@ func (s *Scanner) updateServiceUsage(ctx context.Context, serviceName string, fi
 
 // updateServiceUsageForPaths updates usage for a service, filtering to only specified paths
 // This is used by RescanFiles to update only the rescanned files
-func (s *Scanner) updateServiceUsageForPaths(ctx context.Context, serviceName string, files []serviceFile, pathFilter map[string]bool) error {
+func (s *Scanner) updateServiceUsageForPaths(ctx context.Context, serviceName string, files []api.ServiceFile, pathFilter map[string]bool) error {
+	if err := s.checkServiceListing(ctx, serviceName, len(files)); err != nil {
+		return err
+	}
 	if len(files) == 0 {
 		log.Printf("%s: No files returned from service", serviceName)
 		return nil
@@ -1028,6 +1042,16 @@ func (s *Scanner) updateServiceUsageForPaths(ctx context.Context, serviceName st
 		return fmt.Errorf("failed to get files for deletion: %w", err)
 	}
 
+	fileIDs := make([]int64, 0, len(dbFilesMap))
+	for _, dbFile := range dbFilesMap {
+		fileIDs = append(fileIDs, dbFile.ID)
+	}
+	if before, err := s.db.SnapshotServiceUsage(ctx, serviceName, fileIDs); err != nil {
+		log.Printf("WARNING: Failed to snapshot %s usage, its changes will not be recorded: %v", serviceName, err)
+	} else {
+		defer s.recordUsageChanges(serviceName, before, fileIDs)
+	}
+
 	// Delete existing usage records for these specific files and service
 	for _, dbFile := range dbFilesMap {
 		// Delete only for this service and file
@@ -1040,7 +1064,7 @@ func (s *Scanner) updateServiceUsageForPaths(ctx context.Context, serviceName st
 
 	// Translate all paths and collect for batch lookup - filter to only paths we care about
 	hostPaths := make([]string, 0)
-	pathToFile := make(map[string]serviceFile)
+	pathToFile := make(map[string]api.ServiceFile)
 
 	for _, file := range files {
 		// Check for context cancellation
@@ -1050,7 +1074,7 @@ func (s *Scanner) updateServiceUsageForPaths(ctx context.Context, serviceName st
 		default:
 		}
 
-		originalPath := file.GetPath()
+		originalPath := file.Path
 		hostPath := s.config.TranslatePathToHost(originalPath, serviceName)
 
 		// Only include if this path is in our filter
@@ -1096,8 +1120,8 @@ func (s *Scanner) updateServiceUsageForPaths(ctx context.Context, serviceName st
 		usages = append(usages, &database.Usage{
 			FileID:        dbFile.ID,
 			Service:       serviceName,
-			ReferencePath: file.GetPath(),
-			Metadata:      file.GetMetadata(),
+			ReferencePath: file.Path,
+			Metadata:      file.Metadata,
 		})
 	}
 
@@ -1118,166 +1142,36 @@ func (s *Scanner) updateServiceUsageForPaths(ctx context.Context, serviceName st
 	return nil
 }
 
-// updateAllServicesForPaths queries all configured services and updates usage for specific paths only
-// This is used by RescanFiles to avoid querying all files from services
-func (s *Scanner) updateAllServicesForPaths(ctx context.Context, scanID int64, pathFilter map[string]bool) error {
-	// Count configured services for progress tracking
-	totalServices := 0
-	if s.config.Services.Plex.URL != "" {
-		totalServices++
-	}
-	if s.config.Services.Sonarr.URL != "" {
-		totalServices++
-	}
-	if s.config.Services.Radarr.URL != "" {
-		totalServices++
-	}
-	if s.config.Services.QBittorrent.URL != "" || s.config.Services.QBittorrent.QuiProxyURL != "" {
-		totalServices++
-	}
-	if s.config.Services.Stash.URL != "" {
-		totalServices++
-	}
-	if s.config.Services.Calibre.LibraryPath != "" && s.config.Services.Calibre.DBPath != "" {
-		totalServices++
-	}
-
-	if totalServices == 0 {
+// updateServicesForPaths queries the given services and updates usage for specific paths only
+// This is used by RescanFiles to avoid matching all files from services. Services that fail are
+// skipped, and reported together in the returned error
+func (s *Scanner) updateServicesForPaths(ctx context.Context, providers []api.Provider, pathFilter map[string]bool) error {
+	if len(providers) == 0 {
 		s.progress.Log("No services configured, skipping service updates")
 		return nil
 	}
 
-	currentService := 0
-	s.progress.SetPhase(fmt.Sprintf("Querying %d Services", totalServices))
-
-	// Update Plex if configured
-	if s.config.Services.Plex.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.progress.Log("Querying Plex for tracked files...")
-
-		client := api.NewPlexClient(s.config.Services.Plex.URL, s.config.Services.Plex.Token, s.config.APITimeout)
-		plexFiles, err := client.GetAllFiles(ctx, s.config.Services.Plex.Libraries)
-		if err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to query Plex: %v", err))
-		} else {
-			wrappedFiles := make([]serviceFile, len(plexFiles))
-			for i, f := range plexFiles {
-				wrappedFiles[i] = plexServiceFile{f}
-			}
-			if err := s.updateServiceUsageForPaths(ctx, "plex", wrappedFiles, pathFilter); err != nil {
-				s.progress.Log(fmt.Sprintf("Warning: Failed to update Plex usage: %v", err))
-			}
-		}
-	}
-
-	// Update Sonarr if configured
-	if s.config.Services.Sonarr.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.progress.Log("Querying Sonarr for tracked files...")
+	s.progress.SetPhase(fmt.Sprintf("Querying %d Services", len(providers)))
 
-		client := api.NewSonarrClient(s.config.Services.Sonarr.URL, s.config.Services.Sonarr.APIKey, s.config.APITimeout)
-		sonarrFiles, err := client.GetAllFiles(ctx)
-		if err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to query Sonarr: %v", err))
-		} else {
-			wrappedFiles := make([]serviceFile, len(sonarrFiles))
-			for i, f := range sonarrFiles {
-				wrappedFiles[i] = sonarrServiceFile{f}
-			}
-			if err := s.updateServiceUsageForPaths(ctx, "sonarr", wrappedFiles, pathFilter); err != nil {
-				s.progress.Log(fmt.Sprintf("Warning: Failed to update Sonarr usage: %v", err))
-			}
-		}
-	}
-
-	// Update Radarr if configured
-	if s.config.Services.Radarr.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.progress.Log("Querying Radarr for tracked files...")
-
-		client := api.NewRadarrClient(s.config.Services.Radarr.URL, s.config.Services.Radarr.APIKey, s.config.APITimeout)
-		radarrFiles, err := client.GetAllFiles(ctx)
-		if err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to query Radarr: %v", err))
-		} else {
-			wrappedFiles := make([]serviceFile, len(radarrFiles))
-			for i, f := range radarrFiles {
-				wrappedFiles[i] = radarrServiceFile{f}
-			}
-			if err := s.updateServiceUsageForPaths(ctx, "radarr", wrappedFiles, pathFilter); err != nil {
-				s.progress.Log(fmt.Sprintf("Warning: Failed to update Radarr usage: %v", err))
-			}
-		}
-	}
-
-	// Update qBittorrent if configured
-	if s.config.Services.QBittorrent.URL != "" || s.config.Services.QBittorrent.QuiProxyURL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.progress.Log("Querying qBittorrent for tracked files...")
-
-		client := api.NewQBittorrentClient(s.config.Services.QBittorrent.URL, s.config.Services.QBittorrent.QuiProxyURL, s.config.Services.QBittorrent.Username, s.config.Services.QBittorrent.Password, s.config.APITimeout)
-		qbtFiles, err := client.GetAllFiles(ctx)
-		if err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to query qBittorrent: %v", err))
-		} else {
-			wrappedFiles := make([]serviceFile, len(qbtFiles))
-			for i, f := range qbtFiles {
-				wrappedFiles[i] = qbittorrentServiceFile{f}
-			}
-			if err := s.updateServiceUsageForPaths(ctx, "qbittorrent", wrappedFiles, pathFilter); err != nil {
-				s.progress.Log(fmt.Sprintf("Warning: Failed to update qBittorrent usage: %v", err))
-			}
-		}
-	}
-
-	// Update Stash if configured
-	if s.config.Services.Stash.URL != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.progress.Log("Querying Stash for tracked files...")
+	var failed []error
+	for i, provider := range providers {
+		s.progress.SetServiceProgress(i+1, len(providers))
+		s.progress.Log(fmt.Sprintf("Querying %s for tracked files...", provider.DisplayName()))
 
-		client := api.NewStashClient(s.config.Services.Stash.URL, s.config.Services.Stash.APIKey, s.config.APITimeout)
-		stashFiles, err := client.GetAllFiles(ctx)
+		files, err := s.listServiceFiles(ctx, provider)
 		if err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to query Stash: %v", err))
-		} else {
-			wrappedFiles := make([]serviceFile, len(stashFiles))
-			for i, f := range stashFiles {
-				wrappedFiles[i] = stashServiceFile{f}
-			}
-			if err := s.updateServiceUsageForPaths(ctx, "stash", wrappedFiles, pathFilter); err != nil {
-				s.progress.Log(fmt.Sprintf("Warning: Failed to update Stash usage: %v", err))
-			}
+			s.progress.Log(fmt.Sprintf("Warning: Failed to query %s: %v", provider.DisplayName(), err))
+			failed = append(failed, fmt.Errorf("%s: %w", provider.DisplayName(), err))
+			continue
 		}
-	}
-
-	// Update Calibre if configured
-	if s.config.Services.Calibre.LibraryPath != "" && s.config.Services.Calibre.DBPath != "" {
-		currentService++
-		s.progress.SetServiceProgress(currentService, totalServices)
-		s.progress.Log("Querying Calibre for tracked files...")
-
-		client := api.NewCalibreClient(s.config.Services.Calibre.LibraryPath, s.config.Services.Calibre.DBPath, s.config.APITimeout)
-		calibreFiles, err := client.GetAllFiles(ctx)
-		if err != nil {
-			s.progress.Log(fmt.Sprintf("Warning: Failed to query Calibre: %v", err))
-		} else {
-			wrappedFiles := make([]serviceFile, len(calibreFiles))
-			for i, f := range calibreFiles {
-				wrappedFiles[i] = calibreServiceFile{f}
-			}
-			if err := s.updateServiceUsageForPaths(ctx, "calibre", wrappedFiles, pathFilter); err != nil {
-				s.progress.Log(fmt.Sprintf("Warning: Failed to update Calibre usage: %v", err))
-			}
+		if err := s.updateServiceUsageForPaths(ctx, provider.Name(), files, pathFilter); err != nil {
+			s.progress.Log(fmt.Sprintf("Warning: Failed to update %s usage: %v", provider.DisplayName(), err))
+			failed = append(failed, fmt.Errorf("%s: %w", provider.DisplayName(), err))
 		}
 	}
 
-	s.progress.Log(fmt.Sprintf("Completed querying %d services", totalServices))
-	return nil
+	s.progress.Log(fmt.Sprintf("Completed querying %d services", len(providers)))
+	return errors.Join(failed...)
 }
 
 // scanFilesystem scans the filesystem and processes files
@@ -1316,35 +1210,19 @@ func (s *Scanner) scanFilesystem(ctx context.Context, scanID int64, incremental
 	}
 }
 
-// updatePlexUsage updates usage information from Plex
-func (s *Scanner) updatePlexUsage() error {
-	if s.config.Services.Plex.URL == "" {
-		return nil
-	}
-
-	err := s.updateServiceUsageWithTimeout(
-		"plex",
-		func(ctx context.Context) ([]serviceFile, error) {
-			client := api.NewPlexClient(s.config.Services.Plex.URL, s.config.Services.Plex.Token, s.config.APITimeout)
-			// Pass library filter from config (empty = scan all libraries)
-			files, err := client.GetAllFiles(ctx, s.config.Services.Plex.Libraries)
-			if err != nil {
-				return nil, err
-			}
-			serviceFiles := make([]serviceFile, len(files))
-			for i, f := range files {
-				serviceFiles[i] = plexServiceFile{f}
-			}
-			return serviceFiles, nil
-		},
-	)
+// detectMovedFiles matches files the scan no longer found with new files that are the same file on disk
+// Renames by Sonarr/Radarr then update the existing row instead of deleting it and adding a new one
+func (s *Scanner) detectMovedFiles(ctx context.Context, scanID int64) {
+	s.updatePhase(scanID, "Detecting moved files")
 
+	moved, err := s.db.ApplyScanMoves(ctx, scanID)
 	if err != nil {
-		return err
+		s.progress.Log(fmt.Sprintf("Warning: Failed to detect moved files: %v", err))
+		return
+	}
+	if moved > 0 {
+		s.progress.Log(fmt.Sprintf("Detected %d moved or renamed files", moved))
 	}
-
-	// Associate subtitle files with Plex media
-	return s.associatePlexSubtitles()
 }
 
 // associatePlexSubtitles finds subtitle files associated with Plex media and marks them as used by Plex
@@ -1452,155 +1330,111 @@ func (s *Scanner) associatePlexSubtitles() error {
 	return nil
 }
 
-// updateSonarrUsage updates usage information from Sonarr
-func (s *Scanner) updateSonarrUsage() error {
-	if s.config.Services.Sonarr.URL == "" {
-		return nil
-	}
-
-	return s.updateServiceUsageWithTimeout(
-		"sonarr",
-		func(ctx context.Context) ([]serviceFile, error) {
-			client := api.NewSonarrClient(s.config.Services.Sonarr.URL, s.config.Services.Sonarr.APIKey, s.config.APITimeout)
-			files, err := client.GetAllFiles(ctx)
-			if err != nil {
-				return nil, err
-			}
-			serviceFiles := make([]serviceFile, len(files))
-			for i, f := range files {
-				serviceFiles[i] = sonarrServiceFile{f}
-			}
-			return serviceFiles, nil
-		},
-	)
-}
-
-// updateRadarrUsage updates usage information from Radarr
-func (s *Scanner) updateRadarrUsage() error {
-	if s.config.Services.Radarr.URL == "" {
-		return nil
-	}
-
-	return s.updateServiceUsageWithTimeout(
-		"radarr",
-		func(ctx context.Context) ([]serviceFile, error) {
-			client := api.NewRadarrClient(s.config.Services.Radarr.URL, s.config.Services.Radarr.APIKey, s.config.APITimeout)
-			files, err := client.GetAllFiles(ctx)
-			if err != nil {
-				return nil, err
-			}
-			serviceFiles := make([]serviceFile, len(files))
-			for i, f := range files {
-				serviceFiles[i] = radarrServiceFile{f}
-			}
-			return serviceFiles, nil
-		},
-	)
+// associateQBittorrentIncompleteFiles finds incomplete download files (.!qB) and marks them as used by qBittorrent
+// qBittorrent adds .!qB extension to files during download, then removes it when complete
+func (s *Scanner) associateQBittorrentIncompleteFiles() error {
+	return s.associateIncompleteDownloads("qbittorrent", ".!qb")
 }
 
-// updateQBittorrentUsage updates usage information from qBittorrent
-func (s *Scanner) updateQBittorrentUsage() error {
-	qbConfig := s.config.Services.QBittorrent
-	if qbConfig.URL == "" && qbConfig.QuiProxyURL == "" {
-		return nil
-	}
-
-	err := s.updateServiceUsageWithTimeout(
-		"qbittorrent",
-		func(ctx context.Context) ([]serviceFile, error) {
-			client := api.NewQBittorrentClient(qbConfig.URL, qbConfig.Username, qbConfig.Password, qbConfig.QuiProxyURL, s.config.APITimeout)
-			files, err := client.GetAllFiles(ctx)
-			if err != nil {
-				return nil, err
-			}
-			serviceFiles := make([]serviceFile, len(files))
-			for i, f := range files {
-				serviceFiles[i] = qbittorrentServiceFile{f}
-			}
-			return serviceFiles, nil
-		},
-	)
-
-	if err != nil {
-		return err
-	}
-
-	// Associate incomplete download files (.!qB) with qBittorrent
-	return s.associateQBittorrentIncompleteFiles()
+// associateTransmissionIncompleteFiles finds incomplete download files (.part) and marks them as used by Transmission
+// Transmission appends .part while downloading when "rename partial files" is enabled (the default)
+func (s *Scanner) associateTransmissionIncompleteFiles() error {
+	return s.associateIncompleteDownloads("transmission", ".part")
 }
 
-// associateQBittorrentIncompleteFiles finds incomplete download files (.!qB) and marks them as used by qBittorrent
-// qBittorrent adds .!qB extension to files during download, then removes it when complete
-// The API reports the final filename (without .!qB), so we need to match incomplete files to active torrents
-func (s *Scanner) associateQBittorrentIncompleteFiles() error {
+// associateIncompleteDownloads matches files carrying a download client's partial-file suffix to the
+// files the client reports. The API reports the final filename (without the suffix), so incomplete
+// files are matched by stripping it
+func (s *Scanner) associateIncompleteDownloads(service, suffix string) error {
 	ctx := context.Background()
 	if s.scanCtx != nil {
 		ctx = s.scanCtx
 	}
 
-	log.Printf("qBittorrent: Associating incomplete download files (.!qB)")
+	name := api.DisplayName(service)
+	log.Printf("%s: Associating incomplete download files (%s)", name, suffix)
 
-	// Get all files currently tracked by qBittorrent (these are the final filenames)
-	qbFiles, err := s.db.GetFilesByService(ctx, "qbittorrent")
+	// Get all files currently tracked by the client (these are the final filenames)
+	clientFiles, err := s.db.GetFilesByService(ctx, service)
 	if err != nil {
-		return fmt.Errorf("failed to get qBittorrent files: %w", err)
+		return fmt.Errorf("failed to get %s files: %w", name, err)
 	}
 
-	if len(qbFiles) == 0 {
-		log.Printf("qBittorrent: No files found in active torrents, skipping .!qB association")
-		return nil
+	// Build a map of client file paths for quick lookup
+	clientFilePaths := make(map[string]bool)
+	for _, clientFile := range clientFiles {
+		clientFilePaths[clientFile.Path] = true
 	}
 
-	log.Printf("qBittorrent: Found %d files in active torrents, searching for .!qB files", len(qbFiles))
+	// Files still downloading don't exist under their final name yet, so they were
+	// recorded as missing for this scan rather than as usage
+	var scanID int64
+	if s.progress != nil {
+		scanID = s.progress.GetScanID()
+	}
+	if scanID > 0 {
+		missingFiles, err := s.db.GetMissingFilesByScan(ctx, scanID)
+		if err != nil {
+			return fmt.Errorf("failed to get missing %s files: %w", name, err)
+		}
+		for _, missing := range missingFiles {
+			if missing.Service == service {
+				clientFilePaths[missing.TranslatedPath] = true
+			}
+		}
+	}
 
-	// Build a map of qBittorrent file paths for quick lookup
-	qbFilePaths := make(map[string]bool)
-	for _, qbFile := range qbFiles {
-		qbFilePaths[qbFile.Path] = true
+	if len(clientFilePaths) == 0 {
+		log.Printf("%s: No files found in active torrents, skipping %s association", name, suffix)
+		return nil
 	}
 
-	// Query for all .!qB files in the database
-	// Note: Extensions are stored as compound extensions (.mkv.!qb, .mp4.!qb, etc.)
-	// So we need to search for files where extension ends with .!qb
-	incompleteFiles, err := s.db.GetFilesByExtensionSuffix(ctx, ".!qb")
+	log.Printf("%s: Found %d files in active torrents, searching for %s files", name, len(clientFilePaths), suffix)
+
+	// Query for all incomplete files in the database
+	// Note: Extensions are stored as compound lowercase extensions (.mkv.!qb, .mkv.part, etc.)
+	// So we need to search for files where extension ends with the suffix
+	incompleteFiles, err := s.db.GetFilesByExtensionSuffix(ctx, suffix)
 	if err != nil {
-		return fmt.Errorf("failed to query .!qB files: %w", err)
+		return fmt.Errorf("failed to query %s files: %w", suffix, err)
 	}
 
 	if len(incompleteFiles) == 0 {
-		log.Printf("qBittorrent: No .!qB files found in database")
+		log.Printf("%s: No %s files found in database", name, suffix)
 		return nil
 	}
 
-	log.Printf("qBittorrent: Found %d .!qB files, matching to active torrents...", len(incompleteFiles))
+	log.Printf("%s: Found %d %s files, matching to active torrents...", name, len(incompleteFiles), suffix)
 
-	// Match .!qB files to qBittorrent files by stripping the .!qb extension
+	// Match incomplete files to client files by stripping the suffix
 	var matchedIncomplete []*database.File
 	for _, incompleteFile := range incompleteFiles {
-		// Strip .!qb extension to get the expected final filename
-		// e.g., /downloads/Movie.mkv.!qb -> /downloads/Movie.mkv
-		// Note: Extensions are stored lowercase, so trim lowercase .!qb
-		expectedPath := strings.TrimSuffix(incompleteFile.Path, ".!qb")
+		// e.g., /downloads/Movie.mkv.!qB -> /downloads/Movie.mkv
+		// The path keeps its original case, so strip by length rather than by the lowercase suffix
+		if len(incompleteFile.Path) <= len(suffix) {
+			continue
+		}
+		expectedPath := incompleteFile.Path[:len(incompleteFile.Path)-len(suffix)]
 
-		// Check if qBittorrent has this file in its active torrents
-		if qbFilePaths[expectedPath] {
+		// Check if the client has this file in its active torrents
+		if clientFilePaths[expectedPath] {
 			matchedIncomplete = append(matchedIncomplete, incompleteFile)
 		}
 	}
 
 	if len(matchedIncomplete) == 0 {
-		log.Printf("qBittorrent: No .!qB files matched to active torrents (all may be orphaned)")
+		log.Printf("%s: No %s files matched to active torrents (all may be orphaned)", name, suffix)
 		return nil
 	}
 
-	log.Printf("qBittorrent: Matched %d .!qB files to active torrents, creating usage records", len(matchedIncomplete))
+	log.Printf("%s: Matched %d %s files to active torrents, creating usage records", name, len(matchedIncomplete), suffix)
 
 	// Create usage records for matched incomplete files
 	var usages []*database.Usage
 	for _, file := range matchedIncomplete {
 		usages = append(usages, &database.Usage{
 			FileID:  file.ID,
-			Service: "qbittorrent",
+			Service: service,
 			Metadata: map[string]interface{}{
 				"status": "downloading",
 				"type":   "incomplete",
@@ -1610,16 +1444,17 @@ func (s *Scanner) associateQBittorrentIncompleteFiles() error {
 
 	// Batch upsert usage records
 	if err := s.db.BatchUpsertUsage(ctx, usages); err != nil {
-		return fmt.Errorf("failed to create usage records for .!qB files: %w", err)
+		return fmt.Errorf("failed to create usage records for %s files: %w", suffix, err)
 	}
 
-	log.Printf("qBittorrent: Successfully associated %d incomplete download files", len(usages))
+	log.Printf("%s: Successfully associated %d incomplete download files", name, len(usages))
 	return nil
 }
 
 // associateStashGalleryImages finds image files in Stash gallery folders and marks them as used by Stash
 // Stash gallery folders are tracked by folder path, but individual images aren't returned by the API
-func (s *Scanner) associateStashGalleryImages() error {
+// stashFiles is the Stash file listing, which includes the gallery folders
+func (s *Scanner) associateStashGalleryImages(stashFiles []api.ServiceFile) error {
 	ctx := context.Background()
 	if s.scanCtx != nil {
 		ctx = s.scanCtx
@@ -1627,14 +1462,8 @@ func (s *Scanner) associateStashGalleryImages() error {
 
 	log.Printf("stash: Associating gallery images with folders")
 
-	// Query Stash API directly to get gallery folders
-	// Note: Gallery folder paths are NOT in the database (only actual files are scanned)
-	client := api.NewStashClient(s.config.Services.Stash.URL, s.config.Services.Stash.APIKey, s.config.APITimeout)
-	stashFiles, err := client.GetAllFiles(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get Stash files from API: %w", err)
-	}
-
+	// Gallery folder paths are NOT in the database (only actual files are scanned), so they
+	// come from the listing
 	if len(stashFiles) == 0 {
 		log.Printf("stash: No files found in Stash, skipping gallery image association")
 		return nil
@@ -1657,7 +1486,7 @@ func (s *Scanner) associateStashGalleryImages() error {
 		return nil
 	}
 
-	log.Printf("stash: Found %d gallery folders from Stash API, searching for images", len(galleryFolders))
+	log.Printf("stash: Found %d gallery folders in Stash listing, searching for images", len(galleryFolders))
 
 	// Debug: Log first few gallery folders
 	for i := 0; i < len(galleryFolders) && i < 3; i++ {
@@ -1724,61 +1553,9 @@ func (s *Scanner) associateStashGalleryImages() error {
 	return nil
 }
 
-// updateStashUsage updates usage information from Stash
-func (s *Scanner) updateStashUsage() error {
-	if s.config.Services.Stash.URL == "" {
-		return nil
-	}
-
-	err := s.updateServiceUsageWithTimeout(
-		"stash",
-		func(ctx context.Context) ([]serviceFile, error) {
-			client := api.NewStashClient(s.config.Services.Stash.URL, s.config.Services.Stash.APIKey, s.config.APITimeout)
-			files, err := client.GetAllFiles(ctx)
-			if err != nil {
-				return nil, err
-			}
-			serviceFiles := make([]serviceFile, len(files))
-			for i, f := range files {
-				serviceFiles[i] = stashServiceFile{f}
-			}
-			return serviceFiles, nil
-		},
-	)
-
-	if err != nil {
-		return err
-	}
-
-	// Associate gallery images with Stash
-	return s.associateStashGalleryImages()
-}
-
-func (s *Scanner) updateCalibreUsage() error {
-	if s.config.Services.Calibre.LibraryPath == "" || s.config.Services.Calibre.DBPath == "" {
-		return nil
-	}
-
-	return s.updateServiceUsageWithTimeout(
-		"calibre",
-		func(ctx context.Context) ([]serviceFile, error) {
-			client := api.NewCalibreClient(s.config.Services.Calibre.LibraryPath, s.config.Services.Calibre.DBPath, s.config.APITimeout)
-			files, err := client.GetAllFiles(ctx)
-			if err != nil {
-				return nil, err
-			}
-			serviceFiles := make([]serviceFile, len(files))
-			for i, f := range files {
-				serviceFiles[i] = calibreServiceFile{f}
-			}
-			return serviceFiles, nil
-		},
-	)
-}
-
 // updateServiceUsageWithTimeout is a generic helper to update service usage with timeout handling
 // This eliminates duplication across all service update methods
-func (s *Scanner) updateServiceUsageWithTimeout(serviceName string, getFiles func(context.Context) ([]serviceFile, error)) error {
+func (s *Scanner) updateServiceUsageWithTimeout(serviceName string, getFiles func(context.Context) ([]api.ServiceFile, error)) error {
 	// Use scan context if available (for cancellation during full scans), otherwise use Background (for manual updates)
 	baseCtx := s.scanCtx
 	if baseCtx == nil {
@@ -1865,11 +1642,11 @@ func (s *Scanner) initializeProgressTotal() {
 	}
 }
 
-// UpdateAllServices manually updates all service usage information
+// UpdateAllServices updates all service usage information
 // This can be called independently without a full scan
-func (s *Scanner) UpdateAllServices() error {
+func (s *Scanner) UpdateAllServices(ctx context.Context) error {
 	// Create scan record
-	scan, err := s.db.CreateScan("service_update_all")
+	scan, err := s.db.CreateScanWithTrigger("service_update_all", TriggerFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to create scan record: %w", err)
 	}
@@ -1884,53 +1661,27 @@ func (s *Scanner) UpdateAllServices() error {
 		tempProgress.Stop()
 	}()
 
-	s.progress.Log("Manually updating all services...")
+	s.progress.Log("Updating all services...")
 
 	// Track if we had any errors
 	hadErrors := false
 
-	// Update each service
-	s.progress.Log("Checking Plex...")
-	if err := s.updatePlexUsage(); err != nil {
-		s.progress.Log(fmt.Sprintf("Warning: Failed to update Plex usage: %v", err))
-		hadErrors = true
-	}
-
-	s.progress.Log("Checking Sonarr...")
-	if err := s.updateSonarrUsage(); err != nil {
-		s.progress.Log(fmt.Sprintf("Warning: Failed to update Sonarr usage: %v", err))
-		hadErrors = true
-	}
-
-	s.progress.Log("Checking Radarr...")
-	if err := s.updateRadarrUsage(); err != nil {
-		s.progress.Log(fmt.Sprintf("Warning: Failed to update Radarr usage: %v", err))
-		hadErrors = true
-	}
-
-	s.progress.Log("Checking qBittorrent...")
-	if err := s.updateQBittorrentUsage(); err != nil {
-		s.progress.Log(fmt.Sprintf("Warning: Failed to update qBittorrent usage: %v", err))
-		hadErrors = true
-	}
-
-	s.progress.Log("Checking Stash...")
-	if err := s.updateStashUsage(); err != nil {
-		s.progress.Log(fmt.Sprintf("Warning: Failed to update Stash usage: %v", err))
-		hadErrors = true
-	}
-
-	s.progress.Log("Checking Calibre...")
-	if err := s.updateCalibreUsage(); err != nil {
-		s.progress.Log(fmt.Sprintf("Warning: Failed to update Calibre usage: %v", err))
-		hadErrors = true
+	// Update each configured service
+	for _, provider := range api.ConfiguredProviders(&s.config.Services) {
+		s.progress.Log(fmt.Sprintf("Checking %s...", provider.DisplayName()))
+		if err := s.updateProviderUsage(provider); err != nil {
+			s.progress.Log(fmt.Sprintf("Warning: Failed to update %s usage: %v", provider.DisplayName(), err))
+			hadErrors = true
+		}
 	}
 
 	// Update orphaned status after service checks
 	s.progress.Log("Recalculating orphaned status...")
-	if err := s.db.UpdateOrphanedStatus(context.Background()); err != nil {
+	if err := s.db.UpdateOrphanedStatus(ctx); err != nil {
 		// Mark scan as failed
-		s.db.UpdateScanStatus(scan.ID, "failed", fmt.Sprintf("Failed to update orphaned status: %v", err))
+		errorMsg := fmt.Sprintf("Failed to update orphaned status: %v", err)
+		s.db.UpdateScanStatus(scan.ID, "failed", errorMsg)
+		s.notifyScanFinished(ctx, scan, "failed", 0, &errorMsg)
 		return fmt.Errorf("failed to update orphaned status: %w", err)
 	}
 
@@ -1942,14 +1693,24 @@ func (s *Scanner) UpdateAllServices() error {
 	if err := s.db.CompleteScan(scan.ID, status, ""); err != nil {
 		log.Printf("Warning: Failed to complete scan record: %v", err)
 	}
+	s.notifyScanFinished(ctx, scan, status, 0, nil)
+
+	if s.onOrphansUpdated != nil && status == "completed" {
+		s.onOrphansUpdated(scan.ID)
+	}
 
 	s.progress.Log("All services updated successfully!")
 	return nil
 }
 
 // UpdateSingleService manually updates a specific service's usage information
-// serviceName should be one of: plex, sonarr, radarr, qbittorrent
+// serviceName must be the name of a registered provider
 func (s *Scanner) UpdateSingleService(serviceName string) error {
+	provider := api.Lookup(serviceName)
+	if provider == nil {
+		return fmt.Errorf("unknown service: %s", serviceName)
+	}
+
 	// Create scan record
 	scanType := fmt.Sprintf("service_update_%s", serviceName)
 	scan, err := s.db.CreateScan(scanType)
@@ -1970,23 +1731,10 @@ func (s *Scanner) UpdateSingleService(serviceName string) error {
 	s.progress.Log(fmt.Sprintf("Manually updating %s...", serviceName))
 
 	var updateErr error
-	switch serviceName {
-	case "plex":
-		updateErr = s.updatePlexUsage()
-	case "sonarr":
-		updateErr = s.updateSonarrUsage()
-	case "radarr":
-		updateErr = s.updateRadarrUsage()
-	case "qbittorrent":
-		updateErr = s.updateQBittorrentUsage()
-	case "stash":
-		updateErr = s.updateStashUsage()
-	case "calibre":
-		updateErr = s.updateCalibreUsage()
-	default:
-		errMsg := fmt.Sprintf("unknown service: %s", serviceName)
-		s.db.UpdateScanStatus(scan.ID, "failed", errMsg)
-		return fmt.Errorf("unknown service: %s", serviceName)
+	if provider.Configured(&s.config.Services) {
+		updateErr = s.updateProviderUsage(provider)
+	} else {
+		s.progress.Log(fmt.Sprintf("%s is not configured, skipping", provider.DisplayName()))
 	}
 
 	if updateErr != nil {
@@ -2012,6 +1760,93 @@ func (s *Scanner) UpdateSingleService(serviceName string) error {
 	return nil
 }
 
+// ReplayServiceSnapshot rebuilds a service's usage from a stored snapshot instead of contacting the
+// service, then recalculates orphaned status. The replay is recorded as a service update scan
+func (s *Scanner) ReplayServiceSnapshot(ctx context.Context, snapshotID int64) error {
+	snapshot, err := s.db.GetServiceSnapshot(ctx, snapshotID)
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot %d: %w", snapshotID, err)
+	}
+
+	currentScan, err := s.db.GetCurrentScan()
+	if err != nil {
+		return fmt.Errorf("failed to check for running scan: %w", err)
+	}
+	if currentScan != nil {
+		return fmt.Errorf("cannot replay a snapshot while another scan is running (ID: %d)", currentScan.ID)
+	}
+
+	scan, err := s.db.CreateScanWithTrigger(fmt.Sprintf("service_update_%s", snapshot.Service), TriggerFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to create scan record: %w", err)
+	}
+
+	tempProgress := NewProgress(scan.ID, s.db)
+	tempProgress.SetPhase(fmt.Sprintf("Replaying %s snapshot", snapshot.Service))
+	originalProgress := s.progress
+	s.progress = tempProgress
+	defer func() {
+		s.progress = originalProgress
+		tempProgress.Stop()
+	}()
+
+	s.progress.Log(fmt.Sprintf("Replaying %s snapshot #%d from %s (%d files)...",
+		snapshot.Service, snapshot.ID, snapshot.CreatedAt.Format("2006-01-02 15:04"), snapshot.FileCount))
+
+	fail := func(msg string) error {
+		s.db.UpdateScanStatus(scan.ID, "failed", msg)
+		s.notifyScanFinished(ctx, scan, "failed", 0, &msg)
+		return errors.New(msg)
+	}
+
+	// The snapshot must pass the listing safeguards too, but it isn't a new listing of the service,
+	// so it's only compared with the baseline and never replaces it
+	baseline, hasBaseline, err := s.db.GetServiceBaseline(ctx, snapshot.Service)
+	if err != nil {
+		return fail(fmt.Sprintf("Failed to check %s snapshot: %v", snapshot.Service, err))
+	}
+	if hasBaseline {
+		if reason := safeguardReason(s.config.Safeguards, snapshot.FileCount, baseline); reason != "" {
+			return fail(fmt.Sprintf("%s snapshot #%d has %d files and fails the listing safeguards against the %d %s last returned",
+				snapshot.Service, snapshot.ID, snapshot.FileCount, baseline, snapshot.Service))
+		}
+	}
+
+	err = s.refreshServiceUsage(snapshot.Service, false, func(ctx context.Context) ([]api.ServiceFile, error) {
+		snapshotFiles, err := s.db.GetServiceSnapshotFiles(ctx, snapshot.ID)
+		if err != nil {
+			return nil, err
+		}
+		files := make([]api.ServiceFile, len(snapshotFiles))
+		for i, f := range snapshotFiles {
+			files[i] = api.ServiceFile{
+				Path:     f.Path,
+				Size:     f.Size,
+				Group:    f.Group,
+				GroupID:  f.GroupID,
+				Metadata: f.Metadata,
+			}
+		}
+		return files, nil
+	})
+	if err != nil {
+		return fail(fmt.Sprintf("Failed to replay %s snapshot: %v", snapshot.Service, err))
+	}
+
+	s.progress.Log("Recalculating orphaned status...")
+	if err := s.db.UpdateOrphanedStatus(ctx); err != nil {
+		return fail(fmt.Sprintf("Failed to update orphaned status: %v", err))
+	}
+
+	if err := s.db.CompleteScan(scan.ID, "completed", ""); err != nil {
+		log.Printf("Warning: Failed to complete scan record: %v", err)
+	}
+	s.notifyScanFinished(ctx, scan, "completed", int64(snapshot.FileCount), nil)
+
+	s.progress.Log(fmt.Sprintf("%s usage rebuilt from snapshot #%d", snapshot.Service, snapshot.ID))
+	return nil
+}
+
 // RecalculateOrphanedStatus manually recalculates which files are orphaned
 // This can be called independently without updating services
 func (s *Scanner) RecalculateOrphanedStatus() error {
@@ -2116,8 +1951,8 @@ func (s *Scanner) ScanDiskLocations(detector *disk.Detector) error {
 }
 
 // RunCleanupScan walks the filesystem and removes database entries for files that no longer exist
-// This is a manual cleanup operation that can be run independently of full scans
-func (s *Scanner) RunCleanupScan() error {
+// This cleanup operation can be run independently of full scans
+func (s *Scanner) RunCleanupScan(ctx context.Context) error {
 	// Check if there's already a running scan
 	currentScan, err := s.db.GetCurrentScan()
 	if err != nil {
@@ -2129,7 +1964,7 @@ func (s *Scanner) RunCleanupScan() error {
 	}
 
 	// Create scan record with type 'cleanup'
-	scan, err := s.db.CreateScan("cleanup")
+	scan, err := s.db.CreateScanWithTrigger("cleanup", TriggerFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to create cleanup scan record: %w", err)
 	}
@@ -2148,7 +1983,7 @@ func (s *Scanner) RunCleanupScan() error {
 		}
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// Store scan context
@@ -0,0 +1,29 @@
+package scanner
+
+import "context"
+
+// Trigger sources recorded on scan records
+const (
+	TriggerManual    = "manual"    // Started from the web UI, API or CLI
+	TriggerScheduler = "scheduler" // Started by the built-in cron scheduler
+	TriggerWatcher   = "watcher"   // Started by the filesystem watcher to re-check changed files
+	TriggerWebhook   = "webhook"   // Started by a service webhook to re-check the files it reported
+)
+
+// triggerKey is the context key for the trigger source
+type triggerKey struct{}
+
+// WithTrigger returns a context that records what triggered a scan
+func WithTrigger(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, triggerKey{}, source)
+}
+
+// TriggerFromContext returns the trigger source stored in ctx, defaulting to manual
+func TriggerFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if source, ok := ctx.Value(triggerKey{}).(string); ok && source != "" {
+			return source
+		}
+	}
+	return TriggerManual
+}
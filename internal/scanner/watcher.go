@@ -0,0 +1,457 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/api"
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// watchOp is the kind of change reported by the platform watcher
+type watchOp int
+
+const (
+	watchChanged    watchOp = iota // File created, written or had its attributes changed
+	watchRemoved                   // File or directory deleted, or moved out of the watched paths
+	watchRenamed                   // File or directory moved within a watched path (OldPath -> Path)
+	watchDirAdded                  // Directory created or moved in, its contents need scanning
+	watchDirChanged                // Events for the directory's entries were dropped, only its direct entries need checking
+)
+
+// watchEvent is a single filesystem change
+type watchEvent struct {
+	Op      watchOp
+	Path    string
+	OldPath string
+	IsDir   bool
+}
+
+const (
+	// watchEventBuffer is how many events can queue between the platform watcher and the watcher loop
+	watchEventBuffer = 4096
+
+	// watchBatchSize is the BatchAccumulator size used when writing coalesced changes
+	watchBatchSize = 100
+
+	// maxReassociateFiles bounds how many changed files are matched against services per pass
+	maxReassociateFiles = 10000
+)
+
+// Watcher keeps the files table up to date from filesystem change events on the scan paths
+// Bursts of events are coalesced for the debounce interval, then written through a BatchAccumulator.
+// Changed files are flagged for a service update, which keeps them pending rather than orphaned,
+// and are periodically re-associated with the services that can use them
+type Watcher struct {
+	scanner *Scanner
+	db      *database.DB
+	config  *config.Config
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// Pending changes, only used by the run goroutine
+	changed     map[string]bool // Files to upsert, or delete if they no longer exist
+	removedDirs map[string]bool // Directories deleted or moved out
+	rescanDirs  map[string]bool // Directories to walk (new directories)
+	checkDirs   map[string]bool // Directories whose direct entries need checking (queue overflows)
+}
+
+// NewWatcher creates a filesystem watcher that records changes with the scanner's database
+func NewWatcher(s *Scanner) *Watcher {
+	return &Watcher{
+		scanner: s,
+		db:      s.db,
+		config:  s.config,
+	}
+}
+
+// Start begins watching the configured scan paths in the background
+func (w *Watcher) Start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel != nil {
+		return nil // Already running
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan watchEvent, watchEventBuffer)
+	if err := watchPaths(ctx, w.config.ScanPaths, events); err != nil {
+		cancel()
+		return fmt.Errorf("failed to watch scan paths: %w", err)
+	}
+
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	go w.run(ctx, events, w.done)
+
+	log.Printf("Filesystem watcher started on %d scan paths", len(w.config.ScanPaths))
+	return nil
+}
+
+// Stop halts the watcher after writing any pending changes
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel = nil
+	w.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+	log.Printf("Filesystem watcher stopped")
+}
+
+// Running reports whether the watcher is active
+func (w *Watcher) Running() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.cancel != nil
+}
+
+// Apply starts or stops the watcher to match the current config
+// A running watcher is restarted so changed scan paths are picked up
+func (w *Watcher) Apply() error {
+	w.Stop()
+	if !w.config.Watcher.Enabled {
+		return nil
+	}
+	return w.Start()
+}
+
+// run applies events until ctx is cancelled
+func (w *Watcher) run(ctx context.Context, events <-chan watchEvent, done chan<- struct{}) {
+	defer close(done)
+
+	w.changed = make(map[string]bool)
+	w.removedDirs = make(map[string]bool)
+	w.rescanDirs = make(map[string]bool)
+	w.checkDirs = make(map[string]bool)
+
+	reassociate := time.NewTicker(w.config.Watcher.ReassociateInterval)
+	defer reassociate.Stop()
+
+	// Armed by the first event after a flush, so a burst is written once the debounce interval passes
+	var flush <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Don't lose changes that were already seen
+			if err := w.flush(context.Background()); err != nil {
+				log.Printf("Watcher: Failed to write pending changes: %v", err)
+			}
+			return
+
+		case ev := <-events:
+			w.record(ctx, ev)
+			if flush == nil {
+				flush = time.After(w.config.Watcher.DebounceInterval)
+			}
+
+		case <-flush:
+			flush = nil
+			if err := w.flush(ctx); err != nil {
+				log.Printf("Watcher: Failed to write changes: %v", err)
+			}
+
+		case <-reassociate.C:
+			w.reassociate(ctx)
+		}
+	}
+}
+
+// record adds an event to the pending changes
+// Renames are applied immediately so later events for the new path find the moved rows
+func (w *Watcher) record(ctx context.Context, ev watchEvent) {
+	switch ev.Op {
+	case watchChanged:
+		w.changed[ev.Path] = true
+	case watchRemoved:
+		if ev.IsDir {
+			w.removedDirs[ev.Path] = true
+		} else {
+			w.changed[ev.Path] = true
+		}
+	case watchRenamed:
+		w.rename(ctx, ev.OldPath, ev.Path)
+	case watchDirAdded:
+		w.rescanDirs[ev.Path] = true
+	case watchDirChanged:
+		w.checkDirs[ev.Path] = true
+	}
+}
+
+// rename moves recorded files and pending changes from oldPath to newPath
+func (w *Watcher) rename(ctx context.Context, oldPath, newPath string) {
+	for _, pending := range []map[string]bool{w.changed, w.removedDirs, w.rescanDirs, w.checkDirs} {
+		for path := range pending {
+			if moved, ok := movePath(path, oldPath, newPath); ok {
+				delete(pending, path)
+				pending[moved] = true
+			}
+		}
+	}
+
+	renamed, err := w.db.RenameFilePath(ctx, oldPath, newPath)
+	if err != nil {
+		log.Printf("Watcher: Failed to rename %s to %s, rescanning instead: %v", oldPath, newPath, err)
+		w.changed[oldPath] = true
+		w.removedDirs[oldPath] = true
+		w.rescanDirs[newPath] = true
+		return
+	}
+	if renamed > 0 {
+		log.Printf("Watcher: Moved %d files from %s to %s", renamed, oldPath, newPath)
+	}
+}
+
+// movePath returns path with the oldPath prefix replaced by newPath, if path is oldPath or inside it
+func movePath(path, oldPath, newPath string) (string, bool) {
+	if path == oldPath {
+		return newPath, true
+	}
+	if rest, ok := strings.CutPrefix(path, oldPath+string(filepath.Separator)); ok {
+		return filepath.Join(newPath, rest), true
+	}
+	return "", false
+}
+
+// flush writes the pending changes to the database
+func (w *Watcher) flush(ctx context.Context) error {
+	if len(w.changed) == 0 && len(w.removedDirs) == 0 && len(w.rescanDirs) == 0 && len(w.checkDirs) == 0 {
+		return nil
+	}
+
+	// Directories that are really gone take every file recorded under them
+	var goneDirs []string
+	for dir := range w.removedDirs {
+		if _, err := os.Lstat(dir); os.IsNotExist(err) {
+			goneDirs = append(goneDirs, dir)
+		}
+	}
+	removed, err := w.db.DeleteFilesByPaths(ctx, goneDirs, "Directory removed (filesystem watcher)")
+	if err != nil {
+		return err
+	}
+
+	// Walked directories re-check both what is on disk and what is recorded under them
+	for dir := range w.rescanDirs {
+		if err := w.collectDir(ctx, dir); err != nil {
+			log.Printf("Watcher: Failed to rescan %s: %v", dir, err)
+		}
+	}
+	for dir := range w.checkDirs {
+		if err := w.collectEntries(ctx, dir); err != nil {
+			log.Printf("Watcher: Failed to check %s: %v", dir, err)
+		}
+	}
+
+	paths := make([]string, 0, len(w.changed))
+	for path := range w.changed {
+		paths = append(paths, path)
+	}
+
+	existing, err := w.db.GetFilesByPaths(ctx, paths)
+	if err != nil {
+		return err
+	}
+
+	batch := NewBatchAccumulator(w.db, ctx, watchBatchSize, nil)
+	var upserted, missing []string
+	for _, path := range paths {
+		file, err := watchedFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				if _, recorded := existing[path]; recorded {
+					missing = append(missing, path)
+				}
+			} else {
+				log.Printf("Watcher: Failed to stat %s: %v", path, err)
+			}
+			continue
+		}
+		if file == nil {
+			continue // Not a regular file
+		}
+
+		if prev, ok := existing[path]; ok {
+			if prev.Size == file.Size && prev.Inode == file.Inode && prev.DeviceID == file.DeviceID &&
+				prev.ModifiedTime.Equal(file.ModifiedTime) {
+				continue // Attribute-only change
+			}
+			file.ScanID = prev.ScanID
+		}
+
+		if err := batch.Add(file); err != nil {
+			return err
+		}
+		upserted = append(upserted, path)
+	}
+	if err := batch.Flush(); err != nil {
+		return err
+	}
+
+	deleted, err := w.db.DeleteFilesByPaths(ctx, missing, "File removed (filesystem watcher)")
+	if err != nil {
+		return err
+	}
+
+	if err := w.db.MarkFilesForServiceUpdate(ctx, upserted); err != nil {
+		return err
+	}
+
+	if len(upserted) > 0 || removed+deleted > 0 {
+		log.Printf("Watcher: Updated %d files, removed %d", len(upserted), removed+deleted)
+	}
+
+	clear(w.changed)
+	clear(w.removedDirs)
+	clear(w.rescanDirs)
+	clear(w.checkDirs)
+	return nil
+}
+
+// collectDir adds every file on disk and every file recorded under dir to the pending changes
+func (w *Watcher) collectDir(ctx context.Context, dir string) error {
+	recorded, err := w.db.GetFilePathsUnderPath(ctx, dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range recorded {
+		w.changed[path] = true
+	}
+
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Keep walking, unreadable entries are picked up by the next scan
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if isQuarantineDir(d) {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			w.changed[path] = true
+		}
+		return nil
+	})
+}
+
+// collectEntries adds the files directly in dir, on disk or recorded, to the pending changes
+func (w *Watcher) collectEntries(ctx context.Context, dir string) error {
+	recorded, err := w.db.GetFilePathsUnderPath(ctx, dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range recorded {
+		if filepath.Dir(path) == dir {
+			w.changed[path] = true
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // Recorded files are removed when they fail to stat
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			w.changed[filepath.Join(dir, entry.Name())] = true
+		}
+	}
+	return nil
+}
+
+// watchedFile builds the file record for a path, or returns nil if it is not a regular file
+func watchedFile(path string) (*database.File, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, fmt.Errorf("unable to get system stats for %s", path)
+	}
+
+	return &database.File{
+		Path:         path,
+		Size:         info.Size(),
+		Inode:        int64(stat.Ino),
+		DeviceID:     int64(stat.Dev),
+		ModifiedTime: time.Unix(info.ModTime().Unix(), 0),
+		LastVerified: time.Now(),
+		IsOrphaned:   false, // Pending until the file is re-associated with services
+		Extension:    database.ExtractExtension(path),
+	}, nil
+}
+
+// reassociate matches files changed since the last pass against the services that can use them
+// Successfully matched files are no longer pending; the rest are retried on a later pass
+func (w *Watcher) reassociate(ctx context.Context) {
+	paths, err := w.db.GetFilesNeedingServiceUpdate(ctx, maxReassociateFiles)
+	if err != nil {
+		log.Printf("Watcher: Failed to list changed files: %v", err)
+		return
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	// Scans update service usage themselves; the marks are picked up on a later pass
+	if current, err := w.db.GetCurrentScan(); err != nil || current != nil {
+		return
+	}
+
+	providers := w.servicesForPaths(paths)
+	log.Printf("Watcher: Matching %d changed files against %d services", len(paths), len(providers))
+	if err := w.scanner.rescanFiles(WithTrigger(ctx, TriggerWatcher), paths, providers, true); err != nil {
+		log.Printf("Watcher: Failed to update service usage for changed files: %v", err)
+	}
+}
+
+// servicesForPaths returns the configured services that can report any of paths
+// A service with path mappings is expected to map every folder it uses, so it is only queried for
+// paths under a mapping. Services without mappings see the same paths and are always queried
+func (w *Watcher) servicesForPaths(paths []string) []api.Provider {
+	var providers []api.Provider
+	for _, provider := range api.ConfiguredProviders(&w.config.Services) {
+		mappings := w.config.ServicePathMappings[provider.Name()]
+		if len(mappings) == 0 || anyUnderMapping(paths, mappings) {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// anyUnderMapping reports whether any path is under the local side of one of the mappings
+func anyUnderMapping(paths []string, mappings []config.PathMapping) bool {
+	for _, mapping := range mappings {
+		for _, path := range paths {
+			if strings.HasPrefix(path, mapping.Local) {
+				return true
+			}
+		}
+	}
+	return false
+}
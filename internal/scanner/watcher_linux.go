@@ -0,0 +1,349 @@
+//go:build linux
+
+package scanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask covers the events that change what is stored in the files table
+const inotifyMask = unix.IN_CREATE | unix.IN_CLOSE_WRITE | unix.IN_ATTRIB | unix.IN_DELETE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF | unix.IN_ONLYDIR
+
+// inotifyPollTimeout is how often the reader checks for cancellation, in milliseconds
+const inotifyPollTimeout = 500
+
+// overflowSlack widens the window of directories checked after a queue overflow, covering
+// filesystems with coarse modification times
+const overflowSlack = 2 * time.Second
+
+// watchPaths watches every directory under the roots with inotify and sends changes to events
+// Each root gets its own inotify instance so a queue overflow only checks that root
+func watchPaths(ctx context.Context, roots []string, events chan<- watchEvent) error {
+	var watchers []*inotifyWatcher
+	for _, root := range roots {
+		iw, err := newInotifyWatcher(root, events)
+		if err != nil {
+			for _, started := range watchers {
+				started.close()
+			}
+			return err
+		}
+		watchers = append(watchers, iw)
+	}
+
+	for _, iw := range watchers {
+		go iw.run(ctx)
+	}
+	return nil
+}
+
+// inotifyWatcher watches one scan path recursively
+type inotifyWatcher struct {
+	fd     int
+	root   string
+	events chan<- watchEvent
+	wds    map[int]string // Watch descriptor -> directory
+	dirs   map[string]int // Directory -> watch descriptor
+
+	// A MOVED_FROM waiting for its MOVED_TO, matched by cookie
+	pendingMove *pendingMove
+
+	// When the event queue was last seen empty, events can only have been dropped after it
+	drained time.Time
+}
+
+type pendingMove struct {
+	cookie uint32
+	path   string
+	isDir  bool
+}
+
+func newInotifyWatcher(root string, events chan<- watchEvent) (*inotifyWatcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+
+	iw := &inotifyWatcher{
+		fd:      fd,
+		root:    filepath.Clean(root),
+		events:  events,
+		wds:     make(map[int]string),
+		dirs:    make(map[string]int),
+		drained: time.Now(),
+	}
+	if err := iw.addTree(iw.root); err != nil {
+		iw.close()
+		return nil, err
+	}
+	return iw, nil
+}
+
+// addTree adds a watch on dir and every directory below it
+func (iw *inotifyWatcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil // Skip unreadable directories
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if isQuarantineDir(d) {
+			return filepath.SkipDir // Files moved to the trash are handled by the quarantine
+		}
+
+		wd, err := unix.InotifyAddWatch(iw.fd, path, inotifyMask)
+		if err != nil {
+			if errors.Is(err, unix.ENOSPC) {
+				return fmt.Errorf("inotify watch limit reached at %s, increase fs.inotify.max_user_watches: %w", path, err)
+			}
+			log.Printf("Watcher: Failed to watch %s: %v", path, err)
+			return nil
+		}
+		iw.wds[wd] = path
+		iw.dirs[path] = wd
+		return nil
+	})
+}
+
+// moveTree updates the watched directory paths after a directory is renamed
+// The kernel keeps the watches, only the paths they map to change
+func (iw *inotifyWatcher) moveTree(oldPath, newPath string) {
+	for path, wd := range iw.dirs {
+		if moved, ok := movePath(path, oldPath, newPath); ok {
+			delete(iw.dirs, path)
+			iw.dirs[moved] = wd
+			iw.wds[wd] = moved
+		}
+	}
+}
+
+// forgetTree drops the watch paths for a directory that left the watched tree
+func (iw *inotifyWatcher) forgetTree(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for path, wd := range iw.dirs {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			unix.InotifyRmWatch(iw.fd, uint32(wd))
+			delete(iw.dirs, path)
+			delete(iw.wds, wd)
+		}
+	}
+}
+
+func (iw *inotifyWatcher) close() {
+	unix.Close(iw.fd)
+}
+
+// run reads events until ctx is cancelled
+func (iw *inotifyWatcher) run(ctx context.Context) {
+	defer iw.close()
+
+	buf := make([]byte, 64*1024)
+	fds := []unix.PollFd{{Fd: int32(iw.fd), Events: unix.POLLIN}}
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		n, err := unix.Poll(fds, inotifyPollTimeout)
+		if err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			log.Printf("Watcher: Failed to poll %s: %v", iw.root, err)
+			return
+		}
+		if n == 0 {
+			// A MOVED_FROM with no MOVED_TO by now was a move out of the watched tree
+			iw.flushPendingMove(ctx)
+			iw.drained = time.Now()
+			continue
+		}
+
+		n, err = unix.Read(iw.fd, buf)
+		if err != nil {
+			if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+				continue
+			}
+			log.Printf("Watcher: Failed to read events for %s: %v", iw.root, err)
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			raw := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(raw.Len)]
+			name := strings.TrimRight(string(nameBytes), "\x00")
+			offset += unix.SizeofInotifyEvent + int(raw.Len)
+
+			iw.handle(ctx, raw, name)
+		}
+	}
+}
+
+// handle translates one inotify event into watch events
+func (iw *inotifyWatcher) handle(ctx context.Context, raw *unix.InotifyEvent, name string) {
+	if raw.Mask&unix.IN_Q_OVERFLOW != 0 {
+		iw.pendingMove = nil
+		iw.overflowed(ctx)
+		return
+	}
+
+	dir, ok := iw.wds[int(raw.Wd)]
+	if !ok {
+		return
+	}
+	if raw.Mask&unix.IN_IGNORED != 0 {
+		delete(iw.wds, int(raw.Wd))
+		if iw.dirs[dir] == int(raw.Wd) {
+			delete(iw.dirs, dir)
+		}
+		return
+	}
+	if raw.Mask&unix.IN_DELETE_SELF != 0 {
+		return // The parent directory reports the deletion
+	}
+
+	path := filepath.Join(dir, name)
+	isDir := raw.Mask&unix.IN_ISDIR != 0
+
+	// Any event other than the matching MOVED_TO means the pending move left the tree
+	if iw.pendingMove != nil && (raw.Mask&unix.IN_MOVED_TO == 0 || raw.Cookie != iw.pendingMove.cookie) {
+		iw.flushPendingMove(ctx)
+	}
+
+	switch {
+	case raw.Mask&unix.IN_MOVED_FROM != 0:
+		iw.pendingMove = &pendingMove{cookie: raw.Cookie, path: path, isDir: isDir}
+
+	case raw.Mask&unix.IN_MOVED_TO != 0:
+		if move := iw.pendingMove; move != nil {
+			iw.pendingMove = nil
+			iw.send(ctx, watchEvent{Op: watchRenamed, OldPath: move.path, Path: path, IsDir: isDir})
+			if move.isDir {
+				if _, watched := iw.dirs[move.path]; watched {
+					iw.moveTree(move.path, path)
+				} else {
+					// Renamed before its watch was added, so it is treated as new
+					iw.added(ctx, path, true)
+				}
+			}
+			return
+		}
+		// Moved in from outside the watched tree
+		iw.added(ctx, path, isDir)
+
+	case raw.Mask&unix.IN_CREATE != 0:
+		iw.added(ctx, path, isDir)
+
+	case raw.Mask&unix.IN_DELETE != 0:
+		iw.send(ctx, watchEvent{Op: watchRemoved, Path: path, IsDir: isDir})
+
+	case !isDir && raw.Mask&(unix.IN_CLOSE_WRITE|unix.IN_ATTRIB) != 0:
+		iw.send(ctx, watchEvent{Op: watchChanged, Path: path})
+	}
+}
+
+// added handles a file or directory appearing in a watched directory
+func (iw *inotifyWatcher) added(ctx context.Context, path string, isDir bool) {
+	if !isDir {
+		iw.send(ctx, watchEvent{Op: watchChanged, Path: path})
+		return
+	}
+
+	if filepath.Base(path) == constants.QuarantineDirName {
+		return
+	}
+
+	// Files can land in a new directory before its watch exists, so it is walked as well
+	if err := iw.addTree(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Watcher: Failed to watch new directory %s: %v", path, err)
+	}
+	iw.send(ctx, watchEvent{Op: watchDirAdded, Path: path, IsDir: true})
+}
+
+// overflowed reports the directories that may have lost events when the event queue overflowed
+// Creating, deleting or renaming an entry updates its directory's modification time, so only the
+// directories modified since the queue was last drained are checked. Writes to existing files in
+// otherwise unchanged directories are picked up by the next scan
+func (iw *inotifyWatcher) overflowed(ctx context.Context) {
+	since := iw.drained.Add(-overflowSlack)
+
+	dirs := make([]string, 0, len(iw.dirs))
+	for dir := range iw.dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs) // Parents before their subdirectories
+
+	var changed int
+	for _, dir := range dirs {
+		if _, watched := iw.dirs[dir]; !watched {
+			continue // Below a directory that was already reported removed
+		}
+
+		info, err := os.Lstat(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				iw.forgetTree(dir)
+				iw.send(ctx, watchEvent{Op: watchRemoved, Path: dir, IsDir: true})
+			}
+			continue
+		}
+		if info.ModTime().Before(since) {
+			continue
+		}
+
+		changed++
+		iw.send(ctx, watchEvent{Op: watchDirChanged, Path: dir, IsDir: true})
+
+		// Subdirectories created while events were dropped have no watch yet
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if _, watched := iw.dirs[path]; entry.IsDir() && !watched {
+				iw.added(ctx, path, true)
+			}
+		}
+	}
+	log.Printf("Watcher: Event queue overflowed for %s, checking %d changed directories", iw.root, changed)
+}
+
+// flushPendingMove reports an unmatched MOVED_FROM as a removal
+func (iw *inotifyWatcher) flushPendingMove(ctx context.Context) {
+	move := iw.pendingMove
+	if move == nil {
+		return
+	}
+	iw.pendingMove = nil
+	if move.isDir {
+		iw.forgetTree(move.path)
+	}
+	iw.send(ctx, watchEvent{Op: watchRemoved, Path: move.path, IsDir: move.isDir})
+}
+
+// send delivers an event unless the watcher is stopping
+func (iw *inotifyWatcher) send(ctx context.Context, ev watchEvent) {
+	select {
+	case iw.events <- ev:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package scanner
+
+import (
+	"context"
+	"fmt"
+)
+
+// watchPaths is only implemented with inotify on Linux
+func watchPaths(ctx context.Context, roots []string, events chan<- watchEvent) error {
+	return fmt.Errorf("filesystem watching is only supported on Linux")
+}
@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+)
+
+func TestMovePath(t *testing.T) {
+	tests := []struct {
+		path, oldPath, newPath string
+		want                   string
+		ok                     bool
+	}{
+		{"/media/tv/Show", "/media/tv/Show", "/media/tv/Show (2020)", "/media/tv/Show (2020)", true},
+		{"/media/tv/Show/S01/e1.mkv", "/media/tv/Show", "/media/tv/Renamed", "/media/tv/Renamed/S01/e1.mkv", true},
+		{"/media/tv/Show2/e1.mkv", "/media/tv/Show", "/media/tv/Renamed", "", false},
+		{"/media/movies/a.mkv", "/media/tv", "/media/shows", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := movePath(tt.path, tt.oldPath, tt.newPath)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("movePath(%q, %q, %q) = %q, %v, want %q, %v", tt.path, tt.oldPath, tt.newPath, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestServicesForPaths(t *testing.T) {
+	cfg := &config.Config{
+		Services: config.Services{
+			Plex:        config.PlexConfig{URL: "http://plex", Token: "token"},
+			QBittorrent: config.QBittorrentConfig{URL: "http://qbit"},
+		},
+		ServicePathMappings: map[string][]config.PathMapping{
+			"plex":        {{Service: "/media", Local: "/mnt/user/media"}},
+			"qbittorrent": {{Service: "/downloads", Local: "/mnt/user/downloads"}},
+		},
+	}
+	w := &Watcher{config: cfg}
+
+	names := func(paths ...string) []string {
+		var names []string
+		for _, provider := range w.servicesForPaths(paths) {
+			names = append(names, provider.Name())
+		}
+		return names
+	}
+
+	if got := names("/mnt/user/media/tv/e1.mkv"); len(got) != 1 || got[0] != "plex" {
+		t.Errorf("services for a media path = %v, want [plex]", got)
+	}
+	if got := names("/mnt/user/downloads/x.mkv", "/mnt/user/media/y.mkv"); len(got) != 2 {
+		t.Errorf("services for media and download paths = %v, want both", got)
+	}
+	if got := names("/mnt/user/other/z.mkv"); len(got) != 0 {
+		t.Errorf("services for an unmapped path = %v, want none", got)
+	}
+
+	delete(cfg.ServicePathMappings, "qbittorrent")
+	if got := names("/mnt/user/other/z.mkv"); len(got) != 1 || got[0] != "qbittorrent" {
+		t.Errorf("services without mappings = %v, want [qbittorrent]", got)
+	}
+}
@@ -0,0 +1,261 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/cron"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// tickInterval is how often the scheduler checks for due tasks
+// Cron has minute resolution, so checking a few times per minute is plenty
+const tickInterval = 15 * time.Second
+
+// Task is a job that can be run on a cron schedule
+type Task struct {
+	Name     string                                   // Display name (e.g., "Full Scan")
+	Schedule func(cfg *config.SchedulerConfig) string // Returns the task's current cron expression from config
+	Timeout  time.Duration                            // Maximum run time (0 = no timeout)
+	Run      func(ctx context.Context) error
+}
+
+// TaskStatus describes the scheduling state of a task
+type TaskStatus struct {
+	Name      string
+	Schedule  string     // Current cron expression (empty = disabled)
+	NextRun   *time.Time // Next scheduled run (nil if disabled or scheduler off)
+	LastRun   *time.Time // When the scheduler last fired this task
+	LastError string     // Error (or skip reason) from the last attempt
+}
+
+// Scheduler runs configured tasks on cron schedules
+// Schedules are read from the shared config on every tick, so changes saved
+// from the config page take effect without a restart
+type Scheduler struct {
+	db     *database.DB
+	config *config.Config
+	tasks  []Task
+
+	mu        sync.Mutex
+	lastTick  time.Time
+	lastRun   map[string]time.Time
+	lastError map[string]string
+	parsed    map[string]*cron.Schedule // Cache of parsed expressions
+	running   bool                      // True while a scheduled task is executing
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a scheduler for the given tasks
+func New(db *database.DB, cfg *config.Config, tasks []Task) *Scheduler {
+	return &Scheduler{
+		db:        db,
+		config:    cfg,
+		tasks:     tasks,
+		lastRun:   make(map[string]time.Time),
+		lastError: make(map[string]string),
+		parsed:    make(map[string]*cron.Schedule),
+	}
+}
+
+// Start begins checking schedules in the background
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	if s.stop != nil {
+		s.mu.Unlock()
+		return // Already started
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.lastTick = time.Now()
+	stop, done := s.stop, s.done
+	s.mu.Unlock()
+
+	go s.loop(stop, done)
+	log.Printf("Scheduler started")
+}
+
+// Stop halts the scheduler. Tasks that are already running are not interrupted.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop = nil
+	s.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+	log.Printf("Scheduler stopped")
+}
+
+// loop wakes periodically and fires any tasks that became due since the last tick
+func (s *Scheduler) loop(stop <-chan struct{}, done chan<- struct{}) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	defer close(done)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			s.tick(now)
+		}
+	}
+}
+
+// tick fires every task whose next run after the previous tick is at or before now
+func (s *Scheduler) tick(now time.Time) {
+	s.mu.Lock()
+	since := s.lastTick
+	s.lastTick = now
+	s.mu.Unlock()
+
+	if !s.config.Scheduler.Enabled {
+		return
+	}
+
+	for _, task := range s.tasks {
+		sched := s.scheduleFor(task)
+		if sched == nil {
+			continue
+		}
+
+		next := sched.Next(since)
+		if next.IsZero() || next.After(now) {
+			continue
+		}
+
+		s.fire(task, now)
+	}
+}
+
+// scheduleFor returns the parsed schedule for a task, or nil if it is disabled or invalid
+func (s *Scheduler) scheduleFor(task Task) *cron.Schedule {
+	expr := strings.TrimSpace(task.Schedule(&s.config.Scheduler))
+	if expr == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sched, ok := s.parsed[expr]; ok {
+		return sched
+	}
+
+	sched, err := cron.Parse(expr)
+	if err != nil {
+		// Config validation should prevent this, but don't fire on a bad expression
+		log.Printf("Warning: Scheduler: invalid cron expression for %s (%q): %v", task.Name, expr, err)
+		s.parsed[expr] = nil
+		return nil
+	}
+	s.parsed[expr] = sched
+	return sched
+}
+
+// fire runs a due task in the background unless another scan is active
+func (s *Scheduler) fire(task Task, now time.Time) {
+	s.mu.Lock()
+	s.lastRun[task.Name] = now
+	if s.running {
+		s.lastError[task.Name] = "skipped: another scheduled task is still running"
+		s.mu.Unlock()
+		log.Printf("Scheduler: skipping %s - another scheduled task is still running", task.Name)
+		return
+	}
+	s.mu.Unlock()
+
+	// Skip rather than queue when any scan (manual or scheduled) is in progress
+	currentScan, err := s.db.GetCurrentScan()
+	if err != nil {
+		s.setLastError(task.Name, fmt.Sprintf("failed to check for running scan: %v", err))
+		log.Printf("ERROR: Scheduler: failed to check for running scan before %s: %v", task.Name, err)
+		return
+	}
+	if currentScan != nil {
+		s.setLastError(task.Name, fmt.Sprintf("skipped: scan %d (%s) already running", currentScan.ID, currentScan.ScanType))
+		log.Printf("Scheduler: skipping %s - scan %d (%s) already running", task.Name, currentScan.ID, currentScan.ScanType)
+		return
+	}
+
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("ERROR: Scheduler: %s panicked: %v", task.Name, r)
+				s.setLastError(task.Name, fmt.Sprintf("panic: %v", r))
+			}
+			s.mu.Lock()
+			s.running = false
+			s.mu.Unlock()
+		}()
+
+		ctx := context.Background()
+		if task.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, task.Timeout)
+			defer cancel()
+		}
+
+		log.Printf("INFO: Scheduler: starting %s", task.Name)
+		if err := task.Run(ctx); err != nil {
+			log.Printf("ERROR: Scheduler: %s failed: %v", task.Name, err)
+			s.setLastError(task.Name, err.Error())
+			return
+		}
+		log.Printf("INFO: Scheduler: %s completed", task.Name)
+		s.setLastError(task.Name, "")
+	}()
+}
+
+// setLastError records the outcome of a task's most recent attempt
+func (s *Scheduler) setLastError(name, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError[name] = msg
+}
+
+// Status returns the current schedule state of every task
+func (s *Scheduler) Status() []TaskStatus {
+	now := time.Now()
+	enabled := s.config.Scheduler.Enabled
+
+	statuses := make([]TaskStatus, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		status := TaskStatus{
+			Name:     task.Name,
+			Schedule: strings.TrimSpace(task.Schedule(&s.config.Scheduler)),
+		}
+
+		if sched := s.scheduleFor(task); sched != nil && enabled {
+			if next := sched.Next(now); !next.IsZero() {
+				status.NextRun = &next
+			}
+		}
+
+		s.mu.Lock()
+		if t, ok := s.lastRun[task.Name]; ok {
+			status.LastRun = &t
+		}
+		status.LastError = s.lastError[task.Name]
+		s.mu.Unlock()
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// newTestScheduler returns a scheduler with one task that runs every minute and reports each run
+func newTestScheduler(t *testing.T) (*Scheduler, *database.DB, chan struct{}) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := config.Default()
+	cfg.Scheduler.Enabled = true
+	cfg.Scheduler.FullScanCron = "* * * * *"
+
+	runs := make(chan struct{}, 1)
+	task := Task{
+		Name:     "Full Scan",
+		Schedule: func(cfg *config.SchedulerConfig) string { return cfg.FullScanCron },
+		Run: func(ctx context.Context) error {
+			runs <- struct{}{}
+			return nil
+		},
+	}
+	return New(db, cfg, []Task{task}), db, runs
+}
+
+// waitIdle waits for the running task to finish
+func waitIdle(t *testing.T, s *Scheduler) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		running := s.running
+		s.mu.Unlock()
+		if !running {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("task still running")
+}
+
+func TestTickSkipsWhileScanRunning(t *testing.T) {
+	s, db, runs := newTestScheduler(t)
+	start := time.Date(2024, 9, 2, 10, 0, 30, 0, time.UTC)
+	s.lastTick = start
+
+	scan, err := db.CreateScan("incremental")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.tick(start.Add(time.Minute))
+
+	select {
+	case <-runs:
+		t.Fatal("task ran while a scan was running")
+	default:
+	}
+	status := s.Status()[0]
+	if status.LastRun == nil || !strings.HasPrefix(status.LastError, "skipped: scan") {
+		t.Errorf("status = %+v, want the run recorded as skipped", status)
+	}
+
+	if err := db.CompleteScan(scan.ID, "completed", ""); err != nil {
+		t.Fatal(err)
+	}
+	s.tick(start.Add(2 * time.Minute))
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task didn't run once the scan completed")
+	}
+	waitIdle(t, s)
+	if status := s.Status()[0]; status.LastError != "" {
+		t.Errorf("LastError = %q after a successful run", status.LastError)
+	}
+}
+
+func TestTickSkipsWhileTaskRunning(t *testing.T) {
+	s, _, runs := newTestScheduler(t)
+	start := time.Date(2024, 9, 2, 10, 0, 30, 0, time.UTC)
+	s.lastTick = start
+	s.running = true
+
+	s.tick(start.Add(time.Minute))
+	select {
+	case <-runs:
+		t.Fatal("task ran while another scheduled task was running")
+	default:
+	}
+	if status := s.Status()[0]; !strings.Contains(status.LastError, "another scheduled task") {
+		t.Errorf("LastError = %q, want the run skipped", status.LastError)
+	}
+}
+
+func TestTickOnlyFiresDueTasks(t *testing.T) {
+	s, _, runs := newTestScheduler(t)
+	s.config.Scheduler.FullScanCron = "0 3 * * *"
+	start := time.Date(2024, 9, 2, 2, 58, 0, 0, time.UTC)
+	s.lastTick = start
+
+	s.tick(start.Add(time.Minute))
+	select {
+	case <-runs:
+		t.Fatal("task ran before it was due")
+	default:
+	}
+
+	s.tick(start.Add(2 * time.Minute))
+	select {
+	case <-runs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task didn't run when it became due")
+	}
+	waitIdle(t, s)
+
+	// A disabled scheduler fires nothing
+	s.config.Scheduler.Enabled = false
+	s.tick(start.Add(24 * time.Hour))
+	select {
+	case <-runs:
+		t.Fatal("task ran with the scheduler disabled")
+	default:
+	}
+}
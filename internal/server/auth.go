@@ -0,0 +1,755 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/auth"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// sessionCookieName is the name of the login session cookie
+const sessionCookieName = "media_finder_session"
+
+// principalKey is the context key for the authenticated principal
+const principalKey contextKey = "principal"
+
+// Principal identifies who is making a request
+type Principal struct {
+	Username string // Username, or "api-key:<name>" for API key requests
+	Role     string
+	UserID   int64 // 0 for API key requests
+	APIKeyID int64 // 0 for session requests
+}
+
+// IsAdmin reports whether the principal has the admin role
+func (p *Principal) IsAdmin() bool {
+	return p != nil && p.Role == auth.RoleAdmin
+}
+
+// GetPrincipal returns the authenticated principal from the request context, or nil
+func GetPrincipal(ctx context.Context) *Principal {
+	if p, ok := ctx.Value(principalKey).(*Principal); ok {
+		return p
+	}
+	return nil
+}
+
+// principalName returns a name for logging who performed an action
+func principalName(ctx context.Context) string {
+	if p := GetPrincipal(ctx); p != nil {
+		return p.Username
+	}
+	return "anonymous"
+}
+
+// publicPaths are reachable without authentication
+var publicPaths = []string{
+	"/health",
+	"/login",
+	"/logout",
+	"/setup",
+	"/static/",
+}
+
+// adminPaths require the admin role: destructive operations, settings and credentials
+var adminPaths = []string{
+	"/config",
+	"/advanced",
+	"/access",
+	"/api/config/",
+	"/api/admin/",
+	"/api/auth/",
+	"/api/libraries",
+	"/api/files/delete",
+	"/api/files/batch-delete",
+	"/api/quarantine/",
+	"/api/operations/revert",
+	"/api/policies/run",
+	"/api/notifications/",
+	"/api/services/acknowledge",
+	"/api/snapshots/replay",
+	"/api/reconcile/apply",
+	"/api/mappings/apply",
+	"/api/duplicates/consolidate",
+	"/api/duplicates/hardlink",
+	"/api/hash/clear",
+}
+
+// matchesPath reports whether path equals or falls under one of the given routes
+// Entries ending in "/" match as prefixes, others match exactly
+func matchesPath(path string, routes []string) bool {
+	for _, route := range routes {
+		if strings.HasSuffix(route, "/") {
+			if strings.HasPrefix(path, route) {
+				return true
+			}
+		} else if path == route {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate middleware requires a valid session cookie or API key when auth is enabled
+// API keys are accepted via "Authorization: Bearer <key>" or "X-Api-Key: <key>"
+// Cookie-authenticated state-changing requests must come from this site, see isCrossSite
+func (s *Server) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKeyFromRequest(r) == "" && isCrossSite(r, s.config.CORSAllowedOrigin) {
+			respondError(w, http.StatusForbidden, "Cross-site request blocked", "cross_site")
+			return
+		}
+
+		if !s.config.Auth.Enabled || matchesPath(r.URL.Path, publicPaths) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := s.resolvePrincipal(r)
+		if err != nil {
+			log.Printf("ERROR: Authentication lookup failed: %v", err)
+			respondError(w, http.StatusInternalServerError, "Authentication check failed", "auth_failed")
+			return
+		}
+
+		if principal == nil {
+			s.rejectUnauthenticated(w, r)
+			return
+		}
+
+		if matchesPath(r.URL.Path, adminPaths) && !principal.IsAdmin() {
+			respondError(w, http.StatusForbidden, "Admin role required", "forbidden")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey, principal)))
+	})
+}
+
+// resolvePrincipal identifies the caller from an API key header or session cookie
+// Returns nil (without error) if the request carries no valid credentials
+func (s *Server) resolvePrincipal(r *http.Request) (*Principal, error) {
+	if key := apiKeyFromRequest(r); key != "" {
+		apiKey, err := s.db.GetAPIKeyByHash(auth.HashToken(key))
+		if err != nil {
+			return nil, err
+		}
+		if apiKey == nil || apiKey.IsRevoked() {
+			return nil, nil
+		}
+
+		// Best effort - a failed usage timestamp shouldn't fail the request
+		if err := s.db.TouchAPIKey(apiKey.ID); err != nil {
+			log.Printf("Warning: Failed to update API key last used time: %v", err)
+		}
+
+		return &Principal{
+			Username: "api-key:" + apiKey.Name,
+			Role:     apiKey.Role,
+			APIKeyID: apiKey.ID,
+		}, nil
+	}
+
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, nil
+	}
+
+	user, err := s.db.GetSessionUser(auth.HashToken(cookie.Value))
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	return &Principal{
+		Username: user.Username,
+		Role:     user.Role,
+		UserID:   user.ID,
+	}, nil
+}
+
+// apiKeyFromRequest extracts an API key from the Authorization or X-Api-Key header
+// Webhook endpoints also accept an apikey query parameter, since Plex webhooks can't set headers
+// The Logger middleware masks it, as the query string is otherwise logged with the request
+func apiKeyFromRequest(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("X-Api-Key")); key != "" {
+		return key
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/api/webhooks/") {
+		if key := strings.TrimSpace(r.URL.Query().Get("apikey")); key != "" {
+			return key
+		}
+	}
+
+	authHeader := strings.TrimSpace(r.Header.Get("Authorization"))
+	if authHeader == "" {
+		return ""
+	}
+
+	// Accept "Bearer <key>", "ApiKey <key>" or a bare key
+	if scheme, value, ok := strings.Cut(authHeader, " "); ok {
+		switch strings.ToLower(scheme) {
+		case "bearer", "apikey":
+			return strings.TrimSpace(value)
+		}
+		return ""
+	}
+	return authHeader
+}
+
+// isCrossSite reports whether a state-changing request was sent from a page on another site
+// Browsers send Origin (or at least Referer) with cross-site form posts and fetches, so requests
+// carrying neither come from non-browser clients and are allowed. The configured CORS origin is
+// trusted, except for "*"
+func isCrossSite(r *http.Request, allowedOrigin string) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return false
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return true
+	}
+	if allowedOrigin != "" && allowedOrigin != "*" && u.Scheme+"://"+u.Host == strings.TrimSuffix(allowedOrigin, "/") {
+		return false
+	}
+	// Reverse proxies may rewrite Host; a cross-site page can't set X-Forwarded-Host
+	return !strings.EqualFold(u.Host, r.Host) && !strings.EqualFold(u.Host, r.Header.Get("X-Forwarded-Host"))
+}
+
+// prepareSetupToken generates the token /setup requires while no accounts exist and logs it,
+// so only someone with access to the server log can create the first admin account
+func (s *Server) prepareSetupToken() error {
+	count, err := s.db.CountUsers()
+	if err != nil || count > 0 {
+		return err
+	}
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return err
+	}
+	s.setupToken = token
+
+	log.Printf("No accounts exist yet. Create the first admin account at /setup with setup token %s, or run \"media-finder user create\"", token)
+	return nil
+}
+
+// rejectUnauthenticated responds to a request without valid credentials
+// Browsers are redirected to the login (or first-run setup) page; API clients get 401 JSON
+func (s *Server) rejectUnauthenticated(w http.ResponseWriter, r *http.Request) {
+	target := "/login?next=" + url.QueryEscape(r.URL.RequestURI())
+	if count, err := s.db.CountUsers(); err == nil && count == 0 {
+		target = "/setup"
+	}
+
+	// HTMX requests follow HX-Redirect instead of a 3xx
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", target)
+		respondError(w, http.StatusUnauthorized, "Session expired - please sign in again", "unauthorized")
+		return
+	}
+
+	if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/api/") {
+		http.Redirect(w, r, target, http.StatusSeeOther)
+		return
+	}
+
+	w.Header().Set("WWW-Authenticate", `Bearer realm="media-finder"`)
+	respondError(w, http.StatusUnauthorized, "Authentication required", "unauthorized")
+}
+
+// safeRedirectTarget returns next if it is a local path, otherwise "/"
+func safeRedirectTarget(next string) string {
+	if next == "" || !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// startSession creates a session for a user and sets the session cookie
+func (s *Server) startSession(w http.ResponseWriter, user *database.User) error {
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(s.config.Auth.SessionTTL)
+	if err := s.db.CreateSession(auth.HashToken(token), user.ID, expiresAt); err != nil {
+		return err
+	}
+
+	if err := s.db.UpdateUserLastLogin(user.ID); err != nil {
+		log.Printf("Warning: Failed to update last login for %s: %v", user.Username, err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   s.config.Auth.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nil
+}
+
+// clearSessionCookie expires the session cookie in the browser
+func (s *Server) clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.config.Auth.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// HandleLogin serves the login page and processes login form submissions
+func (s *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if !requireAnyMethod(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if !s.config.Auth.Enabled {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	// No accounts yet - send the user to first-run setup
+	if count, err := s.db.CountUsers(); err == nil && count == 0 {
+		http.Redirect(w, r, "/setup", http.StatusSeeOther)
+		return
+	}
+
+	data := LoginData{
+		Title:   "Sign In",
+		Version: s.version,
+		Next:    safeRedirectTarget(r.FormValue("next")),
+	}
+
+	if r.Method == http.MethodGet {
+		s.renderTemplate(w, "login.html", data)
+		return
+	}
+
+	username := strings.TrimSpace(r.FormValue("username"))
+	password := r.FormValue("password")
+	data.Username = username
+
+	user, err := s.db.GetUserByUsername(username)
+	if err != nil {
+		log.Printf("ERROR: Login lookup failed: %v", err)
+		data.Error = "Sign in failed - please try again"
+		w.WriteHeader(http.StatusInternalServerError)
+		s.renderTemplate(w, "login.html", data)
+		return
+	}
+
+	if user == nil || !auth.CheckPassword(password, user.PasswordHash) {
+		log.Printf("Warning: Failed login attempt for %q from %s", username, r.RemoteAddr)
+		data.Error = "Invalid username or password"
+		w.WriteHeader(http.StatusUnauthorized)
+		s.renderTemplate(w, "login.html", data)
+		return
+	}
+
+	if err := s.startSession(w, user); err != nil {
+		log.Printf("ERROR: Failed to create session: %v", err)
+		data.Error = "Sign in failed - please try again"
+		w.WriteHeader(http.StatusInternalServerError)
+		s.renderTemplate(w, "login.html", data)
+		return
+	}
+
+	http.Redirect(w, r, data.Next, http.StatusSeeOther)
+}
+
+// HandleLogout ends the current session
+func (s *Server) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if !requireAnyMethod(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
+		if err := s.db.DeleteSession(auth.HashToken(cookie.Value)); err != nil {
+			log.Printf("Warning: Failed to delete session: %v", err)
+		}
+	}
+	s.clearSessionCookie(w)
+
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// HandleSetup creates the first admin account
+// Only available while no user accounts exist
+func (s *Server) HandleSetup(w http.ResponseWriter, r *http.Request) {
+	if !requireAnyMethod(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	count, err := s.db.CountUsers()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to check user accounts", "query_failed")
+		return
+	}
+	if count > 0 {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	data := LoginData{
+		Title:   "Initial Setup",
+		Version: s.version,
+		Setup:   true,
+		Next:    "/",
+	}
+
+	if r.Method == http.MethodGet {
+		s.renderTemplate(w, "login.html", data)
+		return
+	}
+
+	username := strings.TrimSpace(r.FormValue("username"))
+	password := r.FormValue("password")
+	data.Username = username
+
+	if s.setupToken == "" || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(r.FormValue("setup_token"))), []byte(s.setupToken)) != 1 {
+		data.Error = "Invalid setup token - it is printed in the server log at startup"
+		w.WriteHeader(http.StatusForbidden)
+		s.renderTemplate(w, "login.html", data)
+		return
+	}
+
+	if msg := validateCredentials(username, password, r.FormValue("confirm_password")); msg != "" {
+		data.Error = msg
+		w.WriteHeader(http.StatusBadRequest)
+		s.renderTemplate(w, "login.html", data)
+		return
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to hash password", "auth_failed")
+		return
+	}
+
+	user, err := s.db.CreateUser(username, hash, auth.RoleAdmin)
+	if err != nil {
+		data.Error = "Failed to create account: " + err.Error()
+		w.WriteHeader(http.StatusInternalServerError)
+		s.renderTemplate(w, "login.html", data)
+		return
+	}
+
+	log.Printf("Created initial admin account %q", username)
+
+	if err := s.startSession(w, user); err != nil {
+		log.Printf("ERROR: Failed to create session: %v", err)
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// validateCredentials checks a new username/password pair, returning an error message or ""
+func validateCredentials(username, password, confirm string) string {
+	if username == "" {
+		return "Username is required"
+	}
+	if len(username) > 64 {
+		return "Username must be 64 characters or fewer"
+	}
+	if len(password) < auth.MinPasswordLength {
+		return "Password must be at least " + strconv.Itoa(auth.MinPasswordLength) + " characters"
+	}
+	if password != confirm {
+		return "Passwords do not match"
+	}
+	return ""
+}
+
+// HandleAccess serves the user and API key management page
+func (s *Server) HandleAccess(w http.ResponseWriter, r *http.Request) {
+	users, err := s.db.ListUsers()
+	if err != nil {
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+
+	keys, err := s.db.ListAPIKeys()
+	if err != nil {
+		http.Error(w, "Failed to load API keys", http.StatusInternalServerError)
+		return
+	}
+
+	s.renderTemplate(w, "access.html", AccessData{
+		Title:       "Access Control",
+		Version:     s.version,
+		Users:       users,
+		APIKeys:     keys,
+		CurrentUser: GetPrincipal(r.Context()),
+	})
+}
+
+// HandleCreateUser creates a new user account
+func (s *Server) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	username := strings.TrimSpace(r.FormValue("username"))
+	password := r.FormValue("password")
+	role := r.FormValue("role")
+	if role == "" {
+		role = auth.RoleUser
+	}
+
+	if !auth.ValidRole(role) {
+		respondError(w, http.StatusBadRequest, "Invalid role", "invalid_parameter")
+		return
+	}
+	if msg := validateCredentials(username, password, r.FormValue("confirm_password")); msg != "" {
+		respondError(w, http.StatusBadRequest, msg, "invalid_parameter")
+		return
+	}
+
+	existing, err := s.db.GetUserByUsername(username)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to check existing users", "query_failed")
+		return
+	}
+	if existing != nil {
+		respondError(w, http.StatusConflict, "A user with that name already exists", "user_exists")
+		return
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to hash password", "auth_failed")
+		return
+	}
+
+	if _, err := s.db.CreateUser(username, hash, role); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to create user", "create_failed")
+		return
+	}
+
+	log.Printf("User %q created by %s (role: %s)", username, principalName(r.Context()), role)
+
+	w.Header().Set("X-Toast-Message", "User "+username+" created")
+	w.Header().Set("X-Toast-Type", "success")
+	w.Header().Set("HX-Refresh", "true")
+	respondSuccess(w, "User created", nil)
+}
+
+// HandleDeleteUser deletes a user account
+func (s *Server) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID", "invalid_parameter")
+		return
+	}
+
+	principal := GetPrincipal(r.Context())
+	if principal != nil && principal.UserID == id {
+		respondError(w, http.StatusBadRequest, "You cannot delete your own account", "invalid_parameter")
+		return
+	}
+
+	user, err := s.db.GetUserByID(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to load user", "query_failed")
+		return
+	}
+	if user == nil {
+		respondError(w, http.StatusNotFound, "User not found", "not_found")
+		return
+	}
+
+	// Never leave the instance without an admin
+	if user.Role == auth.RoleAdmin {
+		admins, err := s.db.CountAdmins()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to count admins", "query_failed")
+			return
+		}
+		if admins <= 1 {
+			respondError(w, http.StatusBadRequest, "Cannot delete the last admin account", "invalid_parameter")
+			return
+		}
+	}
+
+	if err := s.db.DeleteUser(id); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to delete user", "delete_failed")
+		return
+	}
+
+	log.Printf("User %q deleted by %s", user.Username, principalName(r.Context()))
+
+	w.Header().Set("X-Toast-Message", "User "+user.Username+" deleted")
+	w.Header().Set("X-Toast-Type", "success")
+	w.Header().Set("HX-Refresh", "true")
+	respondSuccess(w, "User deleted", nil)
+}
+
+// HandleChangePassword sets a new password for a user and signs out their sessions
+func (s *Server) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID", "invalid_parameter")
+		return
+	}
+
+	user, err := s.db.GetUserByID(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to load user", "query_failed")
+		return
+	}
+	if user == nil {
+		respondError(w, http.StatusNotFound, "User not found", "not_found")
+		return
+	}
+
+	password := r.FormValue("password")
+	if msg := validateCredentials(user.Username, password, r.FormValue("confirm_password")); msg != "" {
+		respondError(w, http.StatusBadRequest, msg, "invalid_parameter")
+		return
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to hash password", "auth_failed")
+		return
+	}
+
+	if err := s.db.UpdateUserPassword(id, hash); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to update password", "update_failed")
+		return
+	}
+
+	w.Header().Set("X-Toast-Message", "Password updated for "+user.Username)
+	w.Header().Set("X-Toast-Type", "success")
+	// Changing your own password signs you out - refresh to reach the login page
+	w.Header().Set("HX-Refresh", "true")
+	respondSuccess(w, "Password updated", nil)
+}
+
+// HandleCreateAPIKey generates a new API key
+// The plaintext key is only returned in this response
+func (s *Server) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	role := r.FormValue("role")
+	if role == "" {
+		role = auth.RoleUser
+	}
+
+	if name == "" {
+		respondError(w, http.StatusBadRequest, "API key name is required", "missing_parameter")
+		return
+	}
+	if !auth.ValidRole(role) {
+		respondError(w, http.StatusBadRequest, "Invalid role", "invalid_parameter")
+		return
+	}
+
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to generate API key", "auth_failed")
+		return
+	}
+
+	var createdBy *int64
+	principal := GetPrincipal(r.Context())
+	if principal != nil && principal.UserID != 0 {
+		createdBy = &principal.UserID
+	}
+
+	apiKey, err := s.db.CreateAPIKey(name, auth.DisplayPrefix(key), auth.HashToken(key), role, createdBy)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save API key", "create_failed")
+		return
+	}
+
+	log.Printf("API key %q created by %s (role: %s)", name, principalName(r.Context()), role)
+
+	respondJSON(w, http.StatusOK, APIKeyCreatedResponse{
+		Status: "success",
+		ID:     apiKey.ID,
+		Name:   apiKey.Name,
+		Role:   apiKey.Role,
+		Key:    key,
+	})
+}
+
+// HandleRevokeAPIKey revokes an API key
+func (s *Server) HandleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid API key ID", "invalid_parameter")
+		return
+	}
+
+	if err := s.db.RevokeAPIKey(id); err != nil {
+		respondError(w, http.StatusNotFound, err.Error(), "not_found")
+		return
+	}
+
+	w.Header().Set("X-Toast-Message", "API key revoked")
+	w.Header().Set("X-Toast-Type", "success")
+	w.Header().Set("HX-Refresh", "true")
+	respondSuccess(w, "API key revoked", nil)
+}
+
+// startSessionCleanup periodically removes expired sessions
+func (s *Server) startSessionCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if n, err := s.db.DeleteExpiredSessions(); err != nil {
+				log.Printf("Warning: Failed to clean up expired sessions: %v", err)
+			} else if n > 0 {
+				log.Printf("Removed %d expired session(s)", n)
+			}
+		}
+	}()
+}
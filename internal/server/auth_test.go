@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsCrossSite(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		allowed string
+		want    bool
+	}{
+		{name: "get from another site", method: http.MethodGet, headers: map[string]string{"Origin": "https://evil.example"}, want: false},
+		{name: "post without origin or referer", method: http.MethodPost, want: false},
+		{name: "post from same host", method: http.MethodPost, headers: map[string]string{"Origin": "http://media.local:8787"}, want: false},
+		{name: "post from another site", method: http.MethodPost, headers: map[string]string{"Origin": "https://evil.example"}, want: true},
+		{name: "referer from another site", method: http.MethodPost, headers: map[string]string{"Referer": "https://evil.example/page"}, want: true},
+		{name: "opaque origin", method: http.MethodPost, headers: map[string]string{"Origin": "null"}, want: true},
+		{name: "configured origin", method: http.MethodDelete, headers: map[string]string{"Origin": "https://ui.example"}, allowed: "https://ui.example", want: false},
+		{name: "wildcard origin is not trusted", method: http.MethodPost, headers: map[string]string{"Origin": "https://evil.example"}, allowed: "*", want: true},
+		{name: "forwarded host", method: http.MethodPost, headers: map[string]string{"Origin": "https://media.example", "X-Forwarded-Host": "media.example"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://media.local:8787/api/scan/start", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := isCrossSite(r, tt.allowed); got != tt.want {
+				t.Errorf("isCrossSite() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
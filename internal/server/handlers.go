@@ -21,10 +21,16 @@ import (
 	"github.com/mmenanno/media-usage-finder/internal/api"
 	"github.com/mmenanno/media-usage-finder/internal/config"
 	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"github.com/mmenanno/media-usage-finder/internal/cron"
 	"github.com/mmenanno/media-usage-finder/internal/database"
 	"github.com/mmenanno/media-usage-finder/internal/disk"
 	"github.com/mmenanno/media-usage-finder/internal/duplicates"
+	"github.com/mmenanno/media-usage-finder/internal/notify"
+	"github.com/mmenanno/media-usage-finder/internal/policy"
+	"github.com/mmenanno/media-usage-finder/internal/quarantine"
+	"github.com/mmenanno/media-usage-finder/internal/reconcile"
 	"github.com/mmenanno/media-usage-finder/internal/scanner"
+	"github.com/mmenanno/media-usage-finder/internal/scheduler"
 	"github.com/mmenanno/media-usage-finder/internal/stats"
 )
 
@@ -44,8 +50,20 @@ type Server struct {
 	clientFactory     *api.ClientFactory      // Factory for creating service clients
 	diskDetector      *disk.Detector          // Disk detector for cross-disk duplicate detection
 	diskResolver      *disk.DeviceResolver    // Device resolver for friendly disk names in UI
+	scheduler         *scheduler.Scheduler    // Cron scheduler for automatic scans
+	watcher           *scanner.Watcher        // Filesystem watcher for real-time file updates
+	rescanQueue       *scanner.RescanQueue    // Rescans the files services report through webhooks
+	quarantine        *quarantine.Manager     // Moves deleted files to a trash directory until purged
+	policies          *policy.Engine          // Evaluates cleanup policies against orphaned files
+	notifier          *notify.Notifier        // Sends scan and cleanup events to the configured sinks
+	reconciler        *reconcile.Engine       // Proposes path mappings for orphans services report as missing
+	snapshotPaths     snapshotPathCache       // Paths of the latest service snapshots, for orphan explanations
+	setupToken        string                  // Required by /setup to create the first admin account, logged at startup
 }
 
+// configPath is where the web UI saves configuration changes
+const configPath = "/appdata/config/config.yaml"
+
 // NewServer creates a new server instance
 func NewServer(db *database.DB, cfg *config.Config, version string) *Server {
 	cacheTTL := cfg.StatsCacheTTL
@@ -64,11 +82,14 @@ func NewServer(db *database.DB, cfg *config.Config, version string) *Server {
 	// Initialize cached template functions
 	srv.templateFuncs = srv.createTemplateFuncs()
 
+	srv.notifier = notify.New(cfg)
 	srv.scanner = scanner.NewScanner(db, cfg)
+	srv.scanner.SetNotifier(srv.notifier)
 
 	// Initialize hash scanner if duplicate detection is enabled
 	if cfg.DuplicateDetection.Enabled {
 		srv.hashScanner = scanner.NewHashScanner(db, &cfg.DuplicateDetection)
+		srv.hashScanner.SetNotifier(srv.notifier)
 		log.Printf("Hash scanner initialized with algorithm: %s", cfg.DuplicateDetection.HashAlgorithm)
 	} else {
 		log.Printf("Duplicate detection disabled in configuration")
@@ -96,9 +117,88 @@ func NewServer(db *database.DB, cfg *config.Config, version string) *Server {
 		srv.statsCache.Invalidate()
 	})
 
+	srv.scheduler = scheduler.New(db, cfg, srv.scheduledTasks())
+	srv.watcher = scanner.NewWatcher(srv.scanner)
+	srv.rescanQueue = scanner.NewRescanQueue(srv.scanner)
+	srv.quarantine = quarantine.New(db, cfg)
+	srv.policies = policy.New(db, cfg, srv.quarantine)
+
+	srv.reconciler = reconcile.New(db, cfg)
+
+	// Evaluate cleanup policies and reconcile orphans with missing files once a scan leaves
+	// orphaned status current
+	srv.scanner.SetOnOrphansUpdated(func(scanID int64) {
+		if _, err := srv.reconciler.Run(context.Background(), scanID); err != nil {
+			log.Printf("WARNING: Failed to reconcile orphaned and missing files: %v", err)
+		}
+		if !srv.policies.Configured() {
+			return
+		}
+		if _, err := srv.policies.Run(context.Background(), scanID, policy.TriggerScan, false); err != nil {
+			log.Printf("WARNING: Failed to run cleanup policies: %v", err)
+		}
+		srv.statsCache.Invalidate()
+	})
+
 	return srv
 }
 
+// scheduledTasks returns the tasks the cron scheduler can trigger
+// Each task runs with a scheduler trigger so its scan record is attributed correctly
+func (s *Server) scheduledTasks() []scheduler.Task {
+	return []scheduler.Task{
+		{
+			Name:     "Full Scan",
+			Schedule: func(c *config.SchedulerConfig) string { return c.FullScanCron },
+			Timeout:  24 * time.Hour,
+			Run: func(ctx context.Context) error {
+				return s.scanner.Scan(scanner.WithTrigger(ctx, scanner.TriggerScheduler), false)
+			},
+		},
+		{
+			Name:     "Incremental Scan",
+			Schedule: func(c *config.SchedulerConfig) string { return c.IncrementalScanCron },
+			Timeout:  24 * time.Hour,
+			Run: func(ctx context.Context) error {
+				return s.scanner.Scan(scanner.WithTrigger(ctx, scanner.TriggerScheduler), true)
+			},
+		},
+		{
+			Name:     "Service Update",
+			Schedule: func(c *config.SchedulerConfig) string { return c.ServiceUpdateCron },
+			Run: func(ctx context.Context) error {
+				err := s.scanner.UpdateAllServices(scanner.WithTrigger(ctx, scanner.TriggerScheduler))
+				s.statsCache.Invalidate()
+				return err
+			},
+		},
+		{
+			Name:     "Hash Scan",
+			Schedule: func(c *config.SchedulerConfig) string { return c.HashScanCron },
+			Timeout:  48 * time.Hour, // Hash scans can take a while
+			Run: func(ctx context.Context) error {
+				// Hash scanner may be re-created (or disabled) when config is saved
+				hashScanner := s.hashScanner
+				if hashScanner == nil {
+					return fmt.Errorf("hash scanning is disabled in configuration")
+				}
+				minSize := s.config.DuplicateDetection.MinFileSize
+				maxSize := s.config.DuplicateDetection.MaxFileSize
+				return hashScanner.Start(scanner.WithTrigger(ctx, scanner.TriggerScheduler), minSize, maxSize)
+			},
+		},
+		{
+			Name:     "Cleanup Scan",
+			Schedule: func(c *config.SchedulerConfig) string { return c.CleanupCron },
+			Run: func(ctx context.Context) error {
+				err := s.scanner.RunCleanupScan(scanner.WithTrigger(ctx, scanner.TriggerScheduler))
+				s.statsCache.Invalidate()
+				return err
+			},
+		},
+	}
+}
+
 // LoadTemplates loads HTML templates
 // Each page template is parsed separately to avoid block name collisions
 func (s *Server) LoadTemplates(pattern string) error {
@@ -117,10 +217,17 @@ func (s *Server) LoadTemplates(pattern string) error {
 		"duplicates.html",
 		"hardlinks.html",
 		"scans.html",
+		"scan_report.html",
 		"logs.html",
+		"quarantine.html",
+		"policies.html",
+		"snapshots.html",
+		"mappings.html",
 		"stats.html",
 		"config.html",
 		"advanced.html",
+		"login.html",
+		"access.html",
 	}
 
 	layoutPath := baseDir + "/layout.html"
@@ -147,6 +254,7 @@ func (s *Server) LoadTemplates(pattern string) error {
 		"logs_table.html",
 		"audit_logs_table.html",
 		"duplicates_table.html",
+		"discovery_results.html",
 	}
 
 	for _, partial := range partials {
@@ -204,6 +312,12 @@ func (s *Server) HandleIndex(w http.ResponseWriter, r *http.Request) {
 		disks = s.diskDetector.GetAllDisks()
 	}
 
+	// Services whose ignored file listings block orphan-based deletes until acknowledged
+	staleServices, err := s.db.ListStaleServices(r.Context())
+	if err != nil {
+		log.Printf("Warning: Failed to list stale services: %v", err)
+	}
+
 	// Duplicate statistics are now included in cached stats (statistics.DuplicateStats)
 	// No need for separate GetDuplicateStats() call
 
@@ -217,6 +331,7 @@ func (s *Server) HandleIndex(w http.ResponseWriter, r *http.Request) {
 		"InterruptedScanPhase": interruptedScanPhase,
 		"Disks":                disks,
 		"DuplicateStats":       statistics.DuplicateStats,
+		"StaleServices":        staleServices,
 	}
 
 	s.renderTemplate(w, "dashboard.html", data)
@@ -283,7 +398,9 @@ func (s *Server) HandleFiles(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	orphanedOnly := r.URL.Query().Get("orphaned") == "true"
+	orphanedDays, _ := strconv.Atoi(r.URL.Query().Get("orphaned_days"))
 	hardlinksOnly := r.URL.Query().Get("hardlink") == "true"
+	safeToDeleteOnly := r.URL.Query().Get("safe_to_delete") == "true"
 	search := r.URL.Query().Get("search")
 	orderBy := r.URL.Query().Get("order")
 	direction := r.URL.Query().Get("direction")
@@ -347,9 +464,9 @@ func (s *Server) HandleFiles(w http.ResponseWriter, r *http.Request) {
 	var err error
 
 	if search != "" {
-		files, total, err = s.db.SearchFiles(search, orphanedOnly, services, serviceFilterMode, hardlinksOnly, extensions, deviceIDs, limit, offset, orderBy, direction)
+		files, total, err = s.db.SearchFiles(search, orphanedOnly, orphanedDays, services, serviceFilterMode, hardlinksOnly, safeToDeleteOnly, extensions, deviceIDs, limit, offset, orderBy, direction)
 	} else {
-		files, total, err = s.db.ListFiles(orphanedOnly, services, serviceFilterMode, hardlinksOnly, extensions, deviceIDs, limit, offset, orderBy, direction)
+		files, total, err = s.db.ListFiles(orphanedOnly, orphanedDays, services, serviceFilterMode, hardlinksOnly, safeToDeleteOnly, extensions, deviceIDs, limit, offset, orderBy, direction)
 	}
 
 	if err != nil {
@@ -409,7 +526,9 @@ func (s *Server) HandleFiles(w http.ResponseWriter, r *http.Request) {
 		TotalPages:               CalculateTotalPages(total, limit),
 		Title:                    "Files",
 		Orphaned:                 orphanedOnly,
+		OrphanedDays:             orphanedDays,
 		Hardlinks:                hardlinksOnly,
+		SafeToDelete:             safeToDeleteOnly,
 		Service:                  legacyService,
 		Services:                 services,
 		ServiceFilterMode:        serviceFilterMode,
@@ -423,6 +542,7 @@ func (s *Server) HandleFiles(w http.ResponseWriter, r *http.Request) {
 		HasDiskLocations:         len(s.config.Disks) > 0,
 		Version:                  s.version,
 		DeleteFilesFromFilesystem: s.config.DeleteFilesFromFilesystem,
+		QuarantineEnabled:        s.quarantine.Enabled(),
 	}
 
 	s.renderTemplate(w, "files.html", data)
@@ -469,6 +589,7 @@ func (s *Server) HandleConfig(w http.ResponseWriter, r *http.Request) {
 		Version:            s.version,
 		CPUCores:           cpuCores,
 		RecommendedWorkers: recommendedWorkers,
+		ScheduledTasks:     s.scheduler.Status(),
 	}
 
 	s.renderTemplate(w, "config.html", data)
@@ -526,7 +647,9 @@ type HardlinkGroup struct {
 // ScanDisplay represents a scan with additional computed fields for display
 type ScanDisplay struct {
 	*database.Scan
-	ActualFileCount int // Actual count of files with this scan_id
+	ActualFileCount int                  // Actual count of files with this scan_id
+	Report          *database.ScanReport // Changes since the previous scan, if a report was created
+	StaleServices   []string             // Services whose file listings this scan ignored
 }
 
 // HandleHardlinks serves the hardlinks page with pagination, search, and sorting
@@ -619,6 +742,15 @@ func (s *Server) HandleScans(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	scanIDs := make([]int64, len(scans))
+	for i, scan := range scans {
+		scanIDs[i] = scan.ID
+	}
+	staleServices, err := s.db.GetScanStaleServices(r.Context(), scanIDs)
+	if err != nil {
+		log.Printf("WARNING: Failed to get stale services of scans: %v", err)
+	}
+
 	// Enhance scans with actual file counts
 	// Prefer the FilesScanned field from the scan record (populated by our persistence logic)
 	// Only query the database as a fallback for old scans that don't have it
@@ -639,6 +771,8 @@ func (s *Server) HandleScans(w http.ResponseWriter, r *http.Request) {
 		scanDisplays = append(scanDisplays, &ScanDisplay{
 			Scan:            scan,
 			ActualFileCount: actualCount,
+			Report:          s.scanReport(scan.ID),
+			StaleServices:   staleServices[scan.ID],
 		})
 	}
 
@@ -928,32 +1062,26 @@ func (s *Server) checkExternalServices() map[string]interface{} {
 		result map[string]string
 	}
 
-	serviceNames := []string{"plex", "sonarr", "radarr", "qbittorrent", "stash", "calibre"}
-	results := make(chan serviceCheck, len(serviceNames))
+	providers := api.Providers()
+	results := make(chan serviceCheck, len(providers))
 	timeout := 2 * time.Second
 	var wg sync.WaitGroup
 
 	// Check all configured services concurrently
-	for _, serviceName := range serviceNames {
-		if !s.clientFactory.IsServiceConfigured(serviceName) {
+	for _, provider := range providers {
+		if !provider.Configured(&s.config.Services) {
 			continue
 		}
 
 		wg.Add(1)
-		go func(name string) {
+		go func(p api.Provider) {
 			defer wg.Done()
-			client, err := s.clientFactory.CreateClient(name, timeout)
-			if err != nil {
-				results <- serviceCheck{name, map[string]string{"status": "error", "error": err.Error()}}
-				return
-			}
-
-			if err := client.Test(); err != nil {
-				results <- serviceCheck{name, map[string]string{"status": "error", "error": err.Error()}}
+			if err := p.Test(s.config, timeout); err != nil {
+				results <- serviceCheck{p.Name(), map[string]string{"status": "error", "error": err.Error()}}
 			} else {
-				results <- serviceCheck{name, map[string]string{"status": "ok"}}
+				results <- serviceCheck{p.Name(), map[string]string{"status": "ok"}}
 			}
-		}(serviceName)
+		}(provider)
 	}
 
 	// Close results channel when all checks complete
@@ -1117,7 +1245,7 @@ func (s *Server) HandleUpdateAllServices(w http.ResponseWriter, r *http.Request)
 
 	// Run service updates in background
 	go func() {
-		if err := s.scanner.UpdateAllServices(); err != nil {
+		if err := s.scanner.UpdateAllServices(context.Background()); err != nil {
 			log.Printf("ERROR: Failed to update all services: %v", err)
 		} else {
 			log.Printf("INFO: All services updated successfully")
@@ -1144,15 +1272,7 @@ func (s *Server) HandleUpdateSingleService(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Validate service name
-	validServices := map[string]bool{
-		"plex":        true,
-		"sonarr":      true,
-		"radarr":      true,
-		"qbittorrent": true,
-		"stash":       true,
-		"calibre":     true,
-	}
-	if !validServices[serviceName] {
+	if api.Lookup(serviceName) == nil {
 		respondError(w, http.StatusBadRequest, "Invalid service name", "invalid_service")
 		return
 	}
@@ -1237,7 +1357,7 @@ func (s *Server) HandleCleanupScan(w http.ResponseWriter, r *http.Request) {
 
 	// Run cleanup scan in background
 	go func() {
-		if err := s.scanner.RunCleanupScan(); err != nil {
+		if err := s.scanner.RunCleanupScan(context.Background()); err != nil {
 			log.Printf("ERROR: Failed to run cleanup scan: %v", err)
 		} else {
 			log.Printf("INFO: Cleanup scan completed successfully")
@@ -1347,9 +1467,17 @@ func (s *Server) HandleScanProgressHTML(w http.ResponseWriter, r *http.Request)
 		"Scanning filesystem":      `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M9 12h6m-6 4h6m2 5H7a2 2 0 01-2-2V5a2 2 0 012-2h5.586a1 1 0 01.707.293l5.414 5.414a1 1 0 01.293.707V19a2 2 0 01-2 2z"></path></svg>`,
 		"Loading File Cache":       `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M4 7v10c0 2.21 3.582 4 8 4s8-1.79 8-4V7M4 7c0 2.21 3.582 4 8 4s8-1.79 8-4M4 7c0-2.21 3.582-4 8-4s8 1.79 8 4m0 5c0 2.21-3.582 4-8 4s-8-1.79-8-4"></path></svg>`,
 		"Checking Plex":            `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 4v16M17 4v16M3 8h4m10 0h4M3 12h18M3 16h4m10 0h4M4 20h16a1 1 0 001-1V5a1 1 0 00-1-1H4a1 1 0 00-1 1v14a1 1 0 001 1z"></path></svg>`,
+		"Checking Jellyfin":        `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 4v16M17 4v16M3 8h4m10 0h4M3 12h18M3 16h4m10 0h4M4 20h16a1 1 0 001-1V5a1 1 0 00-1-1H4a1 1 0 00-1 1v14a1 1 0 001 1z"></path></svg>`,
+		"Checking Emby":            `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 4v16M17 4v16M3 8h4m10 0h4M3 12h18M3 16h4m10 0h4M4 20h16a1 1 0 001-1V5a1 1 0 00-1-1H4a1 1 0 00-1 1v14a1 1 0 001 1z"></path></svg>`,
 		"Checking Sonarr":          `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M9.75 17L9 20l-1 1h8l-1-1-.75-3M3 13h18M5 17h14a2 2 0 002-2V5a2 2 0 00-2-2H5a2 2 0 00-2 2v10a2 2 0 002 2z"></path></svg>`,
 		"Checking Radarr":          `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 4v16M17 4v16M3 8h4m10 0h4M3 12h18M3 16h4m10 0h4M4 20h16a1 1 0 001-1V5a1 1 0 00-1-1H4a1 1 0 00-1 1v14a1 1 0 001 1z"></path></svg>`,
+		"Checking Lidarr":          `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M9 19V6l12-3v13M9 19c0 1.105-1.343 2-3 2s-3-.895-3-2 1.343-2 3-2 3 .895 3 2zm12-3c0 1.105-1.343 2-3 2s-3-.895-3-2 1.343-2 3-2 3 .895 3 2zM9 10l12-3"></path></svg>`,
+		"Checking Readarr":         `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M12 6.253v13m0-13C10.832 5.477 9.246 5 7.5 5S4.168 5.477 3 6.253v13C4.168 18.477 5.754 18 7.5 18s3.332.477 4.5 1.253m0-13C13.168 5.477 14.754 5 16.5 5c1.747 0 3.332.477 4.5 1.253v13C19.832 18.477 18.247 18 16.5 18c-1.746 0-3.332.477-4.5 1.253"></path></svg>`,
 		"Checking qBittorrent":     `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 16a4 4 0 01-.88-7.903A5 5 0 1115.9 6L16 6a5 5 0 011 9.9M15 13l-3-3m0 0l-3 3m3-3v12"></path></svg>`,
+		"Checking Transmission":    `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 16a4 4 0 01-.88-7.903A5 5 0 1115.9 6L16 6a5 5 0 011 9.9M15 13l-3-3m0 0l-3 3m3-3v12"></path></svg>`,
+		"Checking Deluge":          `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 16a4 4 0 01-.88-7.903A5 5 0 1115.9 6L16 6a5 5 0 011 9.9M15 13l-3-3m0 0l-3 3m3-3v12"></path></svg>`,
+		"Checking SABnzbd":         `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 16a4 4 0 01-.88-7.903A5 5 0 1115.9 6L16 6a5 5 0 011 9.9M15 13l-3-3m0 0l-3 3m3-3v12"></path></svg>`,
+		"Checking NZBGet":          `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M7 16a4 4 0 01-.88-7.903A5 5 0 1115.9 6L16 6a5 5 0 011 9.9M15 13l-3-3m0 0l-3 3m3-3v12"></path></svg>`,
 		"Checking Stash":           `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M5 19a2 2 0 01-2-2V7a2 2 0 012-2h4l2 2h4a2 2 0 012 2v1M5 19h14a2 2 0 002-2v-5a2 2 0 00-2-2H9a2 2 0 00-2 2v5a2 2 0 01-2 2z"></path></svg>`,
 		"Updating orphaned status": `<svg class="w-5 h-5 inline-block mr-2" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M4 4v5h.582m15.356 2A8.001 8.001 0 004.582 9m0 0H9m11 11v-5h-.581m0 0a8.003 8.003 0 01-15.357-2m15.357 2H15"></path></svg>`,
 		"Completed":                `<svg class="w-5 h-5 inline-block mr-2 text-green-500" fill="none" stroke="currentColor" viewBox="0 0 24 24"><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M9 12l2 2 4-4m6 2a9 9 0 11-18 0 9 9 0 0118 0z"></path></svg>`,
@@ -1700,58 +1828,36 @@ func (s *Server) HandleSaveConfig(w http.ResponseWriter, r *http.Request) {
 	// Collect all validation errors before updating config
 	var validationErrors []string
 
-	// Validate Plex config
-	plexURL := r.FormValue("plex_url")
-	if err := ValidateURL(plexURL); err != nil {
-		validationErrors = append(validationErrors, fmt.Sprintf("Plex URL: %v", err))
-	}
-
-	// Validate Sonarr config
-	sonarrURL := r.FormValue("sonarr_url")
-	if err := ValidateURL(sonarrURL); err != nil {
-		validationErrors = append(validationErrors, fmt.Sprintf("Sonarr URL: %v", err))
-	}
-	sonarrAPIKey := r.FormValue("sonarr_api_key")
-	if err := ValidateAPIKey(sonarrAPIKey); err != nil {
-		validationErrors = append(validationErrors, fmt.Sprintf("Sonarr API key: %v", err))
-	}
-
-	// Validate Radarr config
-	radarrURL := r.FormValue("radarr_url")
-	if err := ValidateURL(radarrURL); err != nil {
-		validationErrors = append(validationErrors, fmt.Sprintf("Radarr URL: %v", err))
-	}
-	radarrAPIKey := r.FormValue("radarr_api_key")
-	if err := ValidateAPIKey(radarrAPIKey); err != nil {
-		validationErrors = append(validationErrors, fmt.Sprintf("Radarr API key: %v", err))
-	}
+	// Validate service settings for every registered provider
+	for _, provider := range api.Providers() {
+		setRequired, missingRequired := 0, 0
+		for _, field := range provider.ConfigFields() {
+			value := strings.TrimSpace(r.FormValue(field.FormName(provider)))
 
-	// Validate qBittorrent config
-	qbURL := r.FormValue("qbittorrent_url")
-	if err := ValidateURL(qbURL); err != nil {
-		validationErrors = append(validationErrors, fmt.Sprintf("qBittorrent URL: %v", err))
-	}
-	qbProxyURL := r.FormValue("qbittorrent_qui_proxy_url")
-	if err := ValidateURL(qbProxyURL); err != nil {
-		validationErrors = append(validationErrors, fmt.Sprintf("qBittorrent proxy URL: %v", err))
-	}
+			var err error
+			switch field.Kind {
+			case api.FieldURL:
+				err = ValidateURL(value)
+			case api.FieldAPIKey:
+				err = ValidateAPIKey(value)
+			}
+			if err != nil {
+				validationErrors = append(validationErrors, fmt.Sprintf("%s: %v", field.Label, err))
+			}
 
-	// Validate Stash config
-	stashURL := r.FormValue("stash_url")
-	if err := ValidateURL(stashURL); err != nil {
-		validationErrors = append(validationErrors, fmt.Sprintf("Stash URL: %v", err))
-	}
-	stashAPIKey := r.FormValue("stash_api_key")
-	if err := ValidateAPIKey(stashAPIKey); err != nil {
-		validationErrors = append(validationErrors, fmt.Sprintf("Stash API key: %v", err))
-	}
+			if field.Required {
+				if value == "" {
+					missingRequired++
+				} else {
+					setRequired++
+				}
+			}
+		}
 
-	// Validate Calibre config (paths don't need URL validation)
-	calibreLibraryPath := r.FormValue("calibre_library_path")
-	calibreDBPath := r.FormValue("calibre_db_path")
-	// Calibre paths are optional, but if one is set, both should be set
-	if (calibreLibraryPath != "" && calibreDBPath == "") || (calibreLibraryPath == "" && calibreDBPath != "") {
-		validationErrors = append(validationErrors, "Calibre: Both library path and database path must be set together")
+		// Services are optional, but a partially configured service cannot be queried
+		if setRequired > 0 && missingRequired > 0 {
+			validationErrors = append(validationErrors, fmt.Sprintf("%s: All required settings must be set together", provider.DisplayName()))
+		}
 	}
 
 	// If there are validation errors, show them in the error panel
@@ -1761,27 +1867,13 @@ func (s *Server) HandleSaveConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// All validations passed, update config
-	s.config.Services.Plex.URL = plexURL
-	s.config.Services.Plex.Token = r.FormValue("plex_token")
+	for _, provider := range api.Providers() {
+		api.ApplyFormValues(provider, &s.config.Services, r.FormValue)
+	}
 	// Parse selected Plex libraries (multiple checkbox values)
 	s.config.Services.Plex.Libraries = r.Form["plex_libraries"]
-
-	s.config.Services.Sonarr.URL = sonarrURL
-	s.config.Services.Sonarr.APIKey = sonarrAPIKey
-
-	s.config.Services.Radarr.URL = radarrURL
-	s.config.Services.Radarr.APIKey = radarrAPIKey
-
-	s.config.Services.QBittorrent.URL = qbURL
-	s.config.Services.QBittorrent.Username = r.FormValue("qbittorrent_username")
-	s.config.Services.QBittorrent.Password = r.FormValue("qbittorrent_password")
-	s.config.Services.QBittorrent.QuiProxyURL = qbProxyURL
-
-	s.config.Services.Stash.URL = stashURL
-	s.config.Services.Stash.APIKey = stashAPIKey
-
-	s.config.Services.Calibre.LibraryPath = calibreLibraryPath
-	s.config.Services.Calibre.DBPath = calibreDBPath
+	s.config.Services.Jellyfin.Libraries = r.Form["jellyfin_libraries"]
+	s.config.Services.Emby.Libraries = r.Form["emby_libraries"]
 
 	// Parse scan paths (one per line)
 	if scanPathsStr := r.FormValue("scan_paths"); scanPathsStr != "" {
@@ -1903,6 +1995,99 @@ func (s *Server) HandleSaveConfig(w http.ResponseWriter, r *http.Request) {
 	if s.config.DuplicateConsolidation.Strategy == "" {
 		s.config.DuplicateConsolidation.Strategy = "least_full_disk"
 	}
+	s.config.DuplicateConsolidation.CrossDiskMode = r.FormValue("cross_disk_mode")
+	if s.config.DuplicateConsolidation.CrossDiskMode == "" {
+		s.config.DuplicateConsolidation.CrossDiskMode = "delete"
+	}
+	s.config.DuplicateConsolidation.SameDiskMode = r.FormValue("same_disk_mode")
+	if s.config.DuplicateConsolidation.SameDiskMode == "" {
+		s.config.DuplicateConsolidation.SameDiskMode = "hardlink"
+	}
+
+	// Parse scheduler settings (empty schedule = task disabled)
+	s.config.Scheduler.Enabled = r.FormValue("scheduler_enabled") != ""
+	cronFields := []struct {
+		field string
+		label string
+		dest  *string
+	}{
+		{"full_scan_cron", "Full Scan", &s.config.Scheduler.FullScanCron},
+		{"incremental_scan_cron", "Incremental Scan", &s.config.Scheduler.IncrementalScanCron},
+		{"service_update_cron", "Update All Services", &s.config.Scheduler.ServiceUpdateCron},
+		{"hash_scan_cron", "Hash Scan", &s.config.Scheduler.HashScanCron},
+		{"cleanup_cron", "Cleanup Scan", &s.config.Scheduler.CleanupCron},
+	}
+	for _, cf := range cronFields {
+		expr := strings.TrimSpace(r.FormValue(cf.field))
+		if expr != "" {
+			if err := cron.Validate(expr); err != nil {
+				validationErrors = append(validationErrors, fmt.Sprintf("%s schedule: %v", cf.label, err))
+				continue
+			}
+		}
+		*cf.dest = expr
+	}
+
+	// Parse quarantine settings
+	s.config.Quarantine.Enabled = r.FormValue("quarantine_enabled") != ""
+	if v := r.FormValue("quarantine_retention"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			s.config.Quarantine.Retention = d
+		} else {
+			validationErrors = append(validationErrors, "Quarantine retention must be a valid duration (e.g. 168h)")
+		}
+	}
+	if v := r.FormValue("quarantine_min_free_percent"); v != "" {
+		if pct, err := strconv.ParseFloat(v, 64); err == nil {
+			s.config.Quarantine.MinFreePercent = pct
+		} else {
+			validationErrors = append(validationErrors, "Quarantine minimum free space must be a number")
+		}
+	}
+
+	// Parse filesystem watcher settings
+	s.config.Watcher.Enabled = r.FormValue("watcher_enabled") != ""
+	if v := r.FormValue("watcher_debounce_interval"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			s.config.Watcher.DebounceInterval = d
+		} else {
+			validationErrors = append(validationErrors, "Watcher debounce interval must be a valid duration (e.g. 2s)")
+		}
+	}
+	if v := r.FormValue("watcher_reassociate_interval"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			s.config.Watcher.ReassociateInterval = d
+		} else {
+			validationErrors = append(validationErrors, "Watcher service update interval must be a valid duration (e.g. 5m)")
+		}
+	}
+
+	// Parse webhook settings
+	s.config.Webhooks.Enabled = r.FormValue("webhooks_enabled") != ""
+	if v := r.FormValue("webhooks_debounce_interval"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			s.config.Webhooks.DebounceInterval = d
+		} else {
+			validationErrors = append(validationErrors, "Webhook debounce interval must be a valid duration (e.g. 30s)")
+		}
+	}
+
+	// Parse safeguard settings
+	s.config.Safeguards.Enabled = r.FormValue("safeguards_enabled") != ""
+	if v := r.FormValue("safeguards_max_drop_percent"); v != "" {
+		if pct, err := strconv.ParseFloat(v, 64); err == nil {
+			s.config.Safeguards.MaxDropPercent = pct
+		} else {
+			validationErrors = append(validationErrors, "Safeguard maximum drop must be a number")
+		}
+	}
+	if v := r.FormValue("safeguards_min_previous_files"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			s.config.Safeguards.MinPreviousFiles = n
+		} else {
+			validationErrors = append(validationErrors, "Safeguard minimum previous files must be a whole number")
+		}
+	}
 
 	// Parse disk configuration
 	var disks []config.DiskConfig
@@ -1967,7 +2152,7 @@ func (s *Server) HandleSaveConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Save config to file
-	if err := s.config.Save("/appdata/config/config.yaml"); err != nil {
+	if err := s.config.Save(configPath); err != nil {
 		s.renderValidationErrors(w, "Failed to Save Configuration", []string{err.Error()})
 		return
 	}
@@ -1976,6 +2161,7 @@ func (s *Server) HandleSaveConfig(w http.ResponseWriter, r *http.Request) {
 	if s.config.DuplicateDetection.Enabled && s.hashScanner == nil {
 		// Duplicate detection was enabled - initialize hash scanner
 		s.hashScanner = scanner.NewHashScanner(s.db, &s.config.DuplicateDetection)
+		s.hashScanner.SetNotifier(s.notifier)
 		log.Printf("Hash scanner initialized with algorithm: %s", s.config.DuplicateDetection.HashAlgorithm)
 	} else if !s.config.DuplicateDetection.Enabled && s.hashScanner != nil {
 		// Duplicate detection was disabled - clear hash scanner
@@ -1983,6 +2169,11 @@ func (s *Server) HandleSaveConfig(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Hash scanner disabled")
 	}
 
+	// Restart the filesystem watcher so scan path and interval changes apply
+	if err := s.watcher.Apply(); err != nil {
+		log.Printf("Warning: Failed to start filesystem watcher: %v", err)
+	}
+
 	// Success - show toast and clear error panel
 	w.Header().Set("X-Toast-Message", "Configuration saved successfully")
 	w.Header().Set("X-Toast-Type", "success")
@@ -2413,26 +2604,6 @@ func (s *Server) HandleGetFileDiskLocations(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(response)
 }
 
-// getServiceDisplayName returns the properly capitalized display name for a service
-func getServiceDisplayName(serviceName string) string {
-	switch serviceName {
-	case "plex":
-		return "Plex"
-	case "sonarr":
-		return "Sonarr"
-	case "radarr":
-		return "Radarr"
-	case "qbittorrent":
-		return "qBittorrent"
-	case "stash":
-		return "Stash"
-	case "calibre":
-		return "Calibre"
-	default:
-		return serviceName
-	}
-}
-
 // HandleTestService tests connection to a service using current form values
 func (s *Server) HandleTestService(w http.ResponseWriter, r *http.Request) {
 	if !requireMethod(w, r, http.MethodPost) {
@@ -2440,101 +2611,33 @@ func (s *Server) HandleTestService(w http.ResponseWriter, r *http.Request) {
 	}
 
 	serviceName := r.URL.Query().Get("service")
-	displayName := getServiceDisplayName(serviceName)
+	provider := api.Lookup(serviceName)
+	if provider == nil {
+		respondError(w, http.StatusBadRequest, "Unknown service", "unknown_service")
+		return
+	}
+	displayName := provider.DisplayName()
 
 	// Create a temporary config with form values for testing
 	testConfig := *s.config // Copy current config
-
-	// Read form values and validate based on service type
-	var missingField string
-	switch serviceName {
-	case "plex":
-		url := strings.TrimSpace(r.FormValue("plex_url"))
-		token := strings.TrimSpace(r.FormValue("plex_token"))
-		if url == "" {
-			missingField = "Plex URL"
-		} else if token == "" {
-			missingField = "Plex Token"
-		} else {
-			testConfig.Services.Plex.URL = url
-			testConfig.Services.Plex.Token = token
-		}
-	case "sonarr":
-		url := strings.TrimSpace(r.FormValue("sonarr_url"))
-		apiKey := strings.TrimSpace(r.FormValue("sonarr_api_key"))
-		if url == "" {
-			missingField = "Sonarr URL"
-		} else if apiKey == "" {
-			missingField = "Sonarr API Key"
-		} else {
-			testConfig.Services.Sonarr.URL = url
-			testConfig.Services.Sonarr.APIKey = apiKey
-		}
-	case "radarr":
-		url := strings.TrimSpace(r.FormValue("radarr_url"))
-		apiKey := strings.TrimSpace(r.FormValue("radarr_api_key"))
-		if url == "" {
-			missingField = "Radarr URL"
-		} else if apiKey == "" {
-			missingField = "Radarr API Key"
-		} else {
-			testConfig.Services.Radarr.URL = url
-			testConfig.Services.Radarr.APIKey = apiKey
-		}
-	case "qbittorrent":
-		url := strings.TrimSpace(r.FormValue("qbittorrent_url"))
-		if url == "" {
-			missingField = "qBittorrent URL"
-		} else {
-			testConfig.Services.QBittorrent.URL = url
-			testConfig.Services.QBittorrent.Username = strings.TrimSpace(r.FormValue("qbittorrent_username"))
-			testConfig.Services.QBittorrent.Password = strings.TrimSpace(r.FormValue("qbittorrent_password"))
-			testConfig.Services.QBittorrent.QuiProxyURL = strings.TrimSpace(r.FormValue("qbittorrent_qui_proxy_url"))
-		}
-	case "stash":
-		url := strings.TrimSpace(r.FormValue("stash_url"))
-		apiKey := strings.TrimSpace(r.FormValue("stash_api_key"))
-		if url == "" {
-			missingField = "Stash URL"
-		} else if apiKey == "" {
-			missingField = "Stash API Key"
-		} else {
-			testConfig.Services.Stash.URL = url
-			testConfig.Services.Stash.APIKey = apiKey
-		}
-	case "calibre":
-		libraryPath := strings.TrimSpace(r.FormValue("calibre_library_path"))
-		dbPath := strings.TrimSpace(r.FormValue("calibre_db_path"))
-		if libraryPath == "" {
-			missingField = "Calibre Library Path"
-		} else if dbPath == "" {
-			missingField = "Calibre Database Path"
-		} else {
-			testConfig.Services.Calibre.LibraryPath = libraryPath
-			testConfig.Services.Calibre.DBPath = dbPath
-		}
-	}
+	api.ApplyFormValues(provider, &testConfig.Services, r.FormValue)
 
 	// If a required field is missing, return warning toast
-	if missingField != "" {
+	if missingField := api.MissingRequiredField(provider, &testConfig.Services); missingField != "" {
 		w.Header().Set("X-Toast-Message", fmt.Sprintf("No %s provided", missingField))
 		w.Header().Set("X-Toast-Type", "warning")
 		respondSuccess(w, "No configuration to test", nil)
 		return
 	}
-
-	// Create a temporary client factory with the test config
-	testFactory := api.NewClientFactory(&testConfig)
-
-	// Use factory to create client with test config
-	testClient, err := testFactory.CreateClient(serviceName, testConfig.APITimeout)
-	if err != nil {
-		respondError(w, http.StatusBadRequest, "Unknown service", "unknown_service")
+	if !provider.Configured(&testConfig.Services) {
+		w.Header().Set("X-Toast-Message", fmt.Sprintf("No %s configuration provided", displayName))
+		w.Header().Set("X-Toast-Type", "warning")
+		respondSuccess(w, "No configuration to test", nil)
 		return
 	}
 
 	// Test the connection
-	if err := testClient.Test(); err != nil {
+	if err := provider.Test(&testConfig, testConfig.APITimeout); err != nil {
 		w.Header().Set("X-Toast-Message", fmt.Sprintf("%s connection failed: %v", displayName, err))
 		w.Header().Set("X-Toast-Type", "error")
 		respondError(w, http.StatusBadRequest, err.Error(), "connection_failed")
@@ -2552,44 +2655,42 @@ func (s *Server) HandleTestService(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, response)
 }
 
-// HandleGetPlexLibraries fetches available Plex libraries for selection in UI
-func (s *Server) HandleGetPlexLibraries(w http.ResponseWriter, r *http.Request) {
+// HandleGetLibraries fetches a service's libraries for selection in UI
+// The service's settings are read from query parameters named like its form fields (e.g. plex_url)
+func (s *Server) HandleGetLibraries(w http.ResponseWriter, r *http.Request) {
 	if !requireMethod(w, r, http.MethodGet) {
 		return
 	}
 
-	// Get Plex URL and token from query parameters
-	plexURL := strings.TrimSpace(r.URL.Query().Get("url"))
-	plexToken := strings.TrimSpace(r.URL.Query().Get("token"))
-
-	if plexURL == "" {
-		respondError(w, http.StatusBadRequest, "Plex URL is required", "missing_url")
+	provider := api.Lookup(r.URL.Query().Get("service"))
+	if provider == nil {
+		respondError(w, http.StatusBadRequest, "Unknown service", "unknown_service")
 		return
 	}
 
-	if plexToken == "" {
-		respondError(w, http.StatusBadRequest, "Plex token is required", "missing_token")
+	// Create a temporary config with the query values
+	testConfig := *s.config
+	api.ApplyFormValues(provider, &testConfig.Services, r.FormValue)
+	if missingField := api.MissingRequiredField(provider, &testConfig.Services); missingField != "" {
+		respondError(w, http.StatusBadRequest, missingField+" is required", "missing_field")
 		return
 	}
 
-	// Create a temporary Plex client
-	client := api.NewPlexClient(plexURL, plexToken, s.config.APITimeout)
-
 	// First test the connection
-	if err := client.Test(); err != nil {
+	if err := provider.Test(&testConfig, testConfig.APITimeout); err != nil {
 		respondError(w, http.StatusBadRequest, fmt.Sprintf("Connection failed: %v", err), "connection_failed")
 		return
 	}
 
-	// Fetch library sections
-	ctx := r.Context()
-	libraries, err := client.GetLibrarySections(ctx)
+	libraries, err := provider.Libraries(r.Context(), &testConfig, testConfig.APITimeout)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to fetch libraries: %v", err), "fetch_failed")
 		return
 	}
+	if libraries == nil {
+		libraries = []api.Library{}
+	}
 
-	// Return libraries as JSON
 	respondJSON(w, http.StatusOK, libraries)
 }
 
@@ -2715,7 +2816,7 @@ func (s *Server) HandleTestPathMappings(w http.ResponseWriter, r *http.Request)
 	// Test service path mappings
 	if serviceMappingsStr != "" {
 		// First, collect service configurations from form values for intelligent testing
-		serviceConfigs := s.collectServiceConfigsFromForm(r)
+		formConfig := s.serviceConfigFromForm(r)
 
 		lines := strings.Split(serviceMappingsStr, "\n")
 		for i, line := range lines {
@@ -2754,8 +2855,8 @@ func (s *Server) HandleTestPathMappings(w http.ResponseWriter, r *http.Request)
 			}
 
 			// Intelligent validation: query service for actual file and test translation
-			if cfg, hasConfig := serviceConfigs[serviceName]; hasConfig {
-				if err := s.testServicePathMapping(serviceName, localPath, servicePath, cfg); err != nil {
+			if provider := api.Lookup(serviceName); provider != nil && provider.Configured(&formConfig.Services) {
+				if err := s.testServicePathMapping(r.Context(), provider, formConfig, localPath, servicePath); err != nil {
 					errors = append(errors, fmt.Sprintf("%s:%s=%s: mapping validation failed: %v", serviceName, servicePath, localPath, err))
 				} else {
 					successes = append(successes, fmt.Sprintf("%s:%s=%s: OK (local path accessible, mapping verified)", serviceName, servicePath, localPath))
@@ -2791,107 +2892,26 @@ func (s *Server) HandleTestPathMappings(w http.ResponseWriter, r *http.Request)
 	w.Write([]byte(`<script>document.getElementById('validation-errors').classList.add('hidden');</script>`))
 }
 
-// collectServiceConfigsFromForm reads service configurations from form values
-// Returns a map of service name to temporary config for testing
-func (s *Server) collectServiceConfigsFromForm(r *http.Request) map[string]interface{} {
-	configs := make(map[string]interface{})
-
-	// Plex config
-	if plexURL := strings.TrimSpace(r.FormValue("plex_url")); plexURL != "" {
-		if plexToken := strings.TrimSpace(r.FormValue("plex_token")); plexToken != "" {
-			configs["plex"] = map[string]string{
-				"url":   plexURL,
-				"token": plexToken,
-			}
-		}
+// serviceConfigFromForm returns a copy of the configuration with every service's settings taken from
+// the form, so path mappings can be tested before they are saved
+func (s *Server) serviceConfigFromForm(r *http.Request) *config.Config {
+	formConfig := *s.config // Copy current config
+	for _, provider := range api.Providers() {
+		api.ApplyFormValues(provider, &formConfig.Services, r.FormValue)
 	}
-
-	// Sonarr config
-	if sonarrURL := strings.TrimSpace(r.FormValue("sonarr_url")); sonarrURL != "" {
-		if sonarrKey := strings.TrimSpace(r.FormValue("sonarr_api_key")); sonarrKey != "" {
-			configs["sonarr"] = map[string]string{
-				"url":     sonarrURL,
-				"api_key": sonarrKey,
-			}
-		}
-	}
-
-	// Radarr config
-	if radarrURL := strings.TrimSpace(r.FormValue("radarr_url")); radarrURL != "" {
-		if radarrKey := strings.TrimSpace(r.FormValue("radarr_api_key")); radarrKey != "" {
-			configs["radarr"] = map[string]string{
-				"url":     radarrURL,
-				"api_key": radarrKey,
-			}
-		}
-	}
-
-	// qBittorrent config
-	if qbitURL := strings.TrimSpace(r.FormValue("qbittorrent_url")); qbitURL != "" {
-		// qBittorrent requires username and password
-		qbitUsername := strings.TrimSpace(r.FormValue("qbittorrent_username"))
-		qbitPassword := strings.TrimSpace(r.FormValue("qbittorrent_password"))
-		qbitQuiProxy := strings.TrimSpace(r.FormValue("qbittorrent_qui_proxy_url"))
-
-		if qbitUsername != "" && qbitPassword != "" {
-			configs["qbittorrent"] = map[string]string{
-				"url":           qbitURL,
-				"username":      qbitUsername,
-				"password":      qbitPassword,
-				"qui_proxy_url": qbitQuiProxy, // May be empty
-			}
-		}
-	}
-
-	// Stash config
-	if stashURL := strings.TrimSpace(r.FormValue("stash_url")); stashURL != "" {
-		if stashKey := strings.TrimSpace(r.FormValue("stash_api_key")); stashKey != "" {
-			configs["stash"] = map[string]string{
-				"url":     stashURL,
-				"api_key": stashKey,
-			}
-		}
-	}
-
-	return configs
+	return &formConfig
 }
 
 // testServicePathMapping validates a service path mapping by querying the service
 // and testing if the path translation works correctly
-func (s *Server) testServicePathMapping(serviceName, localPath, servicePath string, cfg interface{}) error {
-	// Get a sample file path from the service
-	var sampleFilePath string
-	var err error
-
-	switch serviceName {
-	case "plex":
-		configMap := cfg.(map[string]string)
-		sampleFilePath, err = s.getSamplePlexFilePath(configMap["url"], configMap["token"], servicePath)
-	case "sonarr":
-		configMap := cfg.(map[string]string)
-		sampleFilePath, err = s.getSampleArrFilePath(configMap["url"], configMap["api_key"], servicePath, "sonarr")
-	case "radarr":
-		configMap := cfg.(map[string]string)
-		sampleFilePath, err = s.getSampleArrFilePath(configMap["url"], configMap["api_key"], servicePath, "radarr")
-	case "qbittorrent":
-		configMap := cfg.(map[string]string)
-		sampleFilePath, err = s.getSampleQBittorrentFilePath(configMap["url"], configMap["username"], configMap["password"], configMap["qui_proxy_url"], servicePath)
-	case "stash":
-		configMap := cfg.(map[string]string)
-		sampleFilePath, err = s.getSampleStashFilePath(configMap["url"], configMap["api_key"], servicePath)
-	default:
-		return fmt.Errorf("unsupported service: %s", serviceName)
-	}
-
-	if err != nil {
+func (s *Server) testServicePathMapping(ctx context.Context, provider api.Provider, cfg *config.Config, localPath, servicePath string) error {
+	// Get a sample file path from the service (optimized - stops at first match)
+	samples, err := provider.SampleFiles(ctx, cfg, cfg.APITimeout, servicePath, 1)
+	if err != nil || len(samples) == 0 {
 		// If we can't get a sample file, that's okay - service might be empty or not configured yet
 		return nil
 	}
-
-	if sampleFilePath == "" {
-		// No files found in service - can't validate mapping
-		return nil
-	}
+	sampleFilePath := samples[0]
 
 	// Test if we can translate the service path to local path
 	if !strings.HasPrefix(sampleFilePath, servicePath) {
@@ -2909,128 +2929,11 @@ func (s *Server) testServicePathMapping(serviceName, localPath, servicePath stri
 	return nil
 }
 
-// getSamplePlexFilePath gets a sample file path from Plex library
-func (s *Server) getSamplePlexFilePath(url, token, pathPrefix string) (string, error) {
-	// Create temporary config for testing
-	testConfig := *s.config
-	testConfig.Services.Plex.URL = url
-	testConfig.Services.Plex.Token = token
-
-	// Create client via factory
-	factory := api.NewClientFactory(&testConfig)
-	client, err := factory.CreateClient("plex", testConfig.APITimeout)
-	if err != nil {
-		return "", err
-	}
-
-	// Cast to PlexClient to access GetSampleFile
-	plexClient, ok := client.(*api.PlexClient)
-	if !ok {
-		return "", fmt.Errorf("failed to cast to PlexClient")
-	}
-
-	// Get a sample file that matches the path prefix (optimized - stops at first match)
-	return plexClient.GetSampleFile(pathPrefix)
-}
-
-// getSampleArrFilePath gets a sample file path from Sonarr/Radarr
-func (s *Server) getSampleArrFilePath(url, apiKey, pathPrefix, serviceType string) (string, error) {
-	// Create temporary config for testing
-	testConfig := *s.config
-
-	switch serviceType {
-	case "sonarr":
-		testConfig.Services.Sonarr.URL = url
-		testConfig.Services.Sonarr.APIKey = apiKey
-	case "radarr":
-		testConfig.Services.Radarr.URL = url
-		testConfig.Services.Radarr.APIKey = apiKey
-	}
-
-	// Create client via factory
-	factory := api.NewClientFactory(&testConfig)
-	client, err := factory.CreateClient(serviceType, testConfig.APITimeout)
-	if err != nil {
-		return "", err
-	}
-
-	// Cast to appropriate client type to access GetSampleFile
-	switch serviceType {
-	case "sonarr":
-		sonarrClient, ok := client.(*api.SonarrClient)
-		if !ok {
-			return "", fmt.Errorf("failed to cast to SonarrClient")
-		}
-
-		// Get a sample file that matches the path prefix (optimized - stops at first match)
-		return sonarrClient.GetSampleFile(pathPrefix)
-	case "radarr":
-		radarrClient, ok := client.(*api.RadarrClient)
-		if !ok {
-			return "", fmt.Errorf("failed to cast to RadarrClient")
-		}
-
-		// Get a sample file that matches the path prefix (optimized - stops at first match)
-		return radarrClient.GetSampleFile(pathPrefix)
-	}
-
-	return "", nil
-}
-
-// getSampleQBittorrentFilePath gets a sample file path from qBittorrent
-func (s *Server) getSampleQBittorrentFilePath(url, username, password, quiProxyURL, pathPrefix string) (string, error) {
-	// Create temporary config for testing
-	testConfig := *s.config
-	testConfig.Services.QBittorrent.URL = url
-	testConfig.Services.QBittorrent.Username = username
-	testConfig.Services.QBittorrent.Password = password
-	testConfig.Services.QBittorrent.QuiProxyURL = quiProxyURL
-
-	// Create client via factory
-	factory := api.NewClientFactory(&testConfig)
-	client, err := factory.CreateClient("qbittorrent", testConfig.APITimeout)
-	if err != nil {
-		return "", err
-	}
-
-	// Cast to QBittorrentClient to access GetSampleFile
-	qbitClient, ok := client.(*api.QBittorrentClient)
-	if !ok {
-		return "", fmt.Errorf("failed to cast to QBittorrentClient")
-	}
-
-	// Get a sample file that matches the path prefix (optimized - stops at first match)
-	return qbitClient.GetSampleFile(pathPrefix)
-}
-
-// getSampleStashFilePath gets a sample file path from Stash
-func (s *Server) getSampleStashFilePath(url, apiKey, pathPrefix string) (string, error) {
-	// Create temporary config for testing
-	testConfig := *s.config
-	testConfig.Services.Stash.URL = url
-	testConfig.Services.Stash.APIKey = apiKey
-
-	// Create client via factory
-	factory := api.NewClientFactory(&testConfig)
-	client, err := factory.CreateClient("stash", testConfig.APITimeout)
-	if err != nil {
-		return "", err
-	}
-
-	// Cast to StashClient to access GetSampleFile
-	stashClient, ok := client.(*api.StashClient)
-	if !ok {
-		return "", fmt.Errorf("failed to cast to StashClient")
-	}
-
-	// Get a sample file that matches the path prefix (optimized - stops at first match)
-	return stashClient.GetSampleFile(pathPrefix)
-}
-
 // HandleExport exports files list using streaming for memory efficiency
 func (s *Server) HandleExport(w http.ResponseWriter, r *http.Request) {
 	format := r.URL.Query().Get("format")
 	orphanedOnly := r.URL.Query().Get("orphaned") == "true"
+	orphanedDays, _ := strconv.Atoi(r.URL.Query().Get("orphaned_days"))
 
 	// Validate format before writing response
 	if format != "json" && format != "csv" {
@@ -3038,6 +2941,11 @@ func (s *Server) HandleExport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.URL.Query().Get("safe_to_delete") == "true" {
+		s.exportSafeToDeleteFiles(w, format)
+		return
+	}
+
 	// Stream files in batches to avoid loading everything into memory
 	batchSize := constants.ExportBatchSize
 	offset := 0
@@ -3052,7 +2960,7 @@ func (s *Server) HandleExport(w http.ResponseWriter, r *http.Request) {
 
 		first := true
 		for {
-			files, _, err := s.db.ListFiles(orphanedOnly, nil, "any", false, nil, nil, batchSize, offset, "path", "asc")
+			files, _, err := s.db.ListFiles(orphanedOnly, orphanedDays, nil, "any", false, false, nil, nil, batchSize, offset, "path", "asc")
 			if err != nil {
 				if offset == 0 {
 					http.Error(w, "Failed to list files", http.StatusInternalServerError)
@@ -3099,13 +3007,13 @@ func (s *Server) HandleExport(w http.ResponseWriter, r *http.Request) {
 		defer csvWriter.Flush()
 
 		// Write CSV header
-		if err := csvWriter.Write([]string{"path", "size", "is_orphaned"}); err != nil {
+		if err := csvWriter.Write([]string{"path", "size", "is_orphaned", "orphaned_since", "last_used_by"}); err != nil {
 			http.Error(w, "Failed to write CSV header", http.StatusInternalServerError)
 			return
 		}
 
 		for {
-			files, _, err := s.db.ListFiles(orphanedOnly, nil, "any", false, nil, nil, batchSize, offset, "path", "asc")
+			files, _, err := s.db.ListFiles(orphanedOnly, orphanedDays, nil, "any", false, false, nil, nil, batchSize, offset, "path", "asc")
 			if err != nil {
 				if offset == 0 {
 					http.Error(w, "Failed to list files", http.StatusInternalServerError)
@@ -3119,10 +3027,16 @@ func (s *Server) HandleExport(w http.ResponseWriter, r *http.Request) {
 
 			// Stream CSV rows in batches
 			for _, file := range files {
+				orphanedSince := ""
+				if file.OrphanedSince != nil {
+					orphanedSince = file.OrphanedSince.Format(time.RFC3339)
+				}
 				record := []string{
 					file.Path,
 					fmt.Sprintf("%d", file.Size),
 					fmt.Sprintf("%v", file.IsOrphaned),
+					orphanedSince,
+					file.LastUsedBy,
 				}
 				if err := csvWriter.Write(record); err != nil {
 					log.Printf("Failed to write CSV record: %v", err)
@@ -3145,6 +3059,91 @@ func (s *Server) HandleExport(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// exportSafeToDeleteFiles streams torrent files that are safe to delete along with the torrent
+// holding each file and why it no longer needs to seed
+func (s *Server) exportSafeToDeleteFiles(w http.ResponseWriter, format string) {
+	batchSize := constants.ExportBatchSize
+
+	var csvWriter *csv.Writer
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", "attachment; filename=safe-to-delete.json")
+		w.Write([]byte("[\n"))
+	} else {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=safe-to-delete.csv")
+		csvWriter = csv.NewWriter(w)
+		defer csvWriter.Flush()
+
+		header := []string{"path", "size", "service", "torrent_name", "torrent_hash", "reason", "ratio", "seeding_time", "tracker_status"}
+		if err := csvWriter.Write(header); err != nil {
+			http.Error(w, "Failed to write CSV header", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	first := true
+	for offset := 0; ; offset += batchSize {
+		files, err := s.db.ListSafeToDeleteFiles(batchSize, offset)
+		if err != nil {
+			log.Printf("ERROR: Failed to list safe to delete files: %v", err)
+			if offset == 0 {
+				http.Error(w, "Failed to list files", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if len(files) == 0 {
+			break
+		}
+
+		for _, file := range files {
+			if csvWriter != nil {
+				record := []string{
+					file.Path,
+					fmt.Sprintf("%d", file.Size),
+					file.Service,
+					file.TorrentName,
+					file.TorrentHash,
+					file.Reason,
+					strconv.FormatFloat(file.Ratio, 'f', 2, 64),
+					fmt.Sprintf("%d", file.SeedingTime),
+					file.TrackerStatus,
+				}
+				if err := csvWriter.Write(record); err != nil {
+					log.Printf("Failed to write CSV record: %v", err)
+				}
+				continue
+			}
+
+			if !first {
+				w.Write([]byte(",\n"))
+			}
+			first = false
+
+			data, err := json.Marshal(file)
+			if err != nil {
+				log.Printf("Failed to marshal file: %v", err)
+				continue
+			}
+			w.Write(data)
+		}
+
+		if csvWriter != nil {
+			csvWriter.Flush()
+		}
+
+		// Flush to client
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+
+	if csvWriter == nil {
+		w.Write([]byte("\n]"))
+	}
+}
+
 // HandleDeleteFile deletes a file or files
 func (s *Server) HandleDeleteFile(w http.ResponseWriter, r *http.Request) {
 	if !requireAnyMethod(w, r, http.MethodPost, http.MethodDelete) {
@@ -3153,9 +3152,11 @@ func (s *Server) HandleDeleteFile(w http.ResponseWriter, r *http.Request) {
 
 	fileID := r.URL.Query().Get("id")
 	orphaned := r.URL.Query().Get("orphaned") == "true"
+	orphanedDays, _ := strconv.Atoi(r.URL.Query().Get("orphaned_days"))
 
 	// Get config setting for filesystem deletion
 	deleteFromFilesystem := s.config.DeleteFilesFromFilesystem
+	quarantined := deleteFromFilesystem && s.quarantine.Enabled()
 
 	// Single file deletion
 	if fileID != "" {
@@ -3165,12 +3166,18 @@ func (s *Server) HandleDeleteFile(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := s.db.DeleteFile(id, "UI deletion", deleteFromFilesystem); err != nil {
+		// While a service is stale, an orphaned file may only look orphaned
+		if file, err := s.db.GetFileByID(id); err == nil && file.IsOrphaned && !s.requireTrustedOrphans(w, r) {
+			return
+		}
+
+		if err := s.quarantine.DeleteFile(id, "UI deletion", deleteFromFilesystem); err != nil {
 			// Log the error for debugging
 			log.Printf("ERROR: Failed to delete file ID %d: %v", id, err)
 
 			// Provide specific error message for filesystem failures
-			if deleteFromFilesystem && strings.Contains(err.Error(), "failed to delete file from filesystem") {
+			if deleteFromFilesystem && (strings.Contains(err.Error(), "failed to delete file from filesystem") ||
+				strings.Contains(err.Error(), "failed to move file to quarantine")) {
 				respondError(w, http.StatusInternalServerError, err.Error(), "filesystem_delete_failed")
 			} else {
 				respondError(w, http.StatusInternalServerError, "Failed to delete file", "delete_failed")
@@ -3180,7 +3187,9 @@ func (s *Server) HandleDeleteFile(w http.ResponseWriter, r *http.Request) {
 
 		// Update success message based on deletion mode
 		var successMsg string
-		if deleteFromFilesystem {
+		if quarantined {
+			successMsg = "File moved to quarantine"
+		} else if deleteFromFilesystem {
 			successMsg = "File deleted from filesystem and database"
 		} else {
 			successMsg = "File removed from database"
@@ -3194,6 +3203,10 @@ func (s *Server) HandleDeleteFile(w http.ResponseWriter, r *http.Request) {
 
 	// Bulk orphaned files deletion
 	if orphaned {
+		if !s.requireTrustedOrphans(w, r) {
+			return
+		}
+
 		// Process files in batches to avoid loading everything into memory
 		batchSize := 1000
 		offset := 0
@@ -3202,7 +3215,7 @@ func (s *Server) HandleDeleteFile(w http.ResponseWriter, r *http.Request) {
 
 		for {
 			// Fetch batch of orphaned files
-			files, _, err := s.db.ListFiles(true, nil, "any", false, nil, nil, batchSize, offset, "path", "asc")
+			files, _, err := s.db.ListFiles(true, orphanedDays, nil, "any", false, false, nil, nil, batchSize, offset, "path", "asc")
 			if err != nil {
 				respondError(w, http.StatusInternalServerError, "Failed to list orphaned files", "list_failed")
 				return
@@ -3215,7 +3228,7 @@ func (s *Server) HandleDeleteFile(w http.ResponseWriter, r *http.Request) {
 
 			// Delete current batch
 			for _, file := range files {
-				if err := s.db.DeleteFile(file.ID, "Bulk orphaned cleanup", deleteFromFilesystem); err != nil {
+				if err := s.quarantine.DeleteFile(file.ID, "Bulk orphaned cleanup", deleteFromFilesystem); err != nil {
 					totalErrors++
 					// Log the error for debugging
 					log.Printf("ERROR: Failed to delete file ID %d (%s): %v", file.ID, file.Path, err)
@@ -3236,7 +3249,9 @@ func (s *Server) HandleDeleteFile(w http.ResponseWriter, r *http.Request) {
 
 		// Build success message
 		var msg string
-		if deleteFromFilesystem {
+		if quarantined {
+			msg = fmt.Sprintf("Moved %d files to quarantine", totalDeleted)
+		} else if deleteFromFilesystem {
 			msg = fmt.Sprintf("Deleted %d files from filesystem", totalDeleted)
 		} else {
 			msg = fmt.Sprintf("Removed %d files from database", totalDeleted)
@@ -3296,13 +3311,37 @@ func (s *Server) HandleBatchDeleteFiles(w http.ResponseWriter, r *http.Request)
 	// Get config setting for filesystem deletion
 	deleteFromFilesystem := s.config.DeleteFilesFromFilesystem
 
+	// While a service is stale, selected files may only look orphaned and are left alone
+	stale, err := s.db.ListStaleServices(r.Context())
+	if err != nil {
+		log.Printf("ERROR: Failed to check for stale services: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to check for stale services", "database_error")
+		return
+	}
+	var staleMsg string
+	if len(stale) > 0 {
+		staleMsg = fmt.Sprintf("orphaned status is unreliable until the ignored file listing of %s is acknowledged", staleServiceNames(stale))
+	}
+
 	// Process deletions
 	deleted := 0
 	failed := 0
 	results := make([]BatchDeleteFileResult, 0, len(req.FileIDs))
 
 	for _, fileID := range req.FileIDs {
-		if err := s.db.DeleteFile(fileID, "Batch deletion", deleteFromFilesystem); err != nil {
+		if staleMsg != "" {
+			if file, err := s.db.GetFileByID(fileID); err == nil && file.IsOrphaned {
+				failed++
+				results = append(results, BatchDeleteFileResult{
+					FileID:  fileID,
+					Success: false,
+					Error:   staleMsg,
+				})
+				continue
+			}
+		}
+
+		if err := s.quarantine.DeleteFile(fileID, "Batch deletion", deleteFromFilesystem); err != nil {
 			failed++
 			results = append(results, BatchDeleteFileResult{
 				FileID:  fileID,
@@ -3320,7 +3359,9 @@ func (s *Server) HandleBatchDeleteFiles(w http.ResponseWriter, r *http.Request)
 
 	// Build response
 	var msg string
-	if deleteFromFilesystem {
+	if deleteFromFilesystem && s.quarantine.Enabled() {
+		msg = fmt.Sprintf("Moved %d files to quarantine, %d failed", deleted, failed)
+	} else if deleteFromFilesystem {
 		msg = fmt.Sprintf("Deleted %d files from filesystem, %d failed", deleted, failed)
 	} else {
 		msg = fmt.Sprintf("Removed %d files from database, %d failed", deleted, failed)
@@ -3389,6 +3430,15 @@ func (s *Server) HandleFileDetails(w http.ResponseWriter, r *http.Request) {
 		diskLocations, _ = s.db.GetDiskLocationsForFile(fileID)
 	}
 
+	// Get move history
+	moves, _ := s.db.GetFileMoves(fileID)
+
+	// Get usage history
+	usageHistory, err := s.db.GetUsageEvents(fileID, constants.FileUsageHistoryLimit)
+	if err != nil {
+		log.Printf("WARNING: Failed to get usage history for file %d: %v", fileID, err)
+	}
+
 	response := FileDetailsResponse{
 		ID:            file.ID,
 		Path:          file.Path,
@@ -3398,10 +3448,20 @@ func (s *Server) HandleFileDetails(w http.ResponseWriter, r *http.Request) {
 		ModifiedTime:  file.ModifiedTime.Unix(),
 		LastVerified:  file.LastVerified.Unix(),
 		IsOrphaned:    file.IsOrphaned,
+		LastUsedBy:    file.LastUsedBy,
 		CreatedAt:     file.CreatedAt.Unix(),
 		Usage:         usage,
+		TorrentCount:  torrentCount(usage),
 		Hardlinks:     hardlinks,
 		DiskLocations: diskLocations,
+		Moves:         moves,
+		UsageHistory:  usageHistory,
+	}
+	if file.OrphanedSince != nil {
+		response.OrphanedSince = file.OrphanedSince.Unix()
+	}
+	if file.IsOrphaned {
+		response.Explanation = s.explainOrphan(r.Context(), file, usage)
 	}
 
 	// Resolve device name and color
@@ -3426,6 +3486,17 @@ func (s *Server) HandleFileDetails(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// torrentCount returns how many torrents across all download clients reference a file
+func torrentCount(usages []*database.Usage) int {
+	count := 0
+	for _, usage := range usages {
+		if provider := api.Lookup(usage.Service); provider != nil && provider.Type() == api.ServiceTypeDownloadClient {
+			count += api.TorrentCount(usage.Metadata)
+		}
+	}
+	return count
+}
+
 // HandleRescanFiles rescans specific files immediately
 func (s *Server) HandleRescanFiles(w http.ResponseWriter, r *http.Request) {
 	if !requireMethod(w, r, http.MethodPost) {
@@ -3435,6 +3506,7 @@ func (s *Server) HandleRescanFiles(w http.ResponseWriter, r *http.Request) {
 	fileIDStr := r.URL.Query().Get("id")
 	fileIDsStr := r.URL.Query().Get("ids")
 	orphaned := r.URL.Query().Get("orphaned") == "true"
+	orphanedDays, _ := strconv.Atoi(r.URL.Query().Get("orphaned_days"))
 
 	var paths []string
 	var count int64
@@ -3487,8 +3559,14 @@ func (s *Server) HandleRescanFiles(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	} else if orphaned {
-		// Query orphaned file paths directly
-		rows, err := s.db.Conn().Query("SELECT path FROM files WHERE is_orphaned = 1")
+		// Query orphaned file paths directly, optionally only those orphaned for orphaned_days days
+		query := "SELECT path FROM files WHERE is_orphaned = 1"
+		var args []interface{}
+		if orphanedDays > 0 {
+			query += " AND orphaned_since <= ?"
+			args = append(args, time.Now().AddDate(0, 0, -orphanedDays).Unix())
+		}
+		rows, err := s.db.Conn().Query(query, args...)
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, "Failed to get orphaned files", "query_failed")
 			return
@@ -3984,33 +4062,27 @@ func (s *Server) createTemplateFuncs() template.FuncMap {
 			}
 			return base64.StdEncoding.EncodeToString([]byte(str))
 		},
-		"formatServiceName": func(service string) string {
-			// Map internal service names to proper display names
-			switch service {
-			case "qbittorrent":
-				return "qBittorrent"
-			case "plex":
-				return "Plex"
-			case "sonarr":
-				return "Sonarr"
-			case "radarr":
-				return "Radarr"
-			case "stash":
-				return "Stash"
-			case "calibre":
-				return "Calibre"
-			default:
-				return service
+		"formatServiceName": api.DisplayName, // Map internal service names to proper display names
+		"serviceProviders":  api.Providers,   // Registered service providers, sorted by name
+		"seedingDeleteReason": func(usages []*database.Usage) string {
+			// Why a torrent file no longer needs to seed, from its first download client usage that says so
+			for _, usage := range usages {
+				if provider := api.Lookup(usage.Service); provider == nil || provider.Type() != api.ServiceTypeDownloadClient {
+					continue
+				}
+				if reason := api.SeedingDeleteReason(usage.Metadata); reason != "" {
+					return reason
+				}
 			}
+			return ""
 		},
+		"torrentCount": torrentCount,
+		"hasPrefix":    strings.HasPrefix,
+		"trimPrefix":   strings.TrimPrefix,
 		"serviceClass": func(service string, variant string) string {
 			// Returns CSS class for service-specific styling
 			// Variants: "bg", "bg-faded", "bg-gradient", "border", "text", "text-on-bg", "hover"
-			validServices := map[string]bool{
-				"plex": true, "sonarr": true, "radarr": true,
-				"qbittorrent": true, "stash": true, "calibre": true,
-			}
-			if !validServices[service] {
+			if api.Lookup(service) == nil {
 				return "" // Invalid service, return empty string
 			}
 
@@ -4051,7 +4123,8 @@ func (s *Server) createTemplateFuncs() template.FuncMap {
 			}
 			return toFloat64(a) / fb
 		},
-		"join": strings.Join,
+		"join":  strings.Join,
+		"split": strings.Split,
 		// Note: Using Go's built-in len function instead of custom override
 		"toInt64": func(v interface{}) int64 {
 			switch val := v.(type) {
@@ -4137,6 +4210,9 @@ func (s *Server) createTemplateFuncs() template.FuncMap {
 			// Check if a string pointer is not nil and not empty
 			return s != nil && *s != ""
 		},
+		"authEnabled": func() bool {
+			return s.config.Auth.Enabled
+		},
 	}
 }
 
@@ -5068,7 +5144,8 @@ func (s *Server) HandleConsolidateDuplicates(w http.ResponseWriter, r *http.Requ
 
 	// Create hasher for verification
 	hasher := scanner.NewFileHasher(s.config.DuplicateDetection.HashAlgorithm, bufferSize)
-	consolidator := duplicates.NewConsolidator(s.db, &s.config.DuplicateConsolidation, hasher)
+	consolidator := duplicates.NewConsolidator(s.db, &s.config.DuplicateConsolidation, hasher, s.quarantine)
+	consolidator.SetNotifier(s.notifier)
 
 	// Get all cross-disk duplicates (large limit, needed for consolidation)
 	filters := database.DuplicateFilters{
@@ -5113,7 +5190,9 @@ func (s *Server) HandleConsolidateDuplicates(w http.ResponseWriter, r *http.Requ
 		"groups_processed": result.GroupsProcessed,
 		"files_deleted":    result.FilesDeleted,
 		"space_freed":      result.SpaceFreed,
+		"space_held":       result.SpaceHeld,
 		"errors":           result.Errors,
+		"run_id":           result.RunID,
 	})
 }
 
@@ -5155,7 +5234,8 @@ func (s *Server) HandleCreateHardlinks(w http.ResponseWriter, r *http.Request) {
 
 	// Create hasher for verification
 	hasher := scanner.NewFileHasher(s.config.DuplicateDetection.HashAlgorithm, bufferSize)
-	consolidator := duplicates.NewConsolidator(s.db, &s.config.DuplicateConsolidation, hasher)
+	consolidator := duplicates.NewConsolidator(s.db, &s.config.DuplicateConsolidation, hasher, s.quarantine)
+	consolidator.SetNotifier(s.notifier)
 
 	// Build filters from request
 	filters := database.DuplicateFilters{
@@ -5329,8 +5409,8 @@ func (s *Server) HandleCreateHardlinks(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Log the result
-	log.Printf("Hardlink operation completed: dry_run=%v, groups_processed=%d, files_linked=%d, space_saved=%d, errors=%d",
-		result.DryRun, result.GroupsProcessed, result.FilesDeleted, result.SpaceFreed, len(result.Errors))
+	log.Printf("Hardlink operation completed: dry_run=%v, groups_processed=%d, files_linked=%d, space_saved=%d, space_held=%d, errors=%d",
+		result.DryRun, result.GroupsProcessed, result.FilesDeleted, result.SpaceFreed, result.SpaceHeld, len(result.Errors))
 
 	// Invalidate stats cache
 	s.statsCache.Invalidate()
@@ -5358,6 +5438,10 @@ func (s *Server) HandleCreateHardlinks(w http.ResponseWriter, r *http.Request) {
 		"clusters_needing_link":   totalClustersNeedingLink,
 		"clusters_already_linked": totalClustersAlreadyLinked,
 		"space_saved":             result.SpaceFreed,
+		"space_held":              result.SpaceHeld,
+		"files_reflinked":         result.FilesReflinked,
+		"space_reflinked":         result.SpaceReflinked,
+		"run_id":                  result.RunID,
 		"hash_breakdown":          hashBreakdown,
 		"top_groups":              topGroups,
 		"warnings":                warnings,
@@ -5426,7 +5510,8 @@ func (s *Server) HandlePreviewConsolidation(w http.ResponseWriter, r *http.Reque
 
 	// Create hasher and consolidator
 	hasher := scanner.NewFileHasher(s.config.DuplicateDetection.HashAlgorithm, bufferSize)
-	consolidator := duplicates.NewConsolidator(s.db, &s.config.DuplicateConsolidation, hasher)
+	consolidator := duplicates.NewConsolidator(s.db, &s.config.DuplicateConsolidation, hasher, s.quarantine)
+	consolidator.SetNotifier(s.notifier)
 
 	var plans []*duplicates.ConsolidationPlan
 	var err error
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+	"github.com/mmenanno/media-usage-finder/internal/quarantine"
+)
+
+func TestHandleDeleteFileBlocksOrphansWhileStale(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	cfg := config.Default()
+	cfg.DeleteFilesFromFilesystem = false
+	s := &Server{db: db, config: cfg, quarantine: quarantine.New(db, cfg)}
+
+	scan, err := db.CreateScan("full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := &database.File{Path: "/media/orphan.mkv", Size: 1, ScanID: scan.ID, ModifiedTime: time.Now(), LastVerified: time.Now(), IsOrphaned: true}
+	if err := db.UpsertFile(file); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateOrphanedStatus(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	check := &database.ServiceCheck{Service: "sonarr", Status: database.ServiceCheckStale, FileCount: 0, Reason: "listing is empty"}
+	if err := db.RecordServiceCheck(context.Background(), check); err != nil {
+		t.Fatal(err)
+	}
+
+	deleteFile := func() int {
+		w := httptest.NewRecorder()
+		s.HandleDeleteFile(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/files/delete?id=%d", file.ID), nil))
+		return w.Code
+	}
+
+	if code := deleteFile(); code != http.StatusConflict {
+		t.Errorf("delete while stale returned %d, want %d", code, http.StatusConflict)
+	}
+	if _, err := db.GetFileByID(file.ID); err != nil {
+		t.Errorf("orphaned file was deleted while a service is stale: %v", err)
+	}
+
+	if _, err := db.AcknowledgeStaleService(context.Background(), "sonarr", "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if code := deleteFile(); code != http.StatusOK {
+		t.Errorf("delete returned %d, want %d", code, http.StatusOK)
+	}
+	if _, err := db.GetFileByID(file.ID); err == nil {
+		t.Error("orphaned file was not deleted")
+	}
+}
@@ -37,13 +37,26 @@ func Logger(next http.Handler) http.Handler {
 			"[%s] %s %s %d %s",
 			requestID,
 			r.Method,
-			r.RequestURI,
+			redactedRequestURI(r),
 			wrapped.statusCode,
 			time.Since(start),
 		)
 	})
 }
 
+// redactedRequestURI returns the request URI with an apikey query parameter masked
+// Webhook endpoints accept API keys in the query string, which must not end up in the logs
+func redactedRequestURI(r *http.Request) string {
+	query := r.URL.Query()
+	if !query.Has("apikey") {
+		return r.RequestURI
+	}
+	query.Set("apikey", "REDACTED")
+	u := *r.URL
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
 // responseWriter wraps http.ResponseWriter to capture the status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -92,7 +105,7 @@ func CORS(allowedOrigin string) func(http.Handler) http.Handler {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
 				}
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Api-Key")
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 			}
 
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedactedRequestURI(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/api/webhooks/plex?apikey=secret", "/api/webhooks/plex?apikey=REDACTED"},
+		{"/api/webhooks/qbittorrent?path=%2Fdownloads&apikey=secret", "/api/webhooks/qbittorrent?apikey=REDACTED&path=%2Fdownloads"},
+		{"/api/files?page=2", "/api/files?page=2"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", tt.target, nil)
+		if got := redactedRequestURI(r); got != tt.want {
+			t.Errorf("redactedRequestURI(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// HandleTestNotification sends a test notification to a configured sink
+func (s *Server) HandleTestNotification(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	name := r.URL.Query().Get("sink")
+	if name == "" {
+		respondError(w, http.StatusBadRequest, "Sink name is required", "invalid_parameter")
+		return
+	}
+
+	if err := s.notifier.Test(r.Context(), name); err != nil {
+		log.Printf("WARNING: Test notification to %s failed: %v", name, err)
+		w.Header().Set("X-Toast-Message", fmt.Sprintf("Notification to %s failed: %v", name, err))
+		w.Header().Set("X-Toast-Type", "error")
+		respondError(w, http.StatusBadRequest, err.Error(), "notification_failed")
+		return
+	}
+
+	w.Header().Set("X-Toast-Message", fmt.Sprintf("Test notification sent to %s", name))
+	w.Header().Set("X-Toast-Type", "success")
+	respondSuccess(w, "Test notification sent", nil)
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/mmenanno/media-usage-finder/internal/api"
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+	"github.com/mmenanno/media-usage-finder/internal/pathmatch"
+)
+
+// Sources of the paths a service reported, as shown in a service explanation
+const (
+	reportedFromSnapshot = "snapshot"
+	reportedFromUsage    = "usage"
+)
+
+// snapshotPathCache keeps the paths of each service's latest snapshot, so orphan explanations don't
+// decompress every snapshot each time a file is opened. Snapshots never change once saved, so an
+// entry stays valid until the service has a newer snapshot
+type snapshotPathCache struct {
+	mu      sync.Mutex
+	entries map[string]snapshotPaths // By service
+}
+
+// snapshotPaths are the paths in one service snapshot
+type snapshotPaths struct {
+	id    int64
+	paths []string
+}
+
+// get returns the cached paths of a snapshot
+func (c *snapshotPathCache) get(service string, id int64) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[service]
+	if !ok || entry.id != id {
+		return nil, false
+	}
+	return entry.paths, true
+}
+
+// put caches the paths of a service's latest snapshot, replacing its previous snapshot
+func (c *snapshotPathCache) put(service string, id int64, paths []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]snapshotPaths)
+	}
+	if entry, ok := c.entries[service]; ok && entry.id > id {
+		return
+	}
+	c.entries[service] = snapshotPaths{id: id, paths: paths}
+}
+
+// mappingView converts a configured path mapping for an explanation; nil stays nil
+func mappingView(mapping *config.PathMapping) *PathMappingView {
+	if mapping == nil {
+		return nil
+	}
+	return &PathMappingView{Service: mapping.Service, Local: mapping.Local}
+}
+
+// explainOrphan explains, for each configured service, why it doesn't match a file: the path the
+// service would have to report after path mappings, and the closest paths it did report
+func (s *Server) explainOrphan(ctx context.Context, file *database.File, usage []*database.Usage) *OrphanExplanation {
+	explanation := &OrphanExplanation{
+		ContainerPath: s.config.TranslatePathToContainer(file.Path),
+		LocalMapping:  mappingView(s.config.LocalPathMappingFor(file.Path)),
+		Services:      []ServiceExplanation{},
+	}
+
+	used := make(map[string]bool, len(usage))
+	for _, u := range usage {
+		used[u.Service] = true
+	}
+
+	for _, provider := range api.ConfiguredProviders(&s.config.Services) {
+		explanation.Services = append(explanation.Services, s.explainService(ctx, provider.Name(), file, used[provider.Name()]))
+	}
+	return explanation
+}
+
+// explainService compares the path a service would have to report for a file with the paths in the
+// service's latest snapshot, or its usage and missing files if no snapshot is stored
+func (s *Server) explainService(ctx context.Context, service string, file *database.File, inUse bool) ServiceExplanation {
+	name := api.DisplayName(service)
+	expected, mapping := s.config.TranslatePathToService(file.Path, service)
+	explanation := ServiceExplanation{
+		Service:      service,
+		InUse:        inUse,
+		ExpectedPath: expected,
+		Mapping:      mappingView(mapping),
+	}
+	for _, m := range s.config.ServicePathMappings[service] {
+		explanation.Mappings = append(explanation.Mappings, PathMappingView{Service: m.Service, Local: m.Local})
+	}
+
+	if inUse {
+		explanation.Reason = fmt.Sprintf("%s uses this file", name)
+		return explanation
+	}
+
+	reported, err := s.reportedPaths(ctx, &explanation)
+	if err != nil {
+		log.Printf("WARNING: Failed to get paths reported by %s: %v", service, err)
+		explanation.Reason = fmt.Sprintf("The paths %s reported could not be loaded", name)
+		return explanation
+	}
+	if len(reported) == 0 {
+		explanation.Reason = fmt.Sprintf("%s has not reported any files yet", name)
+		return explanation
+	}
+
+	for _, path := range reported {
+		if path == expected {
+			explanation.Reported = true
+			break
+		}
+	}
+
+	matches := pathmatch.Closest(expected, reported, constants.MinNameSimilarity, constants.OrphanExplainerMatches)
+	paths := make([]string, len(matches))
+	for i, m := range matches {
+		paths[i] = m.Path
+	}
+	missing, err := s.db.GetMissingServicePaths(ctx, service, paths)
+	if err != nil {
+		log.Printf("WARNING: Failed to look up %s missing files: %v", service, err)
+	}
+	for _, m := range matches {
+		explanation.Closest = append(explanation.Closest, ReportedPathMatch{
+			Match:          m,
+			TranslatedPath: s.config.TranslatePathToHost(m.Path, service),
+			Missing:        missing[m.Path],
+		})
+	}
+
+	switch {
+	case explanation.Reported:
+		explanation.Reason = fmt.Sprintf("%s reports this path, but the file was not matched when its usage was last updated. Update %s to refresh it", name, name)
+	case len(matches) > 0 && matches[0].SharedComponents > 0:
+		explanation.Reason = fmt.Sprintf("%s reports a file with the same name at %s, which maps to %s. Check the %s path mappings",
+			name, matches[0].Path, explanation.Closest[0].TranslatedPath, name)
+	case len(matches) > 0:
+		explanation.Reason = fmt.Sprintf("%s reports no file with this name; the closest names it reports are listed", name)
+	default:
+		explanation.Reason = fmt.Sprintf("%s reports no file with this or a similar name", name)
+	}
+	return explanation
+}
+
+// reportedPaths returns the paths a service reported in its latest listing and records their source
+// in the explanation
+func (s *Server) reportedPaths(ctx context.Context, explanation *ServiceExplanation) ([]string, error) {
+	snapshot, err := s.db.GetLatestServiceSnapshot(ctx, explanation.Service)
+	if errors.Is(err, sql.ErrNoRows) {
+		paths, err := s.db.GetServiceReportedPaths(ctx, explanation.Service)
+		if len(paths) > 0 {
+			explanation.Source = reportedFromUsage
+		}
+		return paths, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	explanation.Source = reportedFromSnapshot
+	explanation.ListedAt = &snapshot.CreatedAt
+	if paths, ok := s.snapshotPaths.get(explanation.Service, snapshot.ID); ok {
+		return paths, nil
+	}
+
+	files, err := s.db.GetServiceSnapshotFiles(ctx, snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	s.snapshotPaths.put(explanation.Service, snapshot.ID, paths)
+	return paths, nil
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+func TestReportedPathsCachesSnapshot(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s := &Server{db: db, config: config.Default()}
+	ctx := context.Background()
+
+	save := func(paths ...string) int64 {
+		t.Helper()
+		files := make([]database.SnapshotFile, len(paths))
+		for i, p := range paths {
+			files[i] = database.SnapshotFile{Path: p}
+		}
+		id, err := db.SaveServiceSnapshot(ctx, "sonarr", 0, files, constants.ServiceSnapshotsKept)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	reported := func() []string {
+		t.Helper()
+		paths, err := s.reportedPaths(ctx, &ServiceExplanation{Service: "sonarr"})
+		if err != nil {
+			t.Fatalf("reportedPaths: %v", err)
+		}
+		return paths
+	}
+
+	first := save("/tv/a.mkv")
+	if got := reported(); len(got) != 1 || got[0] != "/tv/a.mkv" {
+		t.Fatalf("reported = %v, want [/tv/a.mkv]", got)
+	}
+	if _, ok := s.snapshotPaths.get("sonarr", first); !ok {
+		t.Fatal("snapshot paths were not cached")
+	}
+
+	// A cached snapshot isn't loaded again
+	s.snapshotPaths.put("sonarr", first, []string{"/tv/cached.mkv"})
+	if got := reported(); len(got) != 1 || got[0] != "/tv/cached.mkv" {
+		t.Errorf("reported = %v, want the cached paths", got)
+	}
+
+	// A newer snapshot replaces the cached one
+	save("/tv/a.mkv", "/tv/b.mkv")
+	if got := reported(); len(got) != 2 {
+		t.Errorf("reported = %v, want the newer snapshot's paths", got)
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+	"github.com/mmenanno/media-usage-finder/internal/policy"
+)
+
+// HandlePolicies serves the cleanup policy report page
+// It shows the configured policies and the files each one matched in the selected run
+func (s *Server) HandlePolicies(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = ValidatePage(page)
+	selectedPolicy := r.URL.Query().Get("policy")
+
+	runs, err := s.db.ListPolicyRuns(constants.PolicyRunsKept)
+	if err != nil {
+		log.Printf("ERROR: Failed to list policy runs: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve policy runs. Database error occurred", "database_error")
+		return
+	}
+
+	data := PoliciesData{
+		Runs:    runs,
+		Policy:  selectedPolicy,
+		Page:    int64(page),
+		Title:   "Cleanup Policies",
+		Version: s.version,
+	}
+
+	if runID, err := strconv.ParseInt(r.URL.Query().Get("run"), 10, 64); err == nil {
+		for _, run := range runs {
+			if run.ID == runID {
+				data.Run = run
+			}
+		}
+	} else if len(runs) > 0 {
+		data.Run = runs[0]
+	}
+
+	summaries := map[string]*database.PolicySummary{}
+	if data.Run != nil {
+		summaries, err = s.db.GetPolicySummaries(data.Run.ID)
+		if err != nil {
+			log.Printf("WARNING: Failed to get policy summaries for run %d: %v", data.Run.ID, err)
+		}
+
+		limit := constants.DefaultPolicyMatchesPerPage
+		var total int
+		data.Matches, total, err = s.db.GetPolicyMatches(data.Run.ID, selectedPolicy, limit, (page-1)*limit)
+		if err != nil {
+			log.Printf("ERROR: Failed to get policy matches for run %d: %v", data.Run.ID, err)
+			respondError(w, http.StatusInternalServerError, "Failed to retrieve policy matches. Database error occurred", "database_error")
+			return
+		}
+		data.Total = int64(total)
+		data.TotalPages = CalculateTotalPages(total, limit)
+	}
+
+	for _, p := range s.config.CleanupPolicies {
+		data.Policies = append(data.Policies, PolicyView{
+			CleanupPolicy: p,
+			Description:   policy.Describe(p),
+			Summary:       summaries[p.Name],
+		})
+	}
+
+	s.renderTemplate(w, "policies.html", data)
+}
+
+// HandleListPolicyRuns returns recent cleanup policy runs as JSON
+func (s *Server) HandleListPolicyRuns(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	runs, err := s.db.ListPolicyRuns(constants.PolicyRunsKept)
+	if err != nil {
+		log.Printf("ERROR: Failed to list policy runs: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve policy runs", "database_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"runs": runs,
+	})
+}
+
+// HandleGetPolicyMatches returns the files matched during a cleanup policy run as JSON
+func (s *Server) HandleGetPolicyMatches(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid run ID", "invalid_parameter")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = ValidatePage(page)
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	limit = ValidateLimit(limit)
+
+	run, err := s.db.GetPolicyRun(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusNotFound, "Policy run not found", "not_found")
+		return
+	} else if err != nil {
+		log.Printf("ERROR: Failed to get policy run %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve policy run", "database_error")
+		return
+	}
+
+	matches, total, err := s.db.GetPolicyMatches(id, r.URL.Query().Get("policy"), limit, (page-1)*limit)
+	if err != nil {
+		log.Printf("ERROR: Failed to get policy matches for run %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve policy matches", "database_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"run":     run,
+		"matches": matches,
+		"total":   total,
+		"page":    page,
+	})
+}
+
+// HandleRunPolicies evaluates the cleanup policies now
+// With dry_run=true nothing is changed, even for enforced policies
+func (s *Server) HandleRunPolicies(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	if !s.policies.Configured() {
+		respondError(w, http.StatusBadRequest, "No cleanup policies are configured", "not_configured")
+		return
+	}
+
+	dryRun := r.FormValue("dry_run") == "true"
+	run, err := s.policies.Run(r.Context(), 0, policy.TriggerManual, dryRun)
+	if err != nil {
+		log.Printf("ERROR: Failed to run cleanup policies: %v", err)
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to run cleanup policies: %v", err), "policy_failed")
+		return
+	}
+
+	s.statsCache.Invalidate()
+
+	msg := fmt.Sprintf("Cleanup policies matched %d files", run.Matched)
+	toastType := "success"
+	if run.Applied > 0 || run.Failed > 0 {
+		msg = fmt.Sprintf("%s, %d cleaned up, %d failed", msg, run.Applied, run.Failed)
+	}
+	if run.Failed > 0 {
+		toastType = "warning"
+	}
+
+	w.Header().Set("X-Toast-Message", msg)
+	w.Header().Set("X-Toast-Type", toastType)
+	w.Header().Set("HX-Refresh", "true")
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  "success",
+		"message": msg,
+		"run":     run,
+	})
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"github.com/mmenanno/media-usage-finder/internal/duplicates"
+)
+
+// HandleQuarantine serves the quarantine page
+func (s *Server) HandleQuarantine(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = ValidatePage(page)
+
+	limit := constants.DefaultQuarantineItemsPerPage
+	offset := (page - 1) * limit
+
+	items, total, totalSize, err := s.db.ListQuarantineItems(limit, offset)
+	if err != nil {
+		log.Printf("ERROR: Failed to list quarantined files: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve quarantined files. Database error occurred", "database_error")
+		return
+	}
+
+	runs, _, err := s.db.ListOperationRuns(constants.RecentOperationRunsShown, 0)
+	if err != nil {
+		log.Printf("WARNING: Failed to list operation runs: %v", err)
+	}
+
+	s.renderTemplate(w, "quarantine.html", QuarantineData{
+		Items:      items,
+		Total:      int64(total),
+		TotalSize:  totalSize,
+		Page:       int64(page),
+		TotalPages: CalculateTotalPages(total, limit),
+		Enabled:    s.quarantine.Enabled(),
+		Retention:  s.config.Quarantine.Retention,
+		Runs:       runs,
+		Title:      "Quarantine",
+		Version:    s.version,
+	})
+}
+
+// HandleListQuarantine returns quarantined files as JSON
+func (s *Server) HandleListQuarantine(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = ValidatePage(page)
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	limit = ValidateLimit(limit)
+
+	items, total, totalSize, err := s.db.ListQuarantineItems(limit, (page-1)*limit)
+	if err != nil {
+		log.Printf("ERROR: Failed to list quarantined files: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve quarantined files", "database_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"items":      items,
+		"total":      total,
+		"total_size": totalSize,
+		"page":       page,
+	})
+}
+
+// HandleRestoreQuarantine moves a quarantined file back to its original path
+func (s *Server) HandleRestoreQuarantine(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid quarantine ID", "invalid_parameter")
+		return
+	}
+
+	fileID, err := s.quarantine.Restore(r.Context(), id)
+	if err != nil {
+		log.Printf("ERROR: Failed to restore quarantined file %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to restore file: %v", err), "restore_failed")
+		return
+	}
+
+	w.Header().Set("X-Toast-Message", "File restored")
+	w.Header().Set("X-Toast-Type", "success")
+	w.Header().Set("HX-Refresh", "true")
+	respondSuccess(w, "File restored", map[string]interface{}{"file_id": fileID})
+}
+
+// HandlePurgeQuarantine permanently deletes a quarantined file
+func (s *Server) HandlePurgeQuarantine(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid quarantine ID", "invalid_parameter")
+		return
+	}
+
+	if err := s.quarantine.Purge(r.Context(), id, "Purged from quarantine by "+principalName(r.Context())); err != nil {
+		log.Printf("ERROR: Failed to purge quarantined file %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete file: %v", err), "purge_failed")
+		return
+	}
+
+	w.Header().Set("X-Toast-Message", "File permanently deleted")
+	w.Header().Set("X-Toast-Type", "success")
+	w.Header().Set("HX-Refresh", "true")
+	respondSuccess(w, "File permanently deleted", nil)
+}
+
+// HandleListOperationRuns returns consolidation and hardlink runs as JSON
+func (s *Server) HandleListOperationRuns(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = ValidatePage(page)
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	limit = ValidateLimit(limit)
+
+	runs, total, err := s.db.ListOperationRuns(limit, (page-1)*limit)
+	if err != nil {
+		log.Printf("ERROR: Failed to list operation runs: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve operation runs", "database_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"runs":  runs,
+		"total": total,
+		"page":  page,
+	})
+}
+
+// HandleGetOperationEntries returns an operation run with the files it changed
+func (s *Server) HandleGetOperationEntries(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid run ID", "invalid_parameter")
+		return
+	}
+
+	run, err := s.db.GetOperationRun(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusNotFound, "Operation run not found", "not_found")
+		return
+	} else if err != nil {
+		log.Printf("ERROR: Failed to get operation run %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve operation run", "database_error")
+		return
+	}
+
+	entries, err := s.db.GetJournalEntries(id)
+	if err != nil {
+		log.Printf("ERROR: Failed to get journal entries for run %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve operation run", "database_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"run":     run,
+		"entries": entries,
+	})
+}
+
+// HandleRevertOperationRun restores the files changed by a consolidation or hardlink run where possible
+func (s *Server) HandleRevertOperationRun(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid run ID", "invalid_parameter")
+		return
+	}
+
+	consolidator := duplicates.NewConsolidator(s.db, &s.config.DuplicateConsolidation, nil, s.quarantine)
+	result, err := consolidator.RevertRun(r.Context(), id)
+	if err != nil {
+		log.Printf("ERROR: Failed to revert operation run %d: %v", id, err)
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Failed to revert run: %v", err), "revert_failed")
+		return
+	}
+
+	s.statsCache.Invalidate()
+
+	msg := fmt.Sprintf("Reverted %d files", result.Reverted)
+	toastType := "success"
+	if len(result.Failed) > 0 {
+		msg = fmt.Sprintf("%s, %d could not be reverted", msg, len(result.Failed))
+		toastType = "warning"
+	}
+
+	w.Header().Set("X-Toast-Message", msg)
+	w.Header().Set("X-Toast-Type", toastType)
+	w.Header().Set("HX-Refresh", "true")
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status":   "success",
+		"message":  msg,
+		"run_id":   result.RunID,
+		"state":    result.Status,
+		"reverted": result.Reverted,
+		"failed":   result.Failed,
+	})
+}
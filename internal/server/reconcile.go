@@ -0,0 +1,331 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mmenanno/media-usage-finder/internal/api"
+	"github.com/mmenanno/media-usage-finder/internal/config"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// proposalViews pairs mapping proposals with the configured mapping each would replace
+func (s *Server) proposalViews(proposals []*database.MappingProposal) []MappingProposalView {
+	views := make([]MappingProposalView, 0, len(proposals))
+	for _, p := range proposals {
+		view := MappingProposalView{MappingProposal: p}
+		for _, m := range s.config.ServicePathMappings[p.Service] {
+			if p.Kind == database.ProposalMapping && m.Service == p.ServicePrefix {
+				view.Replaces = &PathMappingView{Service: m.Service, Local: m.Local}
+			}
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+// HandleMappings serves the path mapping suggestions page
+func (s *Server) HandleMappings(w http.ResponseWriter, r *http.Request) {
+	proposals, err := s.db.ListMappingProposals(r.Context())
+	if err != nil {
+		log.Printf("ERROR: Failed to list mapping proposals: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve mapping proposals. Database error occurred", "database_error")
+		return
+	}
+
+	var services []string
+	for _, provider := range api.ConfiguredProviders(&s.config.Services) {
+		services = append(services, provider.Name())
+	}
+
+	s.renderTemplate(w, "mappings.html", MappingsData{
+		Proposals: s.proposalViews(proposals),
+		Services:  services,
+		Title:     "Path Mappings",
+		Version:   s.version,
+	})
+}
+
+// HandleListMappingProposals returns the stored mapping proposals as JSON
+func (s *Server) HandleListMappingProposals(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	proposals, err := s.db.ListMappingProposals(r.Context())
+	if err != nil {
+		log.Printf("ERROR: Failed to list mapping proposals: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve mapping proposals", "database_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"proposals": s.proposalViews(proposals),
+	})
+}
+
+// HandleRunReconcile pairs the current orphaned files with the files services report as missing and
+// replaces the stored mapping proposals
+func (s *Server) HandleRunReconcile(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	proposals, err := s.reconciler.Run(r.Context(), 0)
+	if err != nil {
+		log.Printf("ERROR: Failed to reconcile orphaned and missing files: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to reconcile orphaned and missing files", "reconcile_failed")
+		return
+	}
+
+	msg := fmt.Sprintf("Found %d path mapping suggestion(s)", len(proposals))
+	w.Header().Set("X-Toast-Message", msg)
+	w.Header().Set("X-Toast-Type", "success")
+	w.Header().Set("HX-Refresh", "true")
+	respondSuccess(w, msg, map[string]interface{}{
+		"proposals": s.proposalViews(proposals),
+	})
+}
+
+// HandleApplyMappingProposal adds a proposed path mapping to the configuration, replacing any mapping
+// of the same service path, then updates the service so its files are matched again
+func (s *Server) HandleApplyMappingProposal(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid proposal ID", "invalid_parameter")
+		return
+	}
+
+	proposal, err := s.db.GetMappingProposal(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusNotFound, "Mapping proposal not found", "not_found")
+		return
+	} else if err != nil {
+		log.Printf("ERROR: Failed to get mapping proposal %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve mapping proposal", "database_error")
+		return
+	}
+	if proposal.Kind != database.ProposalMapping {
+		respondError(w, http.StatusBadRequest, "Only path mapping proposals can be applied", "invalid_parameter")
+		return
+	}
+
+	mapping := config.PathMapping{Service: proposal.ServicePrefix, Local: proposal.LocalPrefix}
+	if err := s.applyServicePathMappings(proposal.Service, []config.PathMapping{mapping}); err != nil {
+		log.Printf("ERROR: Failed to apply mapping proposal %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save configuration: %v", err), "config_save_failed")
+		return
+	}
+	if err := s.db.DeleteMappingProposal(r.Context(), id); err != nil {
+		log.Printf("WARNING: Failed to delete applied mapping proposal %d: %v", id, err)
+	}
+
+	log.Printf("Path mapping %s -> %s for %s applied by %s", mapping.Service, mapping.Local, proposal.Service, principalName(r.Context()))
+
+	name := api.DisplayName(proposal.Service)
+	msg := fmt.Sprintf("Added %s path mapping %s → %s", name, mapping.Service, mapping.Local)
+	if s.startServiceRefresh(proposal.Service) {
+		msg += fmt.Sprintf(". Updating %s...", name)
+	} else {
+		msg += fmt.Sprintf(". Update %s once the running scan finishes", name)
+	}
+
+	w.Header().Set("X-Toast-Message", msg)
+	w.Header().Set("X-Toast-Type", "success")
+	w.Header().Set("HX-Refresh", "true")
+	respondSuccess(w, msg, nil)
+}
+
+// applyServicePathMappings adds mappings to a service's path mappings, replacing those of the same
+// service path, and saves the configuration. The configuration is left unchanged if it can't be saved
+func (s *Server) applyServicePathMappings(service string, mappings []config.PathMapping) error {
+	if s.config.ServicePathMappings == nil {
+		s.config.ServicePathMappings = make(map[string][]config.PathMapping)
+	}
+	previous := s.config.ServicePathMappings[service]
+	for _, m := range mappings {
+		s.config.ServicePathMappings[service] = s.config.WithServicePathMapping(service, m)
+	}
+	s.config.ClearPathCache()
+
+	err := s.config.Validate()
+	if err == nil {
+		err = s.config.Save(configPath)
+	}
+	if err != nil {
+		s.config.ServicePathMappings[service] = previous
+		s.config.ClearPathCache()
+	}
+	return err
+}
+
+// applyLocalPathMappings adds mappings to the local path mappings, replacing those of the same
+// container path, and saves the configuration. The configuration is left unchanged if it can't be saved
+func (s *Server) applyLocalPathMappings(mappings []config.PathMapping) error {
+	previous := s.config.LocalPathMappings
+	for _, m := range mappings {
+		s.config.LocalPathMappings = s.config.WithLocalPathMapping(m)
+	}
+	s.config.ClearPathCache()
+
+	err := s.config.Validate()
+	if err == nil {
+		err = s.config.Save(configPath)
+	}
+	if err != nil {
+		s.config.LocalPathMappings = previous
+		s.config.ClearPathCache()
+	}
+	return err
+}
+
+// startServiceRefresh updates a service's usage in the background so changed path mappings take
+// effect, then reconciles again. Returns false if a scan is running
+func (s *Server) startServiceRefresh(service string) bool {
+	currentScan, err := s.db.GetCurrentScan()
+	if err != nil || currentScan != nil {
+		return false
+	}
+
+	go func() {
+		if err := s.scanner.UpdateSingleService(service); err != nil {
+			log.Printf("ERROR: Failed to update %s: %v", service, err)
+			return
+		}
+		if _, err := s.reconciler.Run(context.Background(), 0); err != nil {
+			log.Printf("WARNING: Failed to reconcile orphaned and missing files: %v", err)
+		}
+		s.statsCache.Invalidate()
+	}()
+	return true
+}
+
+// HandleDiscoverMappings samples a service's files and proposes the service path mappings that
+// place the samples on scanned files, and the local path mappings they imply. Returns the results
+// table for HTMX requests, JSON otherwise
+func (s *Server) HandleDiscoverMappings(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	provider := api.Lookup(r.FormValue("service"))
+	if provider == nil {
+		respondError(w, http.StatusBadRequest, "Invalid service name", "invalid_service")
+		return
+	}
+	if !provider.Configured(&s.config.Services) {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("%s is not configured", provider.DisplayName()), "service_not_configured")
+		return
+	}
+
+	discovery, err := s.reconciler.Discover(r.Context(), provider)
+	if err != nil {
+		log.Printf("ERROR: Failed to discover %s path mappings: %v", provider.Name(), err)
+		respondError(w, http.StatusBadGateway, fmt.Sprintf("Failed to discover %s path mappings: %v", provider.DisplayName(), err), "discovery_failed")
+		return
+	}
+
+	if r.Header.Get("HX-Request") != "true" {
+		respondJSON(w, http.StatusOK, discovery)
+		return
+	}
+
+	tmplSet, ok := s.templates["discovery_results.html"]
+	if !ok {
+		http.Error(w, "discovery_results template not found", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	if err := tmplSet.ExecuteTemplate(w, "discovery_results.html", discovery); err != nil {
+		log.Printf("ERROR: Failed to execute template: %v", err)
+		http.Error(w, "Failed to render discovered mappings", http.StatusInternalServerError)
+	}
+}
+
+// HandleApplyMappings adds service path mappings, paired by position in the service_prefix and
+// local_prefix form values, to a service's configuration and updates the service
+// With scope=local the pairs are container and host paths added to the local path mappings instead
+func (s *Server) HandleApplyMappings(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid form data", "invalid_parameter")
+		return
+	}
+
+	service := r.FormValue("service")
+	if api.Lookup(service) == nil {
+		respondError(w, http.StatusBadRequest, "Invalid service name", "invalid_service")
+		return
+	}
+
+	servicePrefixes := r.Form["service_prefix"]
+	localPrefixes := r.Form["local_prefix"]
+	if len(servicePrefixes) == 0 || len(servicePrefixes) != len(localPrefixes) {
+		respondError(w, http.StatusBadRequest, "Each service_prefix needs a local_prefix", "invalid_parameter")
+		return
+	}
+	mappings := make([]config.PathMapping, len(servicePrefixes))
+	for i := range servicePrefixes {
+		mappings[i] = config.PathMapping{
+			Service: strings.TrimSpace(servicePrefixes[i]),
+			Local:   strings.TrimSpace(localPrefixes[i]),
+		}
+		if mappings[i].Service == "" || mappings[i].Local == "" {
+			respondError(w, http.StatusBadRequest, "Path mappings need a service and a local path", "invalid_parameter")
+			return
+		}
+	}
+
+	if r.FormValue("scope") == "local" {
+		if err := s.applyLocalPathMappings(mappings); err != nil {
+			log.Printf("ERROR: Failed to apply local path mappings: %v", err)
+			respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save configuration: %v", err), "config_save_failed")
+			return
+		}
+		for _, m := range mappings {
+			log.Printf("Local path mapping %s -> %s applied by %s", m.Service, m.Local, principalName(r.Context()))
+		}
+
+		msg := fmt.Sprintf("Added %d local path mapping(s)", len(mappings))
+		w.Header().Set("X-Toast-Message", msg)
+		w.Header().Set("X-Toast-Type", "success")
+		w.Header().Set("HX-Refresh", "true")
+		respondSuccess(w, msg, nil)
+		return
+	}
+
+	if err := s.applyServicePathMappings(service, mappings); err != nil {
+		log.Printf("ERROR: Failed to apply %s path mappings: %v", service, err)
+		respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save configuration: %v", err), "config_save_failed")
+		return
+	}
+
+	for _, m := range mappings {
+		log.Printf("Path mapping %s -> %s for %s applied by %s", m.Service, m.Local, service, principalName(r.Context()))
+	}
+
+	name := api.DisplayName(service)
+	msg := fmt.Sprintf("Added %d %s path mapping(s)", len(mappings), name)
+	if s.startServiceRefresh(service) {
+		msg += fmt.Sprintf(". Updating %s...", name)
+	} else {
+		msg += fmt.Sprintf(". Update %s once the running scan finishes", name)
+	}
+
+	w.Header().Set("X-Toast-Message", msg)
+	w.Header().Set("X-Toast-Type", "success")
+	w.Header().Set("HX-Refresh", "true")
+	respondSuccess(w, msg, nil)
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mmenanno/media-usage-finder/internal/api"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// staleServiceNames returns the display names of the services in stale checks, e.g. "Plex and Sonarr"
+func staleServiceNames(stale []*database.ServiceCheck) string {
+	names := make([]string, len(stale))
+	for i, check := range stale {
+		names[i] = api.DisplayName(check.Service)
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// requireTrustedOrphans responds with a conflict and returns false while a stale service listing
+// hasn't been acknowledged, since orphaned status may then be wrong
+func (s *Server) requireTrustedOrphans(w http.ResponseWriter, r *http.Request) bool {
+	stale, err := s.db.ListStaleServices(r.Context())
+	if err != nil {
+		log.Printf("ERROR: Failed to check for stale services: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to check for stale services", "database_error")
+		return false
+	}
+	if len(stale) == 0 {
+		return true
+	}
+
+	msg := fmt.Sprintf("Orphan-based deletes are blocked until the ignored file listing of %s is acknowledged on the dashboard", staleServiceNames(stale))
+	w.Header().Set("X-Toast-Message", msg)
+	w.Header().Set("X-Toast-Type", "error")
+	respondError(w, http.StatusConflict, msg, "services_stale")
+	return false
+}
+
+// HandleListStaleServices returns the services whose latest file listing failed a safeguard
+// and hasn't been acknowledged
+func (s *Server) HandleListStaleServices(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	stale, err := s.db.ListStaleServices(r.Context())
+	if err != nil {
+		log.Printf("ERROR: Failed to list stale services: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to list stale services", "database_error")
+		return
+	}
+	if stale == nil {
+		stale = []*database.ServiceCheck{}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"stale":                  stale,
+		"orphan_deletes_blocked": len(stale) > 0,
+	})
+}
+
+// HandleAcknowledgeStaleService acknowledges a service's stale listing
+// Its previous usage stays in place; the acknowledged listing's file count becomes the baseline
+// the next listing is compared with
+func (s *Server) HandleAcknowledgeStaleService(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	service := r.FormValue("service")
+	if service == "" {
+		respondError(w, http.StatusBadRequest, "Service is required", "invalid_parameter")
+		return
+	}
+
+	acknowledged, err := s.db.AcknowledgeStaleService(r.Context(), service, principalName(r.Context()))
+	if err != nil {
+		log.Printf("ERROR: Failed to acknowledge stale %s listing: %v", service, err)
+		respondError(w, http.StatusInternalServerError, "Failed to acknowledge stale service", "database_error")
+		return
+	}
+	if acknowledged == 0 {
+		respondError(w, http.StatusNotFound, fmt.Sprintf("%s is not stale", api.DisplayName(service)), "not_found")
+		return
+	}
+
+	log.Printf("Stale %s file listing acknowledged by %s", service, principalName(r.Context()))
+
+	msg := fmt.Sprintf("Acknowledged %s; its next file listing will be compared with the ignored one", api.DisplayName(service))
+	w.Header().Set("X-Toast-Message", msg)
+	w.Header().Set("X-Toast-Type", "success")
+	w.Header().Set("HX-Refresh", "true")
+	respondSuccess(w, msg, nil)
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// scanReport returns a scan's report, or nil if it has none
+func (s *Server) scanReport(scanID int64) *database.ScanReport {
+	report, err := s.db.GetScanReport(scanID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("WARNING: Failed to get report for scan %d: %v", scanID, err)
+		}
+		return nil
+	}
+	return report
+}
+
+// parseScanChangeType validates the type query parameter; an empty type selects every change
+func parseScanChangeType(r *http.Request) (string, bool) {
+	changeType := r.URL.Query().Get("type")
+	if changeType == "" {
+		return "", true
+	}
+	for _, t := range database.ScanChangeTypes {
+		if t == changeType {
+			return changeType, true
+		}
+	}
+	return "", false
+}
+
+// HandleScanReport serves the changes a scan found since the end of the previous full or incremental scan
+func (s *Server) HandleScanReport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid scan ID", "invalid_parameter")
+		return
+	}
+
+	changeType, ok := parseScanChangeType(r)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "Invalid change type", "invalid_parameter")
+		return
+	}
+	service := r.URL.Query().Get("service")
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = ValidatePage(page)
+
+	report, err := s.db.GetScanReport(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusNotFound, "Scan report not found", "not_found")
+		return
+	} else if err != nil {
+		log.Printf("ERROR: Failed to get report for scan %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve scan report. Database error occurred", "database_error")
+		return
+	}
+
+	limit := constants.DefaultScanChangesPerPage
+	changes, total, err := s.db.ListScanChanges(id, changeType, service, limit, (page-1)*limit)
+	if err != nil {
+		log.Printf("ERROR: Failed to list changes of scan %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve scan changes. Database error occurred", "database_error")
+		return
+	}
+
+	data := ScanReportData{
+		Report:     report,
+		Changes:    changes,
+		ChangeType: changeType,
+		Service:    service,
+		Total:      int64(total),
+		Page:       int64(page),
+		TotalPages: CalculateTotalPages(total, limit),
+		Title:      "Scan Report",
+		Version:    s.version,
+	}
+
+	s.renderTemplate(w, "scan_report.html", data)
+}
+
+// HandleExportScanReport downloads a scan report with all of its changes as JSON or CSV
+func (s *Server) HandleExportScanReport(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid scan ID", "invalid_parameter")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		respondError(w, http.StatusBadRequest, "Invalid format. Supported formats: json, csv", "invalid_parameter")
+		return
+	}
+
+	changeType, ok := parseScanChangeType(r)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "Invalid change type", "invalid_parameter")
+		return
+	}
+
+	report, err := s.db.GetScanReport(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusNotFound, "Scan report not found", "not_found")
+		return
+	} else if err != nil {
+		log.Printf("ERROR: Failed to get report for scan %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve scan report", "database_error")
+		return
+	}
+
+	changes, _, err := s.db.ListScanChanges(id, changeType, r.URL.Query().Get("service"), -1, 0)
+	if err != nil {
+		log.Printf("ERROR: Failed to list changes of scan %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve scan changes", "database_error")
+		return
+	}
+
+	filename := fmt.Sprintf("scan_%d_report.%s", id, format)
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"report":  report,
+			"changes": changes,
+		}); err != nil {
+			log.Printf("Failed to write scan report: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	csvWriter := csv.NewWriter(w)
+	defer csvWriter.Flush()
+
+	header := []string{"change_type", "path", "service", "size", "previous_size", "modified_time", "previous_modified_time"}
+	if err := csvWriter.Write(header); err != nil {
+		log.Printf("Failed to write CSV header: %v", err)
+		return
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	for _, c := range changes {
+		previousSize := ""
+		if c.PreviousSize != nil {
+			previousSize = strconv.FormatInt(*c.PreviousSize, 10)
+		}
+
+		record := []string{
+			c.ChangeType,
+			c.Path,
+			c.Service,
+			strconv.FormatInt(c.Size, 10),
+			previousSize,
+			formatTime(c.ModifiedTime),
+			formatTime(c.PreviousModifiedTime),
+		}
+		if err := csvWriter.Write(record); err != nil {
+			log.Printf("Failed to write CSV record: %v", err)
+			return
+		}
+	}
+}
@@ -22,16 +22,27 @@ func (s *Server) Run() error {
 	// Health check
 	mux.HandleFunc("/health", s.HandleHealth)
 
+	// Authentication
+	mux.HandleFunc("/login", s.HandleLogin)
+	mux.HandleFunc("/logout", s.HandleLogout)
+	mux.HandleFunc("/setup", s.HandleSetup)
+
 	// Page routes
 	mux.HandleFunc("/", s.HandleIndex)
 	mux.HandleFunc("/files", s.HandleFiles)
 	mux.HandleFunc("/duplicates", s.HandleDuplicates)
 	mux.HandleFunc("/hardlinks", s.HandleHardlinks)
 	mux.HandleFunc("/scans", s.HandleScans)
+	mux.HandleFunc("/scans/report", s.HandleScanReport)
 	mux.HandleFunc("/logs", s.HandleScanLogsPage)
+	mux.HandleFunc("/quarantine", s.HandleQuarantine)
+	mux.HandleFunc("/policies", s.HandlePolicies)
+	mux.HandleFunc("/snapshots", s.HandleSnapshots)
+	mux.HandleFunc("/mappings", s.HandleMappings)
 	mux.HandleFunc("/stats", s.HandleStats)
 	mux.HandleFunc("/advanced", s.HandleAdvanced)
 	mux.HandleFunc("/config", s.HandleConfig)
+	mux.HandleFunc("/access", s.HandleAccess)
 
 	// API routes
 	mux.HandleFunc("/api/scan/start", s.HandleStartScan)
@@ -69,7 +80,7 @@ func (s *Server) Run() error {
 	mux.HandleFunc("/api/config/save", s.HandleSaveConfig)
 	mux.HandleFunc("/api/config/test", s.HandleTestService)
 	mux.HandleFunc("/api/config/test-scan-paths", s.HandleTestScanPaths)
-	mux.HandleFunc("/api/plex/libraries", s.HandleGetPlexLibraries)
+	mux.HandleFunc("/api/libraries", s.HandleGetLibraries)
 	mux.HandleFunc("/api/config/test-path-mappings", s.HandleTestPathMappings)
 	mux.HandleFunc("/api/disks/detect", s.HandleDetectDisks)
 	mux.HandleFunc("/api/export", s.HandleExport)
@@ -79,6 +90,41 @@ func (s *Server) Run() error {
 	mux.HandleFunc("/api/files/delete", s.HandleDeleteFile)
 	mux.HandleFunc("/api/files/batch-delete", s.HandleBatchDeleteFiles)
 	mux.HandleFunc("/api/files/rescan", s.HandleRescanFiles)
+	mux.HandleFunc("/api/quarantine", s.HandleListQuarantine)
+	mux.HandleFunc("/api/quarantine/restore", s.HandleRestoreQuarantine)
+	mux.HandleFunc("/api/quarantine/purge", s.HandlePurgeQuarantine)
+	mux.HandleFunc("/api/operations", s.HandleListOperationRuns)
+	mux.HandleFunc("/api/operations/entries", s.HandleGetOperationEntries)
+	mux.HandleFunc("/api/operations/revert", s.HandleRevertOperationRun)
+	mux.HandleFunc("/api/policies/runs", s.HandleListPolicyRuns)
+	mux.HandleFunc("/api/policies/matches", s.HandleGetPolicyMatches)
+	mux.HandleFunc("/api/policies/run", s.HandleRunPolicies)
+	mux.HandleFunc("/api/usage-events", s.HandleListUsageEvents)
+	mux.HandleFunc("/api/scans/report", s.HandleExportScanReport)
+	mux.HandleFunc("/api/notifications/test", s.HandleTestNotification)
+	mux.HandleFunc("/api/services/stale", s.HandleListStaleServices)
+	mux.HandleFunc("/api/services/acknowledge", s.HandleAcknowledgeStaleService)
+	mux.HandleFunc("/api/snapshots", s.HandleListSnapshots)
+	mux.HandleFunc("/api/snapshots/diff", s.HandleDiffSnapshots)
+	mux.HandleFunc("/api/snapshots/replay", s.HandleReplaySnapshot)
+	mux.HandleFunc("/api/reconcile/proposals", s.HandleListMappingProposals)
+	mux.HandleFunc("/api/reconcile/run", s.HandleRunReconcile)
+	mux.HandleFunc("/api/reconcile/apply", s.HandleApplyMappingProposal)
+	mux.HandleFunc("/api/mappings/discover", s.HandleDiscoverMappings)
+	mux.HandleFunc("/api/mappings/apply", s.HandleApplyMappings)
+
+	// Service webhooks
+	mux.HandleFunc("/api/webhooks/sonarr", s.HandleSonarrWebhook)
+	mux.HandleFunc("/api/webhooks/radarr", s.HandleRadarrWebhook)
+	mux.HandleFunc("/api/webhooks/plex", s.HandlePlexWebhook)
+	mux.HandleFunc("/api/webhooks/qbittorrent", s.HandleQBittorrentWebhook)
+
+	// User and API key management routes
+	mux.HandleFunc("/api/auth/users", s.HandleCreateUser)
+	mux.HandleFunc("/api/auth/users/delete", s.HandleDeleteUser)
+	mux.HandleFunc("/api/auth/users/password", s.HandleChangePassword)
+	mux.HandleFunc("/api/auth/api-keys", s.HandleCreateAPIKey)
+	mux.HandleFunc("/api/auth/api-keys/revoke", s.HandleRevokeAPIKey)
 
 	// Admin API routes
 	mux.HandleFunc("/api/admin/clear-files", s.HandleAdminClearFiles)
@@ -116,8 +162,17 @@ func (s *Server) Run() error {
 	rateLimiter := NewRateLimiter(10.0, 20)
 	rateLimiter.StartPeriodicCleanup(1 * time.Hour)
 
-	// Apply middleware chain (order matters: Recovery -> RateLimit -> RequestID -> Logger -> RequestSizeLimit -> CORS -> handlers)
-	handler := Recovery(rateLimiter.Middleware(RequestID(Logger(RequestSizeLimit(CORS(s.config.CORSAllowedOrigin)(mux))))))
+	// Remove expired login sessions
+	s.startSessionCleanup(1 * time.Hour)
+	if !s.config.Auth.Enabled {
+		log.Printf("Warning: Authentication is disabled - all routes are accessible without signing in")
+	}
+	if err := s.prepareSetupToken(); err != nil {
+		log.Printf("Warning: Failed to prepare setup token: %v", err)
+	}
+
+	// Apply middleware chain (order matters: Recovery -> RateLimit -> RequestID -> Logger -> RequestSizeLimit -> CORS -> Authenticate -> handlers)
+	handler := Recovery(rateLimiter.Middleware(RequestID(Logger(RequestSizeLimit(CORS(s.config.CORSAllowedOrigin)(s.Authenticate(mux)))))))
 
 	// Start server on hardcoded port 8787
 	addr := ":8787"
@@ -131,6 +186,23 @@ func (s *Server) Run() error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Start cron scheduler (no-op until enabled in config)
+	s.scheduler.Start()
+	defer s.scheduler.Stop()
+
+	// Start filesystem watcher if enabled
+	if s.config.Watcher.Enabled {
+		if err := s.watcher.Start(); err != nil {
+			log.Printf("Warning: Failed to start filesystem watcher: %v", err)
+		}
+	}
+	defer s.watcher.Stop()
+	defer s.rescanQueue.Stop()
+
+	// Purge expired quarantined files in the background
+	s.quarantine.Start()
+	defer s.quarantine.Stop()
+
 	// Run server in a goroutine
 	serverErrors := make(chan error, 1)
 	go func() {
@@ -0,0 +1,328 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/mmenanno/media-usage-finder/internal/api"
+	"github.com/mmenanno/media-usage-finder/internal/constants"
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// snapshotChangeAdded and snapshotChangeRemoved are the change types of a snapshot diff
+const (
+	snapshotChangeAdded   = "added"
+	snapshotChangeRemoved = "removed"
+)
+
+// SnapshotChange is a file a service started or stopped reporting between two snapshots
+type SnapshotChange struct {
+	ChangeType string `json:"change_type"`
+	database.SnapshotFile
+}
+
+// snapshotChanges flattens a diff into its removed files followed by its added files,
+// optionally only those of one change type
+func snapshotChanges(diff *database.SnapshotDiff, changeType string) []SnapshotChange {
+	var changes []SnapshotChange
+	if changeType == "" || changeType == snapshotChangeRemoved {
+		for _, f := range diff.Removed {
+			changes = append(changes, SnapshotChange{ChangeType: snapshotChangeRemoved, SnapshotFile: f})
+		}
+	}
+	if changeType == "" || changeType == snapshotChangeAdded {
+		for _, f := range diff.Added {
+			changes = append(changes, SnapshotChange{ChangeType: snapshotChangeAdded, SnapshotFile: f})
+		}
+	}
+	return changes
+}
+
+// diffSnapshots diffs two snapshots, responding with an error and returning nil if either doesn't
+// exist or they're of different services
+func (s *Server) diffSnapshots(w http.ResponseWriter, r *http.Request, fromID, toID int64) *database.SnapshotDiff {
+	ctx := r.Context()
+	var snapshots [2]*database.ServiceSnapshot
+	for i, id := range []int64{fromID, toID} {
+		snapshot, err := s.db.GetServiceSnapshot(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(w, http.StatusNotFound, fmt.Sprintf("Snapshot %d not found", id), "not_found")
+			return nil
+		} else if err != nil {
+			log.Printf("ERROR: Failed to get snapshot %d: %v", id, err)
+			respondError(w, http.StatusInternalServerError, "Failed to retrieve snapshot", "database_error")
+			return nil
+		}
+		snapshots[i] = snapshot
+	}
+	if snapshots[0].Service != snapshots[1].Service {
+		respondError(w, http.StatusBadRequest, "Snapshots must be of the same service", "invalid_parameter")
+		return nil
+	}
+
+	diff, err := s.db.DiffServiceSnapshots(ctx, fromID, toID)
+	if err != nil {
+		log.Printf("ERROR: Failed to diff snapshots %d and %d: %v", fromID, toID, err)
+		respondError(w, http.StatusInternalServerError, "Failed to compare snapshots", "database_error")
+		return nil
+	}
+	return diff
+}
+
+// parseSnapshotChangeType validates the type query parameter; an empty type selects both change types
+func parseSnapshotChangeType(r *http.Request) (string, bool) {
+	changeType := r.URL.Query().Get("type")
+	switch changeType {
+	case "", snapshotChangeAdded, snapshotChangeRemoved:
+		return changeType, true
+	}
+	return "", false
+}
+
+// HandleSnapshots serves the stored service snapshots and the diff of two snapshots of a service
+// Without from and to, the service's two latest snapshots are compared
+func (s *Server) HandleSnapshots(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	changeType, ok := parseSnapshotChangeType(r)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "Invalid change type", "invalid_parameter")
+		return
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	page = ValidatePage(page)
+
+	all, err := s.db.ListServiceSnapshots(r.Context(), "")
+	if err != nil {
+		log.Printf("ERROR: Failed to list service snapshots: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve snapshots. Database error occurred", "database_error")
+		return
+	}
+
+	seen := make(map[string]bool)
+	var services []string
+	for _, snapshot := range all {
+		if !seen[snapshot.Service] {
+			seen[snapshot.Service] = true
+			services = append(services, snapshot.Service)
+		}
+	}
+	sort.Strings(services)
+
+	service := query.Get("service")
+	if service == "" && len(services) > 0 {
+		service = services[0]
+	}
+
+	var snapshots []*database.ServiceSnapshot
+	for _, snapshot := range all {
+		if snapshot.Service == service {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	data := SnapshotsData{
+		Services:   services,
+		Service:    service,
+		Snapshots:  snapshots,
+		ChangeType: changeType,
+		Page:       int64(page),
+		Title:      "Service Snapshots",
+		Version:    s.version,
+	}
+
+	fromID, _ := strconv.ParseInt(query.Get("from"), 10, 64)
+	toID, _ := strconv.ParseInt(query.Get("to"), 10, 64)
+	if (fromID == 0 || toID == 0) && len(snapshots) > 1 {
+		fromID, toID = snapshots[1].ID, snapshots[0].ID
+	}
+
+	if fromID != 0 && toID != 0 {
+		diff := s.diffSnapshots(w, r, fromID, toID)
+		if diff == nil {
+			return
+		}
+
+		changes := snapshotChanges(diff, changeType)
+		limit := constants.DefaultSnapshotDiffPerPage
+		start := (page - 1) * limit
+		if start > len(changes) {
+			start = len(changes)
+		}
+		end := start + limit
+		if end > len(changes) {
+			end = len(changes)
+		}
+
+		data.Service = diff.From.Service
+		data.Diff = diff
+		data.Changes = changes[start:end]
+		data.Total = int64(len(changes))
+		data.TotalPages = CalculateTotalPages(len(changes), limit)
+	}
+
+	s.renderTemplate(w, "snapshots.html", data)
+}
+
+// HandleListSnapshots returns the stored service snapshots as JSON, optionally for one service
+func (s *Server) HandleListSnapshots(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	snapshots, err := s.db.ListServiceSnapshots(r.Context(), r.URL.Query().Get("service"))
+	if err != nil {
+		log.Printf("ERROR: Failed to list service snapshots: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve snapshots", "database_error")
+		return
+	}
+	if snapshots == nil {
+		snapshots = []*database.ServiceSnapshot{}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"snapshots": snapshots,
+	})
+}
+
+// HandleDiffSnapshots returns the files a service started and stopped reporting between two of its
+// snapshots as JSON or CSV
+func (s *Server) HandleDiffSnapshots(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	query := r.URL.Query()
+	fromID, err := strconv.ParseInt(query.Get("from"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid from snapshot ID", "invalid_parameter")
+		return
+	}
+	toID, err := strconv.ParseInt(query.Get("to"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid to snapshot ID", "invalid_parameter")
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		respondError(w, http.StatusBadRequest, "Invalid format. Supported formats: json, csv", "invalid_parameter")
+		return
+	}
+
+	changeType, ok := parseSnapshotChangeType(r)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "Invalid change type", "invalid_parameter")
+		return
+	}
+
+	diff := s.diffSnapshots(w, r, fromID, toID)
+	if diff == nil {
+		return
+	}
+
+	if format == "json" {
+		if changeType == snapshotChangeAdded {
+			diff.Removed = []database.SnapshotFile{}
+		} else if changeType == snapshotChangeRemoved {
+			diff.Added = []database.SnapshotFile{}
+		}
+		respondJSON(w, http.StatusOK, diff)
+		return
+	}
+
+	filename := fmt.Sprintf("%s_snapshot_%d_to_%d.csv", diff.From.Service, fromID, toID)
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Content-Type", "text/csv")
+	csvWriter := csv.NewWriter(w)
+	defer csvWriter.Flush()
+
+	header := []string{"change_type", "path", "size", "group", "group_id", "metadata"}
+	if err := csvWriter.Write(header); err != nil {
+		log.Printf("Failed to write CSV header: %v", err)
+		return
+	}
+
+	for _, c := range snapshotChanges(diff, changeType) {
+		metadata := ""
+		if len(c.Metadata) > 0 {
+			if encoded, err := json.Marshal(c.Metadata); err == nil {
+				metadata = string(encoded)
+			}
+		}
+
+		record := []string{
+			c.ChangeType,
+			c.Path,
+			strconv.FormatInt(c.Size, 10),
+			c.Group,
+			c.GroupID,
+			metadata,
+		}
+		if err := csvWriter.Write(record); err != nil {
+			log.Printf("Failed to write CSV record: %v", err)
+			return
+		}
+	}
+}
+
+// HandleReplaySnapshot rebuilds a service's usage from a stored snapshot and recalculates orphaned
+// status, without contacting the service
+func (s *Server) HandleReplaySnapshot(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid snapshot ID", "invalid_parameter")
+		return
+	}
+
+	snapshot, err := s.db.GetServiceSnapshot(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(w, http.StatusNotFound, "Snapshot not found", "not_found")
+		return
+	} else if err != nil {
+		log.Printf("ERROR: Failed to get snapshot %d: %v", id, err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve snapshot", "database_error")
+		return
+	}
+
+	currentScan, err := s.db.GetCurrentScan()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to check scan status", "scan_check_failed")
+		return
+	}
+	if currentScan != nil {
+		respondError(w, http.StatusConflict, "Cannot replay a snapshot while a scan is running", "scan_running")
+		return
+	}
+
+	log.Printf("Replay of %s snapshot %d requested by %s", snapshot.Service, snapshot.ID, principalName(r.Context()))
+
+	go func() {
+		if err := s.scanner.ReplayServiceSnapshot(context.Background(), id); err != nil {
+			log.Printf("ERROR: Failed to replay %s snapshot %d: %v", snapshot.Service, id, err)
+		} else {
+			log.Printf("INFO: %s usage rebuilt from snapshot %d", snapshot.Service, id)
+		}
+	}()
+
+	msg := fmt.Sprintf("Rebuilding %s usage from snapshot #%d...", api.DisplayName(snapshot.Service), snapshot.ID)
+	w.Header().Set("X-Toast-Message", msg)
+	w.Header().Set("X-Toast-Type", "info")
+	respondSuccess(w, msg, nil)
+}
@@ -1,9 +1,14 @@
 package server
 
 import (
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/config"
 	"github.com/mmenanno/media-usage-finder/internal/database"
 	"github.com/mmenanno/media-usage-finder/internal/disk"
 	"github.com/mmenanno/media-usage-finder/internal/duplicates"
+	"github.com/mmenanno/media-usage-finder/internal/pathmatch"
+	"github.com/mmenanno/media-usage-finder/internal/scheduler"
 	"github.com/mmenanno/media-usage-finder/internal/stats"
 )
 
@@ -60,10 +65,50 @@ type FileDetailsResponse struct {
 	ModifiedTime  int64                        `json:"modified_time"`
 	LastVerified  int64                        `json:"last_verified"`
 	IsOrphaned    bool                         `json:"is_orphaned"`
+	OrphanedSince int64                        `json:"orphaned_since,omitempty"` // When the file lost all service usage
+	LastUsedBy    string                       `json:"last_used_by,omitempty"`   // Services that used the file before it was orphaned
 	CreatedAt     int64                        `json:"created_at"`
 	Usage         []*database.Usage            `json:"usage"`
+	TorrentCount  int                          `json:"torrent_count"` // Torrents referencing the file across download clients
 	Hardlinks     []string                     `json:"hardlinks,omitempty"`
 	DiskLocations []*database.FileDiskLocation `json:"disk_locations,omitempty"` // Disk-specific locations
+	Moves         []*database.FileMove         `json:"moves,omitempty"`          // Previous paths, most recent first
+	UsageHistory  []*database.UsageEvent       `json:"usage_history,omitempty"`  // Services starting or stopping to use the file, most recent first
+	Explanation   *OrphanExplanation           `json:"orphan_explanation,omitempty"` // Why no service matches the file, for orphaned files
+}
+
+// PathMappingView is a configured path mapping as shown in an orphan explanation
+type PathMappingView struct {
+	Service string `json:"service"`
+	Local   string `json:"local"`
+}
+
+// OrphanExplanation explains why each configured service doesn't match a file
+type OrphanExplanation struct {
+	ContainerPath string               `json:"container_path"`          // The file's path inside media-finder's container
+	LocalMapping  *PathMappingView     `json:"local_mapping,omitempty"` // Local path mapping that produced ContainerPath
+	Services      []ServiceExplanation `json:"services"`
+}
+
+// ServiceExplanation compares the path a service would have to report for a file with what it reports
+type ServiceExplanation struct {
+	Service      string              `json:"service"`
+	InUse        bool                `json:"in_use"`
+	ExpectedPath string              `json:"expected_path"`       // Path the service must report for the file to match
+	Mapping      *PathMappingView    `json:"mapping,omitempty"`   // Service path mapping that produced ExpectedPath
+	Mappings     []PathMappingView   `json:"mappings,omitempty"`  // All of the service's path mappings
+	Source       string              `json:"source,omitempty"`    // Where the reported paths came from: "snapshot" or "usage"
+	ListedAt     *time.Time          `json:"listed_at,omitempty"` // When the snapshot the paths came from was taken
+	Reported     bool                `json:"reported"`            // Whether the service reports ExpectedPath
+	Closest      []ReportedPathMatch `json:"closest,omitempty"`   // Reported paths most like ExpectedPath, best first
+	Reason       string              `json:"reason"`
+}
+
+// ReportedPathMatch is a path a service reported that resembles the path expected from it
+type ReportedPathMatch struct {
+	pathmatch.Match
+	TranslatedPath string `json:"translated_path"` // Where the service path mappings place the reported path
+	Missing        bool   `json:"missing"`         // Whether the path is among the service's missing files
 }
 
 // BulkDeleteResponse represents the result of a bulk deletion
@@ -138,7 +183,9 @@ type FilesData struct {
 	TotalPages        int
 	Title             string
 	Orphaned          bool
+	OrphanedDays      int // Only files orphaned for at least this many days
 	Hardlinks         bool
+	SafeToDelete      bool     // Only torrent files that have finished seeding and no media server uses
 	Service           string   // Deprecated: Use Services instead (kept for backward compatibility)
 	Services          []string
 	ServiceFilterMode string
@@ -152,6 +199,7 @@ type FilesData struct {
 	HasDiskLocations         bool                 // True if disk location tracking is enabled
 	Version                  string               // Application version
 	DeleteFilesFromFilesystem bool                // Config setting for filesystem deletion
+	QuarantineEnabled        bool                 // Deleted files are moved to quarantine instead of removed
 }
 
 // ConfigData represents data for the configuration template
@@ -161,6 +209,89 @@ type ConfigData struct {
 	Version            string // Application version
 	CPUCores           int    // Number of CPU cores on the server
 	RecommendedWorkers int    // Recommended number of hash workers
+	ScheduledTasks     []scheduler.TaskStatus
+}
+
+// LoginData represents data for the login and setup templates
+type LoginData struct {
+	Title    string
+	Version  string
+	Error    string
+	Next     string
+	Username string
+	Setup    bool // True when creating the first admin account
+}
+
+// AccessData represents data for the access control template
+type AccessData struct {
+	Title       string
+	Version     string
+	Users       []*database.User
+	APIKeys     []*database.APIKey
+	CurrentUser *Principal
+}
+
+// QuarantineData represents data for the quarantine template
+type QuarantineData struct {
+	Items      []*database.QuarantineItem
+	Total      int64
+	TotalSize  int64
+	Page       int64
+	TotalPages int
+	Enabled    bool
+	Retention  time.Duration
+	Runs       []*database.OperationRun // Recent consolidation and hardlink runs that can be reverted
+	Title      string
+	Version    string
+}
+
+// PoliciesData represents data for the cleanup policy report template
+type PoliciesData struct {
+	Policies   []PolicyView
+	Runs       []*database.PolicyRun
+	Run        *database.PolicyRun // Run whose matches are shown
+	Matches    []*database.PolicyMatch
+	Policy     string // Policy the matches are filtered to, if any
+	Total      int64
+	Page       int64
+	TotalPages int
+	Title      string
+	Version    string
+}
+
+// SnapshotsData represents data for the service snapshots template
+type SnapshotsData struct {
+	Services   []string // Services with stored snapshots
+	Service    string
+	Snapshots  []*database.ServiceSnapshot // The service's snapshots, newest first
+	Diff       *database.SnapshotDiff      // Nil unless the service has two snapshots to compare
+	Changes    []SnapshotChange
+	ChangeType string // Change type the diff is filtered to, if any
+	Total      int64
+	Page       int64
+	TotalPages int
+	Title      string
+	Version    string
+}
+
+// ScanReportData represents data for the scan report template
+type ScanReportData struct {
+	Report     *database.ScanReport
+	Changes    []*database.ScanChange
+	ChangeType string // Change type the list is filtered to, if any
+	Service    string // Service the missing files are filtered to, if any
+	Total      int64
+	Page       int64
+	TotalPages int
+	Title      string
+	Version    string
+}
+
+// PolicyView is a configured cleanup policy with its results in the shown run
+type PolicyView struct {
+	config.CleanupPolicy
+	Description string
+	Summary     *database.PolicySummary // Nil when the policy matched nothing
 }
 
 // StatsData represents data for the statistics template
@@ -234,3 +365,27 @@ type DuplicatesData struct {
 	FilesMissingDiskLocations int64 // Count of files missing disk location data
 	SameDiskGroupsSkipped     int   // Count of same-disk groups skipped due to missing disk locations
 }
+
+// APIKeyCreatedResponse represents a newly created API key
+// Key is the plaintext key and is only ever returned once
+type APIKeyCreatedResponse struct {
+	Status string `json:"status"`
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Key    string `json:"key"`
+}
+
+// MappingProposalView is a mapping proposal with the configured mapping it would replace, if any
+type MappingProposalView struct {
+	*database.MappingProposal
+	Replaces *PathMappingView `json:"replaces,omitempty"`
+}
+
+// MappingsData represents data for the path mappings template
+type MappingsData struct {
+	Proposals []MappingProposalView
+	Services  []string // Configured services path mappings can be discovered for
+	Title     string
+	Version   string
+}
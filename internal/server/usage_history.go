@@ -0,0 +1,82 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mmenanno/media-usage-finder/internal/database"
+)
+
+// HandleListUsageEvents returns usage history events as JSON
+// Filters: file_id, service, event (added or removed), since and until (RFC 3339 or YYYY-MM-DD) and
+// still_removed=true for removals the service has not reverted, e.g. files that lost Plex usage since a date
+func (s *Server) HandleListUsageEvents(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := database.UsageEventFilter{
+		Service:      query.Get("service"),
+		EventType:    query.Get("event"),
+		StillRemoved: query.Get("still_removed") == "true",
+	}
+
+	switch filter.EventType {
+	case "", database.UsageEventAdded, database.UsageEventRemoved, database.UsageEventChanged:
+	default:
+		respondError(w, http.StatusBadRequest, "Invalid event type. Supported types: added, removed, changed", "invalid_parameter")
+		return
+	}
+
+	if fileIDStr := query.Get("file_id"); fileIDStr != "" {
+		fileID, err := strconv.ParseInt(fileIDStr, 10, 64)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid file ID", "invalid_parameter")
+			return
+		}
+		filter.FileID = fileID
+	}
+
+	var err error
+	if filter.Since, err = parseEventTime(query.Get("since")); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid since date. Use RFC 3339 or YYYY-MM-DD", "invalid_parameter")
+		return
+	}
+	if filter.Until, err = parseEventTime(query.Get("until")); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid until date. Use RFC 3339 or YYYY-MM-DD", "invalid_parameter")
+		return
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	page = ValidatePage(page)
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	limit = ValidateLimit(limit)
+
+	events, total, err := s.db.ListUsageEvents(filter, limit, (page-1)*limit)
+	if err != nil {
+		log.Printf("ERROR: Failed to list usage events: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to retrieve usage history", "database_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"events": events,
+		"total":  total,
+		"page":   page,
+	})
+}
+
+// parseEventTime parses an RFC 3339 timestamp or a YYYY-MM-DD date in local time
+// An empty value returns the zero time
+func parseEventTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
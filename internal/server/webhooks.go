@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mmenanno/media-usage-finder/internal/webhook"
+)
+
+// requireWebhooks checks the request is a POST and webhooks are enabled
+func (s *Server) requireWebhooks(w http.ResponseWriter, r *http.Request) bool {
+	if !requireMethod(w, r, http.MethodPost) {
+		return false
+	}
+	if !s.config.Webhooks.Enabled {
+		respondError(w, http.StatusNotFound, "Webhooks are disabled", "webhooks_disabled")
+		return false
+	}
+	return true
+}
+
+// queueWebhookEvent queues the files of a webhook event for a rescan and reports what was queued
+func (s *Server) queueWebhookEvent(ctx context.Context, w http.ResponseWriter, ev *webhook.Event) {
+	if ev.Empty() {
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"event":    ev.Type,
+			"reported": 0,
+		})
+		return
+	}
+
+	var skipped []string
+	for _, rename := range ev.Renames {
+		skipped = append(skipped, s.rescanQueue.Rename(ctx, ev.Service, rename.From, rename.To)...)
+	}
+	skipped = append(skipped, s.rescanQueue.Add(ev.Service, ev.Paths...)...)
+
+	for _, path := range skipped {
+		log.Printf("Webhooks: Ignoring %s path %s outside the scan paths - check the %s path mappings", ev.Service, path, ev.Service)
+	}
+
+	respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"event":    ev.Type,
+		"reported": len(ev.Paths) + len(ev.Renames),
+		"skipped":  skipped,
+		"pending":  s.rescanQueue.Pending(),
+	})
+}
+
+// HandleSonarrWebhook rescans the episode files a Sonarr webhook reports
+func (s *Server) HandleSonarrWebhook(w http.ResponseWriter, r *http.Request) {
+	if !s.requireWebhooks(w, r) {
+		return
+	}
+
+	ev, err := webhook.ParseSonarr(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid_request")
+		return
+	}
+	s.queueWebhookEvent(r.Context(), w, ev)
+}
+
+// HandleRadarrWebhook rescans the movie files a Radarr webhook reports
+func (s *Server) HandleRadarrWebhook(w http.ResponseWriter, r *http.Request) {
+	if !s.requireWebhooks(w, r) {
+		return
+	}
+
+	ev, err := webhook.ParseRadarr(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid_request")
+		return
+	}
+	s.queueWebhookEvent(r.Context(), w, ev)
+}
+
+// HandlePlexWebhook rescans the files of items Plex adds to a library
+// Plex posts a multipart form whose payload field holds the event; the item's files are looked up
+// with the Plex API since the payload doesn't include them
+func (s *Server) HandlePlexWebhook(w http.ResponseWriter, r *http.Request) {
+	if !s.requireWebhooks(w, r) {
+		return
+	}
+
+	payload := r.FormValue("payload")
+	if payload == "" {
+		respondError(w, http.StatusBadRequest, "Missing payload", "invalid_request")
+		return
+	}
+
+	item, err := webhook.ParsePlex(payload)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid_request")
+		return
+	}
+
+	ev := &webhook.Event{Service: "plex"}
+	if item == nil {
+		s.queueWebhookEvent(r.Context(), w, ev)
+		return
+	}
+	ev.Type = item.Event
+
+	if s.config.Services.Plex.URL == "" {
+		respondError(w, http.StatusServiceUnavailable, "Plex is not configured", "service_not_configured")
+		return
+	}
+
+	files, err := s.clientFactory.CreatePlexClient(s.config.APITimeout).GetItemFiles(r.Context(), item.RatingKey, item.Type)
+	if err != nil {
+		log.Printf("ERROR: Failed to look up files of Plex item %s (%s): %v", item.RatingKey, item.Title, err)
+		respondError(w, http.StatusBadGateway, "Failed to look up the item's files in Plex", "connection_failed")
+		return
+	}
+	for _, f := range files {
+		ev.Paths = append(ev.Paths, f.Path)
+	}
+
+	s.queueWebhookEvent(r.Context(), w, ev)
+}
+
+// HandleQBittorrentWebhook rescans a torrent's content when qBittorrent finishes it
+// Called from qBittorrent's "Run external program on torrent finished" with the content path (%F)
+// as a path form field or JSON body
+func (s *Server) HandleQBittorrentWebhook(w http.ResponseWriter, r *http.Request) {
+	if !s.requireWebhooks(w, r) {
+		return
+	}
+
+	var contentPath string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var body struct {
+			Path string `json:"path"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid JSON body", "invalid_request")
+			return
+		}
+		contentPath = body.Path
+	} else {
+		contentPath = r.FormValue("path")
+	}
+
+	if strings.TrimSpace(contentPath) == "" {
+		respondError(w, http.StatusBadRequest, "Missing torrent content path", "invalid_request")
+		return
+	}
+
+	s.queueWebhookEvent(r.Context(), w, webhook.QBittorrentEvent(contentPath))
+}
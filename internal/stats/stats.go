@@ -21,7 +21,7 @@ type Stats struct {
 	ActiveServiceCount      int64 // Number of services with files
 	OrphanedExtensions      []ExtensionStats
 	OrphanedExtensionsTotal int64 // Total count of unique orphaned extensions
-	OrphanedByAge           []AgeGroupStats
+	OrphanedByAge           []AgeGroupStats // Grouped by how long the files have been orphaned
 	MultiServiceUsage       []ServiceUsageCount
 	LargestOrphanedPath     string // Path of largest orphaned file
 	LargestOrphanedSize     int64  // Size of largest orphaned file
@@ -163,22 +163,24 @@ func (c *Calculator) calculateServiceBreakdown(stats *Stats) error {
 		SELECT u.service, COUNT(DISTINCT f.id), COALESCE(SUM(f.size), 0)
 		FROM files f
 		INNER JOIN usage u ON f.id = u.file_id
-		WHERE u.service IN ('plex', 'sonarr', 'radarr', 'qbittorrent', 'stash', 'calibre')
 		GROUP BY u.service
 	`
 
-	rows, err := c.db.Conn().Query(query)
+	// Initialize all registered services with zero stats
+	services, err := c.db.ListServices()
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-
-	// Initialize all services with zero stats
-	services := []string{"plex", "sonarr", "radarr", "qbittorrent", "stash", "calibre"}
 	for _, service := range services {
-		stats.ServiceBreakdown[service] = ServiceStats{FileCount: 0, TotalSize: 0}
+		stats.ServiceBreakdown[service.Name] = ServiceStats{FileCount: 0, TotalSize: 0}
 	}
 
+	rows, err := c.db.Conn().Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
 	// Update with actual values from query
 	for rows.Next() {
 		var service string
@@ -245,14 +247,14 @@ func (c *Calculator) calculateOrphanedExtensions(stats *Stats) error {
 }
 
 func (c *Calculator) calculateOrphanedByAge(stats *Stats) error {
-	// Query to group orphaned files by age
+	// Query to group orphaned files by how long they have been orphaned
 	query := `
 		SELECT
 			CASE
-				WHEN modified_time >= strftime('%s', 'now', '-30 days') THEN 'Last 30 days'
-				WHEN modified_time >= strftime('%s', 'now', '-90 days') THEN '30-90 days ago'
-				WHEN modified_time >= strftime('%s', 'now', '-1 year') THEN '90 days - 1 year ago'
-				ELSE 'Over 1 year ago'
+				WHEN COALESCE(orphaned_since, strftime('%s', 'now')) >= strftime('%s', 'now', '-30 days') THEN 'Under 30 days'
+				WHEN orphaned_since >= strftime('%s', 'now', '-90 days') THEN '30-90 days'
+				WHEN orphaned_since >= strftime('%s', 'now', '-1 year') THEN '90 days - 1 year'
+				ELSE 'Over 1 year'
 			END as age_group,
 			COUNT(*) as count,
 			COALESCE(SUM(size), 0) as total_size
@@ -261,9 +263,9 @@ func (c *Calculator) calculateOrphanedByAge(stats *Stats) error {
 		GROUP BY age_group
 		ORDER BY
 			CASE age_group
-				WHEN 'Last 30 days' THEN 1
-				WHEN '30-90 days ago' THEN 2
-				WHEN '90 days - 1 year ago' THEN 3
+				WHEN 'Under 30 days' THEN 1
+				WHEN '30-90 days' THEN 2
+				WHEN '90 days - 1 year' THEN 3
 				ELSE 4
 			END
 	`
@@ -0,0 +1,208 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Event is a file change reported by a service
+// Paths are as the service sees them and still need translating with the service's path mappings
+type Event struct {
+	Service string   // Service that sent the event, used to pick path mappings
+	Type    string   // Event type as named by the service
+	Paths   []string // Files or directories that were added, changed or deleted
+	Renames []Rename // Files that moved, applied before Paths are rescanned
+}
+
+// Rename is a file a service moved
+type Rename struct {
+	From string
+	To   string
+}
+
+// Empty reports whether the event doesn't affect any files
+func (e *Event) Empty() bool {
+	return len(e.Paths) == 0 && len(e.Renames) == 0
+}
+
+// addPath appends p unless it is empty
+func (e *Event) addPath(p string) {
+	if p != "" {
+		e.Paths = append(e.Paths, p)
+	}
+}
+
+// addRename appends a rename unless either side is missing
+func (e *Event) addRename(from, to string) {
+	if from != "" && to != "" && from != to {
+		e.Renames = append(e.Renames, Rename{From: from, To: to})
+	}
+}
+
+// arrFile is a media file in a Sonarr or Radarr payload
+type arrFile struct {
+	Path         string `json:"path"`
+	RelativePath string `json:"relativePath"`
+	PreviousPath string `json:"previousPath"` // Only set on renamed files
+}
+
+// fullPath returns the file's path, joining the relative path to root if the payload omits it
+func (f arrFile) fullPath(root string) string {
+	if f.Path != "" {
+		return f.Path
+	}
+	if f.RelativePath != "" && root != "" {
+		return path.Join(root, f.RelativePath)
+	}
+	return ""
+}
+
+// deletedFiles decodes the deletedFiles field, which lists replaced files on Download events
+// but is a boolean on SeriesDelete and MovieDelete events
+func deletedFiles(raw json.RawMessage) []arrFile {
+	var files []arrFile
+	if len(raw) > 0 && raw[0] == '[' {
+		_ = json.Unmarshal(raw, &files)
+	}
+	return files
+}
+
+// sonarrPayload is the subset of a Sonarr webhook the rescan needs
+type sonarrPayload struct {
+	EventType string `json:"eventType"`
+	Series    struct {
+		Path string `json:"path"`
+	} `json:"series"`
+	EpisodeFile         *arrFile        `json:"episodeFile"`
+	EpisodeFiles        []arrFile       `json:"episodeFiles"`
+	RenamedEpisodeFiles []arrFile       `json:"renamedEpisodeFiles"`
+	DeletedFiles        json.RawMessage `json:"deletedFiles"`
+}
+
+// ParseSonarr parses a Sonarr webhook
+// Download (including upgrades), Rename, EpisodeFileDelete and SeriesDelete events report files;
+// other events, such as Test and Grab, return an empty event
+func ParseSonarr(body io.Reader) (*Event, error) {
+	var p sonarrPayload
+	if err := json.NewDecoder(body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid Sonarr payload: %w", err)
+	}
+
+	ev := &Event{Service: "sonarr", Type: p.EventType}
+	root := p.Series.Path
+
+	switch p.EventType {
+	case "Download", "EpisodeFileDelete":
+		if p.EpisodeFile != nil {
+			ev.addPath(p.EpisodeFile.fullPath(root))
+		}
+		for _, f := range p.EpisodeFiles {
+			ev.addPath(f.fullPath(root))
+		}
+		// Files replaced by an upgrade
+		for _, f := range deletedFiles(p.DeletedFiles) {
+			ev.addPath(f.fullPath(root))
+		}
+	case "Rename":
+		for _, f := range p.RenamedEpisodeFiles {
+			ev.addRename(f.PreviousPath, f.fullPath(root))
+		}
+	case "SeriesDelete":
+		// Whether or not the files were deleted, Sonarr no longer uses them
+		ev.addPath(root)
+	}
+
+	return ev, nil
+}
+
+// radarrPayload is the subset of a Radarr webhook the rescan needs
+type radarrPayload struct {
+	EventType string `json:"eventType"`
+	Movie     struct {
+		FolderPath string `json:"folderPath"`
+	} `json:"movie"`
+	MovieFile         *arrFile        `json:"movieFile"`
+	RenamedMovieFiles []arrFile       `json:"renamedMovieFiles"`
+	DeletedFiles      json.RawMessage `json:"deletedFiles"`
+}
+
+// ParseRadarr parses a Radarr webhook
+// Download (including upgrades), Rename, MovieFileDelete and MovieDelete events report files;
+// other events, such as Test and Grab, return an empty event
+func ParseRadarr(body io.Reader) (*Event, error) {
+	var p radarrPayload
+	if err := json.NewDecoder(body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("invalid Radarr payload: %w", err)
+	}
+
+	ev := &Event{Service: "radarr", Type: p.EventType}
+	root := p.Movie.FolderPath
+
+	switch p.EventType {
+	case "Download", "MovieFileDelete":
+		if p.MovieFile != nil {
+			ev.addPath(p.MovieFile.fullPath(root))
+		}
+		// Files replaced by an upgrade
+		for _, f := range deletedFiles(p.DeletedFiles) {
+			ev.addPath(f.fullPath(root))
+		}
+	case "Rename":
+		for _, f := range p.RenamedMovieFiles {
+			ev.addRename(f.PreviousPath, f.fullPath(root))
+		}
+	case "MovieDelete":
+		// Whether or not the files were deleted, Radarr no longer uses them
+		ev.addPath(root)
+	}
+
+	return ev, nil
+}
+
+// PlexItem is the library item a Plex webhook refers to
+// Plex webhooks don't include file paths, so the item's files are looked up with the Plex API
+type PlexItem struct {
+	Event     string // Plex event, e.g. "library.new"
+	RatingKey string // Item's metadata key
+	Type      string // Item type: movie, show, season, episode, artist, album, track...
+	Title     string
+}
+
+// plexPayload is the subset of a Plex webhook the rescan needs
+type plexPayload struct {
+	Event    string `json:"event"`
+	Metadata struct {
+		RatingKey string `json:"ratingKey"`
+		Type      string `json:"type"`
+		Title     string `json:"title"`
+	} `json:"Metadata"`
+}
+
+// ParsePlex parses the JSON payload field of a Plex webhook
+// Returns nil for events other than library.new, which don't change which files Plex uses
+func ParsePlex(payload string) (*PlexItem, error) {
+	var p plexPayload
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		return nil, fmt.Errorf("invalid Plex payload: %w", err)
+	}
+	if p.Event != "library.new" || p.Metadata.RatingKey == "" {
+		return nil, nil
+	}
+	return &PlexItem{
+		Event:     p.Event,
+		RatingKey: p.Metadata.RatingKey,
+		Type:      p.Metadata.Type,
+		Title:     p.Metadata.Title,
+	}, nil
+}
+
+// QBittorrentEvent builds the event for a qBittorrent "run external program on torrent finished"
+// call, where contentPath is the torrent's content path (%F): its single file, or its root folder
+func QBittorrentEvent(contentPath string) *Event {
+	ev := &Event{Service: "qbittorrent", Type: "TorrentFinished"}
+	ev.addPath(strings.TrimSpace(contentPath))
+	return ev
+}
@@ -366,13 +366,17 @@ class BatchSelection {
 
         // Check if filesystem deletion is enabled (from global config)
         const deleteFromFilesystem = window.appConfig?.deleteFilesFromFilesystem || false;
+        const quarantine = deleteFromFilesystem && (window.appConfig?.quarantineEnabled || false);
 
         // Build appropriate confirmation message
         let confirmMessage;
         let confirmTitle;
         let confirmType = 'confirm';
 
-        if (deleteFromFilesystem) {
+        if (quarantine) {
+            confirmMessage = `You are about to move <strong>${this.selectedFiles.size} files</strong> to quarantine.\n\nThey can be restored from the Quarantine page until they are purged.\n\nContinue?`;
+            confirmTitle = 'Move Files To Quarantine';
+        } else if (deleteFromFilesystem) {
             confirmMessage = `<strong>Warning:</strong> You are about to permanently delete <strong>${this.selectedFiles.size} files</strong> from the filesystem.\n\nThis will remove the actual files from disk and <strong>cannot be undone</strong>.\n\nAre you absolutely sure?`;
             confirmTitle = 'Delete Files From Filesystem';
             confirmType = 'warning';
@@ -381,6 +385,13 @@ class BatchSelection {
             confirmTitle = 'Remove From Database';
         }
 
+        // Warn before deleting files torrents still reference, cross-seeded files in particular
+        const torrentWarning = this.torrentWarning();
+        if (torrentWarning) {
+            confirmMessage = torrentWarning + confirmMessage;
+            confirmType = 'warning';
+        }
+
         const confirmed = await window.confirmDialog(confirmMessage, confirmTitle, confirmType);
 
         if (!confirmed) {
@@ -426,9 +437,11 @@ class BatchSelection {
             }
 
             // Show appropriate success message
-            const successMsg = deleteFromFilesystem
-                ? `Deleted ${result.deleted} files from filesystem`
-                : `Removed ${result.deleted} files from database`;
+            const successMsg = quarantine
+                ? `Moved ${result.deleted} files to quarantine`
+                : deleteFromFilesystem
+                    ? `Deleted ${result.deleted} files from filesystem`
+                    : `Removed ${result.deleted} files from database`;
 
             // Include failure info if any
             if (result.failed > 0) {
@@ -459,6 +472,28 @@ class BatchSelection {
         }
     }
 
+    /**
+     * Build a warning about selected files that torrents still reference
+     * @returns {string} Warning to prepend to the confirmation, or '' if no torrent references them
+     */
+    torrentWarning() {
+        let referenced = 0;
+        let crossSeeded = 0;
+        this.selectedFiles.forEach(fileId => {
+            const checkbox = document.querySelector(`.file-checkbox[data-file-id="${fileId}"]`);
+            const count = parseInt(checkbox?.closest('tr')?.dataset.torrentCount || '0', 10);
+            if (count > 0) referenced++;
+            if (count > 1) crossSeeded++;
+        });
+
+        if (referenced === 0) return '';
+        let warning = `<strong>Seeding:</strong> torrents still reference <strong>${referenced}</strong> of the selected files`;
+        if (crossSeeded > 0) {
+            warning += `, <strong>${crossSeeded}</strong> of them cross-seeded in several torrents`;
+        }
+        return warning + '.\n\n';
+    }
+
     /**
      * Set loading state with optional message
      * @param {boolean} loading - Whether to show loading state
@@ -94,6 +94,52 @@ class FileDetailsModal {
               `
             : '';
 
+        // Build move history
+        const moveHistory = fileData.moves && fileData.moves.length > 0
+            ? `
+                <div class="border-t border-gray-700 pt-4">
+                    <h4 class="text-sm font-medium text-gray-400 mb-2">Move History</h4>
+                    <div class="space-y-2 max-h-48 overflow-y-auto">
+                        ${fileData.moves.map(move => `
+                            <div class="text-sm p-2 bg-gray-700 rounded">
+                                <div class="text-xs text-gray-400 mb-1">${new Date(move.moved_at).toLocaleString()}</div>
+                                <div class="font-mono text-gray-400 break-all">${move.old_path}</div>
+                                <div class="font-mono text-gray-300 break-all">&rarr; ${move.new_path}</div>
+                            </div>
+                        `).join('')}
+                    </div>
+                </div>
+              `
+            : '';
+
+        // Build usage history timeline
+        const usageHistory = fileData.usage_history && fileData.usage_history.length > 0
+            ? `
+                <div class="border-t border-gray-700 pt-4">
+                    <h4 class="text-sm font-medium text-gray-400 mb-2">Usage History</h4>
+                    <div class="space-y-2 max-h-48 overflow-y-auto border-l-2 border-gray-600 pl-3">
+                        ${fileData.usage_history.map(event => `
+                            <div class="text-sm p-2 bg-gray-700 rounded">
+                                <div class="flex justify-between text-xs text-gray-400 mb-1">
+                                    <span>${new Date(event.created_at).toLocaleString()}</span>
+                                    ${event.scan_id ? `<span>Scan #${event.scan_id}</span>` : ''}
+                                </div>
+                                <div class="text-gray-300">
+                                    ${this.renderUsageEventSummary(event)}
+                                </div>
+                                ${this.renderMetadataDiff(event)}
+                            </div>
+                        `).join('')}
+                    </div>
+                </div>
+              `
+            : '';
+
+        // Build orphan explanation
+        const orphanExplanation = fileData.orphan_explanation
+            ? this.renderOrphanExplanation(fileData.orphan_explanation)
+            : '';
+
         modal.innerHTML = `
             <div class="fixed inset-0 bg-black/75 bg-opacity-50 flex items-center justify-center p-4 animate-fadeIn" onclick="fileDetailsModal.hide()">
                 <div class="bg-gray-800 rounded-lg shadow-xl max-w-3xl w-full max-h-[90vh] overflow-hidden transform transition-all animate-scaleIn" onclick="event.stopPropagation()">
@@ -142,6 +188,12 @@ class FileDetailsModal {
                                         ? '<span class="px-2 py-1 bg-yellow-600 rounded text-xs">Orphaned</span>'
                                         : '<span class="px-2 py-1 bg-green-600 rounded text-xs">In Use</span>'}
                                 </p>
+                                ${fileData.orphaned_since
+                                    ? `<p class="text-xs text-gray-400 mt-1">Since ${this.formatDate(fileData.orphaned_since)}</p>`
+                                    : ''}
+                                ${fileData.last_used_by
+                                    ? `<p class="text-xs text-gray-400 mt-1">Last used by ${fileData.last_used_by.split(',').join(', ')}</p>`
+                                    : ''}
                             </div>
                             <div>
                                 <label class="text-xs text-gray-500 uppercase">Last Verified</label>
@@ -159,6 +211,9 @@ class FileDetailsModal {
 
                         ${metadataSections}
                         ${hardlinkInfo}
+                        ${moveHistory}
+                        ${usageHistory}
+                        ${orphanExplanation}
                     </div>
 
                     <!-- Footer Actions -->
@@ -173,7 +228,7 @@ class FileDetailsModal {
                             </button>
                             <button
                                 hx-delete="/api/files/delete?id=${fileData.id}"
-                                hx-confirm="Are you sure you want to delete this file? This action cannot be undone."
+                                hx-confirm="${fileData.torrent_count > 1 ? `This file is still referenced by ${fileData.torrent_count} torrents (cross-seeds). ` : ''}Are you sure you want to delete this file? This action cannot be undone."
                                 class="px-4 py-2 bg-red-600 hover:bg-red-700 rounded transition flex items-center gap-2">
                                 ${Icons.get('trash', 5)}
                                 <span>Delete File</span>
@@ -218,6 +273,9 @@ class FileDetailsModal {
             if (key === 'size') {
                 // Format size values as human-readable
                 displayValue = this.formatSize(value);
+            } else if (key === 'torrents') {
+                // Cross-seeded torrents sharing this file, rendered as their own rows below
+                return this.renderTorrents(value);
             } else if (Array.isArray(value)) {
                 // Format arrays as badge pills
                 if (value.length === 0) {
@@ -257,6 +315,122 @@ class FileDetailsModal {
         }).join('');
     }
 
+    renderTorrents(torrents) {
+        if (!Array.isArray(torrents) || torrents.length === 0) {
+            return '';
+        }
+
+        return `
+            <div class="pt-2">
+                <span class="text-gray-400 text-sm">Torrents:</span>
+                <div class="mt-1 space-y-1">
+                    ${torrents.map(t => `
+                        <div class="p-2 bg-gray-700 rounded text-xs space-y-1">
+                            <div class="text-gray-200 break-all">${t.torrent_name || t.torrent_hash || 'Unknown torrent'}</div>
+                            <div class="flex flex-wrap gap-1 text-gray-400">
+                                ${t.tracker ? `<span class="px-2 py-0.5 bg-gray-600 rounded-full">${t.tracker}</span>` : ''}
+                                ${t.category ? `<span class="px-2 py-0.5 bg-gray-600 rounded-full">${t.category}</span>` : ''}
+                                ${t.tracker_status ? `<span class="px-2 py-0.5 ${t.tracker_status === 'unregistered' ? 'bg-red-600 text-white' : 'bg-gray-600'} rounded-full">${this.formatMetadataLabel(t.tracker_status)}</span>` : ''}
+                                ${typeof t.ratio === 'number' ? `<span class="px-2 py-0.5 bg-gray-600 rounded-full">Ratio ${t.ratio.toFixed(2)}</span>` : ''}
+                            </div>
+                        </div>
+                    `).join('')}
+                </div>
+            </div>
+        `;
+    }
+
+    renderOrphanExplanation(explanation) {
+        const mapping = m => m ? `<span class="font-mono">${m.service}</span> &harr; <span class="font-mono">${m.local}</span>` : '';
+        const services = explanation.services.length > 0
+            ? explanation.services.map(svc => `
+                <div class="p-3 bg-gray-700 rounded space-y-2">
+                    <div class="flex justify-between items-center gap-2">
+                        <span class="px-2 py-1 bg-service-${svc.service} text-on-service-${svc.service} rounded text-xs">${this.formatServiceName(svc.service)}</span>
+                        ${svc.source === 'snapshot' && svc.listed_at
+                            ? `<span class="text-xs text-gray-400">Listing from ${new Date(svc.listed_at).toLocaleString()}</span>`
+                            : svc.source === 'usage' ? '<span class="text-xs text-gray-400">From usage and missing files</span>' : ''}
+                    </div>
+                    <div class="text-sm text-gray-200">${svc.reason}</div>
+                    <div class="text-xs text-gray-400">
+                        Expected path: <span class="font-mono text-gray-300 break-all">${svc.expected_path}</span>
+                    </div>
+                    <div class="text-xs text-gray-400">
+                        ${svc.mapping
+                            ? `Mapping applied: ${mapping(svc.mapping)}`
+                            : svc.mappings && svc.mappings.length > 0
+                                ? `None of the ${svc.mappings.length} path mapping(s) apply: ${svc.mappings.map(mapping).join(', ')}`
+                                : 'No path mappings configured for this service'}
+                    </div>
+                    ${svc.closest && svc.closest.length > 0 ? `
+                        <div class="space-y-1">
+                            <div class="text-xs text-gray-500 uppercase">Closest reported paths</div>
+                            ${svc.closest.map(m => `
+                                <div class="text-xs p-2 bg-gray-800 rounded">
+                                    <div class="font-mono text-gray-300 break-all">${m.path}</div>
+                                    <div class="font-mono text-gray-500 break-all">&rarr; ${m.translated_path}</div>
+                                    <div class="flex gap-1 mt-1">
+                                        ${m.shared_components > 0 ? '<span class="px-2 py-0.5 bg-gray-600 rounded-full">Same name</span>' : ''}
+                                        ${m.missing ? '<span class="px-2 py-0.5 bg-purple-600 rounded-full">In missing files</span>' : ''}
+                                    </div>
+                                </div>
+                            `).join('')}
+                        </div>
+                    ` : ''}
+                </div>
+              `).join('')
+            : '<span class="text-gray-500 text-sm">No services are configured</span>';
+
+        return `
+            <div class="border-t border-gray-700 pt-4">
+                <h4 class="text-sm font-medium text-gray-400 mb-2">Why Is This File Orphaned?</h4>
+                <div class="text-xs text-gray-400 mb-2">
+                    Container path: <span class="font-mono text-gray-300 break-all">${explanation.container_path}</span>
+                    ${explanation.local_mapping ? `(${mapping(explanation.local_mapping)})` : ''}
+                </div>
+                <div class="space-y-2">
+                    ${services}
+                </div>
+            </div>
+        `;
+    }
+
+    renderUsageEventSummary(event) {
+        const service = this.formatServiceName(event.service);
+        switch (event.event_type) {
+            case 'added':
+                return `<span class="px-2 py-0.5 bg-green-600 rounded text-xs">Started</span> ${service} started using this file`;
+            case 'changed':
+                return `<span class="px-2 py-0.5 bg-blue-600 rounded text-xs">Changed</span> ${service} changed how it uses this file`;
+            default:
+                return `<span class="px-2 py-0.5 bg-red-600 rounded text-xs">Stopped</span> ${service} stopped using this file`;
+        }
+    }
+
+    renderMetadataDiff(event) {
+        // Scalar metadata fields the service added, dropped or changed with the event
+        const isScalar = value => value !== undefined && value !== null && typeof value !== 'object';
+        const fields = Object.entries(event.metadata_diff || {})
+            .filter(([, change]) => isScalar(change.old) || isScalar(change.new))
+            .map(([key, change]) => {
+                if (event.event_type === 'changed') {
+                    const old = isScalar(change.old) ? change.old : 'none';
+                    const value = isScalar(change.new) ? change.new : 'none';
+                    return [key, `${old} → ${value}`];
+                }
+                return [key, event.event_type === 'added' ? change.new : change.old];
+            })
+            .filter(([, value]) => isScalar(value));
+        if (fields.length === 0) {
+            return '';
+        }
+        return `
+            <div class="mt-1 text-xs text-gray-400 space-x-2">
+                ${fields.map(([key, value]) => `<span>${this.formatMetadataLabel(key)}: <span class="text-gray-300">${value}</span></span>`).join('')}
+            </div>
+        `;
+    }
+
     formatMetadataLabel(key) {
         // Convert snake_case to Title Case with proper handling
         return key